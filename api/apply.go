@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+// applyRequest is the body of POST /apply: the whole desired set of services.
+type applyRequest struct {
+	Services []apihub.ServiceSpec `json:"services"`
+}
+
+// applyServices reconciles the storage with the desired set of services in
+// the body. Only the services the token can access are planned, so a token
+// restricted to namespaces never prunes the services of other teams.
+//
+// The changes are made one at a time: when one fails, the ones made before it
+// are kept and the error is reported.
+func (s *ApihubServer) applyServices(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("apply-services")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	opts, err := ParseApplyOptions(r.URL.Query())
+	if err != nil {
+		log.Error("failed-to-parse-options", err)
+		s.handleError(rw, err)
+		return
+	}
+
+	var req applyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed-to-parse-services", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+
+	desired, err := s.desiredServices(r, req.Services)
+	if err != nil {
+		log.Info("invalid-services", lager.Data{"error": err.Error()})
+		s.handleError(rw, err)
+		return
+	}
+
+	stored, err := s.storage.Services()
+	if err != nil {
+		log.Error("failed-to-list-services", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve service list."))
+		return
+	}
+
+	plan, err := planApply(requestToken(r), desired, stored, opts)
+	if err != nil {
+		log.Info("failed-to-plan", lager.Data{"error": err.Error()})
+		s.handleError(rw, err)
+		return
+	}
+
+	if !opts.DryRun {
+		current := map[string]apihub.ServiceSpec{}
+		for _, service := range stored {
+			current[service.Host] = service
+		}
+		for _, change := range plan.Changes {
+			if err := s.applyChange(log, r, change, current[change.Host], desired[change.Host]); err != nil {
+				log.Error("failed-to-apply", err, lager.Data{"action": change.Action, "host": change.Host})
+				s.handleError(rw, storageError(err, fmt.Sprintf("Failed to %s service '%s'.", change.Action, change.Host)))
+				return
+			}
+		}
+	}
+
+	log.Info("services-applied", lager.Data{"dry-run": opts.DryRun, "changes": len(plan.Changes)})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       plan,
+	})
+}
+
+// desiredServices validates the desired services, reporting the invalid
+// fields of all of them at once, and keys them by host.
+func (s *ApihubServer) desiredServices(r *http.Request, services []apihub.ServiceSpec) (map[string]apihub.ServiceSpec, error) {
+	token := requestToken(r)
+	desired := map[string]apihub.ServiceSpec{}
+
+	var v validation
+	for i, spec := range services {
+		prefix := fmt.Sprintf("services[%d].", i)
+		if spec.Namespace == "" && len(token.Namespaces) == 1 {
+			spec.Namespace = token.Namespaces[0]
+		}
+		spec.Version = 0
+
+		err := validateService(spec)
+		if err == nil {
+			err = s.checkNamespace(r, spec)
+		}
+		var apiErr *apihub.Error
+		if errors.As(err, &apiErr) && apiErr.Code == apihub.E_VALIDATION_FAILED {
+			for _, detail := range apiErr.Details {
+				v.add(prefix+detail.Field, detail.Description)
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if _, ok := desired[spec.Host]; ok {
+			v.add(prefix+"host", "Host is repeated.")
+			continue
+		}
+		desired[spec.Host] = spec
+	}
+
+	if err := v.err("The services are invalid."); err != nil {
+		return nil, err
+	}
+	return desired, nil
+}
+
+// planApply compares the desired services with the stored ones the token can
+// access.
+func planApply(token apihub.Token, desired map[string]apihub.ServiceSpec, stored []apihub.ServiceSpec, opts apihub.ApplyOptions) (apihub.ApplyPlan, error) {
+	plan := apihub.ApplyPlan{DryRun: opts.DryRun, Changes: []apihub.ApplyChange{}, Unchanged: []string{}}
+
+	current := map[string]apihub.ServiceSpec{}
+	for _, service := range stored {
+		if _, ok := desired[service.Host]; ok && !token.CanAccess(service.Namespace) {
+			return apihub.ApplyPlan{}, apihub.NewError(apihub.E_CONFLICT, fmt.Sprintf("Host '%s' is already in use.", service.Host))
+		}
+		if token.CanAccess(service.Namespace) {
+			current[service.Host] = service
+		}
+	}
+
+	hosts := []string{}
+	for host := range desired {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		spec := desired[host]
+		service, ok := current[host]
+		if !ok {
+			data, err := json.Marshal(spec)
+			if err != nil {
+				return apihub.ApplyPlan{}, err
+			}
+			plan.Changes = append(plan.Changes, apihub.ApplyChange{Action: apihub.APPLY_CREATE, Host: host, Diff: data})
+			continue
+		}
+
+		spec.Version = service.Version
+		diff, err := specDiff(service, spec)
+		if err != nil {
+			return apihub.ApplyPlan{}, err
+		}
+		if string(diff) == "{}" {
+			plan.Unchanged = append(plan.Unchanged, host)
+		} else {
+			plan.Changes = append(plan.Changes, apihub.ApplyChange{Action: apihub.APPLY_UPDATE, Host: host, Diff: diff})
+		}
+	}
+
+	if opts.Prune {
+		for _, service := range stored {
+			if _, ok := current[service.Host]; !ok {
+				continue
+			}
+			if _, ok := desired[service.Host]; !ok {
+				plan.Changes = append(plan.Changes, apihub.ApplyChange{Action: apihub.APPLY_DELETE, Host: service.Host})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// applyChange makes a planned change, publishing it and recording it in the
+// audit log as the matching change to a single service would be.
+func (s *ApihubServer) applyChange(log lager.Logger, r *http.Request, change apihub.ApplyChange, stored, spec apihub.ServiceSpec) error {
+	current, err := s.storeChange(change, stored, spec)
+	if err != nil {
+		return err
+	}
+	s.announceChange(log, r, change, stored, current)
+	return nil
+}
+
+// storeChange makes a planned change in the storage, returning the service
+// as stored.
+func (s *ApihubServer) storeChange(change apihub.ApplyChange, stored, spec apihub.ServiceSpec) (apihub.ServiceSpec, error) {
+	switch change.Action {
+	case apihub.APPLY_CREATE:
+		spec.Version = 1
+		return spec, s.storage.AddService(spec)
+
+	case apihub.APPLY_UPDATE:
+		// The stored version makes the update fail when the service changed
+		// since it was planned.
+		spec.Version = stored.Version
+		if err := s.storage.UpdateService(spec); err != nil {
+			return apihub.ServiceSpec{}, err
+		}
+		spec.Version++
+		return spec, nil
+
+	case apihub.APPLY_DELETE:
+		return stored, s.storage.RemoveService(stored.Host)
+	}
+	return apihub.ServiceSpec{}, nil
+}
+
+// announceChange publishes a stored change, sends it to the webhooks and
+// records it in the audit log.
+func (s *ApihubServer) announceChange(log lager.Logger, r *http.Request, change apihub.ApplyChange, stored, current apihub.ServiceSpec) {
+	switch change.Action {
+	case apihub.APPLY_CREATE:
+		s.publishService(log, current)
+		s.notifyWebhooks(log, nil, &current)
+		s.recordAudit(log, r, apihub.AUDIT_ADD_SERVICE, current.Host, nil, &current)
+
+	case apihub.APPLY_UPDATE:
+		s.publishService(log, current)
+		s.notifyWebhooks(log, &stored, &current)
+		s.recordAudit(log, r, apihub.AUDIT_REPLACE_SERVICE, current.Host, &stored, &current)
+
+	case apihub.APPLY_DELETE:
+		s.unpublishService(log, stored.Host)
+		s.notifyWebhooks(log, &stored, nil)
+		s.recordAudit(log, r, apihub.AUDIT_REMOVE_SERVICE, stored.Host, &stored, nil)
+	}
+}
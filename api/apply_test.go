@@ -0,0 +1,169 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Apply", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+	)
+
+	apply := func(query, token, body string) (int, []byte) {
+		resp, data := server.Do(http.MethodPost, "/apply"+query, body, tokenHeader(token))
+		return resp.StatusCode, data
+	}
+
+	decodePlan := func(data []byte) apihub.ApplyPlan {
+		var plan apihub.ApplyPlan
+		Expect(json.Unmarshal(data, &plan)).To(Succeed())
+		return plan
+	}
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-apply-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		fakeStorage.ServicesReturns([]apihub.ServiceSpec{
+			apihub.ServiceSpec{Host: "kept", Version: 3, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+			apihub.ServiceSpec{Host: "changed", Version: 2, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+			apihub.ServiceSpec{Host: "unmanaged", Version: 1, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+		}, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	desired := `{"services":[
+		{"host":"new","backends":[{"address":"http://server-a"}]},
+		{"host":"kept","backends":[{"address":"http://server-a"}]},
+		{"host":"changed","backends":[{"address":"http://server-b"}]}
+	]}`
+
+	It("plans the changes without making them on a dry run", func() {
+		code, data := apply("?dry_run=true&prune=true", "", desired)
+		Expect(code).To(Equal(http.StatusOK))
+
+		plan := decodePlan(data)
+		Expect(plan.DryRun).To(BeTrue())
+		Expect(plan.Unchanged).To(Equal([]string{"kept"}))
+		Expect(plan.Changes).To(HaveLen(3))
+		Expect(plan.Changes[0].Action).To(Equal(apihub.APPLY_UPDATE))
+		Expect(plan.Changes[0].Host).To(Equal("changed"))
+		Expect(string(plan.Changes[0].Diff)).To(ContainSubstring(`"address":"http://server-b"`))
+		Expect(plan.Changes[1].Action).To(Equal(apihub.APPLY_CREATE))
+		Expect(plan.Changes[1].Host).To(Equal("new"))
+		Expect(plan.Changes[2].Action).To(Equal(apihub.APPLY_DELETE))
+		Expect(plan.Changes[2].Host).To(Equal("unmanaged"))
+
+		Expect(fakeStorage.AddServiceCallCount()).To(Equal(0))
+		Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		Expect(fakeStorage.RemoveServiceCallCount()).To(Equal(0))
+		Expect(fakeServicePublisher.PublishCallCount()).To(Equal(0))
+	})
+
+	It("creates and updates the services, and publishes them", func() {
+		code, data := apply("", "", desired)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(decodePlan(data).Changes).To(HaveLen(2))
+
+		Expect(fakeStorage.AddServiceCallCount()).To(Equal(1))
+		Expect(fakeStorage.AddServiceArgsForCall(0).Host).To(Equal("new"))
+		Expect(fakeStorage.AddServiceArgsForCall(0).Version).To(Equal(uint64(1)))
+
+		Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(1))
+		updated := fakeStorage.UpdateServiceArgsForCall(0)
+		Expect(updated.Host).To(Equal("changed"))
+		Expect(updated.Version).To(Equal(uint64(2)))
+
+		Expect(fakeStorage.RemoveServiceCallCount()).To(Equal(0))
+		Expect(fakeServicePublisher.PublishCallCount()).To(Equal(2))
+
+		Expect(fakeStorage.AddAuditEventCallCount()).To(Equal(2))
+		Expect(fakeStorage.AddAuditEventArgsForCall(0).Action).To(Equal(apihub.AUDIT_REPLACE_SERVICE))
+		Expect(fakeStorage.AddAuditEventArgsForCall(1).Action).To(Equal(apihub.AUDIT_ADD_SERVICE))
+	})
+
+	It("deletes the services left out when pruning", func() {
+		code, _ := apply("?prune=true", "", desired)
+		Expect(code).To(Equal(http.StatusOK))
+
+		Expect(fakeStorage.RemoveServiceCallCount()).To(Equal(1))
+		Expect(fakeStorage.RemoveServiceArgsForCall(0)).To(Equal("unmanaged"))
+		Expect(fakeServicePublisher.UnpublishCallCount()).To(Equal(1))
+	})
+
+	It("reports the invalid fields of every service", func() {
+		code, data := apply("", "", `{"services":[
+			{"host":"new"},
+			{"host":"kept","backends":[{"address":"http://server-a"}]},
+			{"host":"kept","backends":[{"address":"http://server-a"}]}
+		]}`)
+		Expect(code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(string(data)).To(ContainSubstring(`"field":"services[0].backends"`))
+		Expect(string(data)).To(ContainSubstring(`{"field":"services[2].host","description":"Host is repeated."}`))
+		Expect(fakeStorage.ServicesCallCount()).To(Equal(0))
+	})
+
+	It("rejects invalid options", func() {
+		code, data := apply("?prune=maybe", "", desired)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(data)).To(ContainSubstring("The prune parameter must be true or false."))
+	})
+
+	It("returns an error when a change fails", func() {
+		fakeStorage.UpdateServiceReturns(apihub.ErrVersionConflict)
+
+		code, data := apply("", "", desired)
+		Expect(code).To(Equal(http.StatusConflict))
+		Expect(string(data)).To(ContainSubstring("Failed to update service 'changed'."))
+	})
+
+	Context("when the token is restricted to a namespace", func() {
+		BeforeEach(func() {
+			apihubServer.EnableAuth("admin-secret")
+			fakeStorage.FindTokenByHashReturns(apihub.Token{ID: "search-team", Role: apihub.ROLE_EDITOR, Namespaces: []string{"search"}}, nil)
+			fakeStorage.FindNamespaceReturns(apihub.Namespace{Name: "search"}, nil)
+			fakeStorage.ServicesReturns([]apihub.ServiceSpec{
+				apihub.ServiceSpec{Host: "mine", Namespace: "search", Version: 1, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+				apihub.ServiceSpec{Host: "theirs", Namespace: "payments", Version: 1, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+			}, nil)
+		})
+
+		It("only prunes the services of its namespaces", func() {
+			code, data := apply("?prune=true", "search-secret", `{"services":[]}`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			plan := decodePlan(data)
+			Expect(plan.Changes).To(Equal([]apihub.ApplyChange{
+				apihub.ApplyChange{Action: apihub.APPLY_DELETE, Host: "mine"},
+			}))
+			Expect(fakeStorage.RemoveServiceArgsForCall(0)).To(Equal("mine"))
+		})
+
+		It("cannot take the hosts of other namespaces", func() {
+			code, data := apply("", "search-secret", `{"services":[{"host":"theirs","backends":[{"address":"http://server-a"}]}]}`)
+			Expect(code).To(Equal(http.StatusConflict))
+			Expect(string(data)).To(ContainSubstring("Host 'theirs' is already in use."))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+	})
+})
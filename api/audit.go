@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+const (
+	// REQUEST_ID_HEADER carries the ID of a request, recorded in its audit
+	// events. Requests without one are given a random ID.
+	REQUEST_ID_HEADER = "X-Request-Id"
+	// ANONYMOUS_ACTOR is the actor of the changes made while authentication
+	// is not enabled.
+	ANONYMOUS_ACTOR = "anonymous"
+
+	maxRequestIDLength = 128
+)
+
+// EnableAuditLog writes every audit event to w as well, as a line of JSON.
+// Events are still recorded in the storage, when it keeps them.
+func (s *ApihubServer) EnableAuditLog(w io.Writer) {
+	s.auditMtx.Lock()
+	defer s.auditMtx.Unlock()
+
+	s.auditLog = w
+}
+
+// identify wraps the handler of a route, keeping the ID of the request in
+// its context and echoing it in the response, see requestID.
+func (s *ApihubServer) identify(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(REQUEST_ID_HEADER)
+		if id == "" || len(id) > maxRequestIDLength {
+			var err error
+			if id, err = randomHex(8); err != nil {
+				s.logger.Error("failed-to-generate-request-id", err)
+			}
+		}
+
+		rw.Header().Set(REQUEST_ID_HEADER, id)
+		next(rw, r.WithContext(context.WithValue(r.Context(), requestIDContextKey, id)))
+	}
+}
+
+// requestID returns the ID the request was identified with.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+// recordAudit records a change made to a service by the request. Failing to
+// record it is only logged, as the change has already been made.
+func (s *ApihubServer) recordAudit(log lager.Logger, r *http.Request, action, host string, before, after *apihub.ServiceSpec) {
+	event := apihub.AuditEvent{
+		Time:      time.Now().UTC(),
+		Actor:     requestToken(r).ID,
+		Action:    action,
+		Host:      host,
+		RequestID: requestID(r),
+		Before:    before,
+		After:     after,
+	}
+	if event.Actor == "" {
+		event.Actor = ANONYMOUS_ACTOR
+	}
+
+	var err error
+	if event.ID, err = randomHex(8); err != nil {
+		log.Error("failed-to-generate-audit-id", err)
+		return
+	}
+	if before != nil && after != nil {
+		if event.Diff, err = specDiff(*before, *after); err != nil {
+			log.Error("failed-to-diff-service", err)
+		}
+	}
+
+	if s.audit != nil {
+		if err := s.audit.AddAuditEvent(event); err != nil {
+			log.Error("failed-to-record-audit-event", err, lager.Data{"action": action, "host": host})
+		}
+	}
+
+	s.auditMtx.Lock()
+	defer s.auditMtx.Unlock()
+	if s.auditLog != nil {
+		if err := json.NewEncoder(s.auditLog).Encode(event); err != nil {
+			log.Error("failed-to-write-audit-log", err)
+		}
+	}
+}
+
+func (s *ApihubServer) listAudit(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("list-audit")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	query, err := ParseAuditQuery(r.URL.Query())
+	if err != nil {
+		log.Error("failed-to-parse-query", err)
+		s.handleError(rw, err)
+		return
+	}
+
+	events, err := s.audit.AuditEvents(query)
+	if err != nil {
+		log.Error("failed-to-list-audit-events", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve audit events."))
+		return
+	}
+
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       Collection(events, len(events)),
+	})
+}
+
+// specDiff returns the JSON Merge Patch turning before into after.
+func specDiff(before, after apihub.ServiceSpec) (json.RawMessage, error) {
+	doc, err := toDocument(before)
+	if err != nil {
+		return nil, err
+	}
+	target, err := toDocument(after)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeDiff(doc, target))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
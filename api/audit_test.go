@@ -0,0 +1,190 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Audit", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+		stored       apihub.ServiceSpec
+	)
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-audit-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		stored = apihub.ServiceSpec{
+			Host:     "my-host",
+			Version:  1,
+			Timeout:  10,
+			Labels:   map[string]string{"team": "payments"},
+			Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+		}
+		fakeStorage.FindServiceByHostReturns(stored, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("recording", func() {
+		It("records added services", func() {
+			resp, _ := server.Do(http.MethodPost, "/services", `{"host":"my-host","backends":[{"address":"http://server-a"}]}`, nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+			Expect(fakeStorage.AddAuditEventCallCount()).To(Equal(1))
+			event := fakeStorage.AddAuditEventArgsForCall(0)
+			Expect(event.ID).NotTo(BeEmpty())
+			Expect(event.Time).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(event.Actor).To(Equal(api.ANONYMOUS_ACTOR))
+			Expect(event.Action).To(Equal(apihub.AUDIT_ADD_SERVICE))
+			Expect(event.Host).To(Equal("my-host"))
+			Expect(event.Before).To(BeNil())
+			Expect(event.After.Version).To(BeEquivalentTo(1))
+			Expect(event.Diff).To(BeNil())
+		})
+
+		It("records the diff of updated services", func() {
+			resp, _ := server.Do(http.MethodPatch, "/services/my-host", `{"timeout":20,"disabled":true}`,
+				http.Header{"Content-Type": {api.MERGE_PATCH_CONTENT_TYPE}})
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			event := fakeStorage.AddAuditEventArgsForCall(0)
+			Expect(event.Action).To(Equal(apihub.AUDIT_UPDATE_SERVICE))
+			Expect(*event.Before).To(Equal(stored))
+			Expect(event.After.Timeout).To(BeEquivalentTo(20))
+			Expect(event.Diff).To(MatchJSON(`{"timeout":20,"disabled":true,"version":2}`))
+		})
+
+		It("records removed properties as null", func() {
+			resp, _ := server.Do(http.MethodPut, "/services/my-host", `{"timeout":10,"backends":[{"address":"http://server-a"}]}`, nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			event := fakeStorage.AddAuditEventArgsForCall(0)
+			Expect(event.Action).To(Equal(apihub.AUDIT_REPLACE_SERVICE))
+			Expect(event.Diff).To(MatchJSON(`{"labels":null,"version":2}`))
+		})
+
+		It("records removed services and faults", func() {
+			resp, _ := server.Do(http.MethodPut, "/services/my-host/faults", `[{"header":"X-Chaos","percentage":10,"abort_status":503}]`, nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			resp, _ = server.Do(http.MethodDelete, "/services/my-host", "", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			Expect(fakeStorage.AddAuditEventArgsForCall(0).Action).To(Equal(apihub.AUDIT_SET_SERVICE_FAULTS))
+			removed := fakeStorage.AddAuditEventArgsForCall(1)
+			Expect(removed.Action).To(Equal(apihub.AUDIT_REMOVE_SERVICE))
+			Expect(*removed.Before).To(Equal(stored))
+			Expect(removed.After).To(BeNil())
+		})
+
+		It("does not record failed changes", func() {
+			fakeStorage.UpdateServiceReturns(apihub.ErrVersionConflict)
+			resp, _ := server.Do(http.MethodPatch, "/services/my-host", `{"timeout":20}`, nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusPreconditionFailed))
+			Expect(fakeStorage.AddAuditEventCallCount()).To(Equal(0))
+		})
+
+		It("records the request ID", func() {
+			resp, _ := server.Do(http.MethodDelete, "/services/my-host", "", http.Header{api.REQUEST_ID_HEADER: {"a-request"}})
+			Expect(resp.Header.Get(api.REQUEST_ID_HEADER)).To(Equal("a-request"))
+			Expect(fakeStorage.AddAuditEventArgsForCall(0).RequestID).To(Equal("a-request"))
+
+			resp, _ = server.Do(http.MethodDelete, "/services/my-host", "", nil)
+			Expect(resp.Header.Get(api.REQUEST_ID_HEADER)).To(HaveLen(16))
+			Expect(fakeStorage.AddAuditEventArgsForCall(1).RequestID).To(Equal(resp.Header.Get(api.REQUEST_ID_HEADER)))
+		})
+
+		It("records the token as the actor", func() {
+			apihubServer.EnableAuth("admin-secret")
+			fakeStorage.FindTokenByHashReturns(apihub.Token{ID: "deploys", Role: apihub.ROLE_EDITOR}, nil)
+
+			resp, _ := server.Do(http.MethodDelete, "/services/my-host", "", tokenHeader("deploys-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(fakeStorage.AddAuditEventArgsForCall(0).Actor).To(Equal("deploys"))
+		})
+
+		It("writes the events to the audit log", func() {
+			var buf bytes.Buffer
+			apihubServer.EnableAuditLog(&buf)
+
+			server.Do(http.MethodDelete, "/services/my-host", "", nil)
+			server.Do(http.MethodDelete, "/services/my-host", "", nil)
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			Expect(lines).To(HaveLen(2))
+			var event apihub.AuditEvent
+			Expect(json.Unmarshal(lines[1], &event)).To(Succeed())
+			Expect(event.ID).To(Equal(fakeStorage.AddAuditEventArgsForCall(1).ID))
+			Expect(event.Action).To(Equal(apihub.AUDIT_REMOVE_SERVICE))
+		})
+
+		It("keeps the change when the event cannot be stored", func() {
+			fakeStorage.AddAuditEventReturns(fmt.Errorf("disk full"))
+			resp, _ := server.Do(http.MethodDelete, "/services/my-host", "", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(log).To(gbytes.Say("failed-to-record-audit-event"))
+		})
+	})
+
+	Describe("listAudit", func() {
+		It("filters the events", func() {
+			fakeStorage.AuditEventsReturns([]apihub.AuditEvent{apihub.AuditEvent{ID: "an-event"}}, nil)
+
+			resp, err := http.Get(server.URL + "/audit?host=my-host&actor=deploys&action=remove-service&since=2020-05-01T10:00:00Z&limit=5")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`"item_count":1`))
+			Expect(fakeStorage.AuditEventsArgsForCall(0)).To(Equal(apihub.AuditQuery{
+				Host:   "my-host",
+				Actor:  "deploys",
+				Action: apihub.AUDIT_REMOVE_SERVICE,
+				Since:  time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+				Limit:  5,
+			}))
+		})
+
+		It("rejects an invalid time", func() {
+			resp, err := http.Get(server.URL + "/audit?until=yesterday")
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(fakeStorage.AuditEventsCallCount()).To(Equal(0))
+		})
+
+		It("is restricted to admins", func() {
+			apihubServer.EnableAuth("admin-secret")
+			fakeStorage.FindTokenByHashReturns(apihub.Token{ID: "deploys", Role: apihub.ROLE_EDITOR}, nil)
+
+			resp, _ := server.Do(http.MethodGet, "/audit", "", tokenHeader("deploys-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+})
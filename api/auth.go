@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+// ADMIN_TOKEN_ID identifies the admin token given to EnableAuth in the logs.
+const ADMIN_TOKEN_ID = "admin"
+
+type contextKey int
+
+const (
+	tokenContextKey contextKey = iota
+	requestIDContextKey
+)
+
+// requestToken returns the token the request was authorized with. Requests
+// served without authentication get a token reaching every namespace.
+func requestToken(r *http.Request) apihub.Token {
+	token, _ := r.Context().Value(tokenContextKey).(apihub.Token)
+	return token
+}
+
+// authorize wraps the handler of a route, only calling it when the bearer
+// token of the request has the role of the route. The token is kept in the
+// context of the request, see requestToken.
+func (s *ApihubServer) authorize(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if !s.authEnabled || role == "" {
+			next(rw, r)
+			return
+		}
+		log := s.logger.Session("authorize")
+
+		token, err := s.authenticate(r)
+		if err != nil {
+			log.Info("unauthenticated", lager.Data{"method": r.Method, "path": r.URL.Path, "error": err.Error()})
+			if errors.Is(err, apihub.ErrUnauthorized) {
+				rw.Header().Set("WWW-Authenticate", `Bearer realm="apihub"`)
+			}
+			s.handleError(rw, err)
+			return
+		}
+
+		if !apihub.RoleAllows(token.Role, role) {
+			log.Info("forbidden", lager.Data{"method": r.Method, "path": r.URL.Path, "token": token.ID, "role": token.Role})
+			s.handleError(rw, apihub.NewError(apihub.E_FORBIDDEN, "The token does not allow this operation."))
+			return
+		}
+
+		next(rw, r.WithContext(context.WithValue(r.Context(), tokenContextKey, token)))
+	}
+}
+
+// authenticate returns the token whose secret is in the Authorization header
+// of the request.
+func (s *ApihubServer) authenticate(r *http.Request) (apihub.Token, error) {
+	header := r.Header.Get("Authorization")
+	const scheme = "bearer "
+	if len(header) <= len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) {
+		return apihub.Token{}, apihub.NewError(apihub.E_UNAUTHORIZED, "A bearer token is required.")
+	}
+	hash := apihub.HashToken(strings.TrimSpace(header[len(scheme):]))
+
+	if s.adminTokenHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(s.adminTokenHash)) == 1 {
+		return apihub.Token{ID: ADMIN_TOKEN_ID, Role: apihub.ROLE_ADMIN}, nil
+	}
+
+	if s.tokens == nil {
+		return apihub.Token{}, apihub.NewError(apihub.E_UNAUTHORIZED, "Invalid token.")
+	}
+	token, err := s.tokens.FindTokenByHash(hash)
+	if errors.Is(err, apihub.ErrTokenNotFound) {
+		return apihub.Token{}, apihub.NewError(apihub.E_UNAUTHORIZED, "Invalid token.")
+	} else if err != nil {
+		return apihub.Token{}, apihub.NewError(apihub.E_INTERNAL_ERROR, "Failed to check the token.")
+	}
+	return token, nil
+}
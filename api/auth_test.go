@@ -0,0 +1,119 @@
+package api_test
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Auth", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+	)
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-auth-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		fakeStorage.FindTokenByHashStub = func(hash string) (apihub.Token, error) {
+			for _, token := range []apihub.Token{
+				apihub.Token{ID: "reader", Role: apihub.ROLE_READ_ONLY, Hash: apihub.HashToken("reader-secret")},
+				apihub.Token{ID: "editor", Role: apihub.ROLE_EDITOR, Hash: apihub.HashToken("editor-secret")},
+			} {
+				if token.Hash == hash {
+					return token, nil
+				}
+			}
+			return apihub.Token{}, apihub.ErrTokenNotFound
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("when authentication is not enabled", func() {
+		It("does not require a token", func() {
+			resp, _ := server.Do(http.MethodGet, "/services", "", tokenHeader(""))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeStorage.FindTokenByHashCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when authentication is enabled", func() {
+		BeforeEach(func() {
+			apihubServer.EnableAuth("admin-secret")
+		})
+
+		It("keeps the ping public", func() {
+			resp, _ := server.Do(http.MethodGet, "/ping", "", tokenHeader(""))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("requires a bearer token", func() {
+			resp, body := server.Do(http.MethodGet, "/services", "", tokenHeader(""))
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(resp.Header.Get("WWW-Authenticate")).To(Equal(`Bearer realm="apihub"`))
+			Expect(body).To(ContainSubstring(`{"error":"unauthorized","error_description":"A bearer token is required."}`))
+			Expect(fakeStorage.ListServicesCallCount()).To(Equal(0))
+		})
+
+		It("rejects an unknown token", func() {
+			resp, body := server.Do(http.MethodGet, "/services", "", tokenHeader("unknown-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(body).To(ContainSubstring(`"error_description":"Invalid token."`))
+		})
+
+		It("looks the tokens up by the hash of their secret", func() {
+			resp, _ := server.Do(http.MethodGet, "/services", "", tokenHeader("reader-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeStorage.FindTokenByHashArgsForCall(0)).To(Equal(apihub.HashToken("reader-secret")))
+		})
+
+		It("enforces the role of the route", func() {
+			resp, body := server.Do(http.MethodDelete, "/services/my-host.apihub.dev", "", tokenHeader("reader-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(body).To(ContainSubstring(`{"error":"forbidden","error_description":"The token does not allow this operation."}`))
+			Expect(fakeStorage.RemoveServiceCallCount()).To(Equal(0))
+
+			resp, _ = server.Do(http.MethodDelete, "/services/my-host.apihub.dev", "", tokenHeader("editor-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		})
+
+		It("only lets admins manage the tokens", func() {
+			resp, _ := server.Do(http.MethodGet, "/tokens", "", tokenHeader("editor-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			resp, _ = server.Do(http.MethodGet, "/tokens", "", tokenHeader("admin-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeStorage.FindTokenByHashCallCount()).To(Equal(1))
+		})
+
+		Context("when the storage fails", func() {
+			BeforeEach(func() {
+				fakeStorage.FindTokenByHashStub = nil
+				fakeStorage.FindTokenByHashReturns(apihub.Token{}, apihub.NewError(apihub.E_INTERNAL_ERROR, "connection refused"))
+			})
+
+			It("returns an internal error", func() {
+				resp, body := server.Do(http.MethodGet, "/services", "", tokenHeader("reader-secret"))
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(body).To(ContainSubstring(`"error_description":"Failed to check the token."`))
+			})
+		})
+	})
+})
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+	"github.com/gorilla/mux"
+)
+
+// maxBackendAttempts bounds the attempts to change the backends of a service
+// updated concurrently.
+const maxBackendAttempts = 3
+
+var errBackendNotFound = apihub.NewError(apihub.E_NOT_FOUND, "Backend not found.")
+
+func (s *ApihubServer) listBackends(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("list-backends")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	host := mux.Vars(r)["host"]
+
+	service, err := s.findVisibleService(r, host)
+	if err != nil {
+		log.Error("failed-to-find-service", err, lager.Data{"host": host})
+		s.handleError(rw, storageError(err, "Failed to find service."))
+		return
+	}
+
+	backends := service.Backends
+	if backends == nil {
+		backends = []apihub.BackendInfo{}
+	}
+	rw.Header().Set("ETag", etag(service.Version))
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       Collection(backends, len(backends)),
+	})
+}
+
+func (s *ApihubServer) addBackend(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("add-backend")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	var backend apihub.BackendInfo
+	if err := json.NewDecoder(r.Body).Decode(&backend); err != nil {
+		log.Error("failed-to-parse-backend", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+
+	service, ok := s.changeBackends(log, rw, r, apihub.AUDIT_ADD_BACKEND, func(backends []apihub.BackendInfo) ([]apihub.BackendInfo, error) {
+		if findBackend(backends, backend.Address) != -1 {
+			return nil, apihub.NewError(apihub.E_CONFLICT, fmt.Sprintf("Backend '%s' already exists.", backend.Address))
+		}
+		return append(backends, backend), nil
+	})
+	if !ok {
+		return
+	}
+
+	log.Info("backend-added", lager.Data{"host": service.Host, "backend": backend})
+	rw.Header().Set("ETag", etag(service.Version))
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusCreated,
+		Body:       backend,
+	})
+}
+
+// updateBackend decodes the body over the stored backend, so members it
+// leaves out are kept. The address of the backend cannot be changed.
+func (s *ApihubServer) updateBackend(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("update-backend")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	address, ok := s.backendAddress(log, rw, r)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error("failed-to-read-backend", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+
+	var backend apihub.BackendInfo
+	service, ok := s.changeBackends(log, rw, r, apihub.AUDIT_UPDATE_BACKEND, func(backends []apihub.BackendInfo) ([]apihub.BackendInfo, error) {
+		i := findBackend(backends, address)
+		if i == -1 {
+			return nil, errBackendNotFound
+		}
+		backend = backends[i]
+		if err := json.Unmarshal(body, &backend); err != nil {
+			return nil, errors.New("Failed to parse request.")
+		}
+		backend.Address = address
+		backends[i] = backend
+		return backends, nil
+	})
+	if !ok {
+		return
+	}
+
+	log.Info("backend-updated", lager.Data{"host": service.Host, "backend": backend})
+	rw.Header().Set("ETag", etag(service.Version))
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       backend,
+	})
+}
+
+func (s *ApihubServer) removeBackend(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("remove-backend")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	address, ok := s.backendAddress(log, rw, r)
+	if !ok {
+		return
+	}
+
+	service, ok := s.changeBackends(log, rw, r, apihub.AUDIT_REMOVE_BACKEND, func(backends []apihub.BackendInfo) ([]apihub.BackendInfo, error) {
+		i := findBackend(backends, address)
+		if i == -1 {
+			return nil, errBackendNotFound
+		}
+		return append(backends[:i], backends[i+1:]...), nil
+	})
+	if !ok {
+		return
+	}
+
+	log.Info("backend-removed", lager.Data{"host": service.Host, "address": address})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusNoContent,
+	})
+}
+
+// backendAddress returns the address of the backend in the path of the
+// request, which is escaped as it holds slashes.
+func (s *ApihubServer) backendAddress(log lager.Logger, rw http.ResponseWriter, r *http.Request) (string, bool) {
+	address, err := url.PathUnescape(mux.Vars(r)["address"])
+	if err != nil {
+		log.Error("failed-to-unescape-address", err)
+		s.handleError(rw, errors.New("Failed to parse backend address."))
+		return "", false
+	}
+	return address, true
+}
+
+// changeBackends applies the change to a copy of the backends of the service
+// of the request, then validates, stores and publishes the service. The
+// service is only stored when it is still at the version it was read with;
+// when another update got there first, the change is applied again to the
+// new version, so neither update is lost.
+func (s *ApihubServer) changeBackends(log lager.Logger, rw http.ResponseWriter, r *http.Request, action string, change func([]apihub.BackendInfo) ([]apihub.BackendInfo, error)) (apihub.ServiceSpec, bool) {
+	host := mux.Vars(r)["host"]
+
+	for attempt := 1; ; attempt++ {
+		stored, err := s.findVisibleService(r, host)
+		if err != nil {
+			log.Error("failed-to-find-service", err, lager.Data{"host": host})
+			s.handleError(rw, storageError(err, "Failed to find service."))
+			return apihub.ServiceSpec{}, false
+		}
+
+		service := stored
+		service.Backends, err = change(append([]apihub.BackendInfo{}, stored.Backends...))
+		if err != nil {
+			log.Info("failed-to-change-backends", lager.Data{"host": host, "error": err.Error()})
+			s.handleError(rw, err)
+			return apihub.ServiceSpec{}, false
+		}
+		if err := validateService(service); err != nil {
+			log.Info("invalid-spec", lager.Data{"spec": service, "error": err.Error()})
+			s.handleError(rw, err)
+			return apihub.ServiceSpec{}, false
+		}
+
+		err = s.storage.UpdateService(service)
+		if errors.Is(err, apihub.ErrVersionConflict) && attempt < maxBackendAttempts {
+			log.Info("version-conflict", lager.Data{"host": host, "version": service.Version, "attempt": attempt})
+			continue
+		}
+		if err != nil {
+			log.Error("failed-to-store-service", err)
+			s.handleError(rw, storageError(err, "Failed to update service."))
+			return apihub.ServiceSpec{}, false
+		}
+		service.Version++
+
+		s.publishService(log, service)
+		s.notifyWebhooks(log, &stored, &service)
+
+		s.recordAudit(log, r, action, host, &stored, &service)
+		return service, true
+	}
+}
+
+// findBackend returns the index of the backend with the address, or -1.
+func findBackend(backends []apihub.BackendInfo, address string) int {
+	for i, backend := range backends {
+		if backend.Address == address {
+			return i
+		}
+	}
+	return -1
+}
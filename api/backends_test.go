@@ -0,0 +1,181 @@
+package api_test
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backends", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+		spec         apihub.ServiceSpec
+	)
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-backends-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		spec = apihub.ServiceSpec{
+			Host:    "my-host.apihub.dev",
+			Version: 2,
+			Backends: []apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-a", HeartBeatAddress: "http://server-a/healthcheck"},
+			},
+		}
+		fakeStorage.FindServiceByHostReturns(spec, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("listBackends", func() {
+		It("lists the backends of the service", func() {
+			code, body := server.Request(http.MethodGet, "/services/my-host.apihub.dev/backends", "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"items":[{"address":"http://server-a"`))
+			Expect(body).To(ContainSubstring(`"item_count":1`))
+		})
+	})
+
+	Describe("addBackend", func() {
+		It("adds the backend, and publishes the service", func() {
+			code, body := server.Request(http.MethodPost, "/services/my-host.apihub.dev/backends", `{"address":"http://server-b"}`)
+			Expect(code).To(Equal(http.StatusCreated))
+			Expect(body).To(ContainSubstring(`"address":"http://server-b"`))
+
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(1))
+			stored := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(stored.Version).To(BeEquivalentTo(2))
+			Expect(stored.Backends).To(Equal([]apihub.BackendInfo{spec.Backends[0], apihub.BackendInfo{Address: "http://server-b"}}))
+
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			_, _, published := fakeServicePublisher.PublishArgsForCall(0)
+			Expect(published.Version).To(BeEquivalentTo(3))
+			Expect(published.Backends).To(HaveLen(2))
+
+			Expect(fakeStorage.AddAuditEventCallCount()).To(Equal(1))
+			Expect(fakeStorage.AddAuditEventArgsForCall(0).Action).To(Equal(apihub.AUDIT_ADD_BACKEND))
+		})
+
+		It("applies the change again to a service updated meanwhile", func() {
+			updated := spec
+			updated.Version = 3
+			updated.Timeout = 100
+			fakeStorage.FindServiceByHostStub = func(string) (apihub.ServiceSpec, error) {
+				if fakeStorage.UpdateServiceCallCount() == 0 {
+					return spec, nil
+				}
+				return updated, nil
+			}
+			fakeStorage.UpdateServiceStub = func(service apihub.ServiceSpec) error {
+				if service.Version != 3 {
+					return apihub.ErrVersionConflict
+				}
+				return nil
+			}
+
+			code, _ := server.Request(http.MethodPost, "/services/my-host.apihub.dev/backends", `{"address":"http://server-b"}`)
+			Expect(code).To(Equal(http.StatusCreated))
+
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(2))
+			stored := fakeStorage.UpdateServiceArgsForCall(1)
+			Expect(stored.Timeout).To(BeEquivalentTo(100))
+			Expect(stored.Backends).To(HaveLen(2))
+		})
+
+		It("gives up on a service updated too often", func() {
+			fakeStorage.UpdateServiceReturns(apihub.ErrVersionConflict)
+
+			code, _ := server.Request(http.MethodPost, "/services/my-host.apihub.dev/backends", `{"address":"http://server-b"}`)
+			Expect(code).To(Equal(http.StatusConflict))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(3))
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(0))
+		})
+
+		It("does not add a backend twice", func() {
+			code, body := server.Request(http.MethodPost, "/services/my-host.apihub.dev/backends", `{"address":"http://server-a"}`)
+			Expect(code).To(Equal(http.StatusConflict))
+			Expect(body).To(ContainSubstring("Backend 'http://server-a' already exists."))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+
+		It("reports an invalid backend", func() {
+			code, body := server.Request(http.MethodPost, "/services/my-host.apihub.dev/backends", `{"address":"ftp://server-b"}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(body).To(ContainSubstring(`"field":"backends[1].address"`))
+		})
+
+		It("returns not found for an unknown service", func() {
+			fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, apihub.ErrServiceNotFound)
+
+			code, _ := server.Request(http.MethodPost, "/services/my-host.apihub.dev/backends", `{"address":"http://server-b"}`)
+			Expect(code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("updateBackend", func() {
+		It("updates the members given, found by the escaped address", func() {
+			code, body := server.Request(http.MethodPatch, "/services/my-host.apihub.dev/backends/http:%2F%2Fserver-a", `{"disabled":true,"address":"http://server-z"}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"address":"http://server-a","disabled":true`))
+
+			stored := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(stored.Backends).To(Equal([]apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-a", Disabled: true, HeartBeatAddress: "http://server-a/healthcheck"},
+			}))
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			Expect(fakeStorage.AddAuditEventArgsForCall(0).Action).To(Equal(apihub.AUDIT_UPDATE_BACKEND))
+		})
+
+		It("returns not found for an unknown backend", func() {
+			code, body := server.Request(http.MethodPatch, "/services/my-host.apihub.dev/backends/http:%2F%2Fserver-z", `{"disabled":true}`)
+			Expect(code).To(Equal(http.StatusNotFound))
+			Expect(body).To(ContainSubstring("Backend not found."))
+		})
+	})
+
+	Describe("removeBackend", func() {
+		BeforeEach(func() {
+			spec.Backends = append(spec.Backends, apihub.BackendInfo{Address: "http://server-b"})
+			fakeStorage.FindServiceByHostReturns(spec, nil)
+		})
+
+		It("removes the backend, and publishes the service", func() {
+			code, _ := server.Request(http.MethodDelete, "/services/my-host.apihub.dev/backends/http:%2F%2Fserver-a", "")
+			Expect(code).To(Equal(http.StatusNoContent))
+
+			stored := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(stored.Backends).To(Equal([]apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-b"}}))
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			Expect(fakeStorage.AddAuditEventArgsForCall(0).Action).To(Equal(apihub.AUDIT_REMOVE_BACKEND))
+		})
+
+		It("keeps the last backend of an enabled service", func() {
+			code, _ := server.Request(http.MethodDelete, "/services/my-host.apihub.dev/backends/http:%2F%2Fserver-a", "")
+			Expect(code).To(Equal(http.StatusNoContent))
+
+			spec.Backends = spec.Backends[1:]
+			fakeStorage.FindServiceByHostReturns(spec, nil)
+			code, body := server.Request(http.MethodDelete, "/services/my-host.apihub.dev/backends/http:%2F%2Fserver-b", "")
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(body).To(ContainSubstring(`"field":"backends"`))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(1))
+		})
+	})
+})
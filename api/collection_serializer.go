@@ -3,6 +3,8 @@ package api
 type CollectionSerializer struct {
 	Items interface{} `json:"items"`
 	Count int         `json:"item_count"`
+	// NextCursor continues a paginated collection, see the cursor parameter.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func Collection(data interface{}, count int) *CollectionSerializer {
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/apihub/apihub"
+)
+
+// etag returns the entity tag of a service version.
+func etag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// etagMatches reports whether the If-Match header matches the version, using
+// the strong comparison required by RFC 7232.
+func etagMatches(ifMatch string, version uint64) bool {
+	current := etag(version)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ApihubServer) preconditionFailed(rw http.ResponseWriter) {
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusPreconditionFailed,
+		Body: apihub.ErrorResponse{
+			Error:       apihub.E_PRECONDITION_FAILED,
+			Description: "The service has been modified since it was read.",
+		},
+	})
+}
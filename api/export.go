@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+// exportServices writes the document of the services the token can access,
+// sorted by host.
+func (s *ApihubServer) exportServices(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("export-services")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	stored, err := s.storage.Services()
+	if err != nil {
+		log.Error("failed-to-list-services", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve service list."))
+		return
+	}
+
+	token := requestToken(r)
+	doc := apihub.ExportDocument{
+		Version:    apihub.EXPORT_FORMAT_VERSION,
+		ExportedAt: time.Now().UTC(),
+		Services:   []apihub.ServiceSpec{},
+	}
+	for _, service := range stored {
+		if token.CanAccess(service.Namespace) {
+			doc.Services = append(doc.Services, service)
+		}
+	}
+	sort.Slice(doc.Services, func(i, j int) bool {
+		return doc.Services[i].Host < doc.Services[j].Host
+	})
+
+	log.Info("services-exported", lager.Data{"services": len(doc.Services)})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       doc,
+	})
+}
+
+// importServices imports the services of an exported document. The import
+// is all or nothing: every service is validated before any is stored, and the
+// changes are stored in a single transaction. They are only published and
+// audited once all of them are stored.
+func (s *ApihubServer) importServices(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("import-services")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	opts, err := ParseImportOptions(r.URL.Query())
+	if err != nil {
+		log.Error("failed-to-parse-options", err)
+		s.handleError(rw, err)
+		return
+	}
+
+	var doc apihub.ExportDocument
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		log.Error("failed-to-parse-document", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+	if doc.Version != apihub.EXPORT_FORMAT_VERSION {
+		var v validation
+		v.add("version", fmt.Sprintf("Version must be %d.", apihub.EXPORT_FORMAT_VERSION))
+		s.handleError(rw, v.err("The document is invalid."))
+		return
+	}
+
+	desired, err := s.desiredServices(r, doc.Services)
+	if err != nil {
+		log.Info("invalid-services", lager.Data{"error": err.Error()})
+		s.handleError(rw, err)
+		return
+	}
+
+	stored, err := s.storage.Services()
+	if err != nil {
+		log.Error("failed-to-list-services", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve service list."))
+		return
+	}
+
+	plan, err := planApply(requestToken(r), desired, stored, apihub.ApplyOptions{Prune: opts.Mode == apihub.IMPORT_REPLACE})
+	if err != nil {
+		log.Info("failed-to-plan", lager.Data{"error": err.Error()})
+		s.handleError(rw, err)
+		return
+	}
+
+	report := apihub.ImportReport{Mode: opts.Mode, Changes: []apihub.ApplyChange{}, Skipped: []string{}, Unchanged: plan.Unchanged}
+	for _, change := range plan.Changes {
+		if opts.Mode == apihub.IMPORT_SKIP_EXISTING && change.Action == apihub.APPLY_UPDATE {
+			report.Skipped = append(report.Skipped, change.Host)
+			continue
+		}
+		report.Changes = append(report.Changes, change)
+	}
+
+	previous := map[string]apihub.ServiceSpec{}
+	for _, service := range stored {
+		previous[service.Host] = service
+	}
+	changes, current := importChanges(report.Changes, previous, desired)
+	if err := s.changer.ChangeServices(changes); err != nil {
+		log.Error("failed-to-import", err, lager.Data{"changes": len(changes)})
+		s.handleError(rw, storageError(err, "Failed to import the services. No service was imported."))
+		return
+	}
+
+	for _, change := range report.Changes {
+		s.announceChange(log, r, change, previous[change.Host], current[change.Host])
+	}
+
+	log.Info("services-imported", lager.Data{"mode": opts.Mode, "changes": len(report.Changes)})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       report,
+	})
+}
+
+// importChanges returns the changes to store for the planned ones, and the
+// services as they will be stored once the changes are made.
+func importChanges(planned []apihub.ApplyChange, previous, desired map[string]apihub.ServiceSpec) ([]apihub.ServiceChange, map[string]apihub.ServiceSpec) {
+	changes := []apihub.ServiceChange{}
+	current := map[string]apihub.ServiceSpec{}
+	for _, change := range planned {
+		spec := desired[change.Host]
+		switch change.Action {
+		case apihub.APPLY_CREATE:
+			spec.Version = 1
+			current[change.Host] = spec
+
+		case apihub.APPLY_UPDATE:
+			// The stored version makes the import fail when the service
+			// changed since it was planned.
+			spec.Version = previous[change.Host].Version
+			updated := spec
+			updated.Version++
+			current[change.Host] = updated
+
+		case apihub.APPLY_DELETE:
+			spec = previous[change.Host]
+			current[change.Host] = spec
+		}
+		changes = append(changes, apihub.ServiceChange{Action: change.Action, Spec: spec})
+	}
+	return changes, current
+}
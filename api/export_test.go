@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Export and import", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+	)
+
+	decodeReport := func(data []byte) apihub.ImportReport {
+		var report apihub.ImportReport
+		Expect(json.Unmarshal(data, &report)).To(Succeed())
+		return report
+	}
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-export-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		fakeStorage.ServicesReturns([]apihub.ServiceSpec{
+			apihub.ServiceSpec{Host: "kept", Version: 3, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+			apihub.ServiceSpec{Host: "changed", Version: 2, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+			apihub.ServiceSpec{Host: "unmanaged", Version: 1, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}},
+		}, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	document := `{"version":1,"services":[
+		{"host":"new","backends":[{"address":"http://server-a"}]},
+		{"host":"kept","backends":[{"address":"http://server-a"}]},
+		{"host":"changed","backends":[{"address":"http://server-b"}]}
+	]}`
+
+	Describe("exportServices", func() {
+		It("exports the services sorted by host", func() {
+			code, data := server.Request(http.MethodGet, "/export", "")
+			Expect(code).To(Equal(http.StatusOK))
+
+			var doc apihub.ExportDocument
+			Expect(json.Unmarshal(data, &doc)).To(Succeed())
+			Expect(doc.Version).To(Equal(apihub.EXPORT_FORMAT_VERSION))
+			Expect(doc.ExportedAt).NotTo(BeZero())
+			Expect(doc.Services).To(HaveLen(3))
+			Expect(doc.Services[0].Host).To(Equal("changed"))
+			Expect(doc.Services[1].Host).To(Equal("kept"))
+			Expect(doc.Services[2].Host).To(Equal("unmanaged"))
+		})
+
+		It("only exports the services of the namespaces of the token", func() {
+			apihubServer.EnableAuth("admin-secret")
+			fakeStorage.FindTokenByHashReturns(apihub.Token{ID: "search-team", Role: apihub.ROLE_READ_ONLY, Namespaces: []string{"search"}}, nil)
+
+			resp, data := server.Do(http.MethodGet, "/export", "", tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(data)).To(ContainSubstring(`"services":[]`))
+		})
+	})
+
+	Describe("importServices", func() {
+		It("creates and replaces the services when merging", func() {
+			code, data := server.Request(http.MethodPost, "/import", document)
+			Expect(code).To(Equal(http.StatusOK))
+
+			report := decodeReport(data)
+			Expect(report.Mode).To(Equal(apihub.IMPORT_MERGE))
+			Expect(report.Changes).To(HaveLen(2))
+			Expect(report.Unchanged).To(Equal([]string{"kept"}))
+
+			Expect(fakeStorage.ChangeServicesCallCount()).To(Equal(1))
+			changes := fakeStorage.ChangeServicesArgsForCall(0)
+			Expect(changes).To(HaveLen(2))
+			Expect(changes[0].Action).To(Equal(apihub.APPLY_UPDATE))
+			Expect(changes[0].Spec.Host).To(Equal("changed"))
+			Expect(changes[0].Spec.Version).To(Equal(uint64(2)))
+			Expect(changes[1].Action).To(Equal(apihub.APPLY_CREATE))
+			Expect(changes[1].Spec.Host).To(Equal("new"))
+			Expect(fakeStorage.AddServiceCallCount()).To(Equal(0))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(2))
+			Expect(fakeStorage.AddAuditEventCallCount()).To(Equal(2))
+		})
+
+		It("removes the services left out when replacing", func() {
+			code, data := server.Request(http.MethodPost, "/import?mode=replace", document)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(decodeReport(data).Changes).To(HaveLen(3))
+
+			changes := fakeStorage.ChangeServicesArgsForCall(0)
+			Expect(changes[2].Action).To(Equal(apihub.APPLY_DELETE))
+			Expect(changes[2].Spec.Host).To(Equal("unmanaged"))
+			Expect(fakeServicePublisher.UnpublishCallCount()).To(Equal(1))
+		})
+
+		It("keeps the existing services when skipping them", func() {
+			code, data := server.Request(http.MethodPost, "/import?mode=skip-existing", document)
+			Expect(code).To(Equal(http.StatusOK))
+
+			report := decodeReport(data)
+			Expect(report.Changes).To(HaveLen(1))
+			Expect(report.Changes[0].Host).To(Equal("new"))
+			Expect(report.Skipped).To(Equal([]string{"changed"}))
+			Expect(fakeStorage.ChangeServicesArgsForCall(0)).To(HaveLen(1))
+		})
+
+		It("rejects documents of other versions", func() {
+			code, data := server.Request(http.MethodPost, "/import", `{"version":2,"services":[]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(string(data)).To(ContainSubstring(`{"field":"version","description":"Version must be 1."}`))
+		})
+
+		It("rejects unknown modes", func() {
+			code, data := server.Request(http.MethodPost, "/import?mode=overwrite", document)
+			Expect(code).To(Equal(http.StatusBadRequest))
+			Expect(string(data)).To(ContainSubstring("The mode must be merge, replace or skip-existing."))
+		})
+
+		Context("when storing the changes fails", func() {
+			BeforeEach(func() {
+				fakeStorage.ChangeServicesReturns(apihub.ErrHostInUse)
+			})
+
+			It("neither publishes nor audits any of them", func() {
+				code, data := server.Request(http.MethodPost, "/import", document)
+				Expect(code).To(Equal(http.StatusConflict))
+				Expect(string(data)).To(ContainSubstring("Failed to import the services. No service was imported."))
+
+				Expect(fakeServicePublisher.PublishCallCount()).To(Equal(0))
+				Expect(fakeStorage.AddAuditEventCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+	"github.com/gorilla/mux"
+)
+
+func (s *ApihubServer) setServiceFaults(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("set-service-faults")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	host := mux.Vars(r)["host"]
+
+	var faults []apihub.FaultRule
+	if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+		log.Error("failed-to-parse-faults", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+
+	if err := validateFaults(faults); err != nil {
+		s.handleError(rw, err)
+		return
+	}
+
+	service, ok := s.updateServiceFaults(log, rw, r, apihub.AUDIT_SET_SERVICE_FAULTS, host, faults)
+	if !ok {
+		return
+	}
+
+	log.Info("service-faults-set", lager.Data{"host": host, "faults": faults})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       service,
+	})
+}
+
+func (s *ApihubServer) clearServiceFaults(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("clear-service-faults")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	host := mux.Vars(r)["host"]
+
+	if _, ok := s.updateServiceFaults(log, rw, r, apihub.AUDIT_CLEAR_SERVICE_FAULTS, host, nil); !ok {
+		return
+	}
+
+	log.Info("service-faults-cleared", lager.Data{"host": host})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusNoContent,
+	})
+}
+
+func (s *ApihubServer) updateServiceFaults(log lager.Logger, rw http.ResponseWriter, r *http.Request, action, host string, faults []apihub.FaultRule) (apihub.ServiceSpec, bool) {
+	stored, err := s.findVisibleService(r, host)
+	if err != nil {
+		log.Error("failed-to-find-service", err, lager.Data{"host": host})
+		s.handleError(rw, storageError(err, "Failed to find service."))
+		return apihub.ServiceSpec{}, false
+	}
+
+	service := stored
+	service.Faults = faults
+	if err := s.storage.UpdateService(service); err != nil {
+		log.Error("failed-to-store-service", err)
+		s.handleError(rw, storageError(err, "Failed to update service."))
+		return apihub.ServiceSpec{}, false
+	}
+	service.Version++
+
+	s.publishService(log, service)
+	s.notifyWebhooks(log, &stored, &service)
+
+	s.recordAudit(log, r, action, host, &stored, &service)
+	return service, true
+}
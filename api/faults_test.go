@@ -0,0 +1,164 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/albertoleal/requests"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Faults", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+		httpClient   requests.HTTPClient
+		spec         apihub.ServiceSpec
+	)
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-faults-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+		httpClient = requests.NewHTTPClient(server.URL)
+
+		spec = apihub.ServiceSpec{
+			Host: "my-host.apihub.dev",
+			Backends: []apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-a"},
+			},
+		}
+		fakeStorage.FindServiceByHostReturns(spec, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("setServiceFaults", func() {
+		It("stores and publishes the faults", func() {
+			_, code, body, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusOK,
+				Method:         http.MethodPut,
+				Path:           "/services/my-host.apihub.dev/faults",
+				Body:           `[{"header":"X-Chaos","percentage":25,"delay":100,"abort_status":503}]`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(body)).To(ContainSubstring(`"faults":[{"header":"X-Chaos","percentage":25,"delay":100,"abort_status":503}]`))
+
+			faults := []apihub.FaultRule{
+				apihub.FaultRule{Header: "X-Chaos", Percentage: 25, Delay: 100, AbortStatus: 503},
+			}
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(1))
+			Expect(fakeStorage.UpdateServiceArgsForCall(0).Faults).To(Equal(faults))
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			_, prefix, s := fakeServicePublisher.PublishArgsForCall(0)
+			Expect(prefix).To(Equal(apihub.SERVICES_PREFIX))
+			Expect(s.Faults).To(Equal(faults))
+		})
+
+		Context("when the service is disabled", func() {
+			BeforeEach(func() {
+				spec.Disabled = true
+				fakeStorage.FindServiceByHostReturns(spec, nil)
+			})
+
+			It("does not publish the service", func() {
+				_, _, _, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusOK,
+					Method:         http.MethodPut,
+					Path:           "/services/my-host.apihub.dev/faults",
+					Body:           `[{"header":"X-Chaos","percentage":25}]`,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeServicePublisher.PublishCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when a fault is invalid", func() {
+			It("returns an error", func() {
+				_, code, body, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusUnprocessableEntity,
+					Method:         http.MethodPut,
+					Path:           "/services/my-host.apihub.dev/faults",
+					Body:           `[{"percentage":25}]`,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusUnprocessableEntity))
+				Expect(string(body)).To(ContainSubstring(`{"error":"validation_failed","error_description":"The faults are invalid.","error_details":[{"field":"faults[0].header","description":"Faults must be restricted to a header."}]}`))
+				Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the service does not exist", func() {
+			BeforeEach(func() {
+				fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, apihub.ErrServiceNotFound)
+			})
+
+			It("returns an error", func() {
+				_, code, body, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusNotFound,
+					Method:         http.MethodPut,
+					Path:           "/services/my-host.apihub.dev/faults",
+					Body:           `[]`,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusNotFound))
+				Expect(string(body)).To(ContainSubstring(`{"error":"not_found","error_description":"Failed to find service."}`))
+			})
+		})
+	})
+
+	Describe("clearServiceFaults", func() {
+		BeforeEach(func() {
+			spec.Faults = []apihub.FaultRule{
+				apihub.FaultRule{Header: "X-Chaos", Percentage: 25},
+			}
+			fakeStorage.FindServiceByHostReturns(spec, nil)
+		})
+
+		It("removes the faults of the service", func() {
+			_, code, _, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusNoContent,
+				Method:         http.MethodDelete,
+				Path:           "/services/my-host.apihub.dev/faults",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusNoContent))
+
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(1))
+			Expect(fakeStorage.UpdateServiceArgsForCall(0).Faults).To(BeEmpty())
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+		})
+
+		Context("when storing the service fails", func() {
+			BeforeEach(func() {
+				fakeStorage.UpdateServiceReturns(errors.New("failed to store service."))
+			})
+
+			It("returns an error", func() {
+				_, code, body, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusInternalServerError,
+					Method:         http.MethodDelete,
+					Path:           "/services/my-host.apihub.dev/faults",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusInternalServerError))
+				Expect(string(body)).To(ContainSubstring(`{"error":"internal_error","error_description":"Failed to update service."}`))
+			})
+		})
+	})
+})
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -29,15 +30,31 @@ type response struct {
 	Body       interface{}
 }
 
+// handleError writes err with the status of its code. Errors without a code
+// are caused by the request, and reported as bad requests.
 func (s *ApihubServer) handleError(rw http.ResponseWriter, err error) {
-	switch err.(type) {
-	default:
-		erro := apihub.ErrorResponse{Error: apihub.E_BAD_REQUEST, Description: err.Error()}
-		s.writeResponse(rw, response{
-			StatusCode: http.StatusBadRequest,
-			Body:       erro,
-		})
+	code := apihub.E_BAD_REQUEST
+	var details []apihub.FieldError
+	var apiErr *apihub.Error
+	if errors.As(err, &apiErr) {
+		code = apiErr.Code
+		details = apiErr.Details
 	}
+
+	status, ok := apihub.StatusCodes[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	s.writeResponse(rw, response{
+		StatusCode: status,
+		Body:       apihub.ErrorResponse{Error: code, Description: err.Error(), Details: details},
+	})
+}
+
+// storageError describes a storage failure for the client, keeping the kind of
+// err so it is reported with the right status.
+func storageError(err error, description string) error {
+	return apihub.NewError(apihub.ErrorCode(err), description)
 }
 
 func (s *ApihubServer) writeResponse(rw http.ResponseWriter, resp response) {
@@ -47,3 +64,9 @@ func (s *ApihubServer) writeResponse(rw http.ResponseWriter, resp response) {
 		json.NewEncoder(rw).Encode(resp.Body)
 	}
 }
+
+// notImplemented reports the storage does not support a feature of the API,
+// see apihub.Storage.
+func notImplemented(feature string) error {
+	return apihub.NewError(apihub.E_NOT_IMPLEMENTED, "The storage does not support "+feature+".")
+}
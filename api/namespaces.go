@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+	"github.com/gorilla/mux"
+)
+
+func (s *ApihubServer) addNamespace(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("add-namespace")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	var ns apihub.Namespace
+	if err := json.NewDecoder(r.Body).Decode(&ns); err != nil {
+		log.Error("failed-to-parse-namespace", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+
+	if err := validateNamespace(ns); err != nil {
+		log.Info("invalid-namespace", lager.Data{"namespace": ns, "error": err.Error()})
+		s.handleError(rw, err)
+		return
+	}
+
+	if err := s.namespaces.AddNamespace(ns); err != nil {
+		log.Error("failed-to-store-namespace", err)
+		s.handleError(rw, storageError(err, "Failed to add namespace."))
+		return
+	}
+
+	log.Info("namespace-added", lager.Data{"namespace": ns})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusCreated,
+		Body:       ns,
+	})
+}
+
+func (s *ApihubServer) listNamespaces(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("list-namespaces")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	namespaces, err := s.namespaces.Namespaces()
+	if err != nil {
+		log.Error("failed-to-list-namespaces", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve namespace list."))
+		return
+	}
+
+	token := requestToken(r)
+	visible := []apihub.Namespace{}
+	for _, ns := range namespaces {
+		if token.CanAccess(ns.Name) {
+			visible = append(visible, ns)
+		}
+	}
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       Collection(visible, len(visible)),
+	})
+}
+
+func (s *ApihubServer) findNamespace(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("find-namespace")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	name := mux.Vars(r)["name"]
+
+	ns, err := s.namespaces.FindNamespace(name)
+	if err == nil && !requestToken(r).CanAccess(name) {
+		err = apihub.ErrNamespaceNotFound
+	}
+	if err != nil {
+		log.Error("failed-to-find-namespace", err, lager.Data{"name": name})
+		s.handleError(rw, storageError(err, "Failed to find namespace."))
+		return
+	}
+
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       ns,
+	})
+}
+
+func (s *ApihubServer) removeNamespace(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("remove-namespace")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	name := mux.Vars(r)["name"]
+
+	page, err := s.selectServices(apihub.ServiceQuery{Namespaces: []string{name}, Limit: 1})
+	if err != nil {
+		log.Error("failed-to-list-services", err, lager.Data{"name": name})
+		s.handleError(rw, storageError(err, "Failed to remove namespace."))
+		return
+	}
+	if len(page.Services) > 0 {
+		log.Info("namespace-in-use", lager.Data{"name": name})
+		s.handleError(rw, apihub.NewError(apihub.E_CONFLICT, "The namespace still has services."))
+		return
+	}
+
+	if err := s.namespaces.RemoveNamespace(name); err != nil {
+		log.Error("failed-to-remove-namespace", err, lager.Data{"name": name})
+		s.handleError(rw, storageError(err, "Failed to remove namespace."))
+		return
+	}
+
+	log.Info("namespace-removed", lager.Data{"name": name})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusNoContent,
+	})
+}
+
+func validateNamespace(ns apihub.Namespace) error {
+	var v validation
+	if !validLabel(ns.Name) {
+		v.add("name", "Name must be a valid label, as in RFC 1123.")
+	}
+	for i, suffix := range ns.DomainSuffixes {
+		if desc := validateHost(suffix); desc != "" {
+			v.add(fmt.Sprintf("domain_suffixes[%d]", i), desc)
+		}
+	}
+	return v.err("The namespace is invalid.")
+}
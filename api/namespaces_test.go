@@ -0,0 +1,173 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namespaces", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+	)
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-namespaces-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		namespaces := []apihub.Namespace{
+			apihub.Namespace{Name: "payments", DomainSuffixes: []string{"payments.apihub.dev"}},
+			apihub.Namespace{Name: "search"},
+		}
+		fakeStorage.NamespacesReturns(namespaces, nil)
+		fakeStorage.FindNamespaceStub = func(name string) (apihub.Namespace, error) {
+			for _, ns := range namespaces {
+				if ns.Name == name {
+					return ns, nil
+				}
+			}
+			return apihub.Namespace{}, apihub.ErrNamespaceNotFound
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("addNamespace", func() {
+		It("adds the namespace", func() {
+			code, _ := server.Request(http.MethodPost, "/namespaces", `{"name":"billing","domain_suffixes":["billing.apihub.dev"]}`)
+			Expect(code).To(Equal(http.StatusCreated))
+			Expect(fakeStorage.AddNamespaceArgsForCall(0)).To(Equal(apihub.Namespace{Name: "billing", DomainSuffixes: []string{"billing.apihub.dev"}}))
+		})
+
+		It("rejects an invalid namespace", func() {
+			code, data := server.Request(http.MethodPost, "/namespaces", `{"name":"Billing team","domain_suffixes":["billing_apihub"]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+
+			var resp apihub.ErrorResponse
+			Expect(json.Unmarshal(data, &resp)).To(Succeed())
+			Expect(resp.Details).To(Equal([]apihub.FieldError{
+				apihub.FieldError{Field: "name", Description: "Name must be a valid label, as in RFC 1123."},
+				apihub.FieldError{Field: "domain_suffixes[0]", Description: "Host must be a valid hostname, as in RFC 1123."},
+			}))
+			Expect(fakeStorage.AddNamespaceCallCount()).To(Equal(0))
+		})
+
+		It("returns a conflict when the name is taken", func() {
+			fakeStorage.AddNamespaceReturns(apihub.ErrNamespaceInUse)
+			code, _ := server.Request(http.MethodPost, "/namespaces", `{"name":"payments"}`)
+			Expect(code).To(Equal(http.StatusConflict))
+		})
+	})
+
+	Describe("removeNamespace", func() {
+		It("removes an empty namespace", func() {
+			code, _ := server.Request(http.MethodDelete, "/namespaces/search", "")
+			Expect(code).To(Equal(http.StatusNoContent))
+			Expect(fakeStorage.ListServicesArgsForCall(0).Namespaces).To(Equal([]string{"search"}))
+			Expect(fakeStorage.RemoveNamespaceArgsForCall(0)).To(Equal("search"))
+		})
+
+		It("keeps a namespace with services", func() {
+			fakeStorage.ListServicesReturns(apihub.ServicePage{Services: []apihub.ServiceSpec{apihub.ServiceSpec{Host: "my-host"}}}, nil)
+			code, data := server.Request(http.MethodDelete, "/namespaces/search", "")
+			Expect(code).To(Equal(http.StatusConflict))
+			Expect(string(data)).To(ContainSubstring(`"error_description":"The namespace still has services."`))
+			Expect(fakeStorage.RemoveNamespaceCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("services", func() {
+		It("checks the namespace exists", func() {
+			code, data := server.Request(http.MethodPost, "/services", `{"host":"my-host","namespace":"billing","backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(string(data)).To(ContainSubstring(`{"field":"namespace","description":"Namespace does not exist."}`))
+			Expect(fakeStorage.AddServiceCallCount()).To(Equal(0))
+		})
+
+		It("restricts the hosts to the domains of the namespace", func() {
+			code, data := server.Request(http.MethodPost, "/services", `{"host":"my-host.apihub.dev","namespace":"payments","backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(string(data)).To(ContainSubstring(`{"field":"host","description":"Host must be in the domains of the namespace: payments.apihub.dev."}`))
+
+			code, _ = server.Request(http.MethodPost, "/services", `{"host":"checkout.payments.apihub.dev","namespace":"payments","backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusCreated))
+		})
+	})
+
+	Context("when the token is restricted to a namespace", func() {
+		BeforeEach(func() {
+			apihubServer.EnableAuth("admin-secret")
+			fakeStorage.FindTokenByHashReturns(apihub.Token{ID: "search-team", Role: apihub.ROLE_EDITOR, Namespaces: []string{"search"}}, nil)
+		})
+
+		It("only lists its namespaces", func() {
+			resp, data := server.Do(http.MethodGet, "/namespaces", "", tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(data)).To(Equal(`{"items":[{"name":"search"}],"item_count":1}` + "\n"))
+
+			resp, _ = server.Do(http.MethodGet, "/namespaces/payments", "", tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("only lists the services of its namespaces", func() {
+			resp, _ := server.Do(http.MethodGet, "/services", "", tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeStorage.ListServicesArgsForCall(0).Namespaces).To(Equal([]string{"search"}))
+		})
+
+		It("lists nothing when filtering by other namespaces", func() {
+			resp, data := server.Do(http.MethodGet, "/services?namespace=payments", "", tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(data)).To(ContainSubstring(`"item_count":0`))
+			Expect(fakeStorage.ListServicesCallCount()).To(Equal(0))
+		})
+
+		It("hides the services of other namespaces", func() {
+			fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{Host: "my-host", Namespace: "payments", Version: 1}, nil)
+
+			resp, _ := server.Do(http.MethodGet, "/services/my-host", "", tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+			resp, _ = server.Do(http.MethodDelete, "/services/my-host", "", tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(fakeStorage.RemoveServiceCallCount()).To(Equal(0))
+		})
+
+		It("adds the services to its namespace by default", func() {
+			resp, _ := server.Do(http.MethodPost, "/services", `{"host":"my-host","backends":[{"address":"http://server-a"}]}`, tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(fakeStorage.AddServiceArgsForCall(0).Namespace).To(Equal("search"))
+		})
+
+		It("cannot move services to other namespaces", func() {
+			fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{
+				Host:      "my-host",
+				Namespace: "search",
+				Version:   1,
+				Backends:  []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+			}, nil)
+
+			resp, data := server.Do(http.MethodPatch, "/services/my-host", `{"namespace":"payments"}`, tokenHeader("search-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(string(data)).To(ContainSubstring(`"error_description":"The token does not allow this namespace."`))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,385 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/apihub/apihub"
+)
+
+const (
+	MERGE_PATCH_CONTENT_TYPE = "application/merge-patch+json"
+	JSON_PATCH_CONTENT_TYPE  = "application/json-patch+json"
+)
+
+// PatchOperation is an operation of a JSON Patch, see RFC 6902. Value is
+// kept raw, so an explicit null is told apart from a missing value.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+var errPatchTestFailed = apihub.NewError(apihub.E_CONFLICT, "The test operation of the patch failed.")
+
+// patchService applies the patch in body to the JSON document of spec. The
+// host and version of the result are always the ones of spec.
+func patchService(spec apihub.ServiceSpec, contentType string, body io.Reader) (apihub.ServiceSpec, error) {
+	doc, err := toDocument(spec)
+	if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	switch contentType {
+	case MERGE_PATCH_CONTENT_TYPE:
+		var patch interface{}
+		if err := decodeJSON(body, &patch); err != nil {
+			return apihub.ServiceSpec{}, errors.New("Failed to parse request.")
+		}
+		if _, ok := patch.(map[string]interface{}); !ok {
+			return apihub.ServiceSpec{}, apihub.NewError(apihub.E_VALIDATION_FAILED, "The merge patch must be a JSON object.")
+		}
+		doc = mergePatch(doc, patch)
+
+	case JSON_PATCH_CONTENT_TYPE:
+		var ops []PatchOperation
+		if err := decodeJSON(body, &ops); err != nil {
+			return apihub.ServiceSpec{}, errors.New("Failed to parse request.")
+		}
+		if doc, err = jsonPatch(doc, ops); err != nil {
+			return apihub.ServiceSpec{}, err
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	var patched apihub.ServiceSpec
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return apihub.ServiceSpec{}, apihub.NewError(apihub.E_VALIDATION_FAILED, "The patched service is invalid.")
+	}
+	patched.Host = spec.Host
+	patched.Version = spec.Version
+	return patched, nil
+}
+
+// patchContentType returns the patch format of the request, or an empty
+// string for a plain JSON body.
+func patchContentType(header string) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case MERGE_PATCH_CONTENT_TYPE, JSON_PATCH_CONTENT_TYPE:
+		return mediaType
+	}
+	return ""
+}
+
+func toDocument(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	err = decodeJSON(bytes.NewReader(data), &doc)
+	return doc, err
+}
+
+// decodeJSON keeps the numbers as json.Number, so durations and versions
+// survive the round trip without losing precision.
+func decodeJSON(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// mergePatch applies a JSON Merge Patch, see RFC 7396: objects are merged
+// recursively, null removes a member and any other value replaces it.
+func mergePatch(doc, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	docObj, ok := doc.(map[string]interface{})
+	if !ok {
+		docObj = map[string]interface{}{}
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(docObj, name)
+		} else {
+			docObj[name] = mergePatch(docObj[name], value)
+		}
+	}
+	return docObj
+}
+
+// mergeDiff returns the JSON Merge Patch turning doc into target, the reverse
+// of mergePatch. Arrays and other values are replaced as a whole.
+func mergeDiff(doc, target interface{}) interface{} {
+	docObj, ok := doc.(map[string]interface{})
+	targetObj, targetOk := target.(map[string]interface{})
+	if !ok || !targetOk {
+		return target
+	}
+
+	diff := map[string]interface{}{}
+	for name := range docObj {
+		if _, ok := targetObj[name]; !ok {
+			diff[name] = nil
+		}
+	}
+	for name, value := range targetObj {
+		if old, ok := docObj[name]; !ok || !reflect.DeepEqual(old, value) {
+			diff[name] = mergeDiff(old, value)
+		}
+	}
+	return diff
+}
+
+// jsonPatch applies the operations of a JSON Patch in order, see RFC 6902.
+// The patch is applied to a copy, so doc is left as is when it fails.
+func jsonPatch(doc interface{}, ops []PatchOperation) (interface{}, error) {
+	doc = deepCopy(doc)
+	for i, op := range ops {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			if err == errPatchTestFailed {
+				return nil, err
+			}
+			return nil, apihub.NewError(apihub.E_VALIDATION_FAILED, "Operation "+strconv.Itoa(i)+" of the patch failed: "+err.Error()+".")
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc interface{}, op PatchOperation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, errors.New("missing value")
+		}
+		var value interface{}
+		if err := decodeJSON(bytes.NewReader(op.Value), &value); err != nil {
+			return nil, errors.New("invalid value")
+		}
+
+		if op.Op == "test" {
+			current, err := getPointer(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, errPatchTestFailed
+			}
+			return doc, nil
+		}
+		return setPointer(doc, path, value, op.Op == "add")
+
+	case "remove":
+		return removePointer(doc, path)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getPointer(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, errors.New("cannot move a value into itself")
+			}
+			if doc, err = removePointer(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return setPointer(doc, path, value, true)
+	}
+
+	return nil, errors.New("unknown operation '" + op.Op + "'")
+}
+
+// parsePointer splits a JSON Pointer in its reference tokens, see RFC 6901.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("invalid path '" + pointer + "'")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func getPointer(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, errors.New("path not found")
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, errors.New("path not found")
+		}
+	}
+	return doc, nil
+}
+
+// setPointer adds or replaces the value at path, returning the new document.
+func setPointer(doc interface{}, path []string, value interface{}, add bool) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return updateParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok && !add {
+				return nil, errors.New("path not found")
+			}
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			if add {
+				if token == "-" {
+					return append(node, value), nil
+				}
+				i, err := arrayIndex(token, len(node))
+				if err != nil {
+					return nil, err
+				}
+				node = append(node, nil)
+				copy(node[i+1:], node[i:])
+				node[i] = value
+				return node, nil
+			}
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = value
+			return node, nil
+		}
+		return nil, errors.New("path not found")
+	})
+}
+
+func removePointer(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+
+	return updateParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, errors.New("path not found")
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, errors.New("path not found")
+	})
+}
+
+// updateParent walks to the parent of the last token of path and replaces it
+// with the result of fn, as arrays change when growing or shrinking.
+func updateParent(doc interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, errors.New("path not found")
+		}
+		updated, err := updateParent(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = updated
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := updateParent(node[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = updated
+		return node, nil
+	}
+	return nil, errors.New("path not found")
+}
+
+// arrayIndex parses an array index token, no greater than max.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.New("invalid array index '" + token + "'")
+	}
+	return i, nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			c[name] = deepCopy(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = deepCopy(item)
+		}
+		return c
+	}
+	return value
+}
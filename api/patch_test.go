@@ -0,0 +1,189 @@
+package api_test
+
+import (
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Patch", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+		spec         apihub.ServiceSpec
+	)
+
+	patch := func(contentType string, body string) (int, string) {
+		resp, data := server.Do(http.MethodPatch, "/services/my-host.apihub.dev", body, http.Header{"Content-Type": {contentType}})
+		return resp.StatusCode, string(data)
+	}
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-patch-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		spec = apihub.ServiceSpec{
+			Host:     "my-host.apihub.dev",
+			Version:  2,
+			Disabled: true,
+			Timeout:  500 * time.Millisecond,
+			Backends: []apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-a"},
+				apihub.BackendInfo{Address: "http://server-b"},
+			},
+			Labels: map[string]string{"team": "payments"},
+		}
+		fakeStorage.FindServiceByHostReturns(spec, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("JSON Merge Patch", func() {
+		It("changes only the members in the patch", func() {
+			code, _ := patch(api.MERGE_PATCH_CONTENT_TYPE, `{"disabled":false}`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			updated := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(updated.Disabled).To(BeFalse())
+			Expect(updated.Timeout).To(Equal(spec.Timeout))
+			Expect(updated.Backends).To(Equal(spec.Backends))
+			Expect(updated.Version).To(BeEquivalentTo(2))
+		})
+
+		It("removes the members set to null", func() {
+			code, _ := patch(api.MERGE_PATCH_CONTENT_TYPE, `{"backends":null,"labels":{"team":null,"tier":"gold"}}`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			updated := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(updated.Backends).To(BeEmpty())
+			Expect(updated.Labels).To(Equal(map[string]string{"tier": "gold"}))
+		})
+
+		It("does not change the host", func() {
+			code, _ := patch(api.MERGE_PATCH_CONTENT_TYPE, `{"host":"another-host.apihub.dev","version":10}`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			updated := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(updated.Host).To(Equal("my-host.apihub.dev"))
+			Expect(updated.Version).To(BeEquivalentTo(2))
+		})
+
+		It("accepts the media type with parameters", func() {
+			code, _ := patch(api.MERGE_PATCH_CONTENT_TYPE+"; charset=utf-8", `{"disabled":false}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(fakeStorage.UpdateServiceArgsForCall(0).Disabled).To(BeFalse())
+		})
+
+		It("rejects a patch that is not an object", func() {
+			code, body := patch(api.MERGE_PATCH_CONTENT_TYPE, `[]`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(body).To(ContainSubstring(`"error":"validation_failed"`))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+
+		It("rejects a patch resulting in an invalid service", func() {
+			code, _ := patch(api.MERGE_PATCH_CONTENT_TYPE, `{"timeout":"soon"}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+		})
+
+		It("rejects an invalid JSON", func() {
+			code, body := patch(api.MERGE_PATCH_CONTENT_TYPE, `not-a-json`)
+			Expect(code).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring(`"error_description":"Failed to parse request."`))
+		})
+	})
+
+	Describe("JSON Patch", func() {
+		It("applies the operations in order", func() {
+			code, body := patch(api.JSON_PATCH_CONTENT_TYPE, `[
+				{"op":"test","path":"/disabled","value":true},
+				{"op":"replace","path":"/disabled","value":false},
+				{"op":"remove","path":"/backends/0"},
+				{"op":"add","path":"/backends/-","value":{"address":"http://server-c"}},
+				{"op":"copy","from":"/labels/team","path":"/labels/owner"},
+				{"op":"move","from":"/labels/team","path":"/labels/~1team"}
+			]`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"version":3`))
+
+			updated := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(updated.Disabled).To(BeFalse())
+			Expect(updated.Timeout).To(Equal(spec.Timeout))
+			Expect(updated.Backends).To(Equal([]apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-b"},
+				apihub.BackendInfo{Address: "http://server-c"},
+			}))
+			Expect(updated.Labels).To(Equal(map[string]string{"owner": "payments", "/team": "payments"}))
+		})
+
+		It("inserts in arrays at the index", func() {
+			code, _ := patch(api.JSON_PATCH_CONTENT_TYPE, `[{"op":"add","path":"/backends/0","value":{"address":"http://server-c"}}]`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			backends := fakeStorage.UpdateServiceArgsForCall(0).Backends
+			Expect(backends).To(HaveLen(3))
+			Expect(backends[0].Address).To(Equal("http://server-c"))
+			Expect(backends[1].Address).To(Equal("http://server-a"))
+		})
+
+		It("sets a member to false", func() {
+			code, _ := patch(api.JSON_PATCH_CONTENT_TYPE, `[{"op":"add","path":"/disabled","value":false}]`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(fakeStorage.UpdateServiceArgsForCall(0).Disabled).To(BeFalse())
+		})
+
+		It("does not apply the patch when a test fails", func() {
+			code, body := patch(api.JSON_PATCH_CONTENT_TYPE, `[
+				{"op":"replace","path":"/disabled","value":false},
+				{"op":"test","path":"/timeout","value":1}
+			]`)
+			Expect(code).To(Equal(http.StatusConflict))
+			Expect(body).To(ContainSubstring(`{"error":"conflict","error_description":"The test operation of the patch failed."}`))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+
+		It("rejects an operation on a missing path", func() {
+			code, body := patch(api.JSON_PATCH_CONTENT_TYPE, `[{"op":"replace","path":"/maintenance/enabled","value":true}]`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(body).To(ContainSubstring(`"error_description":"Operation 0 of the patch failed: path not found."`))
+		})
+
+		It("rejects an index out of range", func() {
+			code, _ := patch(api.JSON_PATCH_CONTENT_TYPE, `[{"op":"remove","path":"/backends/2"}]`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+		})
+
+		It("rejects an unknown operation", func() {
+			code, body := patch(api.JSON_PATCH_CONTENT_TYPE, `[{"op":"merge","path":"/disabled","value":false}]`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(body).To(ContainSubstring(`unknown operation 'merge'`))
+		})
+
+		It("rejects an operation without value", func() {
+			code, body := patch(api.JSON_PATCH_CONTENT_TYPE, `[{"op":"replace","path":"/disabled"}]`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(body).To(ContainSubstring(`missing value`))
+		})
+
+		It("rejects a patch that is not an array", func() {
+			code, _ := patch(api.JSON_PATCH_CONTENT_TYPE, `{"op":"remove","path":"/disabled"}`)
+			Expect(code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
@@ -9,6 +9,10 @@ import (
 	"github.com/hashicorp/consul/api"
 )
 
+// Publisher writes the services to Consul, each in a key made of the prefix
+// and its host, where the gateways subscribed to the prefix read them. The
+// key holds a copy of the spec the API stored: with the consul storage both
+// live in Consul, under prefixes of their own.
 type Publisher struct {
 	client *api.Client
 }
@@ -32,7 +36,7 @@ func (p *Publisher) Publish(logger lager.Logger, prefix string, serviceSpec apih
 		return err
 	}
 
-	kvp := &api.KVPair{Key: fmt.Sprintf("%s%s", apihub.SERVICES_PREFIX, serviceSpec.Host), Value: spec}
+	kvp := &api.KVPair{Key: fmt.Sprintf("%s%s", prefix, serviceSpec.Host), Value: spec}
 	_, err = p.client.KV().Put(kvp, nil)
 	log.Info("published")
 	return err
@@ -54,7 +58,7 @@ func (p *Publisher) Unpublish(logger lager.Logger, prefix string, host string) e
 		log.Error("failed-to-marshal-service-data", err)
 		return err
 	}
-	key := fmt.Sprintf("%s%s", apihub.SERVICES_PREFIX, spec.Host)
+	key := fmt.Sprintf("%s%s", prefix, spec.Host)
 	kvp := &api.KVPair{Key: key, Value: serviceSpec}
 	_, err = p.client.KV().Put(kvp, nil)
 	if err != nil {
@@ -66,3 +70,33 @@ func (p *Publisher) Unpublish(logger lager.Logger, prefix string, host string) e
 	log.Info("unpublished")
 	return err
 }
+
+// Published lists the services published under the prefix, which must end
+// with a separator, see apihub.CheckServicesPrefix.
+func (p *Publisher) Published(logger lager.Logger, prefix string) ([]apihub.ServiceSpec, error) {
+	log := logger.Session("publisher-published")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	if err := apihub.CheckServicesPrefix(prefix); err != nil {
+		log.Error("invalid-prefix", err)
+		return nil, err
+	}
+
+	pairs, _, err := p.client.KV().List(prefix, nil)
+	if err != nil {
+		log.Error("failed-to-list-services", err)
+		return nil, err
+	}
+
+	specs := []apihub.ServiceSpec{}
+	for _, kvp := range pairs {
+		var spec apihub.ServiceSpec
+		if err := json.Unmarshal(kvp.Value, &spec); err != nil {
+			log.Error("failed-to-unmarshal-service-data", err, lager.Data{"key": kvp.Key})
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
@@ -2,6 +2,7 @@ package publisher_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"code.cloudfoundry.org/lager/lagertest"
@@ -50,6 +51,24 @@ var _ = Describe("Publisher", func() {
 		})
 	})
 
+	Context("when given another prefix", func() {
+		It("publishes and unpublishes the service under it", func() {
+			Expect(pub.Publish(logger, "apihub/prod/services/", spec)).To(Succeed())
+
+			kvp, _, err := consulClient.KV().Get("apihub/prod/services/"+spec.Host, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp).NotTo(BeNil())
+			kvp, _, err = consulClient.KV().Get(apihub.SERVICES_PREFIX+spec.Host, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp).To(BeNil())
+
+			Expect(pub.Unpublish(logger, "apihub/prod/services/", spec.Host)).To(Succeed())
+			kvp, _, err = consulClient.KV().Get("apihub/prod/services/"+spec.Host, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp).To(BeNil())
+		})
+	})
+
 	Describe("Unpublish", func() {
 		It("unpublishes a service", func() {
 			Expect(pub.Publish(logger, apihub.SERVICES_PREFIX, spec)).To(Succeed())
@@ -67,4 +86,34 @@ var _ = Describe("Publisher", func() {
 			})
 		})
 	})
+
+	Describe("Published", func() {
+		It("lists the published services", func() {
+			Expect(pub.Publish(logger, apihub.SERVICES_PREFIX, spec)).To(Succeed())
+
+			specs, err := pub.Published(logger, apihub.SERVICES_PREFIX)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(specs).To(ContainElement(spec))
+
+			Expect(pub.Unpublish(logger, apihub.SERVICES_PREFIX, spec.Host)).To(Succeed())
+			specs, err = pub.Published(logger, apihub.SERVICES_PREFIX)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(specs).NotTo(ContainElement(spec))
+		})
+
+		It("does not list the services of a prefix starting with its own", func() {
+			stagingSpec := apihub.ServiceSpec{Host: "staging-host"}
+			Expect(pub.Publish(logger, "apihub/prod/", spec)).To(Succeed())
+			Expect(pub.Publish(logger, "apihub/prod-staging/", stagingSpec)).To(Succeed())
+
+			specs, err := pub.Published(logger, "apihub/prod/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(specs).To(Equal([]apihub.ServiceSpec{spec}))
+		})
+
+		It("rejects a prefix without a trailing separator", func() {
+			_, err := pub.Published(logger, "apihub/prod")
+			Expect(errors.Is(err, apihub.ErrValidation)).To(BeTrue())
+		})
+	})
 })
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apihub/apihub"
+)
+
+const (
+	DEFAULT_PAGE_LIMIT = 100
+	MAX_PAGE_LIMIT     = 1000
+)
+
+// ParseServiceQuery reads the query of GET /services:
+//
+//	limit, cursor        pagination, see apihub.ServiceQuery
+//	host_prefix          services whose host starts with it
+//	disabled             true or false
+//	backend              services with a backend at the address
+//	label                name=value, repeated for every label to match
+//	namespace            services in the namespace, repeated for several
+//	sort                 host, version, -host or -version
+func ParseServiceQuery(values url.Values) (apihub.ServiceQuery, error) {
+	query := apihub.ServiceQuery{
+		HostPrefix: values.Get("host_prefix"),
+		Backend:    values.Get("backend"),
+		Namespaces: values["namespace"],
+		Sort:       values.Get("sort"),
+		Cursor:     values.Get("cursor"),
+		Limit:      DEFAULT_PAGE_LIMIT,
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MAX_PAGE_LIMIT {
+			return apihub.ServiceQuery{}, errors.New("The limit must be between 1 and " + strconv.Itoa(MAX_PAGE_LIMIT) + ".")
+		}
+		query.Limit = n
+	}
+
+	if disabled := values.Get("disabled"); disabled != "" {
+		d, err := strconv.ParseBool(disabled)
+		if err != nil {
+			return apihub.ServiceQuery{}, errors.New("Disabled must be true or false.")
+		}
+		query.Disabled = &d
+	}
+
+	for _, label := range values["label"] {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return apihub.ServiceQuery{}, errors.New("Labels must be given as name=value.")
+		}
+		if query.Labels == nil {
+			query.Labels = map[string]string{}
+		}
+		query.Labels[parts[0]] = parts[1]
+	}
+
+	if err := query.Validate(); err != nil {
+		return apihub.ServiceQuery{}, err
+	}
+	return query, nil
+}
+
+// ParseAuditQuery reads the query of GET /audit:
+//
+//	limit                number of events, newest first
+//	host                 events of the service with the host
+//	actor                events of the token with the ID
+//	action               events of the action, as add-service
+//	since, until         time bounds of the events, as in RFC 3339
+func ParseAuditQuery(values url.Values) (apihub.AuditQuery, error) {
+	query := apihub.AuditQuery{
+		Host:   values.Get("host"),
+		Actor:  values.Get("actor"),
+		Action: values.Get("action"),
+		Limit:  DEFAULT_PAGE_LIMIT,
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MAX_PAGE_LIMIT {
+			return apihub.AuditQuery{}, errors.New("The limit must be between 1 and " + strconv.Itoa(MAX_PAGE_LIMIT) + ".")
+		}
+		query.Limit = n
+	}
+
+	bounds := []struct {
+		name string
+		t    *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}}
+	for _, bound := range bounds {
+		if value := values.Get(bound.name); value != "" {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return apihub.AuditQuery{}, errors.New("The " + bound.name + " time must be in the RFC 3339 format.")
+			}
+			*bound.t = t
+		}
+	}
+	return query, nil
+}
+
+// AuditQueryValues encodes the query for GET /audit, the reverse of
+// ParseAuditQuery.
+func AuditQueryValues(query apihub.AuditQuery) url.Values {
+	values := url.Values{}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Host != "" {
+		values.Set("host", query.Host)
+	}
+	if query.Actor != "" {
+		values.Set("actor", query.Actor)
+	}
+	if query.Action != "" {
+		values.Set("action", query.Action)
+	}
+	if !query.Since.IsZero() {
+		values.Set("since", query.Since.Format(time.RFC3339Nano))
+	}
+	if !query.Until.IsZero() {
+		values.Set("until", query.Until.Format(time.RFC3339Nano))
+	}
+	return values
+}
+
+// ParseApplyOptions reads the query of POST /apply: dry_run and prune, both
+// true or false.
+func ParseApplyOptions(values url.Values) (apihub.ApplyOptions, error) {
+	var opts apihub.ApplyOptions
+	flags := []struct {
+		name string
+		b    *bool
+	}{{"dry_run", &opts.DryRun}, {"prune", &opts.Prune}}
+	for _, flag := range flags {
+		if value := values.Get(flag.name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return apihub.ApplyOptions{}, errors.New("The " + flag.name + " parameter must be true or false.")
+			}
+			*flag.b = b
+		}
+	}
+	return opts, nil
+}
+
+// ApplyOptionsValues encodes the options for POST /apply, the reverse of
+// ParseApplyOptions.
+func ApplyOptionsValues(opts apihub.ApplyOptions) url.Values {
+	values := url.Values{}
+	if opts.DryRun {
+		values.Set("dry_run", "true")
+	}
+	if opts.Prune {
+		values.Set("prune", "true")
+	}
+	return values
+}
+
+// ServiceQueryValues encodes the query for GET /services, the reverse of
+// ParseServiceQuery.
+func ServiceQueryValues(query apihub.ServiceQuery) url.Values {
+	values := url.Values{}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Cursor != "" {
+		values.Set("cursor", query.Cursor)
+	}
+	if query.HostPrefix != "" {
+		values.Set("host_prefix", query.HostPrefix)
+	}
+	if query.Disabled != nil {
+		values.Set("disabled", strconv.FormatBool(*query.Disabled))
+	}
+	if query.Backend != "" {
+		values.Set("backend", query.Backend)
+	}
+	for name, value := range query.Labels {
+		values.Add("label", name+"="+value)
+	}
+	for _, ns := range query.Namespaces {
+		values.Add("namespace", ns)
+	}
+	if query.Sort != "" {
+		values.Set("sort", query.Sort)
+	}
+	return values
+}
+
+// ParseWatchQuery reads the watch parameters of GET /services: watch, true
+// or false, and resource_version, the version to resume the watch from. The
+// other parameters select the services to watch, except for the pagination
+// and sort ones.
+func ParseWatchQuery(values url.Values) (bool, uint64, error) {
+	var watch bool
+	if value := values.Get("watch"); value != "" {
+		w, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, 0, errors.New("The watch parameter must be true or false.")
+		}
+		watch = w
+	}
+
+	var resourceVersion uint64
+	if value := values.Get("resource_version"); value != "" {
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return false, 0, errors.New("The resource version must be a positive number.")
+		}
+		resourceVersion = v
+	}
+	return watch, resourceVersion, nil
+}
+
+// WatchQueryValues encodes the query to watch the services from the
+// resource version, the reverse of ParseWatchQuery.
+func WatchQueryValues(query apihub.ServiceQuery, resourceVersion uint64) url.Values {
+	values := ServiceQueryValues(query)
+	values.Set("watch", "true")
+	if resourceVersion != 0 {
+		values.Set("resource_version", strconv.FormatUint(resourceVersion, 10))
+	}
+	return values
+}
+
+// ParseImportOptions reads the query of POST /import: the mode, merge by
+// default, see apihub.ImportOptions.
+func ParseImportOptions(values url.Values) (apihub.ImportOptions, error) {
+	opts := apihub.ImportOptions{Mode: apihub.IMPORT_MERGE}
+	if mode := values.Get("mode"); mode != "" {
+		switch mode {
+		case apihub.IMPORT_MERGE, apihub.IMPORT_REPLACE, apihub.IMPORT_SKIP_EXISTING:
+			opts.Mode = mode
+		default:
+			return apihub.ImportOptions{}, errors.New("The mode must be merge, replace or skip-existing.")
+		}
+	}
+	return opts, nil
+}
+
+// ImportOptionsValues encodes the options for POST /import, the reverse of
+// ParseImportOptions.
+func ImportOptionsValues(opts apihub.ImportOptions) url.Values {
+	values := url.Values{}
+	if opts.Mode != "" {
+		values.Set("mode", opts.Mode)
+	}
+	return values
+}
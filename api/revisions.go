@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+	"github.com/gorilla/mux"
+)
+
+func (s *ApihubServer) listRevisions(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("list-revisions")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	host := mux.Vars(r)["host"]
+
+	if _, err := s.findVisibleService(r, host); err != nil {
+		log.Error("failed-to-find-service", err, lager.Data{"host": host})
+		s.handleError(rw, storageError(err, "Failed to find service."))
+		return
+	}
+
+	revisions, err := s.revisions.Revisions(host)
+	if err != nil {
+		log.Error("failed-to-list-revisions", err, lager.Data{"host": host})
+		s.handleError(rw, storageError(err, "Failed to retrieve revision list."))
+		return
+	}
+
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       Collection(revisions, len(revisions)),
+	})
+}
+
+// diffRevisions compares the revisions given by the from and to parameters.
+// to defaults to the current version of the service.
+func (s *ApihubServer) diffRevisions(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("diff-revisions")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	host := mux.Vars(r)["host"]
+
+	service, err := s.findVisibleService(r, host)
+	if err != nil {
+		log.Error("failed-to-find-service", err, lager.Data{"host": host})
+		s.handleError(rw, storageError(err, "Failed to find service."))
+		return
+	}
+
+	from, err := revisionParam(r.URL.Query(), "from", 0)
+	if err != nil {
+		s.handleError(rw, err)
+		return
+	}
+	to, err := revisionParam(r.URL.Query(), "to", service.Version)
+	if err != nil {
+		s.handleError(rw, err)
+		return
+	}
+
+	specs := []apihub.ServiceSpec{}
+	for _, version := range []uint64{from, to} {
+		revision, err := s.revisions.FindRevision(host, version)
+		if err != nil {
+			log.Error("failed-to-find-revision", err, lager.Data{"host": host, "version": version})
+			s.handleError(rw, storageError(err, "Revision "+strconv.FormatUint(version, 10)+" not found."))
+			return
+		}
+		specs = append(specs, revision.Spec)
+	}
+
+	diff, err := specDiff(specs[0], specs[1])
+	if err != nil {
+		log.Error("failed-to-diff-revisions", err)
+		s.handleError(rw, apihub.NewError(apihub.E_INTERNAL_ERROR, "Failed to compare the revisions."))
+		return
+	}
+
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       apihub.RevisionDiff{From: from, To: to, Diff: diff},
+	})
+}
+
+// rollbackService stores the revision given by the to parameter as a new
+// version of the service, and publishes it.
+func (s *ApihubServer) rollbackService(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("rollback-service")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	stored, ok := s.serviceToUpdate(log, rw, r)
+	if !ok {
+		return
+	}
+
+	to, err := revisionParam(r.URL.Query(), "to", 0)
+	if err != nil {
+		s.handleError(rw, err)
+		return
+	}
+
+	revision, err := s.revisions.FindRevision(stored.Host, to)
+	if err != nil {
+		log.Error("failed-to-find-revision", err, lager.Data{"host": stored.Host, "version": to})
+		s.handleError(rw, storageError(err, "Revision "+strconv.FormatUint(to, 10)+" not found."))
+		return
+	}
+
+	log.Info("rolling-back", lager.Data{"host": stored.Host, "from": stored.Version, "to": to})
+	service := revision.Spec
+	service.Host = stored.Host
+	service.Version = stored.Version
+
+	s.storeUpdatedService(log, rw, r, apihub.AUDIT_ROLLBACK_SERVICE, stored, service)
+}
+
+// revisionParam reads the version of a revision from the query, returning
+// def when it is missing. A zero def makes the parameter required.
+func revisionParam(values url.Values, name string, def uint64) (uint64, error) {
+	value := values.Get(name)
+	if value == "" && def != 0 {
+		return def, nil
+	}
+
+	version, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || version == 0 {
+		return 0, errors.New("The " + name + " parameter must be the version of a revision.")
+	}
+	return version, nil
+}
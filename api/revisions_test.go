@@ -0,0 +1,148 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Revisions", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+		revisions    []apihub.Revision
+	)
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-revisions-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		good := apihub.ServiceSpec{
+			Host:     "my-host",
+			Version:  1,
+			Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+		}
+		bad := good
+		bad.Version = 2
+		bad.Backends = []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-b"}}
+		revisions = []apihub.Revision{
+			apihub.Revision{Version: 1, Spec: good},
+			apihub.Revision{Version: 2, Spec: bad},
+		}
+
+		fakeStorage.FindServiceByHostReturns(bad, nil)
+		fakeStorage.RevisionsReturns(revisions, nil)
+		fakeStorage.FindRevisionStub = func(host string, version uint64) (apihub.Revision, error) {
+			for _, r := range revisions {
+				if r.Version == version {
+					return r, nil
+				}
+			}
+			return apihub.Revision{}, apihub.ErrRevisionNotFound
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("listRevisions", func() {
+		It("lists the revisions of the service", func() {
+			code, body := server.Request(http.MethodGet, "/services/my-host/revisions", "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(body)).To(ContainSubstring(`"item_count":2`))
+			Expect(fakeStorage.RevisionsArgsForCall(0)).To(Equal("my-host"))
+		})
+
+		It("returns not found for an unknown service", func() {
+			fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, apihub.ErrServiceNotFound)
+			code, _ := server.Request(http.MethodGet, "/services/my-host/revisions", "")
+			Expect(code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("diffRevisions", func() {
+		It("compares two revisions", func() {
+			code, body := server.Request(http.MethodGet, "/services/my-host/revisions/diff?from=2&to=1", "")
+			Expect(code).To(Equal(http.StatusOK))
+
+			var diff apihub.RevisionDiff
+			Expect(json.Unmarshal(body, &diff)).To(Succeed())
+			Expect(diff.From).To(BeEquivalentTo(2))
+			Expect(diff.To).To(BeEquivalentTo(1))
+			Expect(diff.Diff).To(MatchJSON(`{"version":1,"backends":[{"address":"http://server-a","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
+		})
+
+		It("compares with the current version by default", func() {
+			code, body := server.Request(http.MethodGet, "/services/my-host/revisions/diff?from=1", "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(body)).To(ContainSubstring(`"from":1,"to":2`))
+		})
+
+		It("requires the versions", func() {
+			for _, query := range []string{"", "?from=0", "?from=first", "?from=1&to=-1"} {
+				code, _ := server.Request(http.MethodGet, "/services/my-host/revisions/diff"+query, "")
+				Expect(code).To(Equal(http.StatusBadRequest), query)
+			}
+		})
+
+		It("returns not found for an unknown revision", func() {
+			code, body := server.Request(http.MethodGet, "/services/my-host/revisions/diff?from=1&to=5", "")
+			Expect(code).To(Equal(http.StatusNotFound))
+			Expect(string(body)).To(ContainSubstring(`"error_description":"Revision 5 not found."`))
+		})
+	})
+
+	Describe("rollbackService", func() {
+		It("stores and publishes the revision as a new version", func() {
+			code, body := server.Request(http.MethodPost, "/services/my-host/rollback?to=1", "")
+			Expect(code).To(Equal(http.StatusOK))
+
+			var service apihub.ServiceSpec
+			Expect(json.Unmarshal(body, &service)).To(Succeed())
+			Expect(service.Version).To(BeEquivalentTo(3))
+			Expect(service.Backends).To(Equal(revisions[0].Spec.Backends))
+
+			stored := fakeStorage.UpdateServiceArgsForCall(0)
+			Expect(stored.Version).To(BeEquivalentTo(2))
+			Expect(stored.Backends).To(Equal(revisions[0].Spec.Backends))
+
+			_, _, published := fakeServicePublisher.PublishArgsForCall(0)
+			Expect(published.Backends).To(Equal(revisions[0].Spec.Backends))
+
+			event := fakeStorage.AddAuditEventArgsForCall(0)
+			Expect(event.Action).To(Equal(apihub.AUDIT_ROLLBACK_SERVICE))
+		})
+
+		It("checks the If-Match header", func() {
+			resp, _ := server.Do(http.MethodPost, "/services/my-host/rollback?to=1", "", http.Header{"If-Match": {`"1"`}})
+			Expect(resp.StatusCode).To(Equal(http.StatusPreconditionFailed))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+
+		It("returns not found for an unknown revision", func() {
+			code, _ := server.Request(http.MethodPost, "/services/my-host/rollback?to=5", "")
+			Expect(code).To(Equal(http.StatusNotFound))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+
+		It("requires the version", func() {
+			code, _ := server.Request(http.MethodPost, "/services/my-host/rollback", "")
+			Expect(code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
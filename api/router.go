@@ -18,11 +18,15 @@ type RouterArguments struct {
 	Path       string
 	PathPrefix string
 	Method     string
+	// Role is the least privileged role allowed to call the route.
+	Role string
 }
 
 func NewRouter() *Router {
+	// The variables are matched on the encoded path, so an escaped slash,
+	// as in the address of a backend, does not split them.
 	return &Router{
-		r: mux.NewRouter(),
+		r: mux.NewRouter().UseEncodedPath(),
 	}
 }
 
@@ -1,6 +1,10 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/apihub/apihub"
+)
 
 type Route int
 
@@ -12,14 +16,70 @@ const (
 	RemoveService
 	FindService
 	UpdateService
+	ReplaceService
+	SetServiceFaults
+	ClearServiceFaults
+	ListBackends
+	AddBackend
+	UpdateBackend
+	RemoveBackend
+	ListRevisions
+	DiffRevisions
+	RollbackService
+	ApplyServices
+	ExportServices
+	ImportServices
+	AddToken
+	ListTokens
+	RemoveToken
+	AddNamespace
+	ListNamespaces
+	FindNamespace
+	RemoveNamespace
+	ListAudit
+	SyncStatus
+	AddWebhook
+	ListWebhooks
+	FindWebhook
+	RemoveWebhook
+	ListDeliveries
 )
 
+// Routes holds the path, method and role required by every route of the
+// API. Routes without a role are public, even when authentication is enabled.
 var Routes = map[Route]RouterArguments{
-	Home:          RouterArguments{Path: "/", Method: http.MethodGet},
-	Ping:          RouterArguments{Path: "/ping", Method: http.MethodGet},
-	AddService:    RouterArguments{Path: "/services", Method: http.MethodPost},
-	ListServices:  RouterArguments{Path: "/services", Method: http.MethodGet},
-	RemoveService: RouterArguments{Path: "/services/{host}", Method: http.MethodDelete},
-	FindService:   RouterArguments{Path: "/services/{host}", Method: http.MethodGet},
-	UpdateService: RouterArguments{Path: "/services/{host}", Method: http.MethodPatch},
+	Home:               RouterArguments{Path: "/", Method: http.MethodGet},
+	Ping:               RouterArguments{Path: "/ping", Method: http.MethodGet},
+	AddService:         RouterArguments{Path: "/services", Method: http.MethodPost, Role: apihub.ROLE_EDITOR},
+	ListServices:       RouterArguments{Path: "/services", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	RemoveService:      RouterArguments{Path: "/services/{host}", Method: http.MethodDelete, Role: apihub.ROLE_EDITOR},
+	FindService:        RouterArguments{Path: "/services/{host}", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	UpdateService:      RouterArguments{Path: "/services/{host}", Method: http.MethodPatch, Role: apihub.ROLE_EDITOR},
+	ReplaceService:     RouterArguments{Path: "/services/{host}", Method: http.MethodPut, Role: apihub.ROLE_EDITOR},
+	SetServiceFaults:   RouterArguments{Path: "/services/{host}/faults", Method: http.MethodPut, Role: apihub.ROLE_EDITOR},
+	ClearServiceFaults: RouterArguments{Path: "/services/{host}/faults", Method: http.MethodDelete, Role: apihub.ROLE_EDITOR},
+	ListBackends:       RouterArguments{Path: "/services/{host}/backends", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	AddBackend:         RouterArguments{Path: "/services/{host}/backends", Method: http.MethodPost, Role: apihub.ROLE_EDITOR},
+	UpdateBackend:      RouterArguments{Path: "/services/{host}/backends/{address}", Method: http.MethodPatch, Role: apihub.ROLE_EDITOR},
+	RemoveBackend:      RouterArguments{Path: "/services/{host}/backends/{address}", Method: http.MethodDelete, Role: apihub.ROLE_EDITOR},
+	ListRevisions:      RouterArguments{Path: "/services/{host}/revisions", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	DiffRevisions:      RouterArguments{Path: "/services/{host}/revisions/diff", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	RollbackService:    RouterArguments{Path: "/services/{host}/rollback", Method: http.MethodPost, Role: apihub.ROLE_EDITOR},
+	ApplyServices:      RouterArguments{Path: "/apply", Method: http.MethodPost, Role: apihub.ROLE_EDITOR},
+	ExportServices:     RouterArguments{Path: "/export", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	ImportServices:     RouterArguments{Path: "/import", Method: http.MethodPost, Role: apihub.ROLE_EDITOR},
+	AddToken:           RouterArguments{Path: "/tokens", Method: http.MethodPost, Role: apihub.ROLE_ADMIN},
+	ListTokens:         RouterArguments{Path: "/tokens", Method: http.MethodGet, Role: apihub.ROLE_ADMIN},
+	RemoveToken:        RouterArguments{Path: "/tokens/{id}", Method: http.MethodDelete, Role: apihub.ROLE_ADMIN},
+	AddNamespace:       RouterArguments{Path: "/namespaces", Method: http.MethodPost, Role: apihub.ROLE_ADMIN},
+	ListNamespaces:     RouterArguments{Path: "/namespaces", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	FindNamespace:      RouterArguments{Path: "/namespaces/{name}", Method: http.MethodGet, Role: apihub.ROLE_READ_ONLY},
+	RemoveNamespace:    RouterArguments{Path: "/namespaces/{name}", Method: http.MethodDelete, Role: apihub.ROLE_ADMIN},
+	ListAudit:          RouterArguments{Path: "/audit", Method: http.MethodGet, Role: apihub.ROLE_ADMIN},
+	SyncStatus:         RouterArguments{Path: "/sync-status", Method: http.MethodGet, Role: apihub.ROLE_ADMIN},
+	AddWebhook:         RouterArguments{Path: "/webhooks", Method: http.MethodPost, Role: apihub.ROLE_ADMIN},
+	ListWebhooks:       RouterArguments{Path: "/webhooks", Method: http.MethodGet, Role: apihub.ROLE_ADMIN},
+	FindWebhook:        RouterArguments{Path: "/webhooks/{id}", Method: http.MethodGet, Role: apihub.ROLE_ADMIN},
+	RemoveWebhook:      RouterArguments{Path: "/webhooks/{id}", Method: http.MethodDelete, Role: apihub.ROLE_ADMIN},
+	ListDeliveries:     RouterArguments{Path: "/webhooks/{id}/deliveries", Method: http.MethodGet, Role: apihub.ROLE_ADMIN},
 }
@@ -2,8 +2,11 @@ package api
 
 import (
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/apihub/apihub"
 
@@ -20,6 +23,45 @@ type ApihubServer struct {
 	server           *http.Server
 	storage          apihub.Storage
 	servicePublisher apihub.ServicePublisher
+	// changer, revisions, tokens, namespaces, audit, outbox, webhooks and
+	// events are the storage when it implements their interface, or nil. The
+	// features needing them answer with apihub.ErrNotImplemented otherwise.
+	changer    apihub.ServiceChanger
+	revisions  apihub.RevisionStorage
+	tokens     apihub.TokenStorage
+	namespaces apihub.NamespaceStorage
+	audit      apihub.AuditStorage
+	outbox     apihub.OutboxStorage
+	webhooks   apihub.WebhookStorage
+	events     apihub.ServiceEventStorage
+	// servicesPrefix is the key prefix the services are published under, see
+	// SetServicesPrefix.
+	servicesPrefix string
+
+	// authEnabled requires a bearer token on the routes with a role, see
+	// EnableAuth.
+	authEnabled    bool
+	adminTokenHash string
+
+	// auditLog receives a copy of the audit events, see EnableAuditLog.
+	auditMtx sync.Mutex
+	auditLog io.Writer
+
+	// syncMtx guards the state of the outbox and the reconciler, see
+	// StartSync.
+	syncMtx       sync.Mutex
+	syncStop      chan struct{}
+	lastReconcile *apihub.ReconcileReport
+	metrics       apihub.DriftMetrics
+
+	// watch wakes the watchers of GET /services?watch=true up when the
+	// storage logs a change to the services.
+	watch *watchHub
+
+	// webhooksMtx serializes the runs of DeliverWebhooks, so a delivery is
+	// not posted twice.
+	webhooksMtx   sync.Mutex
+	webhookClient *http.Client
 }
 
 func New(log lager.Logger, listenNetwork, listenAddr string, storage apihub.Storage, servicePublisher apihub.ServicePublisher) *ApihubServer {
@@ -30,19 +72,57 @@ func New(log lager.Logger, listenNetwork, listenAddr string, storage apihub.Stor
 		router:           NewRouter(),
 		storage:          storage,
 		servicePublisher: servicePublisher,
+		servicesPrefix:   apihub.SERVICES_PREFIX,
+		webhookClient:    &http.Client{Timeout: webhookTimeout},
 	}
+	server.changer, _ = storage.(apihub.ServiceChanger)
+	server.revisions, _ = storage.(apihub.RevisionStorage)
+	server.tokens, _ = storage.(apihub.TokenStorage)
+	server.namespaces, _ = storage.(apihub.NamespaceStorage)
+	server.audit, _ = storage.(apihub.AuditStorage)
+	server.outbox, _ = storage.(apihub.OutboxStorage)
+	server.webhooks, _ = storage.(apihub.WebhookStorage)
+	server.events, _ = storage.(apihub.ServiceEventStorage)
+	server.watch = newWatchHub(log, server.events)
 
 	var handlers = map[Route]http.HandlerFunc{
-		Home:          http.HandlerFunc(server.homeHandler),
-		Ping:          http.HandlerFunc(server.pingHandler),
-		AddService:    http.HandlerFunc(server.addService),
-		ListServices:  http.HandlerFunc(server.listServices),
-		RemoveService: http.HandlerFunc(server.removeService),
-		FindService:   http.HandlerFunc(server.findService),
-		UpdateService: http.HandlerFunc(server.updateService),
+		Home:               http.HandlerFunc(server.homeHandler),
+		Ping:               http.HandlerFunc(server.pingHandler),
+		AddService:         http.HandlerFunc(server.addService),
+		ListServices:       http.HandlerFunc(server.listServices),
+		RemoveService:      http.HandlerFunc(server.removeService),
+		FindService:        http.HandlerFunc(server.findService),
+		UpdateService:      http.HandlerFunc(server.updateService),
+		ReplaceService:     http.HandlerFunc(server.replaceService),
+		SetServiceFaults:   http.HandlerFunc(server.setServiceFaults),
+		ClearServiceFaults: http.HandlerFunc(server.clearServiceFaults),
+		ListBackends:       http.HandlerFunc(server.listBackends),
+		AddBackend:         http.HandlerFunc(server.addBackend),
+		UpdateBackend:      http.HandlerFunc(server.updateBackend),
+		RemoveBackend:      http.HandlerFunc(server.removeBackend),
+		ListRevisions:      server.requires(server.revisions != nil, "revisions", server.listRevisions),
+		DiffRevisions:      server.requires(server.revisions != nil, "revisions", server.diffRevisions),
+		RollbackService:    server.requires(server.revisions != nil, "revisions", server.rollbackService),
+		ApplyServices:      http.HandlerFunc(server.applyServices),
+		ExportServices:     http.HandlerFunc(server.exportServices),
+		ImportServices:     server.requires(server.changer != nil, "transactions", server.importServices),
+		AddToken:           server.requires(server.tokens != nil, "tokens", server.addToken),
+		ListTokens:         server.requires(server.tokens != nil, "tokens", server.listTokens),
+		RemoveToken:        server.requires(server.tokens != nil, "tokens", server.removeToken),
+		AddNamespace:       server.requires(server.namespaces != nil, "namespaces", server.addNamespace),
+		ListNamespaces:     server.requires(server.namespaces != nil, "namespaces", server.listNamespaces),
+		FindNamespace:      server.requires(server.namespaces != nil, "namespaces", server.findNamespace),
+		RemoveNamespace:    server.requires(server.namespaces != nil, "namespaces", server.removeNamespace),
+		ListAudit:          server.requires(server.audit != nil, "audit events", server.listAudit),
+		SyncStatus:         http.HandlerFunc(server.syncStatus),
+		AddWebhook:         server.requires(server.webhooks != nil, "webhooks", server.addWebhook),
+		ListWebhooks:       server.requires(server.webhooks != nil, "webhooks", server.listWebhooks),
+		FindWebhook:        server.requires(server.webhooks != nil, "webhooks", server.findWebhook),
+		RemoveWebhook:      server.requires(server.webhooks != nil, "webhooks", server.removeWebhook),
+		ListDeliveries:     server.requires(server.webhooks != nil, "webhooks", server.listWebhookDeliveries),
 	}
 	for route, handler := range handlers {
-		server.router.AddHandler(RouterArguments{Path: Routes[route].Path, Method: Routes[route].Method, Handler: handler})
+		server.router.AddHandler(RouterArguments{Path: Routes[route].Path, Method: Routes[route].Method, Handler: server.identify(server.authorize(Routes[route].Role, handler))})
 	}
 	server.router.NotFoundHandler(http.HandlerFunc(server.notFoundHandler))
 
@@ -55,6 +135,44 @@ func New(log lager.Logger, listenNetwork, listenAddr string, storage apihub.Stor
 	return server
 }
 
+// requires answers the requests with apihub.ErrNotImplemented, instead of
+// handling them, when the storage does not support the feature.
+func (a *ApihubServer) requires(kept bool, feature string, handler http.HandlerFunc) http.HandlerFunc {
+	if kept {
+		return handler
+	}
+	return func(rw http.ResponseWriter, r *http.Request) {
+		a.handleError(rw, notImplemented(feature))
+	}
+}
+
+// EnableAuth requires a bearer token with the role of the route on every
+// request. adminToken, when not empty, is accepted as an admin token without
+// being stored, to create the first tokens.
+func (a *ApihubServer) EnableAuth(adminToken string) {
+	a.authEnabled = true
+	if adminToken != "" {
+		a.adminTokenHash = apihub.HashToken(adminToken)
+	}
+}
+
+// SetServicesPrefix publishes the services under prefix instead of
+// apihub.SERVICES_PREFIX, so ApiHub environments sharing a Consul cluster do
+// not see each other's services. The gateways must subscribe to the same
+// prefix, which must end with a separator, see apihub.CheckServicesPrefix.
+func (a *ApihubServer) SetServicesPrefix(prefix string) {
+	a.servicesPrefix = prefix
+}
+
+// SetWatchInterval sets how often the storage is polled for the changes to
+// the services while they are watched, DEFAULT_WATCH_INTERVAL by default.
+func (a *ApihubServer) SetWatchInterval(interval time.Duration) {
+	a.watch.mtx.Lock()
+	defer a.watch.mtx.Unlock()
+
+	a.watch.interval = interval
+}
+
 func (a *ApihubServer) Start(keep bool) error {
 	var err error
 
@@ -89,5 +207,6 @@ func (a *ApihubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *ApihubServer) Stop() error {
+	a.stopSync()
 	return a.Listener.Close()
 }
@@ -1,6 +1,12 @@
 package api_test
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/apihub/apihub/api"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -11,3 +17,47 @@ func TestServer(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Apihub Server Suite")
 }
+
+// testServer serves the handler of an API server, which is never started,
+// for the specs to send requests to.
+type testServer struct {
+	*httptest.Server
+}
+
+func newTestServer(apihubServer *api.ApihubServer) *testServer {
+	return &testServer{Server: httptest.NewServer(apihubServer.Handler())}
+}
+
+// Do sends the request with the header, and returns the response with its
+// body already read.
+func (s *testServer) Do(method, path, body string, header http.Header) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	Expect(err).NotTo(HaveOccurred())
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	Expect(err).NotTo(HaveOccurred())
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	Expect(err).NotTo(HaveOccurred())
+	return resp, data
+}
+
+// Request sends the request without a header, and returns the status code
+// and body of the response.
+func (s *testServer) Request(method, path, body string) (int, []byte) {
+	resp, data := s.Do(method, path, body, nil)
+	return resp.StatusCode, data
+}
+
+// tokenHeader returns the header sending the secret of a token, or no header
+// for an empty secret.
+func tokenHeader(secret string) http.Header {
+	if secret == "" {
+		return nil
+	}
+	return http.Header{"Authorization": []string{"Bearer " + secret}}
+}
@@ -3,6 +3,7 @@ package api_test
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 
@@ -69,4 +70,74 @@ var _ = Describe("Apihub Server", func() {
 			Expect(apihubClient.Ping()).To(Succeed())
 		})
 	})
+
+	Context("when the storage only keeps the services", func() {
+		var server *testServer
+
+		BeforeEach(func() {
+			// The struct hides the methods of the fake beyond apihub.Storage.
+			apihubServer = api.New(log, "tcp", ":0", struct{ apihub.Storage }{fakeStorage}, fakeServicePublisher)
+			server = newTestServer(apihubServer)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("selects the services of the query from all of them", func() {
+			fakeStorage.ServicesReturns([]apihub.ServiceSpec{
+				apihub.ServiceSpec{Host: "b-host.apihub.dev", Version: 1},
+				apihub.ServiceSpec{Host: "a-host.apihub.dev", Version: 1},
+				apihub.ServiceSpec{Host: "other.apihub.dev", Version: 1},
+			}, nil)
+
+			code, body := server.Request(http.MethodGet, "/services?host_prefix=a&limit=1", "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(body)).To(ContainSubstring(`"host":"a-host.apihub.dev"`))
+			Expect(string(body)).NotTo(ContainSubstring(`"host":"b-host.apihub.dev"`))
+			Expect(fakeStorage.ListServicesCallCount()).To(Equal(0))
+		})
+
+		It("publishes the services without recording them in the other features", func() {
+			code, _ := server.Request(http.MethodPost, "/services", `{"host":"my-host.apihub.dev", "backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusCreated))
+
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			Expect(fakeStorage.PutOutboxEntryCallCount()).To(Equal(0))
+			Expect(fakeStorage.AddAuditEventCallCount()).To(Equal(0))
+			Expect(fakeStorage.WebhooksCallCount()).To(Equal(0))
+		})
+
+		It("rejects the services in a namespace", func() {
+			code, body := server.Request(http.MethodPost, "/services", `{"host":"my-host.apihub.dev", "namespace":"payments", "backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusNotImplemented))
+			Expect(string(body)).To(ContainSubstring(`"error":"not_implemented"`))
+			Expect(fakeStorage.AddServiceCallCount()).To(Equal(0))
+		})
+
+		It("answers the requests to the other features with 501", func() {
+			for _, route := range [][]string{
+				{http.MethodGet, "/services/my-host.apihub.dev/revisions"},
+				{http.MethodPost, "/import"},
+				{http.MethodGet, "/tokens"},
+				{http.MethodGet, "/namespaces"},
+				{http.MethodGet, "/audit"},
+				{http.MethodGet, "/webhooks"},
+			} {
+				code, body := server.Request(route[0], route[1], "")
+				Expect(code).To(Equal(http.StatusNotImplemented), route[1])
+				Expect(string(body)).To(ContainSubstring(`"error":"not_implemented"`))
+			}
+		})
+
+		It("accepts the admin token only", func() {
+			apihubServer.EnableAuth("admin-secret")
+
+			resp, _ := server.Do(http.MethodGet, "/services", "", tokenHeader("admin-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			resp, _ = server.Do(http.MethodGet, "/services", "", tokenHeader("other-secret"))
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(fakeStorage.FindTokenByHashCallCount()).To(Equal(0))
+		})
+	})
 })
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"code.cloudfoundry.org/lager"
 
@@ -24,29 +25,34 @@ func (s *ApihubServer) addService(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if spec.Host == "" || len(spec.Backends) == 0 {
-		s.handleError(rw, errors.New("Host and Backend cannot be empty."))
+	// Tokens of a single namespace add their services to it by default.
+	if token := requestToken(r); spec.Namespace == "" && len(token.Namespaces) == 1 {
+		spec.Namespace = token.Namespaces[0]
+	}
+
+	if err := validateService(spec); err != nil {
+		log.Info("invalid-spec", lager.Data{"spec": spec, "error": err.Error()})
+		s.handleError(rw, err)
 		return
 	}
-	if err := s.storage.AddService(spec); err != nil {
-		log.Error("failed-to-store-service", err, lager.Data{"spec": spec})
-		s.handleError(rw, fmt.Errorf("failed to add service: '%s'", err))
+	if err := s.checkNamespace(r, spec); err != nil {
+		log.Info("namespace-not-allowed", lager.Data{"spec": spec, "error": err.Error()})
+		s.handleError(rw, err)
 		return
 	}
 
-	if !spec.Disabled {
-		if err := s.servicePublisher.Publish(log, apihub.SERVICES_PREFIX, spec); err != nil {
-			log.Error("failed-to-publish-service", err)
-			if cleanErr := s.storage.RemoveService(spec.Host); cleanErr != nil {
-				log.Error("failed-to-remove-service", cleanErr)
-			}
-
-			s.handleError(rw, fmt.Errorf("failed to publish service: '%s'", err))
-			return
-		}
+	spec.Version = 1
+	if err := s.storage.AddService(spec); err != nil {
+		log.Error("failed-to-store-service", err, lager.Data{"spec": spec})
+		s.handleError(rw, storageError(err, fmt.Sprintf("failed to add service: '%s'", err)))
+		return
 	}
 
+	s.publishService(log, spec)
+	s.notifyWebhooks(log, nil, &spec)
+	s.recordAudit(log, r, apihub.AUDIT_ADD_SERVICE, spec.Host, nil, &spec)
 	log.Info("service-added", lager.Data{"service": spec})
+	rw.Header().Set("ETag", etag(spec.Version))
 	s.writeResponse(rw, response{
 		StatusCode: http.StatusCreated,
 		Body:       spec,
@@ -58,16 +64,38 @@ func (s *ApihubServer) listServices(rw http.ResponseWriter, r *http.Request) {
 	log.Debug("start")
 	defer log.Debug("end")
 
-	services, err := s.storage.Services()
+	query, err := ParseServiceQuery(r.URL.Query())
 	if err != nil {
-		log.Error("failed-to-list-services", err)
-		s.handleError(rw, errors.New("Failed to retrieve service list."))
+		log.Error("failed-to-parse-query", err)
+		s.handleError(rw, err)
 		return
 	}
 
-	collection := Collection(services, len(services))
+	watch, resourceVersion, err := ParseWatchQuery(r.URL.Query())
+	if err != nil {
+		log.Error("failed-to-parse-query", err)
+		s.handleError(rw, err)
+		return
+	}
+	if watch {
+		s.watchServices(rw, r, query, resourceVersion)
+		return
+	}
 
-	log.Debug("services-found", lager.Data{"services": services})
+	page := apihub.ServicePage{Services: []apihub.ServiceSpec{}}
+	if scopeQuery(requestToken(r), &query) {
+		page, err = s.selectServices(query)
+		if err != nil {
+			log.Error("failed-to-list-services", err)
+			s.handleError(rw, storageError(err, "Failed to retrieve service list."))
+			return
+		}
+	}
+
+	collection := Collection(page.Services, len(page.Services))
+	collection.NextCursor = page.NextCursor
+
+	log.Debug("services-found", lager.Data{"services": page.Services})
 	s.writeResponse(rw, response{
 		StatusCode: http.StatusOK,
 		Body:       collection,
@@ -81,24 +109,24 @@ func (s *ApihubServer) removeService(rw http.ResponseWriter, r *http.Request) {
 
 	host := mux.Vars(r)["host"]
 
-	_, err := s.storage.FindServiceByHost(host)
+	service, err := s.findVisibleService(r, host)
 	if err != nil {
 		log.Error("failed-to-find-service", err, lager.Data{"host": host})
-		s.handleError(rw, errors.New("Host not found."))
+		s.handleError(rw, storageError(err, "Host not found."))
 		return
 	}
 
 	err = s.storage.RemoveService(host)
 	if err != nil {
 		log.Error("failed-to-remove-service", err)
-		s.handleError(rw, errors.New("Failed to remove service."))
+		s.handleError(rw, storageError(err, "Failed to remove service."))
 		return
 	}
 
-	if err := s.servicePublisher.Unpublish(log, apihub.SERVICES_PREFIX, host); err != nil {
-		log.Error("failed-to-unpublish-service", err)
-	}
+	s.unpublishService(log, host)
+	s.notifyWebhooks(log, &service, nil)
 
+	s.recordAudit(log, r, apihub.AUDIT_REMOVE_SERVICE, host, &service, nil)
 	log.Info("service-removed", lager.Data{"host": host})
 	s.writeResponse(rw, response{
 		StatusCode: http.StatusNoContent,
@@ -112,60 +140,210 @@ func (s *ApihubServer) findService(rw http.ResponseWriter, r *http.Request) {
 
 	host := mux.Vars(r)["host"]
 
-	service, err := s.storage.FindServiceByHost(host)
+	service, err := s.findVisibleService(r, host)
 	if err != nil {
 		log.Error("failed-to-find-service", err, lager.Data{"host": host})
-		s.handleError(rw, errors.New("Failed to find service."))
+		s.handleError(rw, storageError(err, "Failed to find service."))
 		return
 	}
 
 	log.Debug("service-found", lager.Data{"service": service})
+	rw.Header().Set("ETag", etag(service.Version))
 	s.writeResponse(rw, response{
 		StatusCode: http.StatusOK,
 		Body:       service,
 	})
 }
 
+// updateService applies a JSON Merge Patch or a JSON Patch, as told by the
+// Content-Type. Other bodies are decoded over the stored spec, so members
+// they leave out are kept.
 func (s *ApihubServer) updateService(rw http.ResponseWriter, r *http.Request) {
 	log := s.logger.Session("update-service")
 	log.Debug("start")
 	defer log.Debug("end")
 
-	host := mux.Vars(r)["host"]
+	stored, ok := s.serviceToUpdate(log, rw, r)
+	if !ok {
+		return
+	}
 
-	service, err := s.storage.FindServiceByHost(host)
+	service := stored
+	var err error
+	if contentType := patchContentType(r.Header.Get("Content-Type")); contentType != "" {
+		service, err = patchService(service, contentType, r.Body)
+	} else {
+		host, version := service.Host, service.Version
+		if err = json.NewDecoder(r.Body).Decode(&service); err != nil {
+			err = errors.New("Failed to parse request.")
+		}
+		service.Host, service.Version = host, version
+	}
 	if err != nil {
-		log.Error("failed-to-find-service", err, lager.Data{"host": host})
-		s.handleError(rw, errors.New("Failed to find service."))
+		log.Error("failed-to-patch-spec", err)
+		s.handleError(rw, err)
+		return
+	}
+
+	s.storeUpdatedService(log, rw, r, apihub.AUDIT_UPDATE_SERVICE, stored, service)
+}
+
+// replaceService replaces the whole spec of the service: the members left
+// out of the body are cleared.
+func (s *ApihubServer) replaceService(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("replace-service")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	stored, ok := s.serviceToUpdate(log, rw, r)
+	if !ok {
 		return
 	}
 
+	var service apihub.ServiceSpec
 	if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
 		log.Error("failed-to-parse-spec", err)
 		s.handleError(rw, errors.New("Failed to parse request."))
 		return
 	}
+	service.Host = stored.Host
+	service.Version = stored.Version
+
+	s.storeUpdatedService(log, rw, r, apihub.AUDIT_REPLACE_SERVICE, stored, service)
+}
+
+// serviceToUpdate finds the service of the request, and checks it against
+// the If-Match header.
+func (s *ApihubServer) serviceToUpdate(log lager.Logger, rw http.ResponseWriter, r *http.Request) (apihub.ServiceSpec, bool) {
+	host := mux.Vars(r)["host"]
+
+	service, err := s.findVisibleService(r, host)
+	if err != nil {
+		log.Error("failed-to-find-service", err, lager.Data{"host": host})
+		s.handleError(rw, storageError(err, "Failed to find service."))
+		return apihub.ServiceSpec{}, false
+	}
+
+	if match := r.Header.Get("If-Match"); match != "" && !etagMatches(match, service.Version) {
+		log.Info("version-mismatch", lager.Data{"host": host, "if-match": match, "version": service.Version})
+		s.preconditionFailed(rw)
+		return apihub.ServiceSpec{}, false
+	}
 
 	service.Host = host
-	if err := s.storage.UpdateService(service); err != nil {
-		log.Error("failed-to-store-service", err)
-		s.handleError(rw, errors.New("Failed to update service."))
+	return service, true
+}
+
+// storeUpdatedService validates and stores the service, only when it is
+// still at the version it was read with, so a concurrent update is not
+// overwritten. The change from stored is recorded as the audit action.
+func (s *ApihubServer) storeUpdatedService(log lager.Logger, rw http.ResponseWriter, r *http.Request, action string, stored, service apihub.ServiceSpec) {
+	if err := validateService(service); err != nil {
+		log.Info("invalid-spec", lager.Data{"spec": service, "error": err.Error()})
+		s.handleError(rw, err)
+		return
+	}
+	if err := s.checkNamespace(r, service); err != nil {
+		log.Info("namespace-not-allowed", lager.Data{"spec": service, "error": err.Error()})
+		s.handleError(rw, err)
 		return
 	}
 
-	if service.Disabled {
-		if err := s.servicePublisher.Unpublish(log, apihub.SERVICES_PREFIX, service.Host); err != nil {
-			log.Error("failed-to-unpublish-service", err)
-		}
-	} else {
-		if err := s.servicePublisher.Publish(log, apihub.SERVICES_PREFIX, service); err != nil {
-			log.Error("failed-to-publish-service", err)
-		}
+	if err := s.storage.UpdateService(service); errors.Is(err, apihub.ErrVersionConflict) {
+		log.Info("version-conflict", lager.Data{"host": service.Host, "version": service.Version})
+		s.preconditionFailed(rw)
+		return
+	} else if err != nil {
+		log.Error("failed-to-store-service", err)
+		s.handleError(rw, storageError(err, "Failed to update service."))
+		return
 	}
+	service.Version++
+	s.publishService(log, service)
+	s.notifyWebhooks(log, &stored, &service)
 
+	s.recordAudit(log, r, action, service.Host, &stored, &service)
 	log.Info("service-updated", lager.Data{"service": service})
+	rw.Header().Set("ETag", etag(service.Version))
 	s.writeResponse(rw, response{
 		StatusCode: http.StatusOK,
 		Body:       service,
 	})
 }
+
+// selectServices returns the services selected by the query, see
+// apihub.ServiceLister.
+func (s *ApihubServer) selectServices(query apihub.ServiceQuery) (apihub.ServicePage, error) {
+	if lister, ok := s.storage.(apihub.ServiceLister); ok {
+		return lister.ListServices(query)
+	}
+	services, err := s.storage.Services()
+	if err != nil {
+		return apihub.ServicePage{}, err
+	}
+	return apihub.SelectServices(services, query)
+}
+
+// findVisibleService finds the service of the host. Services in namespaces
+// the token of the request cannot access are reported as not found.
+func (s *ApihubServer) findVisibleService(r *http.Request, host string) (apihub.ServiceSpec, error) {
+	service, err := s.storage.FindServiceByHost(host)
+	if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	if !requestToken(r).CanAccess(service.Namespace) {
+		return apihub.ServiceSpec{}, apihub.ErrServiceNotFound
+	}
+	return service, nil
+}
+
+// checkNamespace checks the token of the request can access the namespace of
+// the spec, and the namespace allows its host.
+func (s *ApihubServer) checkNamespace(r *http.Request, spec apihub.ServiceSpec) error {
+	if !requestToken(r).CanAccess(spec.Namespace) {
+		return apihub.NewError(apihub.E_FORBIDDEN, "The token does not allow this namespace.")
+	}
+	if spec.Namespace == "" {
+		return nil
+	}
+
+	if s.namespaces == nil {
+		return notImplemented("namespaces")
+	}
+	ns, err := s.namespaces.FindNamespace(spec.Namespace)
+	if errors.Is(err, apihub.ErrNamespaceNotFound) {
+		var v validation
+		v.add("namespace", "Namespace does not exist.")
+		return v.err("The service is invalid.")
+	} else if err != nil {
+		return storageError(err, "Failed to find namespace.")
+	}
+
+	if !ns.AllowsHost(spec.Host) {
+		var v validation
+		v.add("host", "Host must be in the domains of the namespace: "+strings.Join(ns.DomainSuffixes, ", ")+".")
+		return v.err("The service is invalid.")
+	}
+	return nil
+}
+
+// scopeQuery restricts the query to the namespaces the token can access. It
+// returns false when no service can be selected.
+func scopeQuery(token apihub.Token, query *apihub.ServiceQuery) bool {
+	if len(token.Namespaces) == 0 {
+		return true
+	}
+	if len(query.Namespaces) == 0 {
+		query.Namespaces = token.Namespaces
+		return true
+	}
+
+	allowed := []string{}
+	for _, ns := range query.Namespaces {
+		if token.CanAccess(ns) {
+			allowed = append(allowed, ns)
+		}
+	}
+	query.Namespaces = allowed
+	return len(allowed) > 0
+}
@@ -62,8 +62,9 @@ var _ = Describe("Services", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(string(body)).To(ContainSubstring(`{"host":"my-host.apihub.dev","disabled":false,"timeout":0,"backends":[{"address":"http://server-a","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
+			Expect(string(body)).To(ContainSubstring(`{"host":"my-host.apihub.dev","version":1,"disabled":false,"timeout":0,"backends":[{"address":"http://server-a","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
 			Expect(headers["Content-Type"]).To(ContainElement("application/json"))
+			Expect(headers["Etag"]).To(ConsistOf(`"1"`))
 			Expect(code).To(Equal(http.StatusCreated))
 			Expect(fakeStorage.AddServiceCallCount()).To(Equal(1))
 		})
@@ -90,6 +91,7 @@ var _ = Describe("Services", func() {
 
 			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
 			_, prefix, s := fakeServicePublisher.PublishArgsForCall(0)
+			spec.Version = 1
 			Expect(spec).To(Equal(s))
 			Expect(prefix).To(Equal(apihub.SERVICES_PREFIX))
 		})
@@ -124,28 +126,29 @@ var _ = Describe("Services", func() {
 				fakeServicePublisher.PublishReturns(errors.New("failed to publish service"))
 			})
 
-			It("returns an error", func() {
-				_, _, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+			It("adds the service anyway", func() {
+				_, _, _, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusCreated,
 					Method:         http.MethodPost,
 					Path:           "/services",
 					Body:           `{"host":"my-host.apihub.dev", "backends":[{"address":"http://server-a"}]}`,
 				})
 				Expect(err).NotTo(HaveOccurred())
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"failed to publish service: 'failed to publish service'"}`))
+				Expect(fakeStorage.RemoveServiceCallCount()).To(Equal(0))
 			})
 
-			It("removes the service from the storage", func() {
+			It("keeps the service in the outbox", func() {
 				_, _, _, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusCreated,
 					Method:         http.MethodPost,
 					Path:           "/services",
 					Body:           `{"host":"my-host.apihub.dev", "backends":[{"address":"http://server-a"}]}`,
 				})
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeStorage.RemoveServiceCallCount()).To(Equal(1))
-				host := fakeStorage.RemoveServiceArgsForCall(0)
-				Expect(host).To(Equal("my-host.apihub.dev"))
+				Expect(fakeStorage.PutOutboxEntryCallCount()).To(Equal(2))
+				Expect(fakeStorage.PutOutboxEntryArgsForCall(1).Host).To(Equal("my-host.apihub.dev"))
+				Expect(fakeStorage.PutOutboxEntryArgsForCall(1).Attempts).To(Equal(1))
+				Expect(fakeStorage.RemoveOutboxEntryCallCount()).To(Equal(0))
 			})
 		})
 
@@ -168,28 +171,28 @@ var _ = Describe("Services", func() {
 			It("returns an error when missing required fields", func() {
 				bdy := `{"missing":"host"}`
 				headers, code, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusUnprocessableEntity,
 					Method:         http.MethodPost,
 					Path:           "/services",
 					Body:           bdy,
 				})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"Host and Backend cannot be empty."}`))
+				Expect(string(body)).To(ContainSubstring(`{"error":"validation_failed","error_description":"The service is invalid.","error_details":[{"field":"host","description":"Host cannot be empty."},{"field":"backends","description":"Enabled services must have at least one backend."}]}`))
 				Expect(fakeStorage.AddServiceCallCount()).To(Equal(0))
 				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
-				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(code).To(Equal(http.StatusUnprocessableEntity))
 			})
 		})
 
 		Context("when storing a service fails", func() {
 			BeforeEach(func() {
-				fakeStorage.AddServiceReturns(errors.New("host already in use"))
+				fakeStorage.AddServiceReturns(apihub.ErrHostInUse)
 			})
 
 			It("returns an error", func() {
 				headers, code, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusConflict,
 					Method:         http.MethodPost,
 					Path:           "/services",
 					Body:           `{"host":"my-host.apihub.dev", "backends":[{ "address":"http://server-a"}]}`,
@@ -197,26 +200,40 @@ var _ = Describe("Services", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
-				Expect(code).To(Equal(http.StatusBadRequest))
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"failed to add service: 'host already in use'"}`))
+				Expect(code).To(Equal(http.StatusConflict))
+				Expect(string(body)).To(ContainSubstring(`{"error":"conflict","error_description":"failed to add service: 'host already in use'"}`))
 			})
 		})
 	})
 
 	Describe("listServices", func() {
 		BeforeEach(func() {
-			fakeStorage.ServicesReturns([]apihub.ServiceSpec{
-				apihub.ServiceSpec{
-					Host: "my-host.apihub.dev",
-					Backends: []apihub.BackendInfo{
-						apihub.BackendInfo{
-							Address: "http://server-a",
+			fakeStorage.ListServicesReturns(apihub.ServicePage{
+				Services: []apihub.ServiceSpec{
+					apihub.ServiceSpec{
+						Host: "my-host.apihub.dev",
+						Backends: []apihub.BackendInfo{
+							apihub.BackendInfo{
+								Address: "http://server-a",
+							},
 						},
 					},
 				},
 			}, nil)
 		})
 
+		// list requests GET /services with the query, which requests.Args
+		// cannot carry.
+		list := func(query string) (int, string) {
+			resp, err := http.Get(server.URL + "/services?" + query)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			return resp.StatusCode, string(body)
+		}
+
 		It("lists all existing services", func() {
 			headers, code, body, err := httpClient.MakeRequest(requests.Args{
 				AcceptableCode: http.StatusOK,
@@ -228,25 +245,74 @@ var _ = Describe("Services", func() {
 			Expect(string(body)).To(ContainSubstring(`{"items":[{"host":"my-host.apihub.dev","disabled":false,"timeout":0,"backends":[{"address":"http://server-a","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}],"item_count":1}`))
 			Expect(headers["Content-Type"]).To(ContainElement("application/json"))
 			Expect(code).To(Equal(http.StatusOK))
-			Expect(fakeStorage.ServicesCallCount()).To(Equal(1))
+			Expect(fakeStorage.ListServicesCallCount()).To(Equal(1))
+			Expect(fakeStorage.ListServicesArgsForCall(0)).To(Equal(apihub.ServiceQuery{Limit: api.DEFAULT_PAGE_LIMIT}))
+		})
+
+		It("passes the filters, sorting and pagination to the storage", func() {
+			cursor := apihub.ServiceQuery{Sort: "-version"}.NextCursor(apihub.ServiceSpec{Host: "my-host.apihub.dev", Version: 3})
+			code, _ := list("limit=10&cursor=" + cursor + "&host_prefix=my-&disabled=false&backend=http://server-a&label=team=payments&label=tier=gold&sort=-version")
+			Expect(code).To(Equal(http.StatusOK))
+
+			disabled := false
+			Expect(fakeStorage.ListServicesArgsForCall(0)).To(Equal(apihub.ServiceQuery{
+				HostPrefix: "my-",
+				Disabled:   &disabled,
+				Backend:    "http://server-a",
+				Labels:     map[string]string{"team": "payments", "tier": "gold"},
+				Sort:       "-version",
+				Limit:      10,
+				Cursor:     cursor,
+			}))
+		})
+
+		It("returns the cursor of the next page", func() {
+			fakeStorage.ListServicesReturns(apihub.ServicePage{
+				Services:   []apihub.ServiceSpec{apihub.ServiceSpec{Host: "my-host.apihub.dev"}},
+				NextCursor: "next",
+			}, nil)
+
+			code, body := list("limit=1")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"item_count":1,"next_cursor":"next"}`))
+		})
+
+		Context("when the query is invalid", func() {
+			It("returns an error for a limit out of range", func() {
+				code, body := list("limit=5000")
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(`{"error":"bad_request","error_description":"The limit must be between 1 and 1000."}`))
+				Expect(fakeStorage.ListServicesCallCount()).To(Equal(0))
+			})
+
+			It("returns an error for an unknown sort field", func() {
+				code, body := list("sort=timeout")
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(`{"error":"bad_request","error_description":"Services can only be sorted by host or version."}`))
+			})
+
+			It("returns an error for a malformed label", func() {
+				code, _ := list("label=team")
+				Expect(code).To(Equal(http.StatusBadRequest))
+			})
 		})
 
 		Context("when getting the list of services fails", func() {
 			BeforeEach(func() {
-				fakeStorage.ServicesReturns(nil, errors.New("failed to list services."))
+				fakeStorage.ListServicesReturns(apihub.ServicePage{}, errors.New("failed to list services."))
 			})
 
 			It("returns an error", func() {
 				headers, code, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusInternalServerError,
 					Method:         http.MethodGet,
 					Path:           "/services",
 				})
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
-				Expect(code).To(Equal(http.StatusBadRequest))
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"Failed to retrieve service list."}`))
+				Expect(code).To(Equal(http.StatusInternalServerError))
+				Expect(string(body)).To(ContainSubstring(`{"error":"internal_error","error_description":"Failed to retrieve service list."}`))
 			})
 		})
 	})
@@ -286,20 +352,20 @@ var _ = Describe("Services", func() {
 
 		Context("when removing a service fails", func() {
 			BeforeEach(func() {
-				fakeStorage.RemoveServiceReturns(errors.New("service not found"))
+				fakeStorage.RemoveServiceReturns(errors.New("failed to remove service."))
 			})
 
 			It("returns an error", func() {
 				headers, code, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusInternalServerError,
 					Method:         http.MethodDelete,
 					Path:           "/services/my-bad-host",
 				})
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
-				Expect(code).To(Equal(http.StatusBadRequest))
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"Failed to remove service."}`))
+				Expect(code).To(Equal(http.StatusInternalServerError))
+				Expect(string(body)).To(ContainSubstring(`{"error":"internal_error","error_description":"Failed to remove service."}`))
 			})
 		})
 	})
@@ -307,7 +373,8 @@ var _ = Describe("Services", func() {
 	Describe("findService", func() {
 		BeforeEach(func() {
 			fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{
-				Host: "my-host.apihub.dev",
+				Host:    "my-host.apihub.dev",
+				Version: 3,
 				Backends: []apihub.BackendInfo{
 					apihub.BackendInfo{
 						Address: "http://server-a",
@@ -324,12 +391,32 @@ var _ = Describe("Services", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(string(body)).To(ContainSubstring(`{"host":"my-host.apihub.dev","disabled":false,"timeout":0,"backends":[{"address":"http://server-a","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
+			Expect(string(body)).To(ContainSubstring(`{"host":"my-host.apihub.dev","version":3,"disabled":false,"timeout":0,"backends":[{"address":"http://server-a","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
 			Expect(headers["Content-Type"]).To(ContainElement("application/json"))
+			Expect(headers["Etag"]).To(ConsistOf(`"3"`))
 			Expect(code).To(Equal(http.StatusOK))
 			Expect(fakeStorage.FindServiceByHostCallCount()).To(Equal(1))
 		})
 
+		Context("when the service does not exist", func() {
+			BeforeEach(func() {
+				fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, apihub.ErrServiceNotFound)
+			})
+
+			It("returns an error", func() {
+				headers, code, body, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusNotFound,
+					Method:         http.MethodGet,
+					Path:           "/services/invalid-host",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
+				Expect(code).To(Equal(http.StatusNotFound))
+				Expect(string(body)).To(ContainSubstring(`{"error":"not_found","error_description":"Failed to find service."}`))
+			})
+		})
+
 		Context("when finding a service fails", func() {
 			BeforeEach(func() {
 				fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, errors.New("failed to find service."))
@@ -337,15 +424,15 @@ var _ = Describe("Services", func() {
 
 			It("returns an error", func() {
 				headers, code, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusInternalServerError,
 					Method:         http.MethodGet,
 					Path:           "/services/invalid-host",
 				})
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
-				Expect(code).To(Equal(http.StatusBadRequest))
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"Failed to find service."}`))
+				Expect(code).To(Equal(http.StatusInternalServerError))
+				Expect(string(body)).To(ContainSubstring(`{"error":"internal_error","error_description":"Failed to find service."}`))
 			})
 		})
 	})
@@ -372,8 +459,9 @@ var _ = Describe("Services", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(string(body)).To(ContainSubstring(`{"host":"my-host.apihub.dev","disabled":true,"timeout":0,"backends":[{"address":"http://another-server-b","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
+			Expect(string(body)).To(ContainSubstring(`{"host":"my-host.apihub.dev","version":1,"disabled":true,"timeout":0,"backends":[{"address":"http://another-server-b","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
 			Expect(headers["Content-Type"]).To(ContainElement("application/json"))
+			Expect(headers["Etag"]).To(ConsistOf(`"1"`))
 			Expect(code).To(Equal(http.StatusOK))
 			Expect(fakeStorage.FindServiceByHostCallCount()).To(Equal(1))
 			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(1))
@@ -383,6 +471,65 @@ var _ = Describe("Services", func() {
 			Expect(s).To(Equal("my-host.apihub.dev"))
 		})
 
+		Context("when the request has an If-Match header", func() {
+			BeforeEach(func() {
+				fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{
+					Host:    "my-host.apihub.dev",
+					Version: 4,
+				}, nil)
+			})
+
+			It("updates the service when the version matches", func() {
+				headers, code, _, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusOK,
+					Method:         http.MethodPatch,
+					Path:           "/services/my-host.apihub.dev",
+					Body:           `{"disabled":true}`,
+					Headers:        http.Header{"If-Match": []string{`"4"`}},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(code).To(Equal(http.StatusOK))
+				Expect(headers["Etag"]).To(ConsistOf(`"5"`))
+				Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(1))
+				Expect(fakeStorage.UpdateServiceArgsForCall(0).Version).To(BeEquivalentTo(4))
+			})
+
+			It("returns precondition failed when the version does not match", func() {
+				_, code, body, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusPreconditionFailed,
+					Method:         http.MethodPatch,
+					Path:           "/services/my-host.apihub.dev",
+					Body:           `{"disabled":true}`,
+					Headers:        http.Header{"If-Match": []string{`"3"`}},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(code).To(Equal(http.StatusPreconditionFailed))
+				Expect(string(body)).To(ContainSubstring(`{"error":"precondition_failed","error_description":"The service has been modified since it was read."}`))
+				Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the service is updated concurrently", func() {
+			BeforeEach(func() {
+				fakeStorage.UpdateServiceReturns(apihub.ErrVersionConflict)
+			})
+
+			It("returns precondition failed", func() {
+				_, code, _, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusPreconditionFailed,
+					Method:         http.MethodPatch,
+					Path:           "/services/my-host.apihub.dev",
+					Body:           `{"disabled":true}`,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusPreconditionFailed))
+				Expect(fakeServicePublisher.PublishCallCount()).To(Equal(0))
+				Expect(fakeServicePublisher.UnpublishCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when changing the service to be enabled", func() {
 			var spec apihub.ServiceSpec
 
@@ -412,18 +559,19 @@ var _ = Describe("Services", func() {
 				Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
 				_, prefix, s := fakeServicePublisher.PublishArgsForCall(0)
 				Expect(prefix).To(Equal(apihub.SERVICES_PREFIX))
+				spec.Version = 1
 				Expect(s).To(Equal(spec))
 			})
 		})
 
-		Context("when finding a service fails", func() {
+		Context("when the service does not exist", func() {
 			BeforeEach(func() {
-				fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, errors.New("failed to find service."))
+				fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, apihub.ErrServiceNotFound)
 			})
 
 			It("returns an error", func() {
 				headers, code, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusNotFound,
 					Method:         http.MethodPatch,
 					Path:           "/services/my-host.apihub.dev",
 					Body:           "{}",
@@ -431,8 +579,8 @@ var _ = Describe("Services", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
-				Expect(code).To(Equal(http.StatusBadRequest))
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"Failed to find service."}`))
+				Expect(code).To(Equal(http.StatusNotFound))
+				Expect(string(body)).To(ContainSubstring(`{"error":"not_found","error_description":"Failed to find service."}`))
 			})
 		})
 
@@ -460,7 +608,7 @@ var _ = Describe("Services", func() {
 
 			It("returns an error", func() {
 				headers, code, body, err := httpClient.MakeRequest(requests.Args{
-					AcceptableCode: http.StatusBadRequest,
+					AcceptableCode: http.StatusInternalServerError,
 					Method:         http.MethodPatch,
 					Path:           "/services/my-host.apihub.dev",
 					Body:           "{}",
@@ -468,8 +616,101 @@ var _ = Describe("Services", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(headers["Content-Type"]).To(ContainElement("application/json"))
-				Expect(code).To(Equal(http.StatusBadRequest))
-				Expect(string(body)).To(ContainSubstring(`{"error":"bad_request","error_description":"Failed to update service."}`))
+				Expect(code).To(Equal(http.StatusInternalServerError))
+				Expect(string(body)).To(ContainSubstring(`{"error":"internal_error","error_description":"Failed to update service."}`))
+			})
+		})
+	})
+
+	Describe("replaceService", func() {
+		BeforeEach(func() {
+			fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{
+				Host:     "my-host.apihub.dev",
+				Version:  2,
+				Disabled: true,
+				Timeout:  100,
+				Backends: []apihub.BackendInfo{
+					apihub.BackendInfo{Address: "http://server-a"},
+				},
+			}, nil)
+		})
+
+		It("replaces the whole spec of the service", func() {
+			headers, code, body, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusOK,
+				Method:         http.MethodPut,
+				Path:           "/services/my-host.apihub.dev",
+				Body:           `{"backends":[{"address":"http://server-b"}]}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(body)).To(ContainSubstring(`{"host":"my-host.apihub.dev","version":3,"disabled":false,"timeout":0,"backends":[{"address":"http://server-b","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}]}`))
+			Expect(headers["Etag"]).To(ConsistOf(`"3"`))
+
+			Expect(fakeStorage.UpdateServiceArgsForCall(0)).To(Equal(apihub.ServiceSpec{
+				Host:     "my-host.apihub.dev",
+				Version:  2,
+				Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-b"}},
+			}))
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+		})
+
+		It("keeps the host of the path", func() {
+			_, _, _, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusOK,
+				Method:         http.MethodPut,
+				Path:           "/services/my-host.apihub.dev",
+				Body:           `{"host":"another-host.apihub.dev","backends":[{"address":"http://server-b"}]}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeStorage.UpdateServiceArgsForCall(0).Host).To(Equal("my-host.apihub.dev"))
+		})
+
+		Context("when the spec has no backends", func() {
+			It("returns an error", func() {
+				_, code, body, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusUnprocessableEntity,
+					Method:         http.MethodPut,
+					Path:           "/services/my-host.apihub.dev",
+					Body:           `{"timeout":1000}`,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusUnprocessableEntity))
+				Expect(string(body)).To(ContainSubstring(`"error_details":[{"field":"backends","description":"Enabled services must have at least one backend."}]`))
+				Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the If-Match header does not match", func() {
+			It("returns precondition failed", func() {
+				_, code, _, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusPreconditionFailed,
+					Method:         http.MethodPut,
+					Path:           "/services/my-host.apihub.dev",
+					Body:           `{"backends":[{"address":"http://server-b"}]}`,
+					Headers:        http.Header{"If-Match": {`"1"`}},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusPreconditionFailed))
+				Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the service does not exist", func() {
+			BeforeEach(func() {
+				fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{}, apihub.ErrServiceNotFound)
+			})
+
+			It("returns an error", func() {
+				_, code, _, err := httpClient.MakeRequest(requests.Args{
+					AcceptableCode: http.StatusNotFound,
+					Method:         http.MethodPut,
+					Path:           "/services/my-host.apihub.dev",
+					Body:           `{"backends":[{"address":"http://server-b"}]}`,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusNotFound))
 			})
 		})
 	})
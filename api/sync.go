@@ -0,0 +1,394 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+const (
+	DEFAULT_OUTBOX_INTERVAL    = time.Second
+	DEFAULT_RECONCILE_INTERVAL = time.Minute
+
+	// The publishing of a service, and the delivery of a webhook, is retried
+	// after minPublishBackoff, twice as long after every failure, up to
+	// maxPublishBackoff.
+	minPublishBackoff = time.Second
+	maxPublishBackoff = 5 * time.Minute
+)
+
+// StartSync retries the publishing of the services in the outbox every
+// outboxInterval, and reconciles the published services with the stored ones
+// every reconcileInterval, until the server is stopped. The pending webhook
+// payloads are delivered every webhookInterval, apart from the publishing so
+// slow receivers do not hold it up.
+func (s *ApihubServer) StartSync(outboxInterval, reconcileInterval, webhookInterval time.Duration) {
+	s.syncMtx.Lock()
+	defer s.syncMtx.Unlock()
+	if s.syncStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	s.syncStop = stop
+
+	go func() {
+		outbox := time.NewTicker(outboxInterval)
+		defer outbox.Stop()
+		reconcile := time.NewTicker(reconcileInterval)
+		defer reconcile.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-outbox.C:
+				s.ProcessOutbox()
+			case <-reconcile.C:
+				s.Reconcile()
+			}
+		}
+	}()
+
+	go func() {
+		webhooks := time.NewTicker(webhookInterval)
+		defer webhooks.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-webhooks.C:
+				s.DeliverWebhooks()
+			}
+		}
+	}()
+}
+
+func (s *ApihubServer) stopSync() {
+	s.syncMtx.Lock()
+	defer s.syncMtx.Unlock()
+	if s.syncStop != nil {
+		close(s.syncStop)
+		s.syncStop = nil
+	}
+}
+
+// publishService publishes the updated service to the gateways, or
+// unpublishes it when disabled.
+func (s *ApihubServer) publishService(log lager.Logger, service apihub.ServiceSpec) {
+	s.queuePublish(log, service.Host, func() error {
+		if service.Disabled {
+			return s.servicePublisher.Unpublish(log, s.servicesPrefix, service.Host)
+		}
+		return s.servicePublisher.Publish(log, s.servicesPrefix, service)
+	})
+}
+
+// unpublishService removes the service from the gateways.
+func (s *ApihubServer) unpublishService(log lager.Logger, host string) {
+	s.queuePublish(log, host, func() error {
+		return s.servicePublisher.Unpublish(log, s.servicesPrefix, host)
+	})
+}
+
+// queuePublish keeps the host in the outbox until publish succeeds. It is
+// tried right away, and retried by ProcessOutbox when it fails. The service
+// is stored already, so failing to publish it is not reported to the client.
+// Without an outbox in the storage, the failures are left to Reconcile.
+func (s *ApihubServer) queuePublish(log lager.Logger, host string, publish func() error) {
+	entry := apihub.OutboxEntry{Host: host, CreatedAt: time.Now().UTC()}
+	queued := s.outbox != nil
+	if queued {
+		id, err := randomHex(8)
+		if err == nil {
+			entry.ID = id
+			err = s.outbox.PutOutboxEntry(entry)
+		}
+		if err != nil {
+			log.Error("failed-to-queue-service", err, lager.Data{"host": host})
+			queued = false
+		}
+	}
+
+	if err := publish(); err != nil {
+		log.Error("failed-to-publish-service", err, lager.Data{"host": host})
+		if queued {
+			s.retryLater(log, entry, err)
+		}
+		return
+	}
+
+	if queued {
+		if err := s.outbox.RemoveOutboxEntry(host, entry.ID); err != nil {
+			log.Error("failed-to-dequeue-service", err, lager.Data{"host": host})
+		}
+	}
+}
+
+// retryLater records a failed attempt to publish the service of the entry,
+// backing off the next one.
+func (s *ApihubServer) retryLater(log lager.Logger, entry apihub.OutboxEntry, err error) {
+	s.syncMtx.Lock()
+	s.metrics.PublishFailures++
+	s.syncMtx.Unlock()
+
+	entry.NextAttempt = time.Now().UTC().Add(backoff(entry.Attempts))
+	entry.Attempts++
+	entry.LastError = err.Error()
+	if err := s.outbox.PutOutboxEntry(entry); err != nil {
+		log.Error("failed-to-queue-service", err, lager.Data{"host": entry.Host})
+	}
+}
+
+// backoff returns the time to wait after a failure, when as many attempts
+// failed before it: minPublishBackoff after the first failure, twice as long
+// after every other one, up to maxPublishBackoff.
+func backoff(attempts int) time.Duration {
+	if attempts < 16 {
+		if b := minPublishBackoff << uint(attempts); b < maxPublishBackoff {
+			return b
+		}
+	}
+	return maxPublishBackoff
+}
+
+// ProcessOutbox publishes the services in the outbox whose next attempt is
+// due, as they are stored now.
+func (s *ApihubServer) ProcessOutbox() {
+	if s.outbox == nil {
+		return
+	}
+	log := s.logger.Session("process-outbox")
+
+	entries, err := s.outbox.OutboxEntries()
+	if err != nil {
+		log.Error("failed-to-list-outbox", err)
+		return
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.NextAttempt.After(now) {
+			continue
+		}
+
+		s.syncMtx.Lock()
+		s.metrics.PublishRetries++
+		s.syncMtx.Unlock()
+
+		if err := s.publishStored(log, entry.Host); err != nil {
+			log.Error("failed-to-publish-service", err, lager.Data{"host": entry.Host, "attempts": entry.Attempts})
+			s.retryLater(log, entry, err)
+			continue
+		}
+		if err := s.outbox.RemoveOutboxEntry(entry.Host, entry.ID); err != nil {
+			log.Error("failed-to-dequeue-service", err, lager.Data{"host": entry.Host})
+		}
+	}
+}
+
+// publishStored publishes the service with the host as stored, or
+// unpublishes it when it is disabled or no longer stored.
+func (s *ApihubServer) publishStored(log lager.Logger, host string) error {
+	service, err := s.storage.FindServiceByHost(host)
+	if errors.Is(err, apihub.ErrServiceNotFound) || (err == nil && service.Disabled) {
+		return s.servicePublisher.Unpublish(log, s.servicesPrefix, host)
+	}
+	if err != nil {
+		return err
+	}
+	return s.servicePublisher.Publish(log, s.servicesPrefix, service)
+}
+
+// Reconcile compares the published services with the stored ones, and
+// publishes again the ones that differ. The services in the outbox are left
+// to ProcessOutbox.
+func (s *ApihubServer) Reconcile() apihub.ReconcileReport {
+	log := s.logger.Session("reconcile")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	report := apihub.ReconcileReport{
+		StartedAt: time.Now().UTC(),
+		Missing:   []string{},
+		Stale:     []string{},
+		Orphaned:  []string{},
+	}
+	if err := s.findDrift(log, &report); err != nil {
+		log.Error("failed-to-reconcile", err)
+		report.Error = err.Error()
+		s.recordReconcile(report, 0)
+		return report
+	}
+
+	failures := uint64(0)
+	for _, hosts := range [][]string{report.Missing, report.Stale, report.Orphaned} {
+		for _, host := range hosts {
+			if err := s.publishStored(log, host); err != nil {
+				log.Error("failed-to-repair-service", err, lager.Data{"host": host})
+				failures++
+				continue
+			}
+			report.Repaired++
+		}
+	}
+
+	log.Info("reconciled", lager.Data{"missing": len(report.Missing), "stale": len(report.Stale), "orphaned": len(report.Orphaned), "repaired": report.Repaired})
+	s.recordReconcile(report, failures)
+	return report
+}
+
+func (s *ApihubServer) findDrift(log lager.Logger, report *apihub.ReconcileReport) error {
+	stored, err := s.storage.Services()
+	if err != nil {
+		return err
+	}
+	published, err := s.servicePublisher.Published(log, s.servicesPrefix)
+	if err != nil {
+		return err
+	}
+
+	pending := map[string]bool{}
+	entries, err := s.outboxEntries()
+	if err != nil {
+		log.Error("failed-to-list-outbox", err)
+	}
+	for _, entry := range entries {
+		pending[entry.Host] = true
+	}
+
+	publishedByHost := map[string]apihub.ServiceSpec{}
+	for _, spec := range published {
+		publishedByHost[spec.Host] = spec
+	}
+
+	for _, service := range stored {
+		spec, ok := publishedByHost[service.Host]
+		delete(publishedByHost, service.Host)
+		switch {
+		case pending[service.Host]:
+		case service.Disabled:
+			if ok {
+				report.Orphaned = append(report.Orphaned, service.Host)
+			}
+		case !ok:
+			report.Missing = append(report.Missing, service.Host)
+		case !sameSpec(spec, service):
+			report.Stale = append(report.Stale, service.Host)
+		}
+	}
+	for host := range publishedByHost {
+		if !pending[host] {
+			report.Orphaned = append(report.Orphaned, host)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Stale)
+	sort.Strings(report.Orphaned)
+	return nil
+}
+
+func (s *ApihubServer) recordReconcile(report apihub.ReconcileReport, repairFailures uint64) {
+	s.syncMtx.Lock()
+	defer s.syncMtx.Unlock()
+
+	s.lastReconcile = &report
+	s.metrics.Reconciles++
+	if report.Error != "" {
+		s.metrics.ReconcileFailures++
+	}
+	s.metrics.Missing += uint64(len(report.Missing))
+	s.metrics.Stale += uint64(len(report.Stale))
+	s.metrics.Orphaned += uint64(len(report.Orphaned))
+	s.metrics.Repaired += uint64(report.Repaired)
+	s.metrics.RepairFailures += repairFailures
+}
+
+func (s *ApihubServer) syncStatus(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("sync-status")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	entries, err := s.outboxEntries()
+	if err != nil {
+		log.Error("failed-to-list-outbox", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve the outbox."))
+		return
+	}
+
+	s.syncMtx.Lock()
+	status := apihub.SyncStatus{
+		Pending:       entries,
+		LastReconcile: s.lastReconcile,
+		Metrics:       s.metrics,
+	}
+	s.syncMtx.Unlock()
+
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       status,
+	})
+}
+
+// outboxEntries returns the entries of the outbox, none when the storage does
+// not keep it.
+func (s *ApihubServer) outboxEntries() ([]apihub.OutboxEntry, error) {
+	if s.outbox == nil {
+		return []apihub.OutboxEntry{}, nil
+	}
+	return s.outbox.OutboxEntries()
+}
+
+// sameSpec reports whether the published spec is the stored one. Null and
+// empty values are alike, as the storages do not all keep them apart.
+func sameSpec(published, stored apihub.ServiceSpec) bool {
+	a, err := toDocument(published)
+	if err != nil {
+		return false
+	}
+	b, err := toDocument(stored)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(compactDocument(a), compactDocument(b))
+}
+
+// compactDocument drops the null, empty array and empty object members of
+// the objects in doc.
+func compactDocument(doc interface{}) interface{} {
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		compacted := map[string]interface{}{}
+		for name, value := range doc {
+			value = compactDocument(value)
+			switch v := value.(type) {
+			case nil:
+				continue
+			case map[string]interface{}:
+				if len(v) == 0 {
+					continue
+				}
+			case []interface{}:
+				if len(v) == 0 {
+					continue
+				}
+			}
+			compacted[name] = value
+		}
+		return compacted
+	case []interface{}:
+		compacted := make([]interface{}, len(doc))
+		for i, value := range doc {
+			compacted[i] = compactDocument(value)
+		}
+		return compacted
+	}
+	return doc
+}
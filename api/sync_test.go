@@ -0,0 +1,213 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	"github.com/apihub/apihub/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sync", func() {
+	var (
+		store                *storage.Memory
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+		service      apihub.ServiceSpec
+	)
+
+	syncStatus := func() apihub.SyncStatus {
+		code, data := server.Request(http.MethodGet, "/sync-status", "")
+		Expect(code).To(Equal(http.StatusOK))
+
+		var status apihub.SyncStatus
+		Expect(json.Unmarshal(data, &status)).To(Succeed())
+		return status
+	}
+
+	BeforeEach(func() {
+		store = storage.New()
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-sync-test")
+
+		apihubServer = api.New(log, "tcp", "", store, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+
+		service = apihub.ServiceSpec{
+			Host:     "my-host",
+			Version:  1,
+			Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+		}
+		Expect(store.AddService(service)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("outbox", func() {
+		It("leaves nothing pending when publishing succeeds", func() {
+			code, _ := server.Request(http.MethodPatch, "/services/my-host", `{"timeout":100}`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			Expect(syncStatus().Pending).To(BeEmpty())
+		})
+
+		It("publishes under the prefix of the server", func() {
+			apihubServer.SetServicesPrefix("apihub/prod/services/")
+
+			code, _ := server.Request(http.MethodPatch, "/services/my-host", `{"timeout":100}`)
+			Expect(code).To(Equal(http.StatusOK))
+			_, prefix, _ := fakeServicePublisher.PublishArgsForCall(0)
+			Expect(prefix).To(Equal("apihub/prod/services/"))
+
+			apihubServer.Reconcile()
+			_, prefix = fakeServicePublisher.PublishedArgsForCall(0)
+			Expect(prefix).To(Equal("apihub/prod/services/"))
+		})
+
+		Context("when publishing fails", func() {
+			BeforeEach(func() {
+				fakeServicePublisher.PublishReturns(errors.New("connection refused"))
+				fakeServicePublisher.UnpublishReturns(errors.New("connection refused"))
+			})
+
+			It("keeps the service in the outbox", func() {
+				code, _ := server.Request(http.MethodPatch, "/services/my-host", `{"timeout":100}`)
+				Expect(code).To(Equal(http.StatusOK))
+
+				status := syncStatus()
+				Expect(status.Pending).To(HaveLen(1))
+				Expect(status.Pending[0].Host).To(Equal("my-host"))
+				Expect(status.Pending[0].Attempts).To(Equal(1))
+				Expect(status.Pending[0].LastError).To(Equal("connection refused"))
+				Expect(status.Pending[0].NextAttempt).To(BeTemporally(">", status.Pending[0].CreatedAt))
+				Expect(status.Metrics.PublishFailures).To(BeEquivalentTo(1))
+			})
+
+			It("keeps removed services in the outbox", func() {
+				code, _ := server.Request(http.MethodDelete, "/services/my-host", "")
+				Expect(code).To(Equal(http.StatusNoContent))
+				Expect(syncStatus().Pending).To(HaveLen(1))
+			})
+
+			It("does not retry before the backoff", func() {
+				server.Request(http.MethodPatch, "/services/my-host", `{"timeout":100}`)
+
+				apihubServer.ProcessOutbox()
+				Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			})
+		})
+
+		It("publishes the stored service when retrying", func() {
+			Expect(store.PutOutboxEntry(apihub.OutboxEntry{ID: "e1", Host: "my-host", Attempts: 3})).To(Succeed())
+
+			apihubServer.ProcessOutbox()
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(1))
+			_, _, published := fakeServicePublisher.PublishArgsForCall(0)
+			Expect(published).To(Equal(service))
+
+			status := syncStatus()
+			Expect(status.Pending).To(BeEmpty())
+			Expect(status.Metrics.PublishRetries).To(BeEquivalentTo(1))
+		})
+
+		It("unpublishes services no longer stored when retrying", func() {
+			Expect(store.PutOutboxEntry(apihub.OutboxEntry{ID: "e1", Host: "removed-host"})).To(Succeed())
+
+			apihubServer.ProcessOutbox()
+			Expect(fakeServicePublisher.UnpublishCallCount()).To(Equal(1))
+			_, _, host := fakeServicePublisher.UnpublishArgsForCall(0)
+			Expect(host).To(Equal("removed-host"))
+			Expect(syncStatus().Pending).To(BeEmpty())
+		})
+
+		It("backs off again when retrying fails", func() {
+			Expect(store.PutOutboxEntry(apihub.OutboxEntry{ID: "e1", Host: "my-host", Attempts: 3})).To(Succeed())
+			fakeServicePublisher.PublishReturns(errors.New("connection refused"))
+
+			apihubServer.ProcessOutbox()
+			status := syncStatus()
+			Expect(status.Pending).To(HaveLen(1))
+			Expect(status.Pending[0].Attempts).To(Equal(4))
+		})
+	})
+
+	Describe("Reconcile", func() {
+		var published []apihub.ServiceSpec
+
+		BeforeEach(func() {
+			disabled := apihub.ServiceSpec{Host: "disabled-host", Version: 1, Disabled: true, Backends: service.Backends}
+			Expect(store.AddService(disabled)).To(Succeed())
+			Expect(store.AddService(apihub.ServiceSpec{Host: "new-host", Version: 1, Backends: service.Backends})).To(Succeed())
+
+			stale := service
+			stale.Version = 0
+			published = []apihub.ServiceSpec{stale, disabled, apihub.ServiceSpec{Host: "removed-host"}}
+			fakeServicePublisher.PublishedReturns(published, nil)
+		})
+
+		It("repairs the drift", func() {
+			report := apihubServer.Reconcile()
+			Expect(report.Error).To(BeEmpty())
+			Expect(report.Missing).To(Equal([]string{"new-host"}))
+			Expect(report.Stale).To(Equal([]string{"my-host"}))
+			Expect(report.Orphaned).To(Equal([]string{"disabled-host", "removed-host"}))
+			Expect(report.Repaired).To(Equal(4))
+
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(2))
+			Expect(fakeServicePublisher.UnpublishCallCount()).To(Equal(2))
+
+			status := syncStatus()
+			Expect(status.LastReconcile).NotTo(BeNil())
+			Expect(status.LastReconcile.Missing).To(Equal([]string{"new-host"}))
+			Expect(status.Metrics).To(Equal(apihub.DriftMetrics{Reconciles: 1, Missing: 1, Stale: 1, Orphaned: 2, Repaired: 4}))
+		})
+
+		It("finds no drift when the services are published as stored", func() {
+			fakeServicePublisher.PublishedReturns([]apihub.ServiceSpec{
+				apihub.ServiceSpec{Host: "my-host", Version: 1, Backends: service.Backends, Labels: map[string]string{}},
+				apihub.ServiceSpec{Host: "new-host", Version: 1, Backends: service.Backends},
+			}, nil)
+
+			report := apihubServer.Reconcile()
+			Expect(report.Missing).To(BeEmpty())
+			Expect(report.Stale).To(BeEmpty())
+			Expect(report.Orphaned).To(BeEmpty())
+			Expect(fakeServicePublisher.PublishCallCount()).To(Equal(0))
+		})
+
+		It("leaves the services in the outbox to it", func() {
+			Expect(store.PutOutboxEntry(apihub.OutboxEntry{ID: "e1", Host: "new-host"})).To(Succeed())
+
+			report := apihubServer.Reconcile()
+			Expect(report.Missing).To(BeEmpty())
+		})
+
+		It("counts the failed repairs", func() {
+			fakeServicePublisher.PublishReturns(errors.New("connection refused"))
+
+			report := apihubServer.Reconcile()
+			Expect(report.Repaired).To(Equal(2))
+			Expect(syncStatus().Metrics.RepairFailures).To(BeEquivalentTo(2))
+		})
+
+		It("reports when the published services cannot be listed", func() {
+			fakeServicePublisher.PublishedReturns(nil, errors.New("connection refused"))
+
+			report := apihubServer.Reconcile()
+			Expect(report.Error).To(Equal("connection refused"))
+			Expect(syncStatus().Metrics.ReconcileFailures).To(BeEquivalentTo(1))
+		})
+	})
+})
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+	"github.com/gorilla/mux"
+)
+
+func (s *ApihubServer) addToken(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("add-token")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	var token apihub.Token
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		log.Error("failed-to-parse-token", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+
+	var v validation
+	if token.Name == "" {
+		v.add("name", "Name cannot be empty.")
+	}
+	if !apihub.ValidRole(token.Role) {
+		v.add("role", "Role must be one of "+strings.Join(apihub.Roles, ", ")+".")
+	}
+	if token.Role == apihub.ROLE_ADMIN && len(token.Namespaces) > 0 {
+		v.add("namespaces", "Admin tokens cannot be restricted to namespaces.")
+	}
+	if len(token.Namespaces) > 0 && s.namespaces == nil {
+		s.handleError(rw, notImplemented("namespaces"))
+		return
+	}
+	for i, name := range token.Namespaces {
+		_, err := s.namespaces.FindNamespace(name)
+		if errors.Is(err, apihub.ErrNamespaceNotFound) {
+			v.add(fmt.Sprintf("namespaces[%d]", i), "Namespace does not exist.")
+		} else if err != nil {
+			log.Error("failed-to-find-namespace", err, lager.Data{"namespace": name})
+			s.handleError(rw, storageError(err, "Failed to find namespace."))
+			return
+		}
+	}
+	if err := v.err("The token is invalid."); err != nil {
+		s.handleError(rw, err)
+		return
+	}
+
+	issued, err := newToken(token.Name, token.Role, token.Namespaces)
+	if err != nil {
+		log.Error("failed-to-generate-token", err)
+		s.handleError(rw, apihub.NewError(apihub.E_INTERNAL_ERROR, "Failed to generate token."))
+		return
+	}
+
+	if err := s.tokens.AddToken(issued.Token); err != nil {
+		log.Error("failed-to-store-token", err)
+		s.handleError(rw, storageError(err, "Failed to add token."))
+		return
+	}
+
+	issued.Hash = ""
+	log.Info("token-added", lager.Data{"id": issued.ID, "name": issued.Name, "role": issued.Role, "namespaces": issued.Namespaces})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusCreated,
+		Body:       issued,
+	})
+}
+
+func (s *ApihubServer) listTokens(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("list-tokens")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	tokens, err := s.tokens.Tokens()
+	if err != nil {
+		log.Error("failed-to-list-tokens", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve token list."))
+		return
+	}
+
+	for i := range tokens {
+		tokens[i].Hash = ""
+	}
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       Collection(tokens, len(tokens)),
+	})
+}
+
+func (s *ApihubServer) removeToken(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("remove-token")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	id := mux.Vars(r)["id"]
+
+	if err := s.tokens.RemoveToken(id); err != nil {
+		log.Error("failed-to-remove-token", err, lager.Data{"id": id})
+		s.handleError(rw, storageError(err, "Failed to remove token."))
+		return
+	}
+
+	log.Info("token-removed", lager.Data{"id": id})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusNoContent,
+	})
+}
+
+// newToken generates the ID and secret of a token. Only the hash of the
+// secret is kept in the token.
+func newToken(name, role string, namespaces []string) (apihub.IssuedToken, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return apihub.IssuedToken{}, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return apihub.IssuedToken{}, err
+	}
+
+	issued := apihub.IssuedToken{
+		Token: apihub.Token{
+			ID:         hex.EncodeToString(id),
+			Name:       name,
+			Role:       role,
+			Namespaces: namespaces,
+			CreatedAt:  time.Now().UTC().Truncate(time.Second),
+		},
+		Secret: base64.RawURLEncoding.EncodeToString(secret),
+	}
+	issued.Hash = apihub.HashToken(issued.Secret)
+	return issued, nil
+}
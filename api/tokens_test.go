@@ -0,0 +1,150 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/albertoleal/requests"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tokens", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+		httpClient   requests.HTTPClient
+	)
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-tokens-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+		httpClient = requests.NewHTTPClient(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("addToken", func() {
+		It("stores the hash of a new secret", func() {
+			_, code, body, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusCreated,
+				Method:         http.MethodPost,
+				Path:           "/tokens",
+				Body:           `{"name":"deploys","role":"editor"}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusCreated))
+
+			var issued apihub.IssuedToken
+			Expect(json.Unmarshal(body, &issued)).To(Succeed())
+			Expect(issued.ID).NotTo(BeEmpty())
+			Expect(issued.Name).To(Equal("deploys"))
+			Expect(issued.Role).To(Equal(apihub.ROLE_EDITOR))
+			Expect(issued.Secret).To(HaveLen(43))
+			Expect(issued.CreatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(string(body)).NotTo(ContainSubstring(`"hash"`))
+
+			stored := fakeStorage.AddTokenArgsForCall(0)
+			Expect(stored.ID).To(Equal(issued.ID))
+			Expect(stored.Hash).To(Equal(apihub.HashToken(issued.Secret)))
+		})
+
+		It("rejects an invalid token", func() {
+			_, code, body, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusUnprocessableEntity,
+				Method:         http.MethodPost,
+				Path:           "/tokens",
+				Body:           `{"role":"owner"}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(string(body)).To(ContainSubstring(`"error_details":[{"field":"name","description":"Name cannot be empty."},{"field":"role","description":"Role must be one of read-only, editor, admin."}]`))
+			Expect(fakeStorage.AddTokenCallCount()).To(Equal(0))
+		})
+
+		It("restricts the token to existing namespaces", func() {
+			fakeStorage.FindNamespaceStub = func(name string) (apihub.Namespace, error) {
+				if name == "payments" {
+					return apihub.Namespace{Name: name}, nil
+				}
+				return apihub.Namespace{}, apihub.ErrNamespaceNotFound
+			}
+
+			_, code, body, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusUnprocessableEntity,
+				Method:         http.MethodPost,
+				Path:           "/tokens",
+				Body:           `{"name":"deploys","role":"admin","namespaces":["payments","search"]}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(string(body)).To(ContainSubstring(`"error_details":[{"field":"namespaces","description":"Admin tokens cannot be restricted to namespaces."},{"field":"namespaces[1]","description":"Namespace does not exist."}]`))
+
+			_, code, _, err = httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusCreated,
+				Method:         http.MethodPost,
+				Path:           "/tokens",
+				Body:           `{"name":"deploys","role":"editor","namespaces":["payments"]}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusCreated))
+			Expect(fakeStorage.AddTokenArgsForCall(0).Namespaces).To(Equal([]string{"payments"}))
+		})
+	})
+
+	Describe("listTokens", func() {
+		It("lists the tokens without their hash", func() {
+			fakeStorage.TokensReturns([]apihub.Token{
+				apihub.Token{ID: "a-token", Name: "deploys", Role: apihub.ROLE_EDITOR, Hash: apihub.HashToken("secret")},
+			}, nil)
+
+			_, code, body, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusOK,
+				Method:         http.MethodGet,
+				Path:           "/tokens",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(body)).To(ContainSubstring(`{"items":[{"id":"a-token","name":"deploys","role":"editor","created_at":"0001-01-01T00:00:00Z"}],"item_count":1}`))
+		})
+	})
+
+	Describe("removeToken", func() {
+		It("removes the token", func() {
+			_, code, _, err := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusNoContent,
+				Method:         http.MethodDelete,
+				Path:           "/tokens/a-token",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusNoContent))
+			Expect(fakeStorage.RemoveTokenArgsForCall(0)).To(Equal("a-token"))
+		})
+
+		It("returns not found for an unknown token", func() {
+			fakeStorage.RemoveTokenReturns(apihub.ErrTokenNotFound)
+
+			_, code, _, _ := httpClient.MakeRequest(requests.Args{
+				AcceptableCode: http.StatusNoContent,
+				Method:         http.MethodDelete,
+				Path:           "/tokens/a-token",
+			})
+			Expect(code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
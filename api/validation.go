@@ -0,0 +1,192 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apihub/apihub"
+)
+
+const (
+	// MAX_SERVICE_TIMEOUT is the longest a gateway waits for a backend.
+	MAX_SERVICE_TIMEOUT = 10 * time.Minute
+	MAX_HOST_LENGTH     = 253
+	MAX_LABEL_LENGTH    = 63
+)
+
+// BackendSchemes are the schemes the gateway is able to proxy to.
+var BackendSchemes = []string{"http", "https"}
+
+// validation collects the invalid fields of a request, so all of them are
+// reported at once.
+type validation []apihub.FieldError
+
+func (v *validation) add(field, description string) {
+	*v = append(*v, apihub.FieldError{Field: field, Description: description})
+}
+
+// err returns a validation error listing the invalid fields, or nil when
+// there is none.
+func (v validation) err(description string) error {
+	if len(v) == 0 {
+		return nil
+	}
+	return &apihub.Error{
+		Code:        apihub.E_VALIDATION_FAILED,
+		Description: description,
+		Details:     v,
+	}
+}
+
+// validateService checks a service before it is stored, either added or
+// updated. Disabled services may have no backends, so they can be emptied
+// while out of the gateway.
+func validateService(spec apihub.ServiceSpec) error {
+	var v validation
+
+	if err := validateHost(spec.Host); err != "" {
+		v.add("host", err)
+	}
+	if spec.Timeout < 0 || spec.Timeout > MAX_SERVICE_TIMEOUT {
+		v.add("timeout", fmt.Sprintf("Timeout must be between 0 and %s.", MAX_SERVICE_TIMEOUT))
+	}
+
+	if len(spec.Backends) == 0 && !spec.Disabled {
+		v.add("backends", "Enabled services must have at least one backend.")
+	}
+	for i, be := range spec.Backends {
+		field := fmt.Sprintf("backends[%d]", i)
+		if err := validateURL(be.Address); err != "" {
+			v.add(field+".address", err)
+		}
+		if be.HeartBeatAddress != "" {
+			if err := validateURL(be.HeartBeatAddress); err != "" {
+				v.add(field+".heart_beat_address", err)
+			}
+		}
+		if be.HeartBeatTimeout < 0 {
+			v.add(field+".heart_beat_timeout", "Heartbeat timeout cannot be negative.")
+		}
+	}
+
+	if m := spec.Maintenance; m != nil && m.RetryAfter < 0 {
+		v.add("maintenance.retry_after", "Retry after cannot be negative.")
+	}
+	for status := range spec.ErrorPages {
+		if status < 400 || status > 599 {
+			v.add("error_pages."+strconv.Itoa(status), "Error pages must be keyed by an error status.")
+		}
+	}
+	if l := spec.Limits; l != nil {
+		if l.ReadTimeout < 0 {
+			v.add("limits.read_timeout", "Read timeout cannot be negative.")
+		}
+		if l.WriteTimeout < 0 {
+			v.add("limits.write_timeout", "Write timeout cannot be negative.")
+		}
+		if l.MaxHeaderBytes < 0 {
+			v.add("limits.max_header_bytes", "Max header bytes cannot be negative.")
+		}
+		if l.MaxBodyBytes < 0 {
+			v.add("limits.max_body_bytes", "Max body bytes cannot be negative.")
+		}
+	}
+	if t := spec.Transport; t != nil {
+		if t.MaxIdleConns < 0 {
+			v.add("transport.max_idle_conns", "Max idle connections cannot be negative.")
+		}
+		if t.MaxConnsPerHost < 0 {
+			v.add("transport.max_conns_per_host", "Max connections per host cannot be negative.")
+		}
+		if t.KeepAlive < 0 {
+			v.add("transport.keep_alive", "Keep alive cannot be negative.")
+		}
+	}
+	if cb := spec.CircuitBreaker; cb != nil {
+		if cb.Threshold < 1 {
+			v.add("circuit_breaker.threshold", "Circuit breaker threshold must be at least 1.")
+		}
+		if cb.Cooldown < 0 {
+			v.add("circuit_breaker.cooldown", "Circuit breaker cooldown cannot be negative.")
+		}
+	}
+
+	v.addFaults(spec.Faults)
+	return v.err("The service is invalid.")
+}
+
+func validateFaults(faults []apihub.FaultRule) error {
+	var v validation
+	v.addFaults(faults)
+	return v.err("The faults are invalid.")
+}
+
+func (v *validation) addFaults(faults []apihub.FaultRule) {
+	for i, fault := range faults {
+		for _, err := range fault.Validate() {
+			v.add(fmt.Sprintf("faults[%d].%s", i, err.Field), err.Description)
+		}
+	}
+}
+
+// validateHost checks the host is a valid hostname, see RFC 1123, with an
+// optional port as the gateway matches the Host header of the requests.
+// It returns the description of the error, or an empty string.
+func validateHost(host string) string {
+	if host == "" {
+		return "Host cannot be empty."
+	}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "Host has an invalid port."
+		}
+		host = h
+	}
+
+	if len(host) > MAX_HOST_LENGTH {
+		return fmt.Sprintf("Host cannot be longer than %d characters.", MAX_HOST_LENGTH)
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if !validLabel(label) {
+			return "Host must be a valid hostname, as in RFC 1123."
+		}
+	}
+	return ""
+}
+
+func validLabel(label string) bool {
+	if len(label) == 0 || len(label) > MAX_LABEL_LENGTH {
+		return false
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, c := range label {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateURL checks address is an absolute URL the gateway can proxy to. It
+// returns the description of the error, or an empty string.
+func validateURL(address string) string {
+	if address == "" {
+		return "Address cannot be empty."
+	}
+	u, err := url.Parse(address)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "Address must be an absolute URL."
+	}
+	for _, scheme := range BackendSchemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Address scheme must be one of %s.", strings.Join(BackendSchemes, ", "))
+}
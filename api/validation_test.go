@@ -0,0 +1,194 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validation", func() {
+	var (
+		fakeStorage          *apihubfakes.FakeStorage
+		fakeServicePublisher *apihubfakes.FakeServicePublisher
+		log                  *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+	)
+
+	// sendInvalid sends the request, and decodes the error of the response.
+	sendInvalid := func(method, path, body string, header http.Header) (int, apihub.ErrorResponse) {
+		resp, data := server.Do(method, path, body, header)
+		var errResp apihub.ErrorResponse
+		json.Unmarshal(data, &errResp)
+		return resp.StatusCode, errResp
+	}
+
+	addService := func(body string) (int, apihub.ErrorResponse) {
+		return sendInvalid(http.MethodPost, "/services", body, nil)
+	}
+
+	BeforeEach(func() {
+		fakeStorage = new(apihubfakes.FakeStorage)
+		fakeServicePublisher = new(apihubfakes.FakeServicePublisher)
+		log = lagertest.NewTestLogger("apihub-validation-test")
+
+		apihubServer = api.New(log, "tcp", "", fakeStorage, fakeServicePublisher)
+		server = newTestServer(apihubServer)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("host", func() {
+		It("accepts hostnames with an optional port", func() {
+			for _, host := range []string{"my-host", "my-host.apihub.dev", "MY-HOST.apihub.dev.", "10.0.0.1", "my-host.apihub.dev:8080"} {
+				code, _ := addService(`{"host":"` + host + `","backends":[{"address":"http://server-a"}]}`)
+				Expect(code).To(Equal(http.StatusCreated), host)
+			}
+		})
+
+		It("rejects invalid hostnames", func() {
+			for _, host := range []string{"my_host.apihub.dev", "-my-host.apihub.dev", "my-host-.apihub.dev", "my-host..apihub.dev", "my host", "http://my-host", "my-host:0", strings.Repeat("a", 64) + ".apihub.dev"} {
+				code, resp := addService(`{"host":"` + host + `","backends":[{"address":"http://server-a"}]}`)
+				Expect(code).To(Equal(http.StatusUnprocessableEntity), host)
+				Expect(resp.Details).To(HaveLen(1), host)
+				Expect(resp.Details[0].Field).To(Equal("host"))
+			}
+			Expect(fakeStorage.AddServiceCallCount()).To(Equal(0))
+		})
+
+		It("rejects hosts longer than 253 characters", func() {
+			host := strings.Repeat(strings.Repeat("a", 63)+".", 4)
+			code, resp := addService(`{"host":"` + host + `","backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(resp.Details).To(ConsistOf(apihub.FieldError{Field: "host", Description: "Host cannot be longer than 253 characters."}))
+		})
+	})
+
+	Describe("backends", func() {
+		It("rejects addresses that are not absolute URLs", func() {
+			code, resp := addService(`{"host":"my-host","backends":[
+				{"address":"http://server-a"},
+				{"address":"server-b:8080"},
+				{"address":"/server-c"},
+				{"address":"http://%zz"},
+				{"address":""}
+			]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(resp.Error).To(Equal(apihub.E_VALIDATION_FAILED))
+			Expect(resp.Description).To(Equal("The service is invalid."))
+			Expect(resp.Details).To(Equal([]apihub.FieldError{
+				apihub.FieldError{Field: "backends[1].address", Description: "Address must be an absolute URL."},
+				apihub.FieldError{Field: "backends[2].address", Description: "Address must be an absolute URL."},
+				apihub.FieldError{Field: "backends[3].address", Description: "Address must be an absolute URL."},
+				apihub.FieldError{Field: "backends[4].address", Description: "Address cannot be empty."},
+			}))
+		})
+
+		It("rejects schemes the gateway cannot proxy to", func() {
+			code, resp := addService(`{"host":"my-host","backends":[{"address":"ftp://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(resp.Details).To(ConsistOf(apihub.FieldError{Field: "backends[0].address", Description: "Address scheme must be one of http, https."}))
+		})
+
+		It("checks the heartbeat of the backends", func() {
+			code, resp := addService(`{"host":"my-host","backends":[
+				{"address":"https://server-a","heart_beat_address":"https://server-a/healthcheck","heart_beat_timeout":100},
+				{"address":"https://server-b","heart_beat_address":"healthcheck","heart_beat_timeout":-1}
+			]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(resp.Details).To(Equal([]apihub.FieldError{
+				apihub.FieldError{Field: "backends[1].heart_beat_address", Description: "Address must be an absolute URL."},
+				apihub.FieldError{Field: "backends[1].heart_beat_timeout", Description: "Heartbeat timeout cannot be negative."},
+			}))
+		})
+
+		It("accepts disabled services without backends", func() {
+			code, _ := addService(`{"host":"my-host","disabled":true}`)
+			Expect(code).To(Equal(http.StatusCreated))
+		})
+	})
+
+	Describe("timeouts and limits", func() {
+		It("rejects timeouts out of range", func() {
+			for _, timeout := range []string{"-1", "600000000001"} {
+				code, resp := addService(`{"host":"my-host","timeout":` + timeout + `,"backends":[{"address":"http://server-a"}]}`)
+				Expect(code).To(Equal(http.StatusUnprocessableEntity), timeout)
+				Expect(resp.Details).To(ConsistOf(apihub.FieldError{Field: "timeout", Description: "Timeout must be between 0 and 10m0s."}))
+			}
+		})
+
+		It("reports every invalid field", func() {
+			code, resp := addService(`{"host":"my-host","backends":[{"address":"http://server-a"}],
+				"maintenance":{"enabled":true,"retry_after":-1},
+				"error_pages":{"200":{"body":"ok"}},
+				"limits":{"read_timeout":-1,"write_timeout":-1,"max_header_bytes":-1,"max_body_bytes":-1},
+				"transport":{"max_idle_conns":-1,"max_conns_per_host":-1,"keep_alive":-1},
+				"circuit_breaker":{"threshold":0,"cooldown":-1},
+				"faults":[{"header":"X-Chaos","percentage":101,"abort_status":200,"delay":-1}]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+
+			fields := []string{}
+			for _, detail := range resp.Details {
+				fields = append(fields, detail.Field)
+			}
+			Expect(fields).To(Equal([]string{
+				"maintenance.retry_after",
+				"error_pages.200",
+				"limits.read_timeout",
+				"limits.write_timeout",
+				"limits.max_header_bytes",
+				"limits.max_body_bytes",
+				"transport.max_idle_conns",
+				"transport.max_conns_per_host",
+				"transport.keep_alive",
+				"circuit_breaker.threshold",
+				"circuit_breaker.cooldown",
+				"faults[0].percentage",
+				"faults[0].abort_status",
+				"faults[0].delay",
+			}))
+		})
+	})
+
+	Describe("updates", func() {
+		BeforeEach(func() {
+			fakeStorage.FindServiceByHostReturns(apihub.ServiceSpec{
+				Host:     "my-host.apihub.dev",
+				Version:  1,
+				Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+			}, nil)
+		})
+
+		It("validates the result of a patch", func() {
+			code, resp := sendInvalid(http.MethodPatch, "/services/my-host.apihub.dev", `{"backends":[{"address":"server-b"}]}`,
+				http.Header{"Content-Type": {api.MERGE_PATCH_CONTENT_TYPE}})
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(resp.Details).To(ConsistOf(apihub.FieldError{Field: "backends[0].address", Description: "Address must be an absolute URL."}))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+
+		It("validates a plain JSON update", func() {
+			code, resp := sendInvalid(http.MethodPatch, "/services/my-host.apihub.dev", `{"timeout":-1}`, nil)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(resp.Details).To(ConsistOf(apihub.FieldError{Field: "timeout", Description: "Timeout must be between 0 and 10m0s."}))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+
+		It("validates a replacement", func() {
+			code, resp := sendInvalid(http.MethodPut, "/services/my-host.apihub.dev", `{"backends":[{"address":"http://"}]}`, nil)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(resp.Details).To(ConsistOf(apihub.FieldError{Field: "backends[0].address", Description: "Address must be an absolute URL."}))
+			Expect(fakeStorage.UpdateServiceCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+// WATCH_CONTENT_TYPE is the type of the streams of GET /services?watch=true:
+// a WatchEvent per line.
+const WATCH_CONTENT_TYPE = "application/x-ndjson"
+
+const (
+	DEFAULT_WATCH_INTERVAL = time.Second
+
+	watchHeartbeatInterval = 30 * time.Second
+)
+
+var errWatchGone = apihub.NewError(apihub.E_GONE, "The resource version is too old, watch the services again from the start.")
+
+// watchHub polls the version of the last service event logged by the
+// storage, and wakes the watchers up when it changes. The watchers then read
+// the events from the storage themselves, so they see the changes made by
+// every API server sharing it. It only polls while there are watchers.
+type watchHub struct {
+	logger   lager.Logger
+	events   apihub.ServiceEventStorage
+	interval time.Duration
+
+	mtx      sync.Mutex
+	version  uint64
+	changed  chan struct{}
+	watchers int
+	stop     chan struct{}
+}
+
+func newWatchHub(logger lager.Logger, events apihub.ServiceEventStorage) *watchHub {
+	return &watchHub{
+		logger:   logger,
+		events:   events,
+		interval: DEFAULT_WATCH_INTERVAL,
+		changed:  make(chan struct{}),
+	}
+}
+
+func (h *watchHub) subscribe() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.watchers++
+	if h.watchers == 1 {
+		h.stop = make(chan struct{})
+		go h.poll(h.interval, h.stop)
+	}
+}
+
+func (h *watchHub) unsubscribe() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.watchers--
+	if h.watchers == 0 {
+		close(h.stop)
+	}
+}
+
+// wait returns a channel closed once the storage logs a new event.
+func (h *watchHub) wait() <-chan struct{} {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return h.changed
+}
+
+func (h *watchHub) poll(interval time.Duration, stop chan struct{}) {
+	log := h.logger.Session("watch-poll")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			version, err := h.events.ServiceEventsVersion()
+			if err != nil {
+				log.Error("failed-to-read-version", err)
+				continue
+			}
+
+			h.mtx.Lock()
+			if version != h.version {
+				h.version = version
+				close(h.changed)
+				h.changed = make(chan struct{})
+			}
+			h.mtx.Unlock()
+		}
+	}
+}
+
+// watchServices streams the changes to the services selected by the query,
+// a WatchEvent per line, until the client goes away or falls behind the
+// events kept by the storage. Without a resource version, the stream starts
+// with an added event per service; a service changed meanwhile may then be
+// sent twice.
+func (s *ApihubServer) watchServices(rw http.ResponseWriter, r *http.Request, query apihub.ServiceQuery, resourceVersion uint64) {
+	log := s.logger.Session("watch-services")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	if s.events == nil {
+		s.handleError(rw, notImplemented("watches"))
+		return
+	}
+
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		s.handleError(rw, apihub.NewError(apihub.E_INTERNAL_ERROR, "Streaming is not supported."))
+		return
+	}
+
+	visible := scopeQuery(requestToken(r), &query)
+	matches := func(event apihub.ServiceEvent) bool {
+		if !visible {
+			return false
+		}
+		return (event.After != nil && query.Matches(*event.After)) || (event.Before != nil && query.Matches(*event.Before))
+	}
+
+	s.watch.subscribe()
+	defer s.watch.unsubscribe()
+
+	version := resourceVersion
+	replay := []apihub.ServiceEvent{}
+	if version == 0 {
+		var err error
+		if version, err = s.events.ServiceEventsVersion(); err != nil {
+			log.Error("failed-to-read-version", err)
+			s.handleError(rw, storageError(err, "Failed to retrieve the changes to the services."))
+			return
+		}
+		services, err := s.storage.Services()
+		if err != nil {
+			log.Error("failed-to-list-services", err)
+			s.handleError(rw, storageError(err, "Failed to retrieve service list."))
+			return
+		}
+		for i := range services {
+			replay = append(replay, apihub.ServiceEvent{Version: version, Type: apihub.WATCH_ADDED, After: &services[i]})
+		}
+	}
+
+	changed := s.watch.wait()
+	events, err := s.events.ServiceEvents(version)
+	if err == apihub.ErrServiceEventsPruned {
+		log.Info("failed-to-resume", lager.Data{"resource-version": resourceVersion})
+		s.handleError(rw, errWatchGone)
+		return
+	}
+	if err != nil {
+		log.Error("failed-to-read-events", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve the changes to the services."))
+		return
+	}
+
+	rw.Header().Set("Content-Type", WATCH_CONTENT_TYPE)
+	rw.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(rw)
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+	events = append(replay, events...)
+	for {
+		for _, event := range events {
+			version = event.Version
+			if !matches(event) {
+				continue
+			}
+			if err := enc.Encode(event.WatchEvent()); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		events = nil
+		for events == nil {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case <-changed:
+				changed = s.watch.wait()
+				if events, err = s.events.ServiceEvents(version); err != nil {
+					log.Info("failed-to-read-events", lager.Data{"error": err.Error()})
+					return
+				}
+
+			case <-heartbeat.C:
+				if err := enc.Encode(apihub.WatchEvent{Type: apihub.WATCH_HEARTBEAT, ResourceVersion: version}); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	"github.com/apihub/apihub/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watch", func() {
+	var (
+		store   *storage.Memory
+		log     *lagertest.TestLogger
+		cancels []context.CancelFunc
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+	)
+
+	watch := func(query string) (*http.Response, <-chan apihub.WatchEvent) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/services?watch=true"+query, nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		Expect(err).NotTo(HaveOccurred())
+
+		events := make(chan apihub.WatchEvent, 10)
+		go func() {
+			defer GinkgoRecover()
+			defer close(events)
+			defer resp.Body.Close()
+
+			decoder := json.NewDecoder(resp.Body)
+			for {
+				var event apihub.WatchEvent
+				if err := decoder.Decode(&event); err != nil {
+					return
+				}
+				events <- event
+			}
+		}()
+		return resp, events
+	}
+
+	BeforeEach(func() {
+		store = storage.New()
+		cancels = nil
+		log = lagertest.NewTestLogger("apihub-watch-test")
+
+		apihubServer = api.New(log, "tcp", "", store, new(apihubfakes.FakeServicePublisher))
+		apihubServer.SetWatchInterval(10 * time.Millisecond)
+		server = newTestServer(apihubServer)
+
+		Expect(store.AddService(apihub.ServiceSpec{
+			Host:     "my-host",
+			Version:  1,
+			Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		server.Close()
+	})
+
+	It("starts with the services, then streams their changes", func() {
+		resp, events := watch("")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal(api.WATCH_CONTENT_TYPE))
+
+		var event apihub.WatchEvent
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Type).To(Equal(apihub.WATCH_ADDED))
+		Expect(event.Service.Host).To(Equal("my-host"))
+		start := event.ResourceVersion
+
+		code, _ := server.Request(http.MethodPatch, "/services/my-host", `{"timeout":100}`)
+		Expect(code).To(Equal(http.StatusOK))
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Type).To(Equal(apihub.WATCH_UPDATED))
+		Expect(event.Service.Timeout).To(BeEquivalentTo(100))
+		Expect(event.ResourceVersion).To(Equal(start + 1))
+
+		code, _ = server.Request(http.MethodDelete, "/services/my-host", "")
+		Expect(code).To(Equal(http.StatusNoContent))
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Type).To(Equal(apihub.WATCH_REMOVED))
+		Expect(event.Service.Host).To(Equal("my-host"))
+		Expect(event.ResourceVersion).To(Equal(start + 2))
+	})
+
+	It("resumes from a resource version", func() {
+		_, events := watch("")
+		var event apihub.WatchEvent
+		Eventually(events).Should(Receive(&event))
+		start := event.ResourceVersion
+
+		code, _ := server.Request(http.MethodPatch, "/services/my-host", `{"timeout":100}`)
+		Expect(code).To(Equal(http.StatusOK))
+		code, _ = server.Request(http.MethodPatch, "/services/my-host", `{"timeout":200}`)
+		Expect(code).To(Equal(http.StatusOK))
+
+		_, events = watch(fmt.Sprintf("&resource_version=%d", start+1))
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Type).To(Equal(apihub.WATCH_UPDATED))
+		Expect(event.Service.Timeout).To(BeEquivalentTo(200))
+		Consistently(events).ShouldNot(Receive())
+	})
+
+	It("sends the update taking a service out of the query, and nothing after", func() {
+		_, events := watch("&disabled=false")
+		var event apihub.WatchEvent
+		Eventually(events).Should(Receive(&event))
+
+		code, _ := server.Request(http.MethodPatch, "/services/my-host", `{"disabled":true}`)
+		Expect(code).To(Equal(http.StatusOK))
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Type).To(Equal(apihub.WATCH_UPDATED))
+		Expect(event.Service.Disabled).To(BeTrue())
+
+		code, _ = server.Request(http.MethodDelete, "/services/my-host", "")
+		Expect(code).To(Equal(http.StatusNoContent))
+		Consistently(events).ShouldNot(Receive())
+	})
+
+	It("only sends the services matching the query", func() {
+		_, events := watch("&host_prefix=other")
+		Consistently(events).ShouldNot(Receive())
+
+		code, _ := server.Request(http.MethodPost, "/services", `{"host":"other-host","backends":[{"address":"http://server-a"}]}`)
+		Expect(code).To(Equal(http.StatusCreated))
+		var event apihub.WatchEvent
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Type).To(Equal(apihub.WATCH_ADDED))
+		Expect(event.Service.Host).To(Equal("other-host"))
+	})
+
+	It("streams the changes made by the other servers sharing the storage", func() {
+		_, events := watch("")
+		var event apihub.WatchEvent
+		Eventually(events).Should(Receive(&event))
+
+		Expect(store.RemoveService("my-host")).To(Succeed())
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Type).To(Equal(apihub.WATCH_REMOVED))
+		Expect(event.Service.Host).To(Equal("my-host"))
+	})
+
+	It("returns gone when the storage no longer keeps the events after the resource version", func() {
+		for i := 0; i <= storage.SERVICE_EVENT_LIMIT; i++ {
+			Expect(store.UpdateService(apihub.ServiceSpec{Host: "my-host", Version: uint64(i + 1)})).To(Succeed())
+		}
+
+		resp, _ := watch("&resource_version=1")
+		Expect(resp.StatusCode).To(Equal(http.StatusGone))
+	})
+
+	It("rejects an invalid resource version", func() {
+		resp, _ := watch("&resource_version=yesterday")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	Context("when the storage does not log the changes to the services", func() {
+		BeforeEach(func() {
+			server.Close()
+			apihubServer = api.New(log, "tcp", "", struct{ apihub.Storage }{store}, new(apihubfakes.FakeServicePublisher))
+			server = newTestServer(apihubServer)
+		})
+
+		It("returns not implemented", func() {
+			code, data := server.Request(http.MethodGet, "/services?watch=true", "")
+			Expect(code).To(Equal(http.StatusNotImplemented))
+			Expect(string(data)).To(ContainSubstring("The storage does not support watches."))
+		})
+	})
+})
@@ -0,0 +1,343 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+	"github.com/gorilla/mux"
+)
+
+const (
+	DEFAULT_WEBHOOK_INTERVAL = time.Second
+
+	// A delivery fails for good after maxWebhookAttempts, about twenty
+	// minutes after the first one with the backoff of the retries.
+	maxWebhookAttempts = 12
+	webhookTimeout     = 10 * time.Second
+	// webhookConcurrency is the number of webhooks posted to at once.
+	webhookConcurrency = 8
+	// webhookRetention is how long the delivered and failed deliveries are
+	// kept in the delivery log.
+	webhookRetention = 7 * 24 * time.Hour
+)
+
+func (s *ApihubServer) addWebhook(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("add-webhook")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	var webhook apihub.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		log.Error("failed-to-parse-webhook", err)
+		s.handleError(rw, errors.New("Failed to parse request."))
+		return
+	}
+
+	var v validation
+	if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add("url", "URL must be an absolute http or https URL.")
+	}
+	for i, event := range webhook.Events {
+		if !apihub.ValidWebhookEvent(event) {
+			v.add(fmt.Sprintf("events[%d]", i), "Event must be one of "+strings.Join(apihub.WebhookEvents, ", ")+".")
+		}
+	}
+	if err := v.err("The webhook is invalid."); err != nil {
+		s.handleError(rw, err)
+		return
+	}
+
+	var err error
+	if webhook.ID, err = randomHex(8); err == nil && webhook.Secret == "" {
+		webhook.Secret, err = randomHex(32)
+	}
+	if err != nil {
+		log.Error("failed-to-generate-webhook", err)
+		s.handleError(rw, apihub.NewError(apihub.E_INTERNAL_ERROR, "Failed to generate webhook."))
+		return
+	}
+	webhook.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	if err := s.webhooks.AddWebhook(webhook); err != nil {
+		log.Error("failed-to-store-webhook", err)
+		s.handleError(rw, storageError(err, "Failed to add webhook."))
+		return
+	}
+
+	log.Info("webhook-added", lager.Data{"id": webhook.ID, "url": webhook.URL, "events": webhook.Events})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusCreated,
+		Body:       webhook,
+	})
+}
+
+func (s *ApihubServer) listWebhooks(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("list-webhooks")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	webhooks, err := s.webhooks.Webhooks()
+	if err != nil {
+		log.Error("failed-to-list-webhooks", err)
+		s.handleError(rw, storageError(err, "Failed to retrieve webhook list."))
+		return
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       Collection(webhooks, len(webhooks)),
+	})
+}
+
+func (s *ApihubServer) findWebhook(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("find-webhook")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	id := mux.Vars(r)["id"]
+
+	webhook, err := s.webhooks.FindWebhook(id)
+	if err != nil {
+		log.Error("failed-to-find-webhook", err, lager.Data{"id": id})
+		s.handleError(rw, storageError(err, "Webhook not found."))
+		return
+	}
+
+	webhook.Secret = ""
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       webhook,
+	})
+}
+
+func (s *ApihubServer) removeWebhook(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("remove-webhook")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	id := mux.Vars(r)["id"]
+
+	if err := s.webhooks.RemoveWebhook(id); err != nil {
+		log.Error("failed-to-remove-webhook", err, lager.Data{"id": id})
+		s.handleError(rw, storageError(err, "Failed to remove webhook."))
+		return
+	}
+
+	log.Info("webhook-removed", lager.Data{"id": id})
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusNoContent,
+	})
+}
+
+// listWebhookDeliveries writes the delivery log of a webhook, newest first.
+func (s *ApihubServer) listWebhookDeliveries(rw http.ResponseWriter, r *http.Request) {
+	log := s.logger.Session("list-webhook-deliveries")
+	log.Debug("start")
+	defer log.Debug("end")
+
+	id := mux.Vars(r)["id"]
+
+	if _, err := s.webhooks.FindWebhook(id); err != nil {
+		log.Error("failed-to-find-webhook", err, lager.Data{"id": id})
+		s.handleError(rw, storageError(err, "Webhook not found."))
+		return
+	}
+
+	deliveries, err := s.webhooks.WebhookDeliveries(id)
+	if err != nil {
+		log.Error("failed-to-list-deliveries", err, lager.Data{"id": id})
+		s.handleError(rw, storageError(err, "Failed to retrieve delivery list."))
+		return
+	}
+
+	s.writeResponse(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       Collection(deliveries, len(deliveries)),
+	})
+}
+
+// notifyWebhooks queues a delivery of the change to the webhooks subscribed
+// to its event. Before is nil for created services and after for deleted
+// ones. The deliveries are sent by DeliverWebhooks; failing to queue them is
+// only logged, as the change has already been made.
+func (s *ApihubServer) notifyWebhooks(log lager.Logger, before, after *apihub.ServiceSpec) {
+	if s.webhooks == nil {
+		return
+	}
+	payload := apihub.WebhookPayload{Time: time.Now().UTC(), Service: after}
+	switch {
+	case before == nil:
+		payload.Event = apihub.WEBHOOK_SERVICE_CREATED
+	case after == nil:
+		payload.Event = apihub.WEBHOOK_SERVICE_DELETED
+		payload.Service = before
+	case after.Disabled && !before.Disabled:
+		payload.Event = apihub.WEBHOOK_SERVICE_DISABLED
+	default:
+		payload.Event = apihub.WEBHOOK_SERVICE_UPDATED
+	}
+	payload.Host = payload.Service.Host
+
+	webhooks, err := s.webhooks.Webhooks()
+	if err != nil {
+		log.Error("failed-to-list-webhooks", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(payload.Event) {
+			continue
+		}
+
+		delivery := apihub.WebhookDelivery{
+			WebhookID:   webhook.ID,
+			Event:       payload.Event,
+			Host:        payload.Host,
+			Status:      apihub.DELIVERY_PENDING,
+			CreatedAt:   payload.Time,
+			NextAttempt: payload.Time,
+		}
+		if delivery.ID, err = randomHex(8); err == nil {
+			payload.ID = delivery.ID
+			delivery.Payload, err = json.Marshal(payload)
+		}
+		if err == nil {
+			err = s.webhooks.PutWebhookDelivery(delivery)
+		}
+		if err != nil {
+			log.Error("failed-to-queue-delivery", err, lager.Data{"webhook": webhook.ID, "event": payload.Event, "host": payload.Host})
+		}
+	}
+}
+
+// DeliverWebhooks posts the pending deliveries whose next attempt is due,
+// and prunes the deliveries done for longer than webhookRetention. Failed
+// attempts are retried with a backoff, up to maxWebhookAttempts.
+//
+// Up to webhookConcurrency webhooks are posted to at once, each of them its
+// deliveries in order. The first delivery failing stops the run for its
+// webhook, so a receiver that is down does not hold up the others for the
+// timeout of every delivery queued for it.
+func (s *ApihubServer) DeliverWebhooks() {
+	if s.webhooks == nil {
+		return
+	}
+	log := s.logger.Session("deliver-webhooks")
+
+	s.webhooksMtx.Lock()
+	defer s.webhooksMtx.Unlock()
+
+	now := time.Now()
+	if err := s.webhooks.PruneWebhookDeliveries(now.Add(-webhookRetention)); err != nil {
+		log.Error("failed-to-prune-deliveries", err)
+	}
+
+	deliveries, err := s.webhooks.DueWebhookDeliveries(now)
+	if err != nil {
+		log.Error("failed-to-list-deliveries", err)
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	webhooks, err := s.webhooks.Webhooks()
+	if err != nil {
+		log.Error("failed-to-list-webhooks", err)
+		return
+	}
+
+	queued := map[string][]apihub.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		queued[delivery.WebhookID] = append(queued[delivery.WebhookID], delivery)
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, webhookConcurrency)
+	for _, webhook := range webhooks {
+		if len(queued[webhook.ID]) == 0 {
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(webhook apihub.Webhook, deliveries []apihub.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			for _, delivery := range deliveries {
+				s.deliver(log, webhook, &delivery)
+				if err := s.webhooks.PutWebhookDelivery(delivery); err != nil {
+					log.Error("failed-to-store-delivery", err, lager.Data{"webhook": webhook.ID, "delivery": delivery.ID})
+				}
+				if delivery.Status != apihub.DELIVERY_DELIVERED {
+					return
+				}
+			}
+		}(webhook, queued[webhook.ID])
+	}
+	wg.Wait()
+}
+
+// deliver posts the payload of the delivery to the webhook, and records how
+// it went in the delivery.
+func (s *ApihubServer) deliver(log lager.Logger, webhook apihub.Webhook, delivery *apihub.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+	delivery.LastError = ""
+
+	err := s.post(webhook, delivery)
+	if err == nil {
+		delivery.Status = apihub.DELIVERY_DELIVERED
+		log.Info("delivered", lager.Data{"webhook": webhook.ID, "delivery": delivery.ID, "event": delivery.Event})
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxWebhookAttempts {
+		delivery.Status = apihub.DELIVERY_FAILED
+		log.Error("failed-to-deliver", err, lager.Data{"webhook": webhook.ID, "delivery": delivery.ID, "attempts": delivery.Attempts})
+		return
+	}
+	delivery.NextAttempt = time.Now().UTC().Add(backoff(delivery.Attempts - 1))
+	log.Info("delivery-failed", lager.Data{"webhook": webhook.ID, "delivery": delivery.ID, "attempts": delivery.Attempts, "error": err.Error()})
+}
+
+func (s *ApihubServer) post(webhook apihub.Webhook, delivery *apihub.WebhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apihub.WEBHOOK_EVENT_HEADER, delivery.Event)
+	req.Header.Set(apihub.WEBHOOK_DELIVERY_HEADER, delivery.ID)
+	req.Header.Set(apihub.WEBHOOK_SIGNATURE_HEADER, apihub.SignWebhookPayload(webhook.Secret, delivery.Payload))
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	delivery.ResponseStatus = resp.StatusCode
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
+	"github.com/apihub/apihub/apihubfakes"
+	"github.com/apihub/apihub/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Webhooks", func() {
+	var (
+		store    *storage.Memory
+		receiver *ghttp.Server
+		log      *lagertest.TestLogger
+
+		apihubServer *api.ApihubServer
+		server       *testServer
+	)
+
+	addWebhook := func(body string) apihub.Webhook {
+		code, data := server.Request(http.MethodPost, "/webhooks", body)
+		Expect(code).To(Equal(http.StatusCreated))
+
+		var webhook apihub.Webhook
+		Expect(json.Unmarshal(data, &webhook)).To(Succeed())
+		return webhook
+	}
+
+	deliveries := func(id string) []apihub.WebhookDelivery {
+		code, data := server.Request(http.MethodGet, "/webhooks/"+id+"/deliveries", "")
+		Expect(code).To(Equal(http.StatusOK))
+
+		var collection struct {
+			Items []apihub.WebhookDelivery `json:"items"`
+		}
+		Expect(json.Unmarshal(data, &collection)).To(Succeed())
+		return collection.Items
+	}
+
+	BeforeEach(func() {
+		store = storage.New()
+		receiver = ghttp.NewServer()
+		log = lagertest.NewTestLogger("apihub-webhooks-test")
+
+		apihubServer = api.New(log, "tcp", "", store, new(apihubfakes.FakeServicePublisher))
+		server = newTestServer(apihubServer)
+	})
+
+	AfterEach(func() {
+		receiver.Close()
+		server.Close()
+	})
+
+	Describe("subscriptions", func() {
+		It("adds a webhook, returning its secret only once", func() {
+			webhook := addWebhook(`{"url":"https://deploy-bot.example.com/hooks","events":["service.created"]}`)
+			Expect(webhook.ID).NotTo(BeEmpty())
+			Expect(webhook.Secret).To(HaveLen(64))
+			Expect(webhook.Events).To(Equal([]string{apihub.WEBHOOK_SERVICE_CREATED}))
+
+			code, data := server.Request(http.MethodGet, "/webhooks", "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(data)).To(ContainSubstring(`"id":"` + webhook.ID + `"`))
+			Expect(string(data)).NotTo(ContainSubstring(webhook.Secret))
+
+			code, data = server.Request(http.MethodGet, "/webhooks/"+webhook.ID, "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(data)).NotTo(ContainSubstring(webhook.Secret))
+		})
+
+		It("keeps the secret given", func() {
+			webhook := addWebhook(`{"url":"https://deploy-bot.example.com/hooks","secret":"s3cr3t"}`)
+			Expect(webhook.Secret).To(Equal("s3cr3t"))
+		})
+
+		It("reports the invalid fields", func() {
+			code, data := server.Request(http.MethodPost, "/webhooks", `{"url":"deploy-bot","events":["service.created","service.renamed"]}`)
+			Expect(code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(string(data)).To(ContainSubstring(`"field":"url"`))
+			Expect(string(data)).To(ContainSubstring(`"field":"events[1]"`))
+		})
+
+		It("removes a webhook", func() {
+			webhook := addWebhook(`{"url":"https://deploy-bot.example.com/hooks"}`)
+
+			code, _ := server.Request(http.MethodDelete, "/webhooks/"+webhook.ID, "")
+			Expect(code).To(Equal(http.StatusNoContent))
+
+			code, _ = server.Request(http.MethodGet, "/webhooks/"+webhook.ID+"/deliveries", "")
+			Expect(code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("deliveries", func() {
+		var webhook apihub.Webhook
+
+		BeforeEach(func() {
+			webhook = addWebhook(`{"url":"` + receiver.URL() + `/hooks","events":["service.created","service.disabled"],"secret":"s3cr3t"}`)
+		})
+
+		It("posts signed payloads", func() {
+			receiver.AppendHandlers(func(rw http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				body, err := ioutil.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(r.URL.Path).To(Equal("/hooks"))
+				Expect(r.Header.Get(apihub.WEBHOOK_EVENT_HEADER)).To(Equal(apihub.WEBHOOK_SERVICE_CREATED))
+				Expect(r.Header.Get(apihub.WEBHOOK_SIGNATURE_HEADER)).To(Equal(apihub.SignWebhookPayload("s3cr3t", body)))
+
+				var payload apihub.WebhookPayload
+				Expect(json.Unmarshal(body, &payload)).To(Succeed())
+				Expect(payload.ID).To(Equal(r.Header.Get(apihub.WEBHOOK_DELIVERY_HEADER)))
+				Expect(payload.Host).To(Equal("my-host"))
+				Expect(payload.Service.Version).To(BeEquivalentTo(1))
+			})
+
+			code, _ := server.Request(http.MethodPost, "/services", `{"host":"my-host","backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusCreated))
+			Expect(deliveries(webhook.ID)[0].Status).To(Equal(apihub.DELIVERY_PENDING))
+
+			apihubServer.DeliverWebhooks()
+			Expect(receiver.ReceivedRequests()).To(HaveLen(1))
+
+			log := deliveries(webhook.ID)
+			Expect(log).To(HaveLen(1))
+			Expect(log[0].Status).To(Equal(apihub.DELIVERY_DELIVERED))
+			Expect(log[0].Attempts).To(Equal(1))
+			Expect(log[0].ResponseStatus).To(Equal(http.StatusOK))
+		})
+
+		It("only delivers the events the webhook subscribes to", func() {
+			Expect(store.AddService(apihub.ServiceSpec{Host: "my-host", Version: 1, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}})).To(Succeed())
+
+			code, _ := server.Request(http.MethodPatch, "/services/my-host", `{"timeout":100}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(deliveries(webhook.ID)).To(BeEmpty())
+
+			code, _ = server.Request(http.MethodPatch, "/services/my-host", `{"disabled":true}`)
+			Expect(code).To(Equal(http.StatusOK))
+			log := deliveries(webhook.ID)
+			Expect(log).To(HaveLen(1))
+			Expect(log[0].Event).To(Equal(apihub.WEBHOOK_SERVICE_DISABLED))
+		})
+
+		It("retries the failed deliveries after a backoff", func() {
+			receiver.AppendHandlers(ghttp.RespondWith(http.StatusServiceUnavailable, ""))
+
+			code, _ := server.Request(http.MethodPost, "/services", `{"host":"my-host","backends":[{"address":"http://server-a"}]}`)
+			Expect(code).To(Equal(http.StatusCreated))
+
+			apihubServer.DeliverWebhooks()
+			log := deliveries(webhook.ID)
+			Expect(log[0].Status).To(Equal(apihub.DELIVERY_PENDING))
+			Expect(log[0].Attempts).To(Equal(1))
+			Expect(log[0].ResponseStatus).To(Equal(http.StatusServiceUnavailable))
+			Expect(log[0].LastError).To(Equal("unexpected status 503"))
+			Expect(log[0].NextAttempt).To(BeTemporally(">", log[0].CreatedAt))
+
+			apihubServer.DeliverWebhooks()
+			Expect(receiver.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("stops posting to a webhook once a delivery fails, but not to the others", func() {
+			other := ghttp.NewServer()
+			defer other.Close()
+			other.AllowUnhandledRequests = true
+			other.UnhandledRequestStatusCode = http.StatusOK
+			otherWebhook := addWebhook(`{"url":"` + other.URL() + `/hooks","events":["service.created"]}`)
+			receiver.AppendHandlers(ghttp.RespondWith(http.StatusServiceUnavailable, ""))
+
+			for _, host := range []string{"first-host", "second-host"} {
+				code, _ := server.Request(http.MethodPost, "/services", `{"host":"`+host+`","backends":[{"address":"http://server-a"}]}`)
+				Expect(code).To(Equal(http.StatusCreated))
+			}
+
+			apihubServer.DeliverWebhooks()
+			Expect(receiver.ReceivedRequests()).To(HaveLen(1))
+			Expect(other.ReceivedRequests()).To(HaveLen(2))
+
+			log := deliveries(webhook.ID)
+			Expect(log[0].Host).To(Equal("second-host"))
+			Expect(log[0].Attempts).To(Equal(0))
+			Expect(log[1].Attempts).To(Equal(1))
+			for _, delivery := range deliveries(otherWebhook.ID) {
+				Expect(delivery.Status).To(Equal(apihub.DELIVERY_DELIVERED))
+			}
+		})
+
+		It("prunes the deliveries done for longer than the retention", func() {
+			old := apihub.WebhookDelivery{ID: "old", WebhookID: webhook.ID, Status: apihub.DELIVERY_DELIVERED, CreatedAt: time.Now().UTC().AddDate(0, -1, 0)}
+			Expect(store.PutWebhookDelivery(old)).To(Succeed())
+			pending := apihub.WebhookDelivery{ID: "pending", WebhookID: webhook.ID, Status: apihub.DELIVERY_PENDING, CreatedAt: old.CreatedAt, NextAttempt: time.Now().UTC().Add(time.Hour)}
+			Expect(store.PutWebhookDelivery(pending)).To(Succeed())
+
+			apihubServer.DeliverWebhooks()
+			log := deliveries(webhook.ID)
+			Expect(log).To(HaveLen(1))
+			Expect(log[0].ID).To(Equal("pending"))
+		})
+	})
+})
@@ -38,6 +38,27 @@ type FakeClient struct {
 		result1 []apihub.Service
 		result2 error
 	}
+	ListServicesStub        func(query apihub.ServiceQuery) ([]apihub.Service, string, error)
+	listServicesMutex       sync.RWMutex
+	listServicesArgsForCall []struct {
+		query apihub.ServiceQuery
+	}
+	listServicesReturns struct {
+		result1 []apihub.Service
+		result2 string
+		result3 error
+	}
+	WatchServicesStub        func(apihub.ServiceQuery, uint64, <-chan struct{}) (<-chan apihub.WatchEvent, error)
+	watchServicesMutex       sync.RWMutex
+	watchServicesArgsForCall []struct {
+		arg1 apihub.ServiceQuery
+		arg2 uint64
+		arg3 <-chan struct{}
+	}
+	watchServicesReturns struct {
+		result1 <-chan apihub.WatchEvent
+		result2 error
+	}
 	FindServiceStub        func(host string) (apihub.Service, error)
 	findServiceMutex       sync.RWMutex
 	findServiceArgsForCall []struct {
@@ -57,6 +78,54 @@ type FakeClient struct {
 		result1 apihub.Service
 		result2 error
 	}
+	UpdateServiceIfMatchStub        func(host string, version uint64, spec apihub.ServiceSpec) (apihub.Service, error)
+	updateServiceIfMatchMutex       sync.RWMutex
+	updateServiceIfMatchArgsForCall []struct {
+		host    string
+		version uint64
+		spec    apihub.ServiceSpec
+	}
+	updateServiceIfMatchReturns struct {
+		result1 apihub.Service
+		result2 error
+	}
+	ReplaceServiceStub        func(host string, spec apihub.ServiceSpec) (apihub.Service, error)
+	replaceServiceMutex       sync.RWMutex
+	replaceServiceArgsForCall []struct {
+		host string
+		spec apihub.ServiceSpec
+	}
+	replaceServiceReturns struct {
+		result1 apihub.Service
+		result2 error
+	}
+	ApplyStub        func(services []apihub.ServiceSpec, opts apihub.ApplyOptions) (apihub.ApplyPlan, error)
+	applyMutex       sync.RWMutex
+	applyArgsForCall []struct {
+		services []apihub.ServiceSpec
+		opts     apihub.ApplyOptions
+	}
+	applyReturns struct {
+		result1 apihub.ApplyPlan
+		result2 error
+	}
+	ExportStub        func() (apihub.ExportDocument, error)
+	exportMutex       sync.RWMutex
+	exportArgsForCall []struct{}
+	exportReturns     struct {
+		result1 apihub.ExportDocument
+		result2 error
+	}
+	ImportStub        func(doc apihub.ExportDocument, opts apihub.ImportOptions) (apihub.ImportReport, error)
+	importMutex       sync.RWMutex
+	importArgsForCall []struct {
+		doc  apihub.ExportDocument
+		opts apihub.ImportOptions
+	}
+	importReturns struct {
+		result1 apihub.ImportReport
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -179,6 +248,77 @@ func (fake *FakeClient) ServicesReturns(result1 []apihub.Service, result2 error)
 	}{result1, result2}
 }
 
+func (fake *FakeClient) ListServices(query apihub.ServiceQuery) ([]apihub.Service, string, error) {
+	fake.listServicesMutex.Lock()
+	fake.listServicesArgsForCall = append(fake.listServicesArgsForCall, struct {
+		query apihub.ServiceQuery
+	}{query})
+	fake.recordInvocation("ListServices", []interface{}{query})
+	fake.listServicesMutex.Unlock()
+	if fake.ListServicesStub != nil {
+		return fake.ListServicesStub(query)
+	} else {
+		return fake.listServicesReturns.result1, fake.listServicesReturns.result2, fake.listServicesReturns.result3
+	}
+}
+
+func (fake *FakeClient) ListServicesCallCount() int {
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	return len(fake.listServicesArgsForCall)
+}
+
+func (fake *FakeClient) ListServicesArgsForCall(i int) apihub.ServiceQuery {
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	return fake.listServicesArgsForCall[i].query
+}
+
+func (fake *FakeClient) ListServicesReturns(result1 []apihub.Service, result2 string, result3 error) {
+	fake.ListServicesStub = nil
+	fake.listServicesReturns = struct {
+		result1 []apihub.Service
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeClient) WatchServices(arg1 apihub.ServiceQuery, arg2 uint64, arg3 <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+	fake.watchServicesMutex.Lock()
+	fake.watchServicesArgsForCall = append(fake.watchServicesArgsForCall, struct {
+		arg1 apihub.ServiceQuery
+		arg2 uint64
+		arg3 <-chan struct{}
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("WatchServices", []interface{}{arg1, arg2, arg3})
+	fake.watchServicesMutex.Unlock()
+	if fake.WatchServicesStub != nil {
+		return fake.WatchServicesStub(arg1, arg2, arg3)
+	} else {
+		return fake.watchServicesReturns.result1, fake.watchServicesReturns.result2
+	}
+}
+
+func (fake *FakeClient) WatchServicesCallCount() int {
+	fake.watchServicesMutex.RLock()
+	defer fake.watchServicesMutex.RUnlock()
+	return len(fake.watchServicesArgsForCall)
+}
+
+func (fake *FakeClient) WatchServicesArgsForCall(i int) (apihub.ServiceQuery, uint64, <-chan struct{}) {
+	fake.watchServicesMutex.RLock()
+	defer fake.watchServicesMutex.RUnlock()
+	return fake.watchServicesArgsForCall[i].arg1, fake.watchServicesArgsForCall[i].arg2, fake.watchServicesArgsForCall[i].arg3
+}
+
+func (fake *FakeClient) WatchServicesReturns(result1 <-chan apihub.WatchEvent, result2 error) {
+	fake.WatchServicesStub = nil
+	fake.watchServicesReturns = struct {
+		result1 <-chan apihub.WatchEvent
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeClient) FindService(host string) (apihub.Service, error) {
 	fake.findServiceMutex.Lock()
 	fake.findServiceArgsForCall = append(fake.findServiceArgsForCall, struct {
@@ -248,6 +388,178 @@ func (fake *FakeClient) UpdateServiceReturns(result1 apihub.Service, result2 err
 	}{result1, result2}
 }
 
+func (fake *FakeClient) UpdateServiceIfMatch(host string, version uint64, spec apihub.ServiceSpec) (apihub.Service, error) {
+	fake.updateServiceIfMatchMutex.Lock()
+	fake.updateServiceIfMatchArgsForCall = append(fake.updateServiceIfMatchArgsForCall, struct {
+		host    string
+		version uint64
+		spec    apihub.ServiceSpec
+	}{host, version, spec})
+	fake.recordInvocation("UpdateServiceIfMatch", []interface{}{host, version, spec})
+	fake.updateServiceIfMatchMutex.Unlock()
+	if fake.UpdateServiceIfMatchStub != nil {
+		return fake.UpdateServiceIfMatchStub(host, version, spec)
+	} else {
+		return fake.updateServiceIfMatchReturns.result1, fake.updateServiceIfMatchReturns.result2
+	}
+}
+
+func (fake *FakeClient) UpdateServiceIfMatchCallCount() int {
+	fake.updateServiceIfMatchMutex.RLock()
+	defer fake.updateServiceIfMatchMutex.RUnlock()
+	return len(fake.updateServiceIfMatchArgsForCall)
+}
+
+func (fake *FakeClient) UpdateServiceIfMatchArgsForCall(i int) (string, uint64, apihub.ServiceSpec) {
+	fake.updateServiceIfMatchMutex.RLock()
+	defer fake.updateServiceIfMatchMutex.RUnlock()
+	return fake.updateServiceIfMatchArgsForCall[i].host, fake.updateServiceIfMatchArgsForCall[i].version, fake.updateServiceIfMatchArgsForCall[i].spec
+}
+
+func (fake *FakeClient) UpdateServiceIfMatchReturns(result1 apihub.Service, result2 error) {
+	fake.UpdateServiceIfMatchStub = nil
+	fake.updateServiceIfMatchReturns = struct {
+		result1 apihub.Service
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) ReplaceService(host string, spec apihub.ServiceSpec) (apihub.Service, error) {
+	fake.replaceServiceMutex.Lock()
+	fake.replaceServiceArgsForCall = append(fake.replaceServiceArgsForCall, struct {
+		host string
+		spec apihub.ServiceSpec
+	}{host, spec})
+	fake.recordInvocation("ReplaceService", []interface{}{host, spec})
+	fake.replaceServiceMutex.Unlock()
+	if fake.ReplaceServiceStub != nil {
+		return fake.ReplaceServiceStub(host, spec)
+	} else {
+		return fake.replaceServiceReturns.result1, fake.replaceServiceReturns.result2
+	}
+}
+
+func (fake *FakeClient) ReplaceServiceCallCount() int {
+	fake.replaceServiceMutex.RLock()
+	defer fake.replaceServiceMutex.RUnlock()
+	return len(fake.replaceServiceArgsForCall)
+}
+
+func (fake *FakeClient) ReplaceServiceArgsForCall(i int) (string, apihub.ServiceSpec) {
+	fake.replaceServiceMutex.RLock()
+	defer fake.replaceServiceMutex.RUnlock()
+	return fake.replaceServiceArgsForCall[i].host, fake.replaceServiceArgsForCall[i].spec
+}
+
+func (fake *FakeClient) ReplaceServiceReturns(result1 apihub.Service, result2 error) {
+	fake.ReplaceServiceStub = nil
+	fake.replaceServiceReturns = struct {
+		result1 apihub.Service
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) Apply(services []apihub.ServiceSpec, opts apihub.ApplyOptions) (apihub.ApplyPlan, error) {
+	var servicesCopy []apihub.ServiceSpec
+	if services != nil {
+		servicesCopy = make([]apihub.ServiceSpec, len(services))
+		copy(servicesCopy, services)
+	}
+	fake.applyMutex.Lock()
+	fake.applyArgsForCall = append(fake.applyArgsForCall, struct {
+		services []apihub.ServiceSpec
+		opts     apihub.ApplyOptions
+	}{servicesCopy, opts})
+	fake.recordInvocation("Apply", []interface{}{servicesCopy, opts})
+	fake.applyMutex.Unlock()
+	if fake.ApplyStub != nil {
+		return fake.ApplyStub(services, opts)
+	} else {
+		return fake.applyReturns.result1, fake.applyReturns.result2
+	}
+}
+
+func (fake *FakeClient) ApplyCallCount() int {
+	fake.applyMutex.RLock()
+	defer fake.applyMutex.RUnlock()
+	return len(fake.applyArgsForCall)
+}
+
+func (fake *FakeClient) ApplyArgsForCall(i int) ([]apihub.ServiceSpec, apihub.ApplyOptions) {
+	fake.applyMutex.RLock()
+	defer fake.applyMutex.RUnlock()
+	return fake.applyArgsForCall[i].services, fake.applyArgsForCall[i].opts
+}
+
+func (fake *FakeClient) ApplyReturns(result1 apihub.ApplyPlan, result2 error) {
+	fake.ApplyStub = nil
+	fake.applyReturns = struct {
+		result1 apihub.ApplyPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) Export() (apihub.ExportDocument, error) {
+	fake.exportMutex.Lock()
+	fake.exportArgsForCall = append(fake.exportArgsForCall, struct{}{})
+	fake.recordInvocation("Export", []interface{}{})
+	fake.exportMutex.Unlock()
+	if fake.ExportStub != nil {
+		return fake.ExportStub()
+	} else {
+		return fake.exportReturns.result1, fake.exportReturns.result2
+	}
+}
+
+func (fake *FakeClient) ExportCallCount() int {
+	fake.exportMutex.RLock()
+	defer fake.exportMutex.RUnlock()
+	return len(fake.exportArgsForCall)
+}
+
+func (fake *FakeClient) ExportReturns(result1 apihub.ExportDocument, result2 error) {
+	fake.ExportStub = nil
+	fake.exportReturns = struct {
+		result1 apihub.ExportDocument
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) Import(doc apihub.ExportDocument, opts apihub.ImportOptions) (apihub.ImportReport, error) {
+	fake.importMutex.Lock()
+	fake.importArgsForCall = append(fake.importArgsForCall, struct {
+		doc  apihub.ExportDocument
+		opts apihub.ImportOptions
+	}{doc, opts})
+	fake.recordInvocation("Import", []interface{}{doc, opts})
+	fake.importMutex.Unlock()
+	if fake.ImportStub != nil {
+		return fake.ImportStub(doc, opts)
+	} else {
+		return fake.importReturns.result1, fake.importReturns.result2
+	}
+}
+
+func (fake *FakeClient) ImportCallCount() int {
+	fake.importMutex.RLock()
+	defer fake.importMutex.RUnlock()
+	return len(fake.importArgsForCall)
+}
+
+func (fake *FakeClient) ImportArgsForCall(i int) (apihub.ExportDocument, apihub.ImportOptions) {
+	fake.importMutex.RLock()
+	defer fake.importMutex.RUnlock()
+	return fake.importArgsForCall[i].doc, fake.importArgsForCall[i].opts
+}
+
+func (fake *FakeClient) ImportReturns(result1 apihub.ImportReport, result2 error) {
+	fake.ImportStub = nil
+	fake.importReturns = struct {
+		result1 apihub.ImportReport
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -259,10 +571,24 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.removeServiceMutex.RUnlock()
 	fake.servicesMutex.RLock()
 	defer fake.servicesMutex.RUnlock()
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	fake.watchServicesMutex.RLock()
+	defer fake.watchServicesMutex.RUnlock()
 	fake.findServiceMutex.RLock()
 	defer fake.findServiceMutex.RUnlock()
 	fake.updateServiceMutex.RLock()
 	defer fake.updateServiceMutex.RUnlock()
+	fake.updateServiceIfMatchMutex.RLock()
+	defer fake.updateServiceIfMatchMutex.RUnlock()
+	fake.replaceServiceMutex.RLock()
+	defer fake.replaceServiceMutex.RUnlock()
+	fake.applyMutex.RLock()
+	defer fake.applyMutex.RUnlock()
+	fake.exportMutex.RLock()
+	defer fake.exportMutex.RUnlock()
+	fake.importMutex.RLock()
+	defer fake.importMutex.RUnlock()
 	return fake.invocations
 }
 
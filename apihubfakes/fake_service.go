@@ -41,6 +41,38 @@ type FakeService struct {
 		result1 []apihub.BackendInfo
 		result2 error
 	}
+	AddBackendStub        func(apihub.BackendInfo) error
+	addBackendMutex       sync.RWMutex
+	addBackendArgsForCall []struct {
+		arg1 apihub.BackendInfo
+	}
+	addBackendReturns struct {
+		result1 error
+	}
+	RemoveBackendStub        func(string) error
+	removeBackendMutex       sync.RWMutex
+	removeBackendArgsForCall []struct {
+		arg1 string
+	}
+	removeBackendReturns struct {
+		result1 error
+	}
+	EnableBackendStub        func(string) error
+	enableBackendMutex       sync.RWMutex
+	enableBackendArgsForCall []struct {
+		arg1 string
+	}
+	enableBackendReturns struct {
+		result1 error
+	}
+	DisableBackendStub        func(string) error
+	disableBackendMutex       sync.RWMutex
+	disableBackendArgsForCall []struct {
+		arg1 string
+	}
+	disableBackendReturns struct {
+		result1 error
+	}
 	SetTimeoutStub        func(time.Duration) error
 	setTimeoutMutex       sync.RWMutex
 	setTimeoutArgsForCall []struct {
@@ -49,6 +81,35 @@ type FakeService struct {
 	setTimeoutReturns struct {
 		result1 error
 	}
+	SetFaultsStub        func([]apihub.FaultRule) error
+	setFaultsMutex       sync.RWMutex
+	setFaultsArgsForCall []struct {
+		arg1 []apihub.FaultRule
+	}
+	setFaultsReturns struct {
+		result1 error
+	}
+	ClearFaultsStub        func() error
+	clearFaultsMutex       sync.RWMutex
+	clearFaultsArgsForCall []struct{}
+	clearFaultsReturns     struct {
+		result1 error
+	}
+	RevisionsStub        func() ([]apihub.Revision, error)
+	revisionsMutex       sync.RWMutex
+	revisionsArgsForCall []struct{}
+	revisionsReturns     struct {
+		result1 []apihub.Revision
+		result2 error
+	}
+	RollbackStub        func(uint64) error
+	rollbackMutex       sync.RWMutex
+	rollbackArgsForCall []struct {
+		arg1 uint64
+	}
+	rollbackReturns struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -180,6 +241,138 @@ func (fake *FakeService) BackendsReturns(result1 []apihub.BackendInfo, result2 e
 	}{result1, result2}
 }
 
+func (fake *FakeService) AddBackend(arg1 apihub.BackendInfo) error {
+	fake.addBackendMutex.Lock()
+	fake.addBackendArgsForCall = append(fake.addBackendArgsForCall, struct {
+		arg1 apihub.BackendInfo
+	}{arg1})
+	fake.recordInvocation("AddBackend", []interface{}{arg1})
+	fake.addBackendMutex.Unlock()
+	if fake.AddBackendStub != nil {
+		return fake.AddBackendStub(arg1)
+	} else {
+		return fake.addBackendReturns.result1
+	}
+}
+
+func (fake *FakeService) AddBackendCallCount() int {
+	fake.addBackendMutex.RLock()
+	defer fake.addBackendMutex.RUnlock()
+	return len(fake.addBackendArgsForCall)
+}
+
+func (fake *FakeService) AddBackendArgsForCall(i int) apihub.BackendInfo {
+	fake.addBackendMutex.RLock()
+	defer fake.addBackendMutex.RUnlock()
+	return fake.addBackendArgsForCall[i].arg1
+}
+
+func (fake *FakeService) AddBackendReturns(result1 error) {
+	fake.AddBackendStub = nil
+	fake.addBackendReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeService) RemoveBackend(arg1 string) error {
+	fake.removeBackendMutex.Lock()
+	fake.removeBackendArgsForCall = append(fake.removeBackendArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RemoveBackend", []interface{}{arg1})
+	fake.removeBackendMutex.Unlock()
+	if fake.RemoveBackendStub != nil {
+		return fake.RemoveBackendStub(arg1)
+	} else {
+		return fake.removeBackendReturns.result1
+	}
+}
+
+func (fake *FakeService) RemoveBackendCallCount() int {
+	fake.removeBackendMutex.RLock()
+	defer fake.removeBackendMutex.RUnlock()
+	return len(fake.removeBackendArgsForCall)
+}
+
+func (fake *FakeService) RemoveBackendArgsForCall(i int) string {
+	fake.removeBackendMutex.RLock()
+	defer fake.removeBackendMutex.RUnlock()
+	return fake.removeBackendArgsForCall[i].arg1
+}
+
+func (fake *FakeService) RemoveBackendReturns(result1 error) {
+	fake.RemoveBackendStub = nil
+	fake.removeBackendReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeService) EnableBackend(arg1 string) error {
+	fake.enableBackendMutex.Lock()
+	fake.enableBackendArgsForCall = append(fake.enableBackendArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("EnableBackend", []interface{}{arg1})
+	fake.enableBackendMutex.Unlock()
+	if fake.EnableBackendStub != nil {
+		return fake.EnableBackendStub(arg1)
+	} else {
+		return fake.enableBackendReturns.result1
+	}
+}
+
+func (fake *FakeService) EnableBackendCallCount() int {
+	fake.enableBackendMutex.RLock()
+	defer fake.enableBackendMutex.RUnlock()
+	return len(fake.enableBackendArgsForCall)
+}
+
+func (fake *FakeService) EnableBackendArgsForCall(i int) string {
+	fake.enableBackendMutex.RLock()
+	defer fake.enableBackendMutex.RUnlock()
+	return fake.enableBackendArgsForCall[i].arg1
+}
+
+func (fake *FakeService) EnableBackendReturns(result1 error) {
+	fake.EnableBackendStub = nil
+	fake.enableBackendReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeService) DisableBackend(arg1 string) error {
+	fake.disableBackendMutex.Lock()
+	fake.disableBackendArgsForCall = append(fake.disableBackendArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("DisableBackend", []interface{}{arg1})
+	fake.disableBackendMutex.Unlock()
+	if fake.DisableBackendStub != nil {
+		return fake.DisableBackendStub(arg1)
+	} else {
+		return fake.disableBackendReturns.result1
+	}
+}
+
+func (fake *FakeService) DisableBackendCallCount() int {
+	fake.disableBackendMutex.RLock()
+	defer fake.disableBackendMutex.RUnlock()
+	return len(fake.disableBackendArgsForCall)
+}
+
+func (fake *FakeService) DisableBackendArgsForCall(i int) string {
+	fake.disableBackendMutex.RLock()
+	defer fake.disableBackendMutex.RUnlock()
+	return fake.disableBackendArgsForCall[i].arg1
+}
+
+func (fake *FakeService) DisableBackendReturns(result1 error) {
+	fake.DisableBackendStub = nil
+	fake.disableBackendReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeService) SetTimeout(arg1 time.Duration) error {
 	fake.setTimeoutMutex.Lock()
 	fake.setTimeoutArgsForCall = append(fake.setTimeoutArgsForCall, struct {
@@ -213,6 +406,128 @@ func (fake *FakeService) SetTimeoutReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeService) SetFaults(arg1 []apihub.FaultRule) error {
+	var arg1Copy []apihub.FaultRule
+	if arg1 != nil {
+		arg1Copy = make([]apihub.FaultRule, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.setFaultsMutex.Lock()
+	fake.setFaultsArgsForCall = append(fake.setFaultsArgsForCall, struct {
+		arg1 []apihub.FaultRule
+	}{arg1Copy})
+	fake.recordInvocation("SetFaults", []interface{}{arg1Copy})
+	fake.setFaultsMutex.Unlock()
+	if fake.SetFaultsStub != nil {
+		return fake.SetFaultsStub(arg1)
+	} else {
+		return fake.setFaultsReturns.result1
+	}
+}
+
+func (fake *FakeService) SetFaultsCallCount() int {
+	fake.setFaultsMutex.RLock()
+	defer fake.setFaultsMutex.RUnlock()
+	return len(fake.setFaultsArgsForCall)
+}
+
+func (fake *FakeService) SetFaultsArgsForCall(i int) []apihub.FaultRule {
+	fake.setFaultsMutex.RLock()
+	defer fake.setFaultsMutex.RUnlock()
+	return fake.setFaultsArgsForCall[i].arg1
+}
+
+func (fake *FakeService) SetFaultsReturns(result1 error) {
+	fake.SetFaultsStub = nil
+	fake.setFaultsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeService) ClearFaults() error {
+	fake.clearFaultsMutex.Lock()
+	fake.clearFaultsArgsForCall = append(fake.clearFaultsArgsForCall, struct{}{})
+	fake.recordInvocation("ClearFaults", []interface{}{})
+	fake.clearFaultsMutex.Unlock()
+	if fake.ClearFaultsStub != nil {
+		return fake.ClearFaultsStub()
+	} else {
+		return fake.clearFaultsReturns.result1
+	}
+}
+
+func (fake *FakeService) ClearFaultsCallCount() int {
+	fake.clearFaultsMutex.RLock()
+	defer fake.clearFaultsMutex.RUnlock()
+	return len(fake.clearFaultsArgsForCall)
+}
+
+func (fake *FakeService) ClearFaultsReturns(result1 error) {
+	fake.ClearFaultsStub = nil
+	fake.clearFaultsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeService) Revisions() ([]apihub.Revision, error) {
+	fake.revisionsMutex.Lock()
+	fake.revisionsArgsForCall = append(fake.revisionsArgsForCall, struct{}{})
+	fake.recordInvocation("Revisions", []interface{}{})
+	fake.revisionsMutex.Unlock()
+	if fake.RevisionsStub != nil {
+		return fake.RevisionsStub()
+	} else {
+		return fake.revisionsReturns.result1, fake.revisionsReturns.result2
+	}
+}
+
+func (fake *FakeService) RevisionsCallCount() int {
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	return len(fake.revisionsArgsForCall)
+}
+
+func (fake *FakeService) RevisionsReturns(result1 []apihub.Revision, result2 error) {
+	fake.RevisionsStub = nil
+	fake.revisionsReturns = struct {
+		result1 []apihub.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeService) Rollback(arg1 uint64) error {
+	fake.rollbackMutex.Lock()
+	fake.rollbackArgsForCall = append(fake.rollbackArgsForCall, struct {
+		arg1 uint64
+	}{arg1})
+	fake.recordInvocation("Rollback", []interface{}{arg1})
+	fake.rollbackMutex.Unlock()
+	if fake.RollbackStub != nil {
+		return fake.RollbackStub(arg1)
+	} else {
+		return fake.rollbackReturns.result1
+	}
+}
+
+func (fake *FakeService) RollbackCallCount() int {
+	fake.rollbackMutex.RLock()
+	defer fake.rollbackMutex.RUnlock()
+	return len(fake.rollbackArgsForCall)
+}
+
+func (fake *FakeService) RollbackArgsForCall(i int) uint64 {
+	fake.rollbackMutex.RLock()
+	defer fake.rollbackMutex.RUnlock()
+	return fake.rollbackArgsForCall[i].arg1
+}
+
+func (fake *FakeService) RollbackReturns(result1 error) {
+	fake.RollbackStub = nil
+	fake.rollbackReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeService) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -226,8 +541,24 @@ func (fake *FakeService) Invocations() map[string][][]interface{} {
 	defer fake.infoMutex.RUnlock()
 	fake.backendsMutex.RLock()
 	defer fake.backendsMutex.RUnlock()
+	fake.addBackendMutex.RLock()
+	defer fake.addBackendMutex.RUnlock()
+	fake.removeBackendMutex.RLock()
+	defer fake.removeBackendMutex.RUnlock()
+	fake.enableBackendMutex.RLock()
+	defer fake.enableBackendMutex.RUnlock()
+	fake.disableBackendMutex.RLock()
+	defer fake.disableBackendMutex.RUnlock()
 	fake.setTimeoutMutex.RLock()
 	defer fake.setTimeoutMutex.RUnlock()
+	fake.setFaultsMutex.RLock()
+	defer fake.setFaultsMutex.RUnlock()
+	fake.clearFaultsMutex.RLock()
+	defer fake.clearFaultsMutex.RUnlock()
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	fake.rollbackMutex.RLock()
+	defer fake.rollbackMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -29,6 +29,16 @@ type FakeServicePublisher struct {
 	unpublishReturns struct {
 		result1 error
 	}
+	PublishedStub        func(logger lager.Logger, prefix string) ([]apihub.ServiceSpec, error)
+	publishedMutex       sync.RWMutex
+	publishedArgsForCall []struct {
+		logger lager.Logger
+		prefix string
+	}
+	publishedReturns struct {
+		result1 []apihub.ServiceSpec
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -103,6 +113,41 @@ func (fake *FakeServicePublisher) UnpublishReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeServicePublisher) Published(logger lager.Logger, prefix string) ([]apihub.ServiceSpec, error) {
+	fake.publishedMutex.Lock()
+	fake.publishedArgsForCall = append(fake.publishedArgsForCall, struct {
+		logger lager.Logger
+		prefix string
+	}{logger, prefix})
+	fake.recordInvocation("Published", []interface{}{logger, prefix})
+	fake.publishedMutex.Unlock()
+	if fake.PublishedStub != nil {
+		return fake.PublishedStub(logger, prefix)
+	} else {
+		return fake.publishedReturns.result1, fake.publishedReturns.result2
+	}
+}
+
+func (fake *FakeServicePublisher) PublishedCallCount() int {
+	fake.publishedMutex.RLock()
+	defer fake.publishedMutex.RUnlock()
+	return len(fake.publishedArgsForCall)
+}
+
+func (fake *FakeServicePublisher) PublishedArgsForCall(i int) (lager.Logger, string) {
+	fake.publishedMutex.RLock()
+	defer fake.publishedMutex.RUnlock()
+	return fake.publishedArgsForCall[i].logger, fake.publishedArgsForCall[i].prefix
+}
+
+func (fake *FakeServicePublisher) PublishedReturns(result1 []apihub.ServiceSpec, result2 error) {
+	fake.PublishedStub = nil
+	fake.publishedReturns = struct {
+		result1 []apihub.ServiceSpec
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeServicePublisher) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -110,6 +155,8 @@ func (fake *FakeServicePublisher) Invocations() map[string][][]interface{} {
 	defer fake.publishMutex.RUnlock()
 	fake.unpublishMutex.RLock()
 	defer fake.unpublishMutex.RUnlock()
+	fake.publishedMutex.RLock()
+	defer fake.publishedMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -3,6 +3,7 @@ package apihubfakes
 
 import (
 	"sync"
+	"time"
 
 	"github.com/apihub/apihub"
 )
@@ -40,6 +41,15 @@ type FakeStorage struct {
 		result1 []apihub.ServiceSpec
 		result2 error
 	}
+	ListServicesStub        func(apihub.ServiceQuery) (apihub.ServicePage, error)
+	listServicesMutex       sync.RWMutex
+	listServicesArgsForCall []struct {
+		arg1 apihub.ServiceQuery
+	}
+	listServicesReturns struct {
+		result1 apihub.ServicePage
+		result2 error
+	}
 	RemoveServiceStub        func(string) error
 	removeServiceMutex       sync.RWMutex
 	removeServiceArgsForCall []struct {
@@ -48,6 +58,220 @@ type FakeStorage struct {
 	removeServiceReturns struct {
 		result1 error
 	}
+	ChangeServicesStub        func([]apihub.ServiceChange) error
+	changeServicesMutex       sync.RWMutex
+	changeServicesArgsForCall []struct {
+		arg1 []apihub.ServiceChange
+	}
+	changeServicesReturns struct {
+		result1 error
+	}
+	RevisionsStub        func(string) ([]apihub.Revision, error)
+	revisionsMutex       sync.RWMutex
+	revisionsArgsForCall []struct {
+		arg1 string
+	}
+	revisionsReturns struct {
+		result1 []apihub.Revision
+		result2 error
+	}
+	FindRevisionStub        func(string, uint64) (apihub.Revision, error)
+	findRevisionMutex       sync.RWMutex
+	findRevisionArgsForCall []struct {
+		arg1 string
+		arg2 uint64
+	}
+	findRevisionReturns struct {
+		result1 apihub.Revision
+		result2 error
+	}
+	AddTokenStub        func(apihub.Token) error
+	addTokenMutex       sync.RWMutex
+	addTokenArgsForCall []struct {
+		arg1 apihub.Token
+	}
+	addTokenReturns struct {
+		result1 error
+	}
+	FindTokenByHashStub        func(string) (apihub.Token, error)
+	findTokenByHashMutex       sync.RWMutex
+	findTokenByHashArgsForCall []struct {
+		arg1 string
+	}
+	findTokenByHashReturns struct {
+		result1 apihub.Token
+		result2 error
+	}
+	TokensStub        func() ([]apihub.Token, error)
+	tokensMutex       sync.RWMutex
+	tokensArgsForCall []struct{}
+	tokensReturns     struct {
+		result1 []apihub.Token
+		result2 error
+	}
+	RemoveTokenStub        func(string) error
+	removeTokenMutex       sync.RWMutex
+	removeTokenArgsForCall []struct {
+		arg1 string
+	}
+	removeTokenReturns struct {
+		result1 error
+	}
+	AddNamespaceStub        func(apihub.Namespace) error
+	addNamespaceMutex       sync.RWMutex
+	addNamespaceArgsForCall []struct {
+		arg1 apihub.Namespace
+	}
+	addNamespaceReturns struct {
+		result1 error
+	}
+	FindNamespaceStub        func(string) (apihub.Namespace, error)
+	findNamespaceMutex       sync.RWMutex
+	findNamespaceArgsForCall []struct {
+		arg1 string
+	}
+	findNamespaceReturns struct {
+		result1 apihub.Namespace
+		result2 error
+	}
+	NamespacesStub        func() ([]apihub.Namespace, error)
+	namespacesMutex       sync.RWMutex
+	namespacesArgsForCall []struct{}
+	namespacesReturns     struct {
+		result1 []apihub.Namespace
+		result2 error
+	}
+	RemoveNamespaceStub        func(string) error
+	removeNamespaceMutex       sync.RWMutex
+	removeNamespaceArgsForCall []struct {
+		arg1 string
+	}
+	removeNamespaceReturns struct {
+		result1 error
+	}
+	AddAuditEventStub        func(apihub.AuditEvent) error
+	addAuditEventMutex       sync.RWMutex
+	addAuditEventArgsForCall []struct {
+		arg1 apihub.AuditEvent
+	}
+	addAuditEventReturns struct {
+		result1 error
+	}
+	AuditEventsStub        func(apihub.AuditQuery) ([]apihub.AuditEvent, error)
+	auditEventsMutex       sync.RWMutex
+	auditEventsArgsForCall []struct {
+		arg1 apihub.AuditQuery
+	}
+	auditEventsReturns struct {
+		result1 []apihub.AuditEvent
+		result2 error
+	}
+	PutOutboxEntryStub        func(apihub.OutboxEntry) error
+	putOutboxEntryMutex       sync.RWMutex
+	putOutboxEntryArgsForCall []struct {
+		arg1 apihub.OutboxEntry
+	}
+	putOutboxEntryReturns struct {
+		result1 error
+	}
+	OutboxEntriesStub        func() ([]apihub.OutboxEntry, error)
+	outboxEntriesMutex       sync.RWMutex
+	outboxEntriesArgsForCall []struct{}
+	outboxEntriesReturns     struct {
+		result1 []apihub.OutboxEntry
+		result2 error
+	}
+	RemoveOutboxEntryStub        func(host string, id string) error
+	removeOutboxEntryMutex       sync.RWMutex
+	removeOutboxEntryArgsForCall []struct {
+		host string
+		id   string
+	}
+	removeOutboxEntryReturns struct {
+		result1 error
+	}
+	AddWebhookStub        func(apihub.Webhook) error
+	addWebhookMutex       sync.RWMutex
+	addWebhookArgsForCall []struct {
+		arg1 apihub.Webhook
+	}
+	addWebhookReturns struct {
+		result1 error
+	}
+	FindWebhookStub        func(string) (apihub.Webhook, error)
+	findWebhookMutex       sync.RWMutex
+	findWebhookArgsForCall []struct {
+		arg1 string
+	}
+	findWebhookReturns struct {
+		result1 apihub.Webhook
+		result2 error
+	}
+	WebhooksStub        func() ([]apihub.Webhook, error)
+	webhooksMutex       sync.RWMutex
+	webhooksArgsForCall []struct{}
+	webhooksReturns     struct {
+		result1 []apihub.Webhook
+		result2 error
+	}
+	RemoveWebhookStub        func(string) error
+	removeWebhookMutex       sync.RWMutex
+	removeWebhookArgsForCall []struct {
+		arg1 string
+	}
+	removeWebhookReturns struct {
+		result1 error
+	}
+	PutWebhookDeliveryStub        func(apihub.WebhookDelivery) error
+	putWebhookDeliveryMutex       sync.RWMutex
+	putWebhookDeliveryArgsForCall []struct {
+		arg1 apihub.WebhookDelivery
+	}
+	putWebhookDeliveryReturns struct {
+		result1 error
+	}
+	WebhookDeliveriesStub        func(string) ([]apihub.WebhookDelivery, error)
+	webhookDeliveriesMutex       sync.RWMutex
+	webhookDeliveriesArgsForCall []struct {
+		arg1 string
+	}
+	webhookDeliveriesReturns struct {
+		result1 []apihub.WebhookDelivery
+		result2 error
+	}
+	DueWebhookDeliveriesStub        func(time.Time) ([]apihub.WebhookDelivery, error)
+	dueWebhookDeliveriesMutex       sync.RWMutex
+	dueWebhookDeliveriesArgsForCall []struct {
+		arg1 time.Time
+	}
+	dueWebhookDeliveriesReturns struct {
+		result1 []apihub.WebhookDelivery
+		result2 error
+	}
+	PruneWebhookDeliveriesStub        func(time.Time) error
+	pruneWebhookDeliveriesMutex       sync.RWMutex
+	pruneWebhookDeliveriesArgsForCall []struct {
+		arg1 time.Time
+	}
+	pruneWebhookDeliveriesReturns struct {
+		result1 error
+	}
+	ServiceEventsStub        func(after uint64) ([]apihub.ServiceEvent, error)
+	serviceEventsMutex       sync.RWMutex
+	serviceEventsArgsForCall []struct {
+		after uint64
+	}
+	serviceEventsReturns struct {
+		result1 []apihub.ServiceEvent
+		result2 error
+	}
+	ServiceEventsVersionStub        func() (uint64, error)
+	serviceEventsVersionMutex       sync.RWMutex
+	serviceEventsVersionArgsForCall []struct{}
+	serviceEventsVersionReturns     struct {
+		result1 uint64
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -178,6 +402,40 @@ func (fake *FakeStorage) ServicesReturns(result1 []apihub.ServiceSpec, result2 e
 	}{result1, result2}
 }
 
+func (fake *FakeStorage) ListServices(arg1 apihub.ServiceQuery) (apihub.ServicePage, error) {
+	fake.listServicesMutex.Lock()
+	fake.listServicesArgsForCall = append(fake.listServicesArgsForCall, struct {
+		arg1 apihub.ServiceQuery
+	}{arg1})
+	fake.recordInvocation("ListServices", []interface{}{arg1})
+	fake.listServicesMutex.Unlock()
+	if fake.ListServicesStub != nil {
+		return fake.ListServicesStub(arg1)
+	} else {
+		return fake.listServicesReturns.result1, fake.listServicesReturns.result2
+	}
+}
+
+func (fake *FakeStorage) ListServicesCallCount() int {
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	return len(fake.listServicesArgsForCall)
+}
+
+func (fake *FakeStorage) ListServicesArgsForCall(i int) apihub.ServiceQuery {
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	return fake.listServicesArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) ListServicesReturns(result1 apihub.ServicePage, result2 error) {
+	fake.ListServicesStub = nil
+	fake.listServicesReturns = struct {
+		result1 apihub.ServicePage
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeStorage) RemoveService(arg1 string) error {
 	fake.removeServiceMutex.Lock()
 	fake.removeServiceArgsForCall = append(fake.removeServiceArgsForCall, struct {
@@ -211,6 +469,845 @@ func (fake *FakeStorage) RemoveServiceReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeStorage) ChangeServices(arg1 []apihub.ServiceChange) error {
+	var arg1Copy []apihub.ServiceChange
+	if arg1 != nil {
+		arg1Copy = make([]apihub.ServiceChange, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.changeServicesMutex.Lock()
+	fake.changeServicesArgsForCall = append(fake.changeServicesArgsForCall, struct {
+		arg1 []apihub.ServiceChange
+	}{arg1Copy})
+	fake.recordInvocation("ChangeServices", []interface{}{arg1Copy})
+	fake.changeServicesMutex.Unlock()
+	if fake.ChangeServicesStub != nil {
+		return fake.ChangeServicesStub(arg1)
+	} else {
+		return fake.changeServicesReturns.result1
+	}
+}
+
+func (fake *FakeStorage) ChangeServicesCallCount() int {
+	fake.changeServicesMutex.RLock()
+	defer fake.changeServicesMutex.RUnlock()
+	return len(fake.changeServicesArgsForCall)
+}
+
+func (fake *FakeStorage) ChangeServicesArgsForCall(i int) []apihub.ServiceChange {
+	fake.changeServicesMutex.RLock()
+	defer fake.changeServicesMutex.RUnlock()
+	return fake.changeServicesArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) ChangeServicesReturns(result1 error) {
+	fake.ChangeServicesStub = nil
+	fake.changeServicesReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) Revisions(arg1 string) ([]apihub.Revision, error) {
+	fake.revisionsMutex.Lock()
+	fake.revisionsArgsForCall = append(fake.revisionsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Revisions", []interface{}{arg1})
+	fake.revisionsMutex.Unlock()
+	if fake.RevisionsStub != nil {
+		return fake.RevisionsStub(arg1)
+	} else {
+		return fake.revisionsReturns.result1, fake.revisionsReturns.result2
+	}
+}
+
+func (fake *FakeStorage) RevisionsCallCount() int {
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	return len(fake.revisionsArgsForCall)
+}
+
+func (fake *FakeStorage) RevisionsArgsForCall(i int) string {
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	return fake.revisionsArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) RevisionsReturns(result1 []apihub.Revision, result2 error) {
+	fake.RevisionsStub = nil
+	fake.revisionsReturns = struct {
+		result1 []apihub.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) FindRevision(arg1 string, arg2 uint64) (apihub.Revision, error) {
+	fake.findRevisionMutex.Lock()
+	fake.findRevisionArgsForCall = append(fake.findRevisionArgsForCall, struct {
+		arg1 string
+		arg2 uint64
+	}{arg1, arg2})
+	fake.recordInvocation("FindRevision", []interface{}{arg1, arg2})
+	fake.findRevisionMutex.Unlock()
+	if fake.FindRevisionStub != nil {
+		return fake.FindRevisionStub(arg1, arg2)
+	} else {
+		return fake.findRevisionReturns.result1, fake.findRevisionReturns.result2
+	}
+}
+
+func (fake *FakeStorage) FindRevisionCallCount() int {
+	fake.findRevisionMutex.RLock()
+	defer fake.findRevisionMutex.RUnlock()
+	return len(fake.findRevisionArgsForCall)
+}
+
+func (fake *FakeStorage) FindRevisionArgsForCall(i int) (string, uint64) {
+	fake.findRevisionMutex.RLock()
+	defer fake.findRevisionMutex.RUnlock()
+	return fake.findRevisionArgsForCall[i].arg1, fake.findRevisionArgsForCall[i].arg2
+}
+
+func (fake *FakeStorage) FindRevisionReturns(result1 apihub.Revision, result2 error) {
+	fake.FindRevisionStub = nil
+	fake.findRevisionReturns = struct {
+		result1 apihub.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) AddToken(arg1 apihub.Token) error {
+	fake.addTokenMutex.Lock()
+	fake.addTokenArgsForCall = append(fake.addTokenArgsForCall, struct {
+		arg1 apihub.Token
+	}{arg1})
+	fake.recordInvocation("AddToken", []interface{}{arg1})
+	fake.addTokenMutex.Unlock()
+	if fake.AddTokenStub != nil {
+		return fake.AddTokenStub(arg1)
+	} else {
+		return fake.addTokenReturns.result1
+	}
+}
+
+func (fake *FakeStorage) AddTokenCallCount() int {
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	return len(fake.addTokenArgsForCall)
+}
+
+func (fake *FakeStorage) AddTokenArgsForCall(i int) apihub.Token {
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	return fake.addTokenArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) AddTokenReturns(result1 error) {
+	fake.AddTokenStub = nil
+	fake.addTokenReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) FindTokenByHash(arg1 string) (apihub.Token, error) {
+	fake.findTokenByHashMutex.Lock()
+	fake.findTokenByHashArgsForCall = append(fake.findTokenByHashArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("FindTokenByHash", []interface{}{arg1})
+	fake.findTokenByHashMutex.Unlock()
+	if fake.FindTokenByHashStub != nil {
+		return fake.FindTokenByHashStub(arg1)
+	} else {
+		return fake.findTokenByHashReturns.result1, fake.findTokenByHashReturns.result2
+	}
+}
+
+func (fake *FakeStorage) FindTokenByHashCallCount() int {
+	fake.findTokenByHashMutex.RLock()
+	defer fake.findTokenByHashMutex.RUnlock()
+	return len(fake.findTokenByHashArgsForCall)
+}
+
+func (fake *FakeStorage) FindTokenByHashArgsForCall(i int) string {
+	fake.findTokenByHashMutex.RLock()
+	defer fake.findTokenByHashMutex.RUnlock()
+	return fake.findTokenByHashArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) FindTokenByHashReturns(result1 apihub.Token, result2 error) {
+	fake.FindTokenByHashStub = nil
+	fake.findTokenByHashReturns = struct {
+		result1 apihub.Token
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) Tokens() ([]apihub.Token, error) {
+	fake.tokensMutex.Lock()
+	fake.tokensArgsForCall = append(fake.tokensArgsForCall, struct{}{})
+	fake.recordInvocation("Tokens", []interface{}{})
+	fake.tokensMutex.Unlock()
+	if fake.TokensStub != nil {
+		return fake.TokensStub()
+	} else {
+		return fake.tokensReturns.result1, fake.tokensReturns.result2
+	}
+}
+
+func (fake *FakeStorage) TokensCallCount() int {
+	fake.tokensMutex.RLock()
+	defer fake.tokensMutex.RUnlock()
+	return len(fake.tokensArgsForCall)
+}
+
+func (fake *FakeStorage) TokensReturns(result1 []apihub.Token, result2 error) {
+	fake.TokensStub = nil
+	fake.tokensReturns = struct {
+		result1 []apihub.Token
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) RemoveToken(arg1 string) error {
+	fake.removeTokenMutex.Lock()
+	fake.removeTokenArgsForCall = append(fake.removeTokenArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RemoveToken", []interface{}{arg1})
+	fake.removeTokenMutex.Unlock()
+	if fake.RemoveTokenStub != nil {
+		return fake.RemoveTokenStub(arg1)
+	} else {
+		return fake.removeTokenReturns.result1
+	}
+}
+
+func (fake *FakeStorage) RemoveTokenCallCount() int {
+	fake.removeTokenMutex.RLock()
+	defer fake.removeTokenMutex.RUnlock()
+	return len(fake.removeTokenArgsForCall)
+}
+
+func (fake *FakeStorage) RemoveTokenArgsForCall(i int) string {
+	fake.removeTokenMutex.RLock()
+	defer fake.removeTokenMutex.RUnlock()
+	return fake.removeTokenArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) RemoveTokenReturns(result1 error) {
+	fake.RemoveTokenStub = nil
+	fake.removeTokenReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) AddNamespace(arg1 apihub.Namespace) error {
+	fake.addNamespaceMutex.Lock()
+	fake.addNamespaceArgsForCall = append(fake.addNamespaceArgsForCall, struct {
+		arg1 apihub.Namespace
+	}{arg1})
+	fake.recordInvocation("AddNamespace", []interface{}{arg1})
+	fake.addNamespaceMutex.Unlock()
+	if fake.AddNamespaceStub != nil {
+		return fake.AddNamespaceStub(arg1)
+	} else {
+		return fake.addNamespaceReturns.result1
+	}
+}
+
+func (fake *FakeStorage) AddNamespaceCallCount() int {
+	fake.addNamespaceMutex.RLock()
+	defer fake.addNamespaceMutex.RUnlock()
+	return len(fake.addNamespaceArgsForCall)
+}
+
+func (fake *FakeStorage) AddNamespaceArgsForCall(i int) apihub.Namespace {
+	fake.addNamespaceMutex.RLock()
+	defer fake.addNamespaceMutex.RUnlock()
+	return fake.addNamespaceArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) AddNamespaceReturns(result1 error) {
+	fake.AddNamespaceStub = nil
+	fake.addNamespaceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) FindNamespace(arg1 string) (apihub.Namespace, error) {
+	fake.findNamespaceMutex.Lock()
+	fake.findNamespaceArgsForCall = append(fake.findNamespaceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("FindNamespace", []interface{}{arg1})
+	fake.findNamespaceMutex.Unlock()
+	if fake.FindNamespaceStub != nil {
+		return fake.FindNamespaceStub(arg1)
+	} else {
+		return fake.findNamespaceReturns.result1, fake.findNamespaceReturns.result2
+	}
+}
+
+func (fake *FakeStorage) FindNamespaceCallCount() int {
+	fake.findNamespaceMutex.RLock()
+	defer fake.findNamespaceMutex.RUnlock()
+	return len(fake.findNamespaceArgsForCall)
+}
+
+func (fake *FakeStorage) FindNamespaceArgsForCall(i int) string {
+	fake.findNamespaceMutex.RLock()
+	defer fake.findNamespaceMutex.RUnlock()
+	return fake.findNamespaceArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) FindNamespaceReturns(result1 apihub.Namespace, result2 error) {
+	fake.FindNamespaceStub = nil
+	fake.findNamespaceReturns = struct {
+		result1 apihub.Namespace
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) Namespaces() ([]apihub.Namespace, error) {
+	fake.namespacesMutex.Lock()
+	fake.namespacesArgsForCall = append(fake.namespacesArgsForCall, struct{}{})
+	fake.recordInvocation("Namespaces", []interface{}{})
+	fake.namespacesMutex.Unlock()
+	if fake.NamespacesStub != nil {
+		return fake.NamespacesStub()
+	} else {
+		return fake.namespacesReturns.result1, fake.namespacesReturns.result2
+	}
+}
+
+func (fake *FakeStorage) NamespacesCallCount() int {
+	fake.namespacesMutex.RLock()
+	defer fake.namespacesMutex.RUnlock()
+	return len(fake.namespacesArgsForCall)
+}
+
+func (fake *FakeStorage) NamespacesReturns(result1 []apihub.Namespace, result2 error) {
+	fake.NamespacesStub = nil
+	fake.namespacesReturns = struct {
+		result1 []apihub.Namespace
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) RemoveNamespace(arg1 string) error {
+	fake.removeNamespaceMutex.Lock()
+	fake.removeNamespaceArgsForCall = append(fake.removeNamespaceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RemoveNamespace", []interface{}{arg1})
+	fake.removeNamespaceMutex.Unlock()
+	if fake.RemoveNamespaceStub != nil {
+		return fake.RemoveNamespaceStub(arg1)
+	} else {
+		return fake.removeNamespaceReturns.result1
+	}
+}
+
+func (fake *FakeStorage) RemoveNamespaceCallCount() int {
+	fake.removeNamespaceMutex.RLock()
+	defer fake.removeNamespaceMutex.RUnlock()
+	return len(fake.removeNamespaceArgsForCall)
+}
+
+func (fake *FakeStorage) RemoveNamespaceArgsForCall(i int) string {
+	fake.removeNamespaceMutex.RLock()
+	defer fake.removeNamespaceMutex.RUnlock()
+	return fake.removeNamespaceArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) RemoveNamespaceReturns(result1 error) {
+	fake.RemoveNamespaceStub = nil
+	fake.removeNamespaceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) AddAuditEvent(arg1 apihub.AuditEvent) error {
+	fake.addAuditEventMutex.Lock()
+	fake.addAuditEventArgsForCall = append(fake.addAuditEventArgsForCall, struct {
+		arg1 apihub.AuditEvent
+	}{arg1})
+	fake.recordInvocation("AddAuditEvent", []interface{}{arg1})
+	fake.addAuditEventMutex.Unlock()
+	if fake.AddAuditEventStub != nil {
+		return fake.AddAuditEventStub(arg1)
+	} else {
+		return fake.addAuditEventReturns.result1
+	}
+}
+
+func (fake *FakeStorage) AddAuditEventCallCount() int {
+	fake.addAuditEventMutex.RLock()
+	defer fake.addAuditEventMutex.RUnlock()
+	return len(fake.addAuditEventArgsForCall)
+}
+
+func (fake *FakeStorage) AddAuditEventArgsForCall(i int) apihub.AuditEvent {
+	fake.addAuditEventMutex.RLock()
+	defer fake.addAuditEventMutex.RUnlock()
+	return fake.addAuditEventArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) AddAuditEventReturns(result1 error) {
+	fake.AddAuditEventStub = nil
+	fake.addAuditEventReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) AuditEvents(arg1 apihub.AuditQuery) ([]apihub.AuditEvent, error) {
+	fake.auditEventsMutex.Lock()
+	fake.auditEventsArgsForCall = append(fake.auditEventsArgsForCall, struct {
+		arg1 apihub.AuditQuery
+	}{arg1})
+	fake.recordInvocation("AuditEvents", []interface{}{arg1})
+	fake.auditEventsMutex.Unlock()
+	if fake.AuditEventsStub != nil {
+		return fake.AuditEventsStub(arg1)
+	} else {
+		return fake.auditEventsReturns.result1, fake.auditEventsReturns.result2
+	}
+}
+
+func (fake *FakeStorage) AuditEventsCallCount() int {
+	fake.auditEventsMutex.RLock()
+	defer fake.auditEventsMutex.RUnlock()
+	return len(fake.auditEventsArgsForCall)
+}
+
+func (fake *FakeStorage) AuditEventsArgsForCall(i int) apihub.AuditQuery {
+	fake.auditEventsMutex.RLock()
+	defer fake.auditEventsMutex.RUnlock()
+	return fake.auditEventsArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) AuditEventsReturns(result1 []apihub.AuditEvent, result2 error) {
+	fake.AuditEventsStub = nil
+	fake.auditEventsReturns = struct {
+		result1 []apihub.AuditEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) PutOutboxEntry(arg1 apihub.OutboxEntry) error {
+	fake.putOutboxEntryMutex.Lock()
+	fake.putOutboxEntryArgsForCall = append(fake.putOutboxEntryArgsForCall, struct {
+		arg1 apihub.OutboxEntry
+	}{arg1})
+	fake.recordInvocation("PutOutboxEntry", []interface{}{arg1})
+	fake.putOutboxEntryMutex.Unlock()
+	if fake.PutOutboxEntryStub != nil {
+		return fake.PutOutboxEntryStub(arg1)
+	} else {
+		return fake.putOutboxEntryReturns.result1
+	}
+}
+
+func (fake *FakeStorage) PutOutboxEntryCallCount() int {
+	fake.putOutboxEntryMutex.RLock()
+	defer fake.putOutboxEntryMutex.RUnlock()
+	return len(fake.putOutboxEntryArgsForCall)
+}
+
+func (fake *FakeStorage) PutOutboxEntryArgsForCall(i int) apihub.OutboxEntry {
+	fake.putOutboxEntryMutex.RLock()
+	defer fake.putOutboxEntryMutex.RUnlock()
+	return fake.putOutboxEntryArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) PutOutboxEntryReturns(result1 error) {
+	fake.PutOutboxEntryStub = nil
+	fake.putOutboxEntryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) OutboxEntries() ([]apihub.OutboxEntry, error) {
+	fake.outboxEntriesMutex.Lock()
+	fake.outboxEntriesArgsForCall = append(fake.outboxEntriesArgsForCall, struct{}{})
+	fake.recordInvocation("OutboxEntries", []interface{}{})
+	fake.outboxEntriesMutex.Unlock()
+	if fake.OutboxEntriesStub != nil {
+		return fake.OutboxEntriesStub()
+	} else {
+		return fake.outboxEntriesReturns.result1, fake.outboxEntriesReturns.result2
+	}
+}
+
+func (fake *FakeStorage) OutboxEntriesCallCount() int {
+	fake.outboxEntriesMutex.RLock()
+	defer fake.outboxEntriesMutex.RUnlock()
+	return len(fake.outboxEntriesArgsForCall)
+}
+
+func (fake *FakeStorage) OutboxEntriesReturns(result1 []apihub.OutboxEntry, result2 error) {
+	fake.OutboxEntriesStub = nil
+	fake.outboxEntriesReturns = struct {
+		result1 []apihub.OutboxEntry
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) RemoveOutboxEntry(host string, id string) error {
+	fake.removeOutboxEntryMutex.Lock()
+	fake.removeOutboxEntryArgsForCall = append(fake.removeOutboxEntryArgsForCall, struct {
+		host string
+		id   string
+	}{host, id})
+	fake.recordInvocation("RemoveOutboxEntry", []interface{}{host, id})
+	fake.removeOutboxEntryMutex.Unlock()
+	if fake.RemoveOutboxEntryStub != nil {
+		return fake.RemoveOutboxEntryStub(host, id)
+	} else {
+		return fake.removeOutboxEntryReturns.result1
+	}
+}
+
+func (fake *FakeStorage) RemoveOutboxEntryCallCount() int {
+	fake.removeOutboxEntryMutex.RLock()
+	defer fake.removeOutboxEntryMutex.RUnlock()
+	return len(fake.removeOutboxEntryArgsForCall)
+}
+
+func (fake *FakeStorage) RemoveOutboxEntryArgsForCall(i int) (string, string) {
+	fake.removeOutboxEntryMutex.RLock()
+	defer fake.removeOutboxEntryMutex.RUnlock()
+	return fake.removeOutboxEntryArgsForCall[i].host, fake.removeOutboxEntryArgsForCall[i].id
+}
+
+func (fake *FakeStorage) RemoveOutboxEntryReturns(result1 error) {
+	fake.RemoveOutboxEntryStub = nil
+	fake.removeOutboxEntryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) AddWebhook(arg1 apihub.Webhook) error {
+	fake.addWebhookMutex.Lock()
+	fake.addWebhookArgsForCall = append(fake.addWebhookArgsForCall, struct {
+		arg1 apihub.Webhook
+	}{arg1})
+	fake.recordInvocation("AddWebhook", []interface{}{arg1})
+	fake.addWebhookMutex.Unlock()
+	if fake.AddWebhookStub != nil {
+		return fake.AddWebhookStub(arg1)
+	} else {
+		return fake.addWebhookReturns.result1
+	}
+}
+
+func (fake *FakeStorage) AddWebhookCallCount() int {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	return len(fake.addWebhookArgsForCall)
+}
+
+func (fake *FakeStorage) AddWebhookArgsForCall(i int) apihub.Webhook {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	return fake.addWebhookArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) AddWebhookReturns(result1 error) {
+	fake.AddWebhookStub = nil
+	fake.addWebhookReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) FindWebhook(arg1 string) (apihub.Webhook, error) {
+	fake.findWebhookMutex.Lock()
+	fake.findWebhookArgsForCall = append(fake.findWebhookArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("FindWebhook", []interface{}{arg1})
+	fake.findWebhookMutex.Unlock()
+	if fake.FindWebhookStub != nil {
+		return fake.FindWebhookStub(arg1)
+	} else {
+		return fake.findWebhookReturns.result1, fake.findWebhookReturns.result2
+	}
+}
+
+func (fake *FakeStorage) FindWebhookCallCount() int {
+	fake.findWebhookMutex.RLock()
+	defer fake.findWebhookMutex.RUnlock()
+	return len(fake.findWebhookArgsForCall)
+}
+
+func (fake *FakeStorage) FindWebhookArgsForCall(i int) string {
+	fake.findWebhookMutex.RLock()
+	defer fake.findWebhookMutex.RUnlock()
+	return fake.findWebhookArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) FindWebhookReturns(result1 apihub.Webhook, result2 error) {
+	fake.FindWebhookStub = nil
+	fake.findWebhookReturns = struct {
+		result1 apihub.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) Webhooks() ([]apihub.Webhook, error) {
+	fake.webhooksMutex.Lock()
+	fake.webhooksArgsForCall = append(fake.webhooksArgsForCall, struct{}{})
+	fake.recordInvocation("Webhooks", []interface{}{})
+	fake.webhooksMutex.Unlock()
+	if fake.WebhooksStub != nil {
+		return fake.WebhooksStub()
+	} else {
+		return fake.webhooksReturns.result1, fake.webhooksReturns.result2
+	}
+}
+
+func (fake *FakeStorage) WebhooksCallCount() int {
+	fake.webhooksMutex.RLock()
+	defer fake.webhooksMutex.RUnlock()
+	return len(fake.webhooksArgsForCall)
+}
+
+func (fake *FakeStorage) WebhooksReturns(result1 []apihub.Webhook, result2 error) {
+	fake.WebhooksStub = nil
+	fake.webhooksReturns = struct {
+		result1 []apihub.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) RemoveWebhook(arg1 string) error {
+	fake.removeWebhookMutex.Lock()
+	fake.removeWebhookArgsForCall = append(fake.removeWebhookArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RemoveWebhook", []interface{}{arg1})
+	fake.removeWebhookMutex.Unlock()
+	if fake.RemoveWebhookStub != nil {
+		return fake.RemoveWebhookStub(arg1)
+	} else {
+		return fake.removeWebhookReturns.result1
+	}
+}
+
+func (fake *FakeStorage) RemoveWebhookCallCount() int {
+	fake.removeWebhookMutex.RLock()
+	defer fake.removeWebhookMutex.RUnlock()
+	return len(fake.removeWebhookArgsForCall)
+}
+
+func (fake *FakeStorage) RemoveWebhookArgsForCall(i int) string {
+	fake.removeWebhookMutex.RLock()
+	defer fake.removeWebhookMutex.RUnlock()
+	return fake.removeWebhookArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) RemoveWebhookReturns(result1 error) {
+	fake.RemoveWebhookStub = nil
+	fake.removeWebhookReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) PutWebhookDelivery(arg1 apihub.WebhookDelivery) error {
+	fake.putWebhookDeliveryMutex.Lock()
+	fake.putWebhookDeliveryArgsForCall = append(fake.putWebhookDeliveryArgsForCall, struct {
+		arg1 apihub.WebhookDelivery
+	}{arg1})
+	fake.recordInvocation("PutWebhookDelivery", []interface{}{arg1})
+	fake.putWebhookDeliveryMutex.Unlock()
+	if fake.PutWebhookDeliveryStub != nil {
+		return fake.PutWebhookDeliveryStub(arg1)
+	} else {
+		return fake.putWebhookDeliveryReturns.result1
+	}
+}
+
+func (fake *FakeStorage) PutWebhookDeliveryCallCount() int {
+	fake.putWebhookDeliveryMutex.RLock()
+	defer fake.putWebhookDeliveryMutex.RUnlock()
+	return len(fake.putWebhookDeliveryArgsForCall)
+}
+
+func (fake *FakeStorage) PutWebhookDeliveryArgsForCall(i int) apihub.WebhookDelivery {
+	fake.putWebhookDeliveryMutex.RLock()
+	defer fake.putWebhookDeliveryMutex.RUnlock()
+	return fake.putWebhookDeliveryArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) PutWebhookDeliveryReturns(result1 error) {
+	fake.PutWebhookDeliveryStub = nil
+	fake.putWebhookDeliveryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) WebhookDeliveries(arg1 string) ([]apihub.WebhookDelivery, error) {
+	fake.webhookDeliveriesMutex.Lock()
+	fake.webhookDeliveriesArgsForCall = append(fake.webhookDeliveriesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("WebhookDeliveries", []interface{}{arg1})
+	fake.webhookDeliveriesMutex.Unlock()
+	if fake.WebhookDeliveriesStub != nil {
+		return fake.WebhookDeliveriesStub(arg1)
+	} else {
+		return fake.webhookDeliveriesReturns.result1, fake.webhookDeliveriesReturns.result2
+	}
+}
+
+func (fake *FakeStorage) WebhookDeliveriesCallCount() int {
+	fake.webhookDeliveriesMutex.RLock()
+	defer fake.webhookDeliveriesMutex.RUnlock()
+	return len(fake.webhookDeliveriesArgsForCall)
+}
+
+func (fake *FakeStorage) WebhookDeliveriesArgsForCall(i int) string {
+	fake.webhookDeliveriesMutex.RLock()
+	defer fake.webhookDeliveriesMutex.RUnlock()
+	return fake.webhookDeliveriesArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) WebhookDeliveriesReturns(result1 []apihub.WebhookDelivery, result2 error) {
+	fake.WebhookDeliveriesStub = nil
+	fake.webhookDeliveriesReturns = struct {
+		result1 []apihub.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) DueWebhookDeliveries(arg1 time.Time) ([]apihub.WebhookDelivery, error) {
+	fake.dueWebhookDeliveriesMutex.Lock()
+	fake.dueWebhookDeliveriesArgsForCall = append(fake.dueWebhookDeliveriesArgsForCall, struct {
+		arg1 time.Time
+	}{arg1})
+	fake.recordInvocation("DueWebhookDeliveries", []interface{}{arg1})
+	fake.dueWebhookDeliveriesMutex.Unlock()
+	if fake.DueWebhookDeliveriesStub != nil {
+		return fake.DueWebhookDeliveriesStub(arg1)
+	} else {
+		return fake.dueWebhookDeliveriesReturns.result1, fake.dueWebhookDeliveriesReturns.result2
+	}
+}
+
+func (fake *FakeStorage) DueWebhookDeliveriesCallCount() int {
+	fake.dueWebhookDeliveriesMutex.RLock()
+	defer fake.dueWebhookDeliveriesMutex.RUnlock()
+	return len(fake.dueWebhookDeliveriesArgsForCall)
+}
+
+func (fake *FakeStorage) DueWebhookDeliveriesArgsForCall(i int) time.Time {
+	fake.dueWebhookDeliveriesMutex.RLock()
+	defer fake.dueWebhookDeliveriesMutex.RUnlock()
+	return fake.dueWebhookDeliveriesArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) DueWebhookDeliveriesReturns(result1 []apihub.WebhookDelivery, result2 error) {
+	fake.DueWebhookDeliveriesStub = nil
+	fake.dueWebhookDeliveriesReturns = struct {
+		result1 []apihub.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) PruneWebhookDeliveries(arg1 time.Time) error {
+	fake.pruneWebhookDeliveriesMutex.Lock()
+	fake.pruneWebhookDeliveriesArgsForCall = append(fake.pruneWebhookDeliveriesArgsForCall, struct {
+		arg1 time.Time
+	}{arg1})
+	fake.recordInvocation("PruneWebhookDeliveries", []interface{}{arg1})
+	fake.pruneWebhookDeliveriesMutex.Unlock()
+	if fake.PruneWebhookDeliveriesStub != nil {
+		return fake.PruneWebhookDeliveriesStub(arg1)
+	} else {
+		return fake.pruneWebhookDeliveriesReturns.result1
+	}
+}
+
+func (fake *FakeStorage) PruneWebhookDeliveriesCallCount() int {
+	fake.pruneWebhookDeliveriesMutex.RLock()
+	defer fake.pruneWebhookDeliveriesMutex.RUnlock()
+	return len(fake.pruneWebhookDeliveriesArgsForCall)
+}
+
+func (fake *FakeStorage) PruneWebhookDeliveriesArgsForCall(i int) time.Time {
+	fake.pruneWebhookDeliveriesMutex.RLock()
+	defer fake.pruneWebhookDeliveriesMutex.RUnlock()
+	return fake.pruneWebhookDeliveriesArgsForCall[i].arg1
+}
+
+func (fake *FakeStorage) PruneWebhookDeliveriesReturns(result1 error) {
+	fake.PruneWebhookDeliveriesStub = nil
+	fake.pruneWebhookDeliveriesReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorage) ServiceEvents(after uint64) ([]apihub.ServiceEvent, error) {
+	fake.serviceEventsMutex.Lock()
+	fake.serviceEventsArgsForCall = append(fake.serviceEventsArgsForCall, struct {
+		after uint64
+	}{after})
+	fake.recordInvocation("ServiceEvents", []interface{}{after})
+	fake.serviceEventsMutex.Unlock()
+	if fake.ServiceEventsStub != nil {
+		return fake.ServiceEventsStub(after)
+	} else {
+		return fake.serviceEventsReturns.result1, fake.serviceEventsReturns.result2
+	}
+}
+
+func (fake *FakeStorage) ServiceEventsCallCount() int {
+	fake.serviceEventsMutex.RLock()
+	defer fake.serviceEventsMutex.RUnlock()
+	return len(fake.serviceEventsArgsForCall)
+}
+
+func (fake *FakeStorage) ServiceEventsArgsForCall(i int) uint64 {
+	fake.serviceEventsMutex.RLock()
+	defer fake.serviceEventsMutex.RUnlock()
+	return fake.serviceEventsArgsForCall[i].after
+}
+
+func (fake *FakeStorage) ServiceEventsReturns(result1 []apihub.ServiceEvent, result2 error) {
+	fake.ServiceEventsStub = nil
+	fake.serviceEventsReturns = struct {
+		result1 []apihub.ServiceEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) ServiceEventsVersion() (uint64, error) {
+	fake.serviceEventsVersionMutex.Lock()
+	fake.serviceEventsVersionArgsForCall = append(fake.serviceEventsVersionArgsForCall, struct{}{})
+	fake.recordInvocation("ServiceEventsVersion", []interface{}{})
+	fake.serviceEventsVersionMutex.Unlock()
+	if fake.ServiceEventsVersionStub != nil {
+		return fake.ServiceEventsVersionStub()
+	} else {
+		return fake.serviceEventsVersionReturns.result1, fake.serviceEventsVersionReturns.result2
+	}
+}
+
+func (fake *FakeStorage) ServiceEventsVersionCallCount() int {
+	fake.serviceEventsVersionMutex.RLock()
+	defer fake.serviceEventsVersionMutex.RUnlock()
+	return len(fake.serviceEventsVersionArgsForCall)
+}
+
+func (fake *FakeStorage) ServiceEventsVersionReturns(result1 uint64, result2 error) {
+	fake.ServiceEventsVersionStub = nil
+	fake.serviceEventsVersionReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeStorage) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -222,8 +1319,62 @@ func (fake *FakeStorage) Invocations() map[string][][]interface{} {
 	defer fake.findServiceByHostMutex.RUnlock()
 	fake.servicesMutex.RLock()
 	defer fake.servicesMutex.RUnlock()
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
 	fake.removeServiceMutex.RLock()
 	defer fake.removeServiceMutex.RUnlock()
+	fake.changeServicesMutex.RLock()
+	defer fake.changeServicesMutex.RUnlock()
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	fake.findRevisionMutex.RLock()
+	defer fake.findRevisionMutex.RUnlock()
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	fake.findTokenByHashMutex.RLock()
+	defer fake.findTokenByHashMutex.RUnlock()
+	fake.tokensMutex.RLock()
+	defer fake.tokensMutex.RUnlock()
+	fake.removeTokenMutex.RLock()
+	defer fake.removeTokenMutex.RUnlock()
+	fake.addNamespaceMutex.RLock()
+	defer fake.addNamespaceMutex.RUnlock()
+	fake.findNamespaceMutex.RLock()
+	defer fake.findNamespaceMutex.RUnlock()
+	fake.namespacesMutex.RLock()
+	defer fake.namespacesMutex.RUnlock()
+	fake.removeNamespaceMutex.RLock()
+	defer fake.removeNamespaceMutex.RUnlock()
+	fake.addAuditEventMutex.RLock()
+	defer fake.addAuditEventMutex.RUnlock()
+	fake.auditEventsMutex.RLock()
+	defer fake.auditEventsMutex.RUnlock()
+	fake.putOutboxEntryMutex.RLock()
+	defer fake.putOutboxEntryMutex.RUnlock()
+	fake.outboxEntriesMutex.RLock()
+	defer fake.outboxEntriesMutex.RUnlock()
+	fake.removeOutboxEntryMutex.RLock()
+	defer fake.removeOutboxEntryMutex.RUnlock()
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	fake.findWebhookMutex.RLock()
+	defer fake.findWebhookMutex.RUnlock()
+	fake.webhooksMutex.RLock()
+	defer fake.webhooksMutex.RUnlock()
+	fake.removeWebhookMutex.RLock()
+	defer fake.removeWebhookMutex.RUnlock()
+	fake.putWebhookDeliveryMutex.RLock()
+	defer fake.putWebhookDeliveryMutex.RUnlock()
+	fake.webhookDeliveriesMutex.RLock()
+	defer fake.webhookDeliveriesMutex.RUnlock()
+	fake.dueWebhookDeliveriesMutex.RLock()
+	defer fake.dueWebhookDeliveriesMutex.RUnlock()
+	fake.pruneWebhookDeliveriesMutex.RLock()
+	defer fake.pruneWebhookDeliveriesMutex.RUnlock()
+	fake.serviceEventsMutex.RLock()
+	defer fake.serviceEventsMutex.RUnlock()
+	fake.serviceEventsVersionMutex.RLock()
+	defer fake.serviceEventsVersionMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -239,4 +1390,4 @@ func (fake *FakeStorage) recordInvocation(key string, args []interface{}) {
 	fake.invocations[key] = append(fake.invocations[key], args)
 }
 
-var _ apihub.Storage = new(FakeStorage)
+var _ apihub.AdminStorage = new(FakeStorage)
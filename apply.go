@@ -0,0 +1,43 @@
+package apihub
+
+import "encoding/json"
+
+const (
+	APPLY_CREATE string = "create"
+	APPLY_UPDATE string = "update"
+	APPLY_DELETE string = "delete"
+)
+
+// ApplyOptions tunes how a desired set of services is applied. DryRun only
+// plans the changes, and Prune deletes the services left out of the set,
+// which are kept otherwise.
+type ApplyOptions struct {
+	DryRun bool
+	Prune  bool
+}
+
+// ApplyChange is a change planned to reach the desired set of services. Diff
+// holds the whole spec of created services, and the JSON Merge Patch applied
+// to updated ones.
+type ApplyChange struct {
+	Action string          `json:"action"`
+	Host   string          `json:"host"`
+	Diff   json.RawMessage `json:"diff,omitempty"`
+}
+
+// ServiceChange is a change stored along with others by
+// Storage.ChangeServices. Action is one of the APPLY_* actions, and a delete
+// only reads the host of the spec.
+type ServiceChange struct {
+	Action string
+	Spec   ServiceSpec
+}
+
+// ApplyPlan holds the changes made, or to be made on a dry run, to reach a
+// desired set of services, sorted by host: creates and updates first, then
+// deletes.
+type ApplyPlan struct {
+	DryRun    bool          `json:"dry_run"`
+	Changes   []ApplyChange `json:"changes"`
+	Unchanged []string      `json:"unchanged"`
+}
@@ -0,0 +1,90 @@
+package apihub
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const (
+	AUDIT_ADD_SERVICE          string = "add-service"
+	AUDIT_UPDATE_SERVICE       string = "update-service"
+	AUDIT_REPLACE_SERVICE      string = "replace-service"
+	AUDIT_REMOVE_SERVICE       string = "remove-service"
+	AUDIT_SET_SERVICE_FAULTS   string = "set-service-faults"
+	AUDIT_CLEAR_SERVICE_FAULTS string = "clear-service-faults"
+	AUDIT_ROLLBACK_SERVICE     string = "rollback-service"
+	AUDIT_ADD_BACKEND          string = "add-backend"
+	AUDIT_UPDATE_BACKEND       string = "update-backend"
+	AUDIT_REMOVE_BACKEND       string = "remove-backend"
+)
+
+// AuditEvent records a change made to a service through the admin API. Before
+// is nil for added services and After for removed ones. Diff is the JSON
+// Merge Patch, see RFC 7396, turning Before into After.
+type AuditEvent struct {
+	ID        string          `json:"id"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Host      string          `json:"host"`
+	RequestID string          `json:"request_id"`
+	Before    *ServiceSpec    `json:"before,omitempty"`
+	After     *ServiceSpec    `json:"after,omitempty"`
+	Diff      json.RawMessage `json:"diff,omitempty"`
+}
+
+// AuditQuery selects audit events, newest first. Zero fields do not filter,
+// and a zero Limit returns every matching event.
+type AuditQuery struct {
+	Host   string
+	Actor  string
+	Action string
+	// Since and Until bound the time of the events, both inclusive.
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// Matches reports whether the event passes the filters of the query.
+func (q AuditQuery) Matches(event AuditEvent) bool {
+	if q.Host != "" && event.Host != q.Host {
+		return false
+	}
+	if q.Actor != "" && event.Actor != q.Actor {
+		return false
+	}
+	if q.Action != "" && event.Action != q.Action {
+		return false
+	}
+	if !q.Since.IsZero() && event.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && event.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// SelectAuditEvents applies the query to events, for the storages that cannot
+// filter and sort them on their own. Events recorded at the same time are
+// sorted by ID.
+func SelectAuditEvents(events []AuditEvent, q AuditQuery) []AuditEvent {
+	selected := []AuditEvent{}
+	for _, event := range events {
+		if q.Matches(event) {
+			selected = append(selected, event)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if !selected[i].Time.Equal(selected[j].Time) {
+			return selected[i].Time.After(selected[j].Time)
+		}
+		return selected[i].ID > selected[j].ID
+	})
+
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected
+}
@@ -0,0 +1,79 @@
+package apihub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	ROLE_ADMIN     string = "admin"
+	ROLE_EDITOR    string = "editor"
+	ROLE_READ_ONLY string = "read-only"
+)
+
+// Roles lists the roles of the admin API, from the least privileged to the
+// most privileged: read-only reads the services, editor changes them too and
+// admin also manages the tokens.
+var Roles = []string{ROLE_READ_ONLY, ROLE_EDITOR, ROLE_ADMIN}
+
+// Token grants a role on the admin API to the bearer of its secret. Only the
+// hash of the secret is stored, see HashToken.
+type Token struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// Namespaces restricts the token to the services in these namespaces.
+	// Tokens without namespaces reach every service.
+	Namespaces []string  `json:"namespaces,omitempty"`
+	Hash       string    `json:"hash,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CanAccess reports whether the token reaches the services in the namespace.
+func (t Token) CanAccess(namespace string) bool {
+	if len(t.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range t.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// IssuedToken is a token just created, along with its secret. The secret
+// cannot be retrieved afterwards.
+type IssuedToken struct {
+	Token
+	Secret string `json:"secret"`
+}
+
+// HashToken returns the hash a token secret is stored and looked up with.
+// Secrets are random, so a plain SHA-256 is enough to keep them unusable
+// from a copy of the storage.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidRole reports whether role is one of Roles.
+func ValidRole(role string) bool {
+	return roleLevel(role) >= 0
+}
+
+// RoleAllows reports whether role grants the permissions of required.
+func RoleAllows(role, required string) bool {
+	level := roleLevel(role)
+	return level >= 0 && level >= roleLevel(required)
+}
+
+func roleLevel(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
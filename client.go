@@ -26,6 +26,27 @@ type Client interface {
 	// * None.
 	Services() ([]Service, error)
 
+	// ListServices lists a page of the services selected by the query, and
+	// the cursor of the next page, empty on the last one.
+	//
+	// Errors:
+	// * The query is invalid.
+	ListServices(query ServiceQuery) ([]Service, string, error)
+
+	// WatchServices streams the changes to the services selected by the
+	// query, after the resource version, or starting with an added event per
+	// service when it is zero. When the stream drops, it is resumed from the
+	// last event received. The channel is closed once stop is closed, or when
+	// the watch can no longer be resumed; watch again from zero then. Only
+	// the changes made through the API server serving the watch are sent, so
+	// servers sharing a storage reject watches.
+	//
+	// Errors:
+	// * The query is invalid.
+	// * The resource version is too old.
+	// * The server does not serve watches.
+	WatchServices(query ServiceQuery, resourceVersion uint64, stop <-chan struct{}) (<-chan WatchEvent, error)
+
 	// FindService returns the service with the specified host.
 	//
 	// Errors:
@@ -37,4 +58,47 @@ type Client interface {
 	// Errors:
 	// * Service not found.
 	UpdateService(string, ServiceSpec) (Service, error)
+
+	// UpdateServiceIfMatch updates the service with the specified host, only
+	// when it has not changed since the given version was read.
+	//
+	// Errors:
+	// * Service not found.
+	// * The service version is not the given one.
+	UpdateServiceIfMatch(host string, version uint64, spec ServiceSpec) (Service, error)
+
+	// ReplaceService replaces the whole spec of the service with the
+	// specified host: the fields left empty are cleared.
+	//
+	// Errors:
+	// * Service not found.
+	// * The spec has no backends.
+	ReplaceService(host string, spec ServiceSpec) (Service, error)
+
+	// Apply reconciles the services with the desired set: the missing ones
+	// are created, the changed ones replaced and, when pruning, the ones left
+	// out deleted. A dry run only returns the plan.
+	//
+	// Errors:
+	// * Any desired service is invalid.
+	// * A desired host is taken by a service the token cannot access.
+	// * A change fails; the changes made before it are kept.
+	Apply(services []ServiceSpec, opts ApplyOptions) (ApplyPlan, error)
+
+	// Export returns a document with the services, to be imported into
+	// another ApiHub.
+	//
+	// Errors:
+	// * None.
+	Export() (ExportDocument, error)
+
+	// Import imports the services of an exported document. Either every
+	// change is made, or none is.
+	//
+	// Errors:
+	// * The document is of another version, or any service is invalid.
+	// * A host is taken by a service the token cannot access.
+	// * A change fails, or a service changed since the changes were planned;
+	//   none of the changes is made.
+	Import(doc ExportDocument, opts ImportOptions) (ImportReport, error)
 }
@@ -1,10 +1,16 @@
 package client
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/apihub/apihub"
 	"github.com/apihub/apihub/client/connection"
 )
 
+// watchRetryInterval is the time to wait before resuming a dropped watch.
+const watchRetryInterval = 500 * time.Millisecond
+
 type client struct {
 	conn connection.Connection
 }
@@ -42,6 +48,69 @@ func (cli *client) Services() ([]apihub.Service, error) {
 	return services, nil
 }
 
+func (cli *client) ListServices(query apihub.ServiceQuery) ([]apihub.Service, string, error) {
+	page, err := cli.conn.ListServices(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	services := []apihub.Service{}
+	for _, spec := range page.Services {
+		services = append(services, newService(spec.Host, cli.conn))
+	}
+
+	return services, page.NextCursor, nil
+}
+
+func (cli *client) WatchServices(query apihub.ServiceQuery, resourceVersion uint64, stop <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+	stream, err := cli.conn.WatchServices(query, resourceVersion, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan apihub.WatchEvent)
+	go func() {
+		defer close(events)
+		for {
+			for event := range stream {
+				resourceVersion = event.ResourceVersion
+				if event.Type == apihub.WATCH_HEARTBEAT {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-stop:
+					return
+				}
+			}
+
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(watchRetryInterval):
+				}
+				stream, err = cli.conn.WatchServices(query, resourceVersion, stop)
+				if err == nil {
+					break
+				}
+				if isClientError(err) {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// isClientError reports whether the API rejected the request, with a 4xx
+// response, so sending it again would fail the same way.
+func isClientError(err error) bool {
+	status := apihub.StatusCodes[apihub.ErrorCode(err)]
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError
+}
+
 func (cli *client) RemoveService(host string) error {
 	return cli.conn.RemoveService(host)
 }
@@ -63,3 +132,33 @@ func (cli *client) UpdateService(host string, spec apihub.ServiceSpec) (apihub.S
 
 	return newService(service.Host, cli.conn), nil
 }
+
+func (cli *client) UpdateServiceIfMatch(host string, version uint64, spec apihub.ServiceSpec) (apihub.Service, error) {
+	service, err := cli.conn.UpdateServiceIfMatch(host, version, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return newService(service.Host, cli.conn), nil
+}
+
+func (cli *client) ReplaceService(host string, spec apihub.ServiceSpec) (apihub.Service, error) {
+	service, err := cli.conn.ReplaceService(host, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return newService(service.Host, cli.conn), nil
+}
+
+func (cli *client) Apply(services []apihub.ServiceSpec, opts apihub.ApplyOptions) (apihub.ApplyPlan, error) {
+	return cli.conn.Apply(services, opts)
+}
+
+func (cli *client) Export() (apihub.ExportDocument, error) {
+	return cli.conn.Export()
+}
+
+func (cli *client) Import(doc apihub.ExportDocument, opts apihub.ImportOptions) (apihub.ImportReport, error) {
+	return cli.conn.Import(doc, opts)
+}
@@ -2,6 +2,7 @@ package client_test
 
 import (
 	"errors"
+	"time"
 
 	"github.com/apihub/apihub"
 	"github.com/apihub/apihub/client"
@@ -78,6 +79,139 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("ListServices", func() {
+		It("lists a page of services", func() {
+			fakeConnection.ListServicesReturns(apihub.ServicePage{
+				Services:   []apihub.ServiceSpec{apihub.ServiceSpec{Host: "my-host.apihub.dev"}},
+				NextCursor: "next",
+			}, nil)
+
+			query := apihub.ServiceQuery{HostPrefix: "my-", Limit: 1}
+			services, cursor, err := cli.ListServices(query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services).To(HaveLen(1))
+			Expect(cursor).To(Equal("next"))
+			Expect(fakeConnection.ListServicesArgsForCall(0)).To(Equal(query))
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				fakeConnection.ListServicesReturns(apihub.ServicePage{}, errors.New("failed to list services"))
+			})
+
+			It("returns an error", func() {
+				_, _, err := cli.ListServices(apihub.ServiceQuery{})
+				Expect(err).To(MatchError(ContainSubstring("failed to list services")))
+			})
+		})
+	})
+
+	Describe("WatchServices", func() {
+		var stop chan struct{}
+
+		BeforeEach(func() {
+			stop = make(chan struct{})
+		})
+
+		AfterEach(func() {
+			close(stop)
+		})
+
+		It("resumes from the last event when the stream drops", func() {
+			first := make(chan apihub.WatchEvent, 2)
+			first <- apihub.WatchEvent{Type: apihub.WATCH_ADDED, ResourceVersion: 7, Service: &apihub.ServiceSpec{Host: "my-host"}}
+			first <- apihub.WatchEvent{Type: apihub.WATCH_HEARTBEAT, ResourceVersion: 9}
+			close(first)
+			second := make(chan apihub.WatchEvent, 1)
+			second <- apihub.WatchEvent{Type: apihub.WATCH_REMOVED, ResourceVersion: 10, Service: &apihub.ServiceSpec{Host: "my-host"}}
+			close(second)
+			streams := []chan apihub.WatchEvent{first, second}
+			fakeConnection.WatchServicesStub = func(apihub.ServiceQuery, uint64, <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+				stream := streams[0]
+				if len(streams) > 1 {
+					streams = streams[1:]
+				}
+				return stream, nil
+			}
+
+			events, err := cli.WatchServices(apihub.ServiceQuery{}, 0, stop)
+			Expect(err).NotTo(HaveOccurred())
+
+			var event apihub.WatchEvent
+			Eventually(events).Should(Receive(&event))
+			Expect(event.Type).To(Equal(apihub.WATCH_ADDED))
+			Eventually(events, 2*time.Second).Should(Receive(&event))
+			Expect(event.Type).To(Equal(apihub.WATCH_REMOVED))
+
+			_, resourceVersion, _ := fakeConnection.WatchServicesArgsForCall(1)
+			Expect(resourceVersion).To(BeEquivalentTo(9))
+		})
+
+		It("closes the channel when the watch cannot be resumed", func() {
+			first := make(chan apihub.WatchEvent)
+			close(first)
+			fakeConnection.WatchServicesStub = func(apihub.ServiceQuery, uint64, <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+				if fakeConnection.WatchServicesCallCount() == 1 {
+					return first, nil
+				}
+				return nil, apihub.NewError(apihub.E_GONE, "The resource version is too old.")
+			}
+
+			events, err := cli.WatchServices(apihub.ServiceQuery{}, 5, stop)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(events, 2*time.Second).Should(BeClosed())
+		})
+
+		It("closes the channel when the server rejects the watch", func() {
+			first := make(chan apihub.WatchEvent)
+			close(first)
+			fakeConnection.WatchServicesStub = func(apihub.ServiceQuery, uint64, <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+				if fakeConnection.WatchServicesCallCount() == 1 {
+					return first, nil
+				}
+				return nil, apihub.NewError(apihub.E_UNAUTHORIZED, "The token is invalid.")
+			}
+
+			events, err := cli.WatchServices(apihub.ServiceQuery{}, 5, stop)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(events, 2*time.Second).Should(BeClosed())
+			Expect(fakeConnection.WatchServicesCallCount()).To(Equal(2))
+		})
+
+		It("keeps resuming while the server cannot be reached", func() {
+			first := make(chan apihub.WatchEvent)
+			close(first)
+			last := make(chan apihub.WatchEvent, 1)
+			last <- apihub.WatchEvent{Type: apihub.WATCH_ADDED, ResourceVersion: 6, Service: &apihub.ServiceSpec{Host: "my-host"}}
+			fakeConnection.WatchServicesStub = func(apihub.ServiceQuery, uint64, <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+				switch fakeConnection.WatchServicesCallCount() {
+				case 1:
+					return first, nil
+				case 2:
+					return nil, errors.New("connection refused")
+				}
+				return last, nil
+			}
+
+			events, err := cli.WatchServices(apihub.ServiceQuery{}, 5, stop)
+			Expect(err).NotTo(HaveOccurred())
+			var event apihub.WatchEvent
+			Eventually(events, 3*time.Second).Should(Receive(&event))
+			Expect(event.ResourceVersion).To(BeEquivalentTo(6))
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				fakeConnection.WatchServicesReturns(nil, errors.New("failed to watch services"))
+			})
+
+			It("returns an error", func() {
+				_, err := cli.WatchServices(apihub.ServiceQuery{}, 0, stop)
+				Expect(err).To(MatchError(ContainSubstring("failed to watch services")))
+			})
+		})
+	})
+
 	Describe("RemoveService", func() {
 		It("sends a request to remove a service", func() {
 			fakeConnection.RemoveServiceReturns(nil)
@@ -156,4 +290,101 @@ var _ = Describe("Client", func() {
 			})
 		})
 	})
+	Describe("UpdateServiceIfMatch", func() {
+		var spec apihub.ServiceSpec
+
+		BeforeEach(func() {
+			spec = apihub.ServiceSpec{
+				Host: "my-host.apihub.dev",
+			}
+		})
+
+		It("sends a conditional request to update a service", func() {
+			fakeConnection.UpdateServiceIfMatchReturns(spec, nil)
+
+			service, err := cli.UpdateServiceIfMatch("my-host.apihub.dev", 3, spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(service.Host()).To(Equal("my-host.apihub.dev"))
+
+			host, version, s := fakeConnection.UpdateServiceIfMatchArgsForCall(0)
+			Expect(host).To(Equal("my-host.apihub.dev"))
+			Expect(version).To(BeEquivalentTo(3))
+			Expect(s).To(Equal(spec))
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				fakeConnection.UpdateServiceIfMatchReturns(apihub.ServiceSpec{}, errors.New("The service has been modified since it was read."))
+			})
+
+			It("returns an error", func() {
+				_, err := cli.UpdateServiceIfMatch("my-host.apihub.dev", 3, spec)
+				Expect(err).To(MatchError(ContainSubstring("The service has been modified")))
+			})
+		})
+	})
+
+	Describe("ReplaceService", func() {
+		var spec apihub.ServiceSpec
+
+		BeforeEach(func() {
+			spec = apihub.ServiceSpec{
+				Host:     "my-host.apihub.dev",
+				Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+			}
+		})
+
+		It("sends a request to replace a service", func() {
+			fakeConnection.ReplaceServiceReturns(spec, nil)
+
+			service, err := cli.ReplaceService("my-host.apihub.dev", spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(service.Host()).To(Equal("my-host.apihub.dev"))
+
+			host, s := fakeConnection.ReplaceServiceArgsForCall(0)
+			Expect(host).To(Equal("my-host.apihub.dev"))
+			Expect(s).To(Equal(spec))
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				fakeConnection.ReplaceServiceReturns(apihub.ServiceSpec{}, errors.New("failed to replace service"))
+			})
+
+			It("returns an error", func() {
+				_, err := cli.ReplaceService("my-host.apihub.dev", spec)
+				Expect(err).To(MatchError(ContainSubstring("failed to replace service")))
+			})
+		})
+	})
+
+	Describe("Apply", func() {
+		It("returns the plan of the connection", func() {
+			specs := []apihub.ServiceSpec{apihub.ServiceSpec{Host: "my-host.apihub.dev"}}
+			fakeConnection.ApplyReturns(apihub.ApplyPlan{DryRun: true, Unchanged: []string{"my-host.apihub.dev"}}, nil)
+
+			plan, err := cli.Apply(specs, apihub.ApplyOptions{DryRun: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.Unchanged).To(Equal([]string{"my-host.apihub.dev"}))
+
+			s, opts := fakeConnection.ApplyArgsForCall(0)
+			Expect(s).To(Equal(specs))
+			Expect(opts).To(Equal(apihub.ApplyOptions{DryRun: true}))
+		})
+	})
+
+	Describe("Import", func() {
+		It("returns the report of the connection", func() {
+			doc := apihub.ExportDocument{Version: apihub.EXPORT_FORMAT_VERSION}
+			fakeConnection.ImportReturns(apihub.ImportReport{Mode: apihub.IMPORT_REPLACE}, nil)
+
+			report, err := cli.Import(doc, apihub.ImportOptions{Mode: apihub.IMPORT_REPLACE})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Mode).To(Equal(apihub.IMPORT_REPLACE))
+
+			d, opts := fakeConnection.ImportArgsForCall(0)
+			Expect(d).To(Equal(doc))
+			Expect(opts.Mode).To(Equal(apihub.IMPORT_REPLACE))
+		})
+	})
 })
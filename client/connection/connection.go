@@ -2,12 +2,14 @@ package connection
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,18 +22,56 @@ type Connection interface {
 	Ping() error
 	AddService(apihub.ServiceSpec) (apihub.ServiceSpec, error)
 	Services() ([]apihub.ServiceSpec, error)
+	ListServices(apihub.ServiceQuery) (apihub.ServicePage, error)
+	WatchServices(apihub.ServiceQuery, uint64, <-chan struct{}) (<-chan apihub.WatchEvent, error)
 	RemoveService(string) error
 	FindService(string) (apihub.ServiceSpec, error)
 	UpdateService(string, apihub.ServiceSpec) (apihub.ServiceSpec, error)
+	UpdateServiceIfMatch(string, uint64, apihub.ServiceSpec) (apihub.ServiceSpec, error)
+	PatchService(string, string, interface{}) (apihub.ServiceSpec, error)
+	ReplaceService(string, apihub.ServiceSpec) (apihub.ServiceSpec, error)
+	SetServiceFaults(string, []apihub.FaultRule) (apihub.ServiceSpec, error)
+	ClearServiceFaults(string) error
+	Backends(string) ([]apihub.BackendInfo, error)
+	AddBackend(string, apihub.BackendInfo) (apihub.BackendInfo, error)
+	UpdateBackend(string, string, interface{}) (apihub.BackendInfo, error)
+	RemoveBackend(string, string) error
+	Revisions(string) ([]apihub.Revision, error)
+	DiffRevisions(string, uint64, uint64) (apihub.RevisionDiff, error)
+	RollbackService(string, uint64) (apihub.ServiceSpec, error)
+	Apply([]apihub.ServiceSpec, apihub.ApplyOptions) (apihub.ApplyPlan, error)
+	Export() (apihub.ExportDocument, error)
+	Import(apihub.ExportDocument, apihub.ImportOptions) (apihub.ImportReport, error)
+	AddToken(string, string, []string) (apihub.IssuedToken, error)
+	Tokens() ([]apihub.Token, error)
+	RemoveToken(string) error
+	AddNamespace(apihub.Namespace) (apihub.Namespace, error)
+	Namespaces() ([]apihub.Namespace, error)
+	FindNamespace(string) (apihub.Namespace, error)
+	RemoveNamespace(string) error
+	AuditEvents(apihub.AuditQuery) ([]apihub.AuditEvent, error)
+	SyncStatus() (apihub.SyncStatus, error)
+	AddWebhook(apihub.Webhook) (apihub.Webhook, error)
+	Webhooks() ([]apihub.Webhook, error)
+	FindWebhook(string) (apihub.Webhook, error)
+	RemoveWebhook(string) error
+	WebhookDeliveries(string) ([]apihub.WebhookDelivery, error)
 }
 
 type Params map[string]string
 
 type connection struct {
 	client *http.Client
+	token  string
 }
 
 func New(listenNetwork, listenAddr string) *connection {
+	return NewWithToken(listenNetwork, listenAddr, "")
+}
+
+// NewWithToken returns a connection sending the secret of a token with every
+// request, for APIs requiring authentication.
+func NewWithToken(listenNetwork, listenAddr, token string) *connection {
 	return &connection{
 		client: &http.Client{
 			Transport: &http.Transport{
@@ -40,6 +80,7 @@ func New(listenNetwork, listenAddr string) *connection {
 				},
 			},
 		},
+		token: token,
 	}
 }
 
@@ -56,17 +97,97 @@ func (c *connection) AddService(spec apihub.ServiceSpec) (apihub.ServiceSpec, er
 	return service, nil
 }
 
+// Services follows the cursors of GET /services until the last page.
 func (c *connection) Services() ([]apihub.ServiceSpec, error) {
+	specs := []apihub.ServiceSpec{}
+	query := apihub.ServiceQuery{Limit: api.MAX_PAGE_LIMIT}
+	for {
+		page, err := c.ListServices(query)
+		if err != nil {
+			return []apihub.ServiceSpec{}, err
+		}
+
+		specs = append(specs, page.Services...)
+		if page.NextCursor == "" {
+			return specs, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// ListServices returns a page of the services selected by the query.
+func (c *connection) ListServices(query apihub.ServiceQuery) (apihub.ServicePage, error) {
 	specs := struct {
-		Items []apihub.ServiceSpec `json:"items"`
-		Count int                  `json:"item_count"`
+		Items      []apihub.ServiceSpec `json:"items"`
+		Count      int                  `json:"item_count"`
+		NextCursor string               `json:"next_cursor"`
 	}{}
 
-	if err := c.do(api.ListServices, nil, nil, &specs); err != nil {
-		return []apihub.ServiceSpec{}, err
+	req, err := createRequest("http://api", api.ListServices, nil, nil)
+	if err != nil {
+		return apihub.ServicePage{}, err
 	}
+	req.URL.RawQuery = api.ServiceQueryValues(query).Encode()
 
-	return specs.Items, nil
+	if err := c.doRequest(req, &specs); err != nil {
+		return apihub.ServicePage{}, err
+	}
+
+	return apihub.ServicePage{Services: specs.Items, NextCursor: specs.NextCursor}, nil
+}
+
+// WatchServices streams the changes to the services selected by the query,
+// heartbeats included, from the resource version. The channel is closed when
+// the stream ends, or once stop is closed.
+func (c *connection) WatchServices(query apihub.ServiceQuery, resourceVersion uint64, stop <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+	req, err := createRequest("http://api", api.ListServices, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = api.WatchQueryValues(query, resourceVersion).Encode()
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	response, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if response.StatusCode < http.StatusOK || response.StatusCode > 299 {
+		defer cancel()
+		defer response.Body.Close()
+		return nil, c.hostError(response.StatusCode, response.Body)
+	}
+
+	events := make(chan apihub.WatchEvent)
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		defer close(events)
+		defer response.Body.Close()
+		defer cancel()
+
+		decoder := json.NewDecoder(response.Body)
+		for {
+			var event apihub.WatchEvent
+			if err := decoder.Decode(&event); err != nil {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
 }
 
 func (c *connection) RemoveService(host string) error {
@@ -96,27 +217,411 @@ func (c *connection) UpdateService(host string, spec apihub.ServiceSpec) (apihub
 	return updatedSpec, nil
 }
 
-func (c *connection) hostError(body io.ReadCloser) error {
-	var err apihub.ErrorResponse
-	if err := json.NewDecoder(body).Decode(&err); err != nil {
-		return errors.New("request failed")
+// UpdateServiceIfMatch updates the service only when its stored version is
+// the given one.
+func (c *connection) UpdateServiceIfMatch(host string, version uint64, spec apihub.ServiceSpec) (apihub.ServiceSpec, error) {
+	params := map[string]string{"host": host}
+	header := http.Header{"If-Match": []string{strconv.Quote(strconv.FormatUint(version, 10))}}
+
+	var updatedSpec apihub.ServiceSpec
+	if err := c.doWithHeader(api.UpdateService, params, header, spec, &updatedSpec); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	return updatedSpec, nil
+}
+
+// PatchService sends the patch with the content type, either
+// api.MERGE_PATCH_CONTENT_TYPE or api.JSON_PATCH_CONTENT_TYPE.
+func (c *connection) PatchService(host string, contentType string, patch interface{}) (apihub.ServiceSpec, error) {
+	params := map[string]string{"host": host}
+	header := http.Header{"Content-Type": []string{contentType}}
+
+	var updatedSpec apihub.ServiceSpec
+	if err := c.doWithHeader(api.UpdateService, params, header, patch, &updatedSpec); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	return updatedSpec, nil
+}
+
+func (c *connection) ReplaceService(host string, spec apihub.ServiceSpec) (apihub.ServiceSpec, error) {
+	params := map[string]string{"host": host}
+
+	var replacedSpec apihub.ServiceSpec
+	if err := c.do(api.ReplaceService, params, spec, &replacedSpec); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	return replacedSpec, nil
+}
+
+func (c *connection) SetServiceFaults(host string, faults []apihub.FaultRule) (apihub.ServiceSpec, error) {
+	params := map[string]string{"host": host}
+
+	var updatedSpec apihub.ServiceSpec
+	if err := c.do(api.SetServiceFaults, params, faults, &updatedSpec); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	return updatedSpec, nil
+}
+
+func (c *connection) ClearServiceFaults(host string) error {
+	params := map[string]string{"host": host}
+	return c.do(api.ClearServiceFaults, params, nil, &struct{}{})
+}
+
+func (c *connection) Backends(host string) ([]apihub.BackendInfo, error) {
+	backends := struct {
+		Items []apihub.BackendInfo `json:"items"`
+		Count int                  `json:"item_count"`
+	}{}
+
+	params := map[string]string{"host": host}
+	if err := c.do(api.ListBackends, params, nil, &backends); err != nil {
+		return nil, err
+	}
+
+	return backends.Items, nil
+}
+
+func (c *connection) AddBackend(host string, backend apihub.BackendInfo) (apihub.BackendInfo, error) {
+	params := map[string]string{"host": host}
+
+	var addedBackend apihub.BackendInfo
+	if err := c.do(api.AddBackend, params, backend, &addedBackend); err != nil {
+		return apihub.BackendInfo{}, err
+	}
+
+	return addedBackend, nil
+}
+
+// UpdateBackend decodes the patch over the backend with the address, so
+// the members it leaves out are kept.
+func (c *connection) UpdateBackend(host, address string, patch interface{}) (apihub.BackendInfo, error) {
+	params := map[string]string{"host": host, "address": url.PathEscape(address)}
+
+	var updatedBackend apihub.BackendInfo
+	if err := c.do(api.UpdateBackend, params, patch, &updatedBackend); err != nil {
+		return apihub.BackendInfo{}, err
+	}
+
+	return updatedBackend, nil
+}
+
+func (c *connection) RemoveBackend(host, address string) error {
+	params := map[string]string{"host": host, "address": url.PathEscape(address)}
+	return c.do(api.RemoveBackend, params, nil, &struct{}{})
+}
+
+func (c *connection) Revisions(host string) ([]apihub.Revision, error) {
+	revisions := struct {
+		Items []apihub.Revision `json:"items"`
+		Count int               `json:"item_count"`
+	}{}
+
+	params := map[string]string{"host": host}
+	if err := c.do(api.ListRevisions, params, nil, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions.Items, nil
+}
+
+// DiffRevisions compares two revisions of the service. A zero to compares
+// with the current version.
+func (c *connection) DiffRevisions(host string, from, to uint64) (apihub.RevisionDiff, error) {
+	req, err := createRequest("http://api", api.DiffRevisions, Params{"host": host}, nil)
+	if err != nil {
+		return apihub.RevisionDiff{}, err
+	}
+	values := url.Values{"from": {strconv.FormatUint(from, 10)}}
+	if to != 0 {
+		values.Set("to", strconv.FormatUint(to, 10))
+	}
+	req.URL.RawQuery = values.Encode()
+
+	var diff apihub.RevisionDiff
+	if err := c.doRequest(req, &diff); err != nil {
+		return apihub.RevisionDiff{}, err
+	}
+
+	return diff, nil
+}
+
+// RollbackService stores the revision with the given version as the next
+// version of the service.
+func (c *connection) RollbackService(host string, to uint64) (apihub.ServiceSpec, error) {
+	req, err := createRequest("http://api", api.RollbackService, Params{"host": host}, nil)
+	if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	req.URL.RawQuery = url.Values{"to": {strconv.FormatUint(to, 10)}}.Encode()
+
+	var service apihub.ServiceSpec
+	if err := c.doRequest(req, &service); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	return service, nil
+}
+
+// Apply reconciles the services with the desired set, returning the changes
+// made, or only planned on a dry run.
+func (c *connection) Apply(services []apihub.ServiceSpec, opts apihub.ApplyOptions) (apihub.ApplyPlan, error) {
+	if services == nil {
+		services = []apihub.ServiceSpec{}
+	}
+	body := map[string][]apihub.ServiceSpec{"services": services}
+	req, err := createRequest("http://api", api.ApplyServices, nil, body)
+	if err != nil {
+		return apihub.ApplyPlan{}, err
+	}
+	req.URL.RawQuery = api.ApplyOptionsValues(opts).Encode()
+
+	var plan apihub.ApplyPlan
+	if err := c.doRequest(req, &plan); err != nil {
+		return apihub.ApplyPlan{}, err
+	}
+
+	return plan, nil
+}
+
+func (c *connection) Export() (apihub.ExportDocument, error) {
+	var doc apihub.ExportDocument
+	if err := c.do(api.ExportServices, nil, nil, &doc); err != nil {
+		return apihub.ExportDocument{}, err
+	}
+
+	return doc, nil
+}
+
+// Import imports the services of an exported document, all of them or none.
+func (c *connection) Import(doc apihub.ExportDocument, opts apihub.ImportOptions) (apihub.ImportReport, error) {
+	req, err := createRequest("http://api", api.ImportServices, nil, doc)
+	if err != nil {
+		return apihub.ImportReport{}, err
+	}
+	req.URL.RawQuery = api.ImportOptionsValues(opts).Encode()
+
+	var report apihub.ImportReport
+	if err := c.doRequest(req, &report); err != nil {
+		return apihub.ImportReport{}, err
+	}
+
+	return report, nil
+}
+
+// AddToken creates a token with the given name and role, restricted to the
+// namespaces when any is given. The secret of the token is only returned here.
+func (c *connection) AddToken(name, role string, namespaces []string) (apihub.IssuedToken, error) {
+	body := struct {
+		Name       string   `json:"name"`
+		Role       string   `json:"role"`
+		Namespaces []string `json:"namespaces,omitempty"`
+	}{name, role, namespaces}
+
+	var token apihub.IssuedToken
+	if err := c.do(api.AddToken, nil, body, &token); err != nil {
+		return apihub.IssuedToken{}, err
+	}
+
+	return token, nil
+}
+
+func (c *connection) Tokens() ([]apihub.Token, error) {
+	tokens := struct {
+		Items []apihub.Token `json:"items"`
+		Count int            `json:"item_count"`
+	}{}
+
+	if err := c.do(api.ListTokens, nil, nil, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens.Items, nil
+}
+
+func (c *connection) RemoveToken(id string) error {
+	params := map[string]string{"id": id}
+	return c.do(api.RemoveToken, params, nil, &struct{}{})
+}
+
+func (c *connection) AddNamespace(ns apihub.Namespace) (apihub.Namespace, error) {
+	var namespace apihub.Namespace
+	if err := c.do(api.AddNamespace, nil, ns, &namespace); err != nil {
+		return apihub.Namespace{}, err
+	}
+
+	return namespace, nil
+}
+
+func (c *connection) Namespaces() ([]apihub.Namespace, error) {
+	namespaces := struct {
+		Items []apihub.Namespace `json:"items"`
+		Count int                `json:"item_count"`
+	}{}
+
+	if err := c.do(api.ListNamespaces, nil, nil, &namespaces); err != nil {
+		return nil, err
+	}
+
+	return namespaces.Items, nil
+}
+
+func (c *connection) FindNamespace(name string) (apihub.Namespace, error) {
+	params := map[string]string{"name": name}
+	var namespace apihub.Namespace
+	if err := c.do(api.FindNamespace, params, nil, &namespace); err != nil {
+		return apihub.Namespace{}, err
+	}
+
+	return namespace, nil
+}
+
+func (c *connection) RemoveNamespace(name string) error {
+	params := map[string]string{"name": name}
+	return c.do(api.RemoveNamespace, params, nil, &struct{}{})
+}
+
+// AuditEvents returns the audit events selected by the query, newest first.
+func (c *connection) AuditEvents(query apihub.AuditQuery) ([]apihub.AuditEvent, error) {
+	events := struct {
+		Items []apihub.AuditEvent `json:"items"`
+		Count int                 `json:"item_count"`
+	}{}
+
+	req, err := createRequest("http://api", api.ListAudit, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = api.AuditQueryValues(query).Encode()
+
+	if err := c.doRequest(req, &events); err != nil {
+		return nil, err
+	}
+
+	return events.Items, nil
+}
+
+// SyncStatus returns the services waiting to be published, and what the
+// reconciler found last.
+func (c *connection) SyncStatus() (apihub.SyncStatus, error) {
+	var status apihub.SyncStatus
+	if err := c.do(api.SyncStatus, nil, nil, &status); err != nil {
+		return apihub.SyncStatus{}, err
 	}
-	return errors.New(err.Description)
+
+	return status, nil
+}
+
+func (c *connection) AddWebhook(webhook apihub.Webhook) (apihub.Webhook, error) {
+	var added apihub.Webhook
+	if err := c.do(api.AddWebhook, nil, webhook, &added); err != nil {
+		return apihub.Webhook{}, err
+	}
+
+	return added, nil
+}
+
+func (c *connection) Webhooks() ([]apihub.Webhook, error) {
+	webhooks := struct {
+		Items []apihub.Webhook `json:"items"`
+		Count int              `json:"item_count"`
+	}{}
+
+	if err := c.do(api.ListWebhooks, nil, nil, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks.Items, nil
+}
+
+func (c *connection) FindWebhook(id string) (apihub.Webhook, error) {
+	params := map[string]string{"id": id}
+	var webhook apihub.Webhook
+	if err := c.do(api.FindWebhook, params, nil, &webhook); err != nil {
+		return apihub.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (c *connection) RemoveWebhook(id string) error {
+	params := map[string]string{"id": id}
+	return c.do(api.RemoveWebhook, params, nil, &struct{}{})
+}
+
+func (c *connection) WebhookDeliveries(id string) ([]apihub.WebhookDelivery, error) {
+	params := map[string]string{"id": id}
+	deliveries := struct {
+		Items []apihub.WebhookDelivery `json:"items"`
+		Count int                      `json:"item_count"`
+	}{}
+
+	if err := c.do(api.ListDeliveries, params, nil, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries.Items, nil
+}
+
+// hostError returns the error responded by the API as an *apihub.Error, so
+// callers can check its kind with errors.Is. Responses without an error code
+// are classified by their status. The field details of validation errors are
+// kept.
+func (c *connection) hostError(statusCode int, body io.ReadCloser) error {
+	var resp apihub.ErrorResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		resp = apihub.ErrorResponse{Description: "request failed"}
+	}
+	if resp.Error == "" {
+		resp.Error = errorCode(statusCode)
+	}
+	return &apihub.Error{Code: resp.Error, Description: resp.Description, Details: resp.Details}
+}
+
+func errorCode(statusCode int) string {
+	for code, status := range apihub.StatusCodes {
+		if status == statusCode {
+			return code
+		}
+	}
+	if statusCode < http.StatusInternalServerError {
+		return apihub.E_BAD_REQUEST
+	}
+	return apihub.E_INTERNAL_ERROR
 }
 
 func (c *connection) do(route api.Route, params Params, body interface{}, res interface{}) error {
+	return c.doWithHeader(route, params, nil, body, res)
+}
+
+func (c *connection) doWithHeader(route api.Route, params Params, header http.Header, body interface{}, res interface{}) error {
 	req, err := createRequest("http://api", route, params, body)
 	if err != nil {
 		return err
 	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	return c.doRequest(req, res)
+}
 
+func (c *connection) doRequest(req *http.Request, res interface{}) error {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
 	response, err := c.client.Do(req)
 	if err != nil {
 		return err
 	}
 
 	if response.StatusCode < http.StatusOK || response.StatusCode > 299 {
-		return c.hostError(response.Body)
+		defer response.Body.Close()
+		return c.hostError(response.StatusCode, response.Body)
 	}
 
 	if response.StatusCode != http.StatusNoContent && response.Body != nil {
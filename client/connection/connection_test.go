@@ -1,7 +1,9 @@
 package connection_test
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/apihub/apihub"
 	"github.com/apihub/apihub/api"
@@ -59,6 +61,43 @@ var _ = Describe("Connection", func() {
 			err := conn.Ping()
 			Expect(err).To(MatchError(ContainSubstring("Failed to ping.")))
 		})
+
+		It("returns an error of the kind of the error code", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/services/my-host"),
+					ghttp.RespondWith(404, `{"error":"not_found", "error_description":"Failed to find service."}`),
+				),
+			)
+
+			_, err := conn.FindService("my-host")
+			Expect(errors.Is(err, apihub.ErrNotFound)).To(BeTrue())
+			Expect(errors.Is(err, apihub.ErrConflict)).To(BeFalse())
+			Expect(err).To(MatchError("Failed to find service."))
+		})
+
+		It("classifies the error by its status when the response has no error code", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, api.Routes[api.AddService].Path),
+					ghttp.RespondWith(409, `this is not a valid json`),
+				),
+			)
+
+			_, err := conn.AddService(apihub.ServiceSpec{Host: "my-host"})
+			Expect(errors.Is(err, apihub.ErrConflict)).To(BeTrue())
+		})
+
+		It("classifies unknown server failures as internal errors", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.Ping].Path),
+					ghttp.RespondWith(503, "{}"),
+				),
+			)
+
+			Expect(errors.Is(conn.Ping(), apihub.ErrInternal)).To(BeTrue())
+		})
 	})
 
 	Describe("Ping", func() {
@@ -132,6 +171,26 @@ var _ = Describe("Connection", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("when the spec is invalid", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPost, api.Routes[api.AddService].Path),
+						ghttp.RespondWith(422, `{"error":"validation_failed","error_description":"The service is invalid.","error_details":[{"field":"host","description":"Host cannot be empty."}]}`),
+					),
+				)
+			})
+
+			It("returns the invalid fields", func() {
+				_, err := conn.AddService(apihub.ServiceSpec{})
+				Expect(errors.Is(err, apihub.ErrValidation)).To(BeTrue())
+
+				var apiErr *apihub.Error
+				Expect(errors.As(err, &apiErr)).To(BeTrue())
+				Expect(apiErr.Details).To(ConsistOf(apihub.FieldError{Field: "host", Description: "Host cannot be empty."}))
+			})
+		})
 	})
 
 	Describe("Services", func() {
@@ -157,6 +216,31 @@ var _ = Describe("Connection", func() {
 			})
 		})
 
+		Context("when there are several pages", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListServices].Path, "limit=1000"),
+						ghttp.RespondWith(200, `{"items":[{"host":"my-host"}],"item_count":1,"next_cursor":"next"}`),
+					),
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListServices].Path, "cursor=next&limit=1000"),
+						ghttp.RespondWith(200, `{"items":[{"host":"another-host"}],"item_count":1}`),
+					),
+				)
+			})
+
+			It("follows the cursors until the last page", func() {
+				specs, err := conn.Services()
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(specs).To(Equal([]apihub.ServiceSpec{
+					apihub.ServiceSpec{Host: "my-host"},
+					apihub.ServiceSpec{Host: "another-host"},
+				}))
+			})
+		})
+
 		Context("when the request fails", func() {
 			BeforeEach(func() {
 				server.AppendHandlers(
@@ -174,6 +258,80 @@ var _ = Describe("Connection", func() {
 		})
 	})
 
+	Describe("ListServices", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListServices].Path, "host_prefix=my-&label=team%3Dpayments&limit=2&sort=-version"),
+						ghttp.RespondWith(200, `{"items":[{"host":"my-host"}, {"host":"another-host"}],"item_count":2,"next_cursor":"next"}`),
+					),
+				)
+			})
+
+			It("lists a page of services specs", func() {
+				page, err := conn.ListServices(apihub.ServiceQuery{
+					HostPrefix: "my-",
+					Labels:     map[string]string{"team": "payments"},
+					Sort:       "-version",
+					Limit:      2,
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(page.Services).To(ConsistOf([]apihub.ServiceSpec{
+					apihub.ServiceSpec{Host: "my-host"},
+					apihub.ServiceSpec{Host: "another-host"},
+				}))
+				Expect(page.NextCursor).To(Equal("next"))
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListServices].Path),
+						ghttp.RespondWith(400, "{}"),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := conn.ListServices(apihub.ServiceQuery{})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("WatchServices", func() {
+		It("streams the events", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListServices].Path, "host_prefix=my-&resource_version=7&watch=true"),
+					ghttp.RespondWith(200, `{"type":"updated","resource_version":8,"service":{"host":"my-host"}}`+"\n"+`{"type":"heartbeat","resource_version":8}`+"\n"),
+				),
+			)
+
+			events, err := conn.WatchServices(apihub.ServiceQuery{HostPrefix: "my-"}, 7, make(chan struct{}))
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(events).Should(Receive(Equal(apihub.WatchEvent{Type: apihub.WATCH_UPDATED, ResourceVersion: 8, Service: &apihub.ServiceSpec{Host: "my-host"}})))
+			Eventually(events).Should(Receive(Equal(apihub.WatchEvent{Type: apihub.WATCH_HEARTBEAT, ResourceVersion: 8})))
+			Eventually(events).Should(BeClosed())
+		})
+
+		It("returns an error when the resource version is too old", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListServices].Path),
+					ghttp.RespondWith(410, `{"error":"gone","error_description":"The resource version is too old."}`),
+				),
+			)
+
+			_, err := conn.WatchServices(apihub.ServiceQuery{}, 1, make(chan struct{}))
+			Expect(errors.Is(err, apihub.ErrGone)).To(BeTrue())
+		})
+	})
+
 	Describe("RemoveService", func() {
 		Context("when the request succeeds", func() {
 			BeforeEach(func() {
@@ -285,4 +443,720 @@ var _ = Describe("Connection", func() {
 		})
 	})
 
+	Describe("UpdateServiceIfMatch", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPatch, "/services/my-host"),
+						ghttp.VerifyHeaderKV("If-Match", `"3"`),
+						ghttp.RespondWith(200, `{"host":"my-host","version":4}`),
+					),
+				)
+			})
+
+			It("updates the service", func() {
+				spec, err := conn.UpdateServiceIfMatch("my-host", 3, apihub.ServiceSpec{Disabled: true})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Version).To(BeEquivalentTo(4))
+			})
+		})
+
+		Context("when the version does not match", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPatch, "/services/my-host"),
+						ghttp.RespondWith(412, `{"error":"precondition_failed","error_description":"The service has been modified since it was read."}`),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := conn.UpdateServiceIfMatch("my-host", 3, apihub.ServiceSpec{})
+				Expect(err).To(MatchError("The service has been modified since it was read."))
+				Expect(errors.Is(err, apihub.ErrPreconditionFailed)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("PatchService", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPatch, "/services/my-host"),
+						ghttp.VerifyContentType(api.MERGE_PATCH_CONTENT_TYPE),
+						ghttp.VerifyBody([]byte(`{"disabled":false}`+"\n")),
+						ghttp.RespondWith(200, `{"host":"my-host","timeout":100}`),
+					),
+				)
+			})
+
+			It("sends the patch with its content type", func() {
+				spec, err := conn.PatchService("my-host", api.MERGE_PATCH_CONTENT_TYPE, map[string]interface{}{"disabled": false})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Timeout).To(BeEquivalentTo(100))
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPatch, "/services/my-host"),
+						ghttp.RespondWith(409, `{"error":"conflict","error_description":"The test operation of the patch failed."}`),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := conn.PatchService("my-host", api.JSON_PATCH_CONTENT_TYPE, []api.PatchOperation{})
+				Expect(errors.Is(err, apihub.ErrConflict)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("ReplaceService", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPut, "/services/my-host"),
+						ghttp.RespondWith(200, `{"host":"my-host","version":2}`),
+					),
+				)
+			})
+
+			It("replaces the service", func() {
+				spec, err := conn.ReplaceService("my-host", apihub.ServiceSpec{Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Version).To(BeEquivalentTo(2))
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPut, "/services/invalid-host"),
+						ghttp.RespondWith(404, "{}"),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := conn.ReplaceService("invalid-host", apihub.ServiceSpec{})
+				Expect(errors.Is(err, apihub.ErrNotFound)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("SetServiceFaults", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPut, "/services/my-host/faults"),
+						ghttp.VerifyBody([]byte(`[{"header":"X-Chaos","percentage":10,"abort_status":503}]`+"\n")),
+						ghttp.RespondWith(200, `{"host":"my-host","faults":[{"header":"X-Chaos","percentage":10,"abort_status":503}]}`),
+					),
+				)
+			})
+
+			It("sets the faults of the service", func() {
+				spec, err := conn.SetServiceFaults("my-host", []apihub.FaultRule{
+					apihub.FaultRule{Header: "X-Chaos", Percentage: 10, AbortStatus: 503},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Faults).To(HaveLen(1))
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPut, "/services/invalid-host/faults"),
+						ghttp.RespondWith(400, "{}"),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := conn.SetServiceFaults("invalid-host", nil)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("ClearServiceFaults", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodDelete, "/services/my-host/faults"),
+						ghttp.RespondWith(204, ""),
+					),
+				)
+			})
+
+			It("clears the faults of the service", func() {
+				Expect(conn.ClearServiceFaults("my-host")).To(Succeed())
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodDelete, "/services/invalid-host/faults"),
+						ghttp.RespondWith(400, "{}"),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				Expect(conn.ClearServiceFaults("invalid-host")).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("backends", func() {
+		It("lists the backends of the service", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/services/my-host/backends"),
+					ghttp.RespondWith(200, `{"items":[{"address":"http://server-a"}],"item_count":1}`),
+				),
+			)
+
+			backends, err := conn.Backends("my-host")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backends).To(Equal([]apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}}))
+		})
+
+		It("adds a backend", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, "/services/my-host/backends"),
+					ghttp.VerifyBody([]byte(`{"address":"http://server-b","disabled":false,"heart_beat_address":"","heart_beat_timeout":0}`+"\n")),
+					ghttp.RespondWith(201, `{"address":"http://server-b"}`),
+				),
+			)
+
+			backend, err := conn.AddBackend("my-host", apihub.BackendInfo{Address: "http://server-b"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backend.Address).To(Equal("http://server-b"))
+		})
+
+		It("escapes the address of the backend to update", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					func(rw http.ResponseWriter, r *http.Request) {
+						Expect(r.Method).To(Equal(http.MethodPatch))
+						Expect(r.RequestURI).To(Equal("/services/my-host/backends/http:%2F%2Fserver-b"))
+					},
+					ghttp.VerifyBody([]byte(`{"disabled":true}`+"\n")),
+					ghttp.RespondWith(200, `{"address":"http://server-b","disabled":true}`),
+				),
+			)
+
+			backend, err := conn.UpdateBackend("my-host", "http://server-b", map[string]interface{}{"disabled": true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backend.Disabled).To(BeTrue())
+		})
+
+		It("removes a backend", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					func(rw http.ResponseWriter, r *http.Request) {
+						Expect(r.Method).To(Equal(http.MethodDelete))
+						Expect(r.RequestURI).To(Equal("/services/my-host/backends/http:%2F%2Fserver-b"))
+					},
+					ghttp.RespondWith(204, ""),
+				),
+			)
+
+			Expect(conn.RemoveBackend("my-host", "http://server-b")).To(Succeed())
+		})
+
+		It("returns the error of the API", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodDelete, "/services/my-host/backends/http://server-c"),
+					ghttp.RespondWith(404, `{"error":"not_found","error_description":"Backend not found."}`),
+				),
+			)
+
+			err := conn.RemoveBackend("my-host", "http://server-c")
+			Expect(errors.Is(err, apihub.ErrNotFound)).To(BeTrue())
+		})
+	})
+
+	Describe("NewWithToken", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/services/my-host"),
+					ghttp.VerifyHeaderKV("Authorization", "Bearer my-secret"),
+					ghttp.RespondWith(200, `{"host":"my-host"}`),
+				),
+			)
+		})
+
+		It("sends the token with the requests", func() {
+			conn = connection.NewWithToken(network, address, "my-secret")
+			_, err := conn.FindService("my-host")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("AddToken", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPost, api.Routes[api.AddToken].Path),
+						ghttp.VerifyBody([]byte(`{"name":"deploys","role":"editor"}`+"\n")),
+						ghttp.RespondWith(201, `{"id":"a-token","name":"deploys","role":"editor","secret":"my-secret"}`),
+					),
+				)
+			})
+
+			It("returns the secret of the token", func() {
+				token, err := conn.AddToken("deploys", apihub.ROLE_EDITOR, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(token.ID).To(Equal("a-token"))
+				Expect(token.Secret).To(Equal("my-secret"))
+			})
+		})
+
+		Context("when the token is restricted to namespaces", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPost, api.Routes[api.AddToken].Path),
+						ghttp.VerifyBody([]byte(`{"name":"deploys","role":"editor","namespaces":["payments"]}`+"\n")),
+						ghttp.RespondWith(201, `{"id":"a-token","name":"deploys","role":"editor","namespaces":["payments"],"secret":"my-secret"}`),
+					),
+				)
+			})
+
+			It("sends the namespaces", func() {
+				token, err := conn.AddToken("deploys", apihub.ROLE_EDITOR, []string{"payments"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(token.Namespaces).To(Equal([]string{"payments"}))
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodPost, api.Routes[api.AddToken].Path),
+						ghttp.RespondWith(403, `{"error":"forbidden","error_description":"The token does not allow this operation."}`),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := conn.AddToken("deploys", apihub.ROLE_EDITOR, nil)
+				Expect(errors.Is(err, apihub.ErrForbidden)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Tokens", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListTokens].Path),
+					ghttp.RespondWith(200, `{"items":[{"id":"a-token","name":"deploys","role":"editor"}],"item_count":1}`),
+				),
+			)
+		})
+
+		It("lists the tokens", func() {
+			tokens, err := conn.Tokens()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokens).To(ConsistOf(apihub.Token{ID: "a-token", Name: "deploys", Role: apihub.ROLE_EDITOR}))
+		})
+	})
+
+	Describe("RemoveToken", func() {
+		Context("when the request succeeds", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodDelete, "/tokens/a-token"),
+						ghttp.RespondWith(204, ""),
+					),
+				)
+			})
+
+			It("removes the token", func() {
+				Expect(conn.RemoveToken("a-token")).To(Succeed())
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodDelete, "/tokens/a-token"),
+						ghttp.RespondWith(401, `{"error":"unauthorized","error_description":"Invalid token."}`),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				err := conn.RemoveToken("a-token")
+				Expect(errors.Is(err, apihub.ErrUnauthorized)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("AddNamespace", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, api.Routes[api.AddNamespace].Path),
+					ghttp.VerifyBody([]byte(`{"name":"payments","domain_suffixes":["payments.apihub.dev"]}`+"\n")),
+					ghttp.RespondWith(201, `{"name":"payments","domain_suffixes":["payments.apihub.dev"]}`),
+				),
+			)
+		})
+
+		It("adds the namespace", func() {
+			ns, err := conn.AddNamespace(apihub.Namespace{Name: "payments", DomainSuffixes: []string{"payments.apihub.dev"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ns.Name).To(Equal("payments"))
+		})
+	})
+
+	Describe("Namespaces", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListNamespaces].Path),
+					ghttp.RespondWith(200, `{"items":[{"name":"payments"}],"item_count":1}`),
+				),
+			)
+		})
+
+		It("lists the namespaces", func() {
+			namespaces, err := conn.Namespaces()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespaces).To(ConsistOf(apihub.Namespace{Name: "payments"}))
+		})
+	})
+
+	Describe("FindNamespace", func() {
+		Context("when the namespace exists", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, "/namespaces/payments"),
+						ghttp.RespondWith(200, `{"name":"payments"}`),
+					),
+				)
+			})
+
+			It("returns the namespace", func() {
+				ns, err := conn.FindNamespace("payments")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ns).To(Equal(apihub.Namespace{Name: "payments"}))
+			})
+		})
+
+		Context("when the namespace does not exist", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, "/namespaces/payments"),
+						ghttp.RespondWith(404, `{"error":"not_found","error_description":"Failed to find namespace."}`),
+					),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := conn.FindNamespace("payments")
+				Expect(errors.Is(err, apihub.ErrNotFound)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("RemoveNamespace", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodDelete, "/namespaces/payments"),
+					ghttp.RespondWith(409, `{"error":"conflict","error_description":"The namespace still has services."}`),
+				),
+			)
+		})
+
+		It("returns the conflict", func() {
+			err := conn.RemoveNamespace("payments")
+			Expect(errors.Is(err, apihub.ErrConflict)).To(BeTrue())
+		})
+	})
+
+	Describe("AuditEvents", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListAudit].Path, "action=remove-service&host=my-host&limit=10&since=2020-05-01T10%3A00%3A00Z"),
+					ghttp.RespondWith(200, `{"items":[{"id":"an-event","actor":"deploys","action":"remove-service","host":"my-host"}],"item_count":1}`),
+				),
+			)
+		})
+
+		It("lists the audit events", func() {
+			events, err := conn.AuditEvents(apihub.AuditQuery{
+				Host:   "my-host",
+				Action: apihub.AUDIT_REMOVE_SERVICE,
+				Since:  time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+				Limit:  10,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(ConsistOf(apihub.AuditEvent{ID: "an-event", Actor: "deploys", Action: apihub.AUDIT_REMOVE_SERVICE, Host: "my-host"}))
+		})
+	})
+
+	Describe("Revisions", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/services/my-host/revisions"),
+					ghttp.RespondWith(200, `{"items":[{"version":1,"spec":{"host":"my-host","version":1}}],"item_count":1}`),
+				),
+			)
+		})
+
+		It("lists the revisions", func() {
+			revisions, err := conn.Revisions("my-host")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(revisions).To(ConsistOf(apihub.Revision{Version: 1, Spec: apihub.ServiceSpec{Host: "my-host", Version: 1}}))
+		})
+	})
+
+	Describe("DiffRevisions", func() {
+		Context("when comparing with the current version", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, "/services/my-host/revisions/diff", "from=1"),
+						ghttp.RespondWith(200, `{"from":1,"to":3,"diff":{"disabled":true}}`),
+					),
+				)
+			})
+
+			It("returns the diff", func() {
+				diff, err := conn.DiffRevisions("my-host", 1, 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(diff.To).To(BeEquivalentTo(3))
+				Expect(diff.Diff).To(MatchJSON(`{"disabled":true}`))
+			})
+		})
+
+		Context("when comparing two revisions", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(http.MethodGet, "/services/my-host/revisions/diff", "from=1&to=2"),
+						ghttp.RespondWith(404, `{"error":"not_found","error_description":"Revision 2 not found."}`),
+					),
+				)
+			})
+
+			It("sends both versions", func() {
+				_, err := conn.DiffRevisions("my-host", 1, 2)
+				Expect(errors.Is(err, apihub.ErrNotFound)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("RollbackService", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, "/services/my-host/rollback", "to=2"),
+					ghttp.RespondWith(200, `{"host":"my-host","version":4}`),
+				),
+			)
+		})
+
+		It("returns the new version of the service", func() {
+			service, err := conn.RollbackService("my-host", 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(service.Version).To(BeEquivalentTo(4))
+		})
+	})
+
+	Describe("Apply", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, api.Routes[api.ApplyServices].Path, "dry_run=true&prune=true"),
+					ghttp.VerifyBody([]byte(`{"services":[{"host":"my-host","disabled":false,"timeout":0}]}`+"\n")),
+					ghttp.RespondWith(200, `{"dry_run":true,"changes":[{"action":"create","host":"my-host"}],"unchanged":[]}`),
+				),
+			)
+		})
+
+		It("returns the plan", func() {
+			plan, err := conn.Apply([]apihub.ServiceSpec{apihub.ServiceSpec{Host: "my-host"}}, apihub.ApplyOptions{DryRun: true, Prune: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.DryRun).To(BeTrue())
+			Expect(plan.Changes).To(ConsistOf(apihub.ApplyChange{Action: apihub.APPLY_CREATE, Host: "my-host"}))
+		})
+	})
+
+	Describe("Export", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ExportServices].Path),
+					ghttp.RespondWith(200, `{"version":1,"exported_at":"2020-05-01T10:00:00Z","services":[{"host":"my-host","version":2}]}`),
+				),
+			)
+		})
+
+		It("returns the document", func() {
+			doc, err := conn.Export()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(doc.Version).To(Equal(1))
+			Expect(doc.Services).To(ConsistOf(apihub.ServiceSpec{Host: "my-host", Version: 2}))
+		})
+	})
+
+	Describe("Import", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, api.Routes[api.ImportServices].Path, "mode=skip-existing"),
+					ghttp.VerifyBody([]byte(`{"version":1,"exported_at":"2020-05-01T10:00:00Z","services":[{"host":"my-host","disabled":false,"timeout":0}]}`+"\n")),
+					ghttp.RespondWith(200, `{"mode":"skip-existing","changes":[],"skipped":["my-host"],"unchanged":[]}`),
+				),
+			)
+		})
+
+		It("returns the report", func() {
+			doc := apihub.ExportDocument{
+				Version:    1,
+				ExportedAt: time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+				Services:   []apihub.ServiceSpec{apihub.ServiceSpec{Host: "my-host"}},
+			}
+			report, err := conn.Import(doc, apihub.ImportOptions{Mode: apihub.IMPORT_SKIP_EXISTING})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped).To(Equal([]string{"my-host"}))
+		})
+	})
+
+	Describe("SyncStatus", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.SyncStatus].Path),
+					ghttp.RespondWith(200, `{"pending":[{"id":"e1","host":"my-host","attempts":2,"last_error":"connection refused"}],"metrics":{"reconciles":3,"stale":1}}`),
+				),
+			)
+		})
+
+		It("returns the status", func() {
+			status, err := conn.SyncStatus()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Pending).To(HaveLen(1))
+			Expect(status.Pending[0].LastError).To(Equal("connection refused"))
+			Expect(status.LastReconcile).To(BeNil())
+			Expect(status.Metrics).To(Equal(apihub.DriftMetrics{Reconciles: 3, Stale: 1}))
+		})
+	})
+
+	Describe("webhooks", func() {
+		It("adds a webhook", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, api.Routes[api.AddWebhook].Path),
+					ghttp.VerifyBody([]byte(`{"id":"","url":"https://deploy-bot.example.com/hooks","events":["service.created"],"created_at":"0001-01-01T00:00:00Z"}`+"\n")),
+					ghttp.RespondWith(201, `{"id":"w1","url":"https://deploy-bot.example.com/hooks","events":["service.created"],"secret":"s3cr3t"}`),
+				),
+			)
+
+			webhook, err := conn.AddWebhook(apihub.Webhook{URL: "https://deploy-bot.example.com/hooks", Events: []string{apihub.WEBHOOK_SERVICE_CREATED}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhook.ID).To(Equal("w1"))
+			Expect(webhook.Secret).To(Equal("s3cr3t"))
+		})
+
+		It("lists the webhooks", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, api.Routes[api.ListWebhooks].Path),
+					ghttp.RespondWith(200, `{"items":[{"id":"w1","url":"https://deploy-bot.example.com/hooks"}],"item_count":1}`),
+				),
+			)
+
+			webhooks, err := conn.Webhooks()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhooks).To(Equal([]apihub.Webhook{apihub.Webhook{ID: "w1", URL: "https://deploy-bot.example.com/hooks"}}))
+		})
+
+		It("finds a webhook", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/webhooks/w1"),
+					ghttp.RespondWith(200, `{"id":"w1","url":"https://deploy-bot.example.com/hooks"}`),
+				),
+			)
+
+			webhook, err := conn.FindWebhook("w1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhook.URL).To(Equal("https://deploy-bot.example.com/hooks"))
+		})
+
+		It("removes a webhook", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodDelete, "/webhooks/w1"),
+					ghttp.RespondWith(204, ""),
+				),
+			)
+
+			Expect(conn.RemoveWebhook("w1")).To(Succeed())
+		})
+
+		It("lists the deliveries of a webhook", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/webhooks/w1/deliveries"),
+					ghttp.RespondWith(200, `{"items":[{"id":"d1","webhook_id":"w1","event":"service.deleted","status":"failed","attempts":12,"last_error":"unexpected status 500"}],"item_count":1}`),
+				),
+			)
+
+			deliveries, err := conn.WebhookDeliveries("w1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].Status).To(Equal(apihub.DELIVERY_FAILED))
+			Expect(deliveries[0].LastError).To(Equal("unexpected status 500"))
+		})
+
+		It("returns an error when the webhook is not found", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/webhooks/unknown"),
+					ghttp.RespondWith(404, `{"error":"not_found","error_description":"Webhook not found."}`),
+				),
+			)
+
+			_, err := conn.FindWebhook("unknown")
+			Expect(errors.Is(err, apihub.ErrNotFound)).To(BeTrue())
+		})
+	})
 })
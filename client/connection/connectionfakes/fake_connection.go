@@ -31,6 +31,26 @@ type FakeConnection struct {
 		result1 []apihub.ServiceSpec
 		result2 error
 	}
+	ListServicesStub        func(apihub.ServiceQuery) (apihub.ServicePage, error)
+	listServicesMutex       sync.RWMutex
+	listServicesArgsForCall []struct {
+		arg1 apihub.ServiceQuery
+	}
+	listServicesReturns struct {
+		result1 apihub.ServicePage
+		result2 error
+	}
+	WatchServicesStub        func(apihub.ServiceQuery, uint64, <-chan struct{}) (<-chan apihub.WatchEvent, error)
+	watchServicesMutex       sync.RWMutex
+	watchServicesArgsForCall []struct {
+		arg1 apihub.ServiceQuery
+		arg2 uint64
+		arg3 <-chan struct{}
+	}
+	watchServicesReturns struct {
+		result1 <-chan apihub.WatchEvent
+		result2 error
+	}
 	RemoveServiceStub        func(string) error
 	removeServiceMutex       sync.RWMutex
 	removeServiceArgsForCall []struct {
@@ -58,6 +78,269 @@ type FakeConnection struct {
 		result1 apihub.ServiceSpec
 		result2 error
 	}
+	UpdateServiceIfMatchStub        func(string, uint64, apihub.ServiceSpec) (apihub.ServiceSpec, error)
+	updateServiceIfMatchMutex       sync.RWMutex
+	updateServiceIfMatchArgsForCall []struct {
+		arg1 string
+		arg2 uint64
+		arg3 apihub.ServiceSpec
+	}
+	updateServiceIfMatchReturns struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}
+	PatchServiceStub        func(string, string, interface{}) (apihub.ServiceSpec, error)
+	patchServiceMutex       sync.RWMutex
+	patchServiceArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 interface{}
+	}
+	patchServiceReturns struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}
+	ReplaceServiceStub        func(string, apihub.ServiceSpec) (apihub.ServiceSpec, error)
+	replaceServiceMutex       sync.RWMutex
+	replaceServiceArgsForCall []struct {
+		arg1 string
+		arg2 apihub.ServiceSpec
+	}
+	replaceServiceReturns struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}
+	SetServiceFaultsStub        func(string, []apihub.FaultRule) (apihub.ServiceSpec, error)
+	setServiceFaultsMutex       sync.RWMutex
+	setServiceFaultsArgsForCall []struct {
+		arg1 string
+		arg2 []apihub.FaultRule
+	}
+	setServiceFaultsReturns struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}
+	ClearServiceFaultsStub        func(string) error
+	clearServiceFaultsMutex       sync.RWMutex
+	clearServiceFaultsArgsForCall []struct {
+		arg1 string
+	}
+	clearServiceFaultsReturns struct {
+		result1 error
+	}
+	BackendsStub        func(string) ([]apihub.BackendInfo, error)
+	backendsMutex       sync.RWMutex
+	backendsArgsForCall []struct {
+		arg1 string
+	}
+	backendsReturns struct {
+		result1 []apihub.BackendInfo
+		result2 error
+	}
+	AddBackendStub        func(string, apihub.BackendInfo) (apihub.BackendInfo, error)
+	addBackendMutex       sync.RWMutex
+	addBackendArgsForCall []struct {
+		arg1 string
+		arg2 apihub.BackendInfo
+	}
+	addBackendReturns struct {
+		result1 apihub.BackendInfo
+		result2 error
+	}
+	UpdateBackendStub        func(string, string, interface{}) (apihub.BackendInfo, error)
+	updateBackendMutex       sync.RWMutex
+	updateBackendArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 interface{}
+	}
+	updateBackendReturns struct {
+		result1 apihub.BackendInfo
+		result2 error
+	}
+	RemoveBackendStub        func(string, string) error
+	removeBackendMutex       sync.RWMutex
+	removeBackendArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	removeBackendReturns struct {
+		result1 error
+	}
+	RevisionsStub        func(string) ([]apihub.Revision, error)
+	revisionsMutex       sync.RWMutex
+	revisionsArgsForCall []struct {
+		arg1 string
+	}
+	revisionsReturns struct {
+		result1 []apihub.Revision
+		result2 error
+	}
+	DiffRevisionsStub        func(string, uint64, uint64) (apihub.RevisionDiff, error)
+	diffRevisionsMutex       sync.RWMutex
+	diffRevisionsArgsForCall []struct {
+		arg1 string
+		arg2 uint64
+		arg3 uint64
+	}
+	diffRevisionsReturns struct {
+		result1 apihub.RevisionDiff
+		result2 error
+	}
+	RollbackServiceStub        func(string, uint64) (apihub.ServiceSpec, error)
+	rollbackServiceMutex       sync.RWMutex
+	rollbackServiceArgsForCall []struct {
+		arg1 string
+		arg2 uint64
+	}
+	rollbackServiceReturns struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}
+	ApplyStub        func([]apihub.ServiceSpec, apihub.ApplyOptions) (apihub.ApplyPlan, error)
+	applyMutex       sync.RWMutex
+	applyArgsForCall []struct {
+		arg1 []apihub.ServiceSpec
+		arg2 apihub.ApplyOptions
+	}
+	applyReturns struct {
+		result1 apihub.ApplyPlan
+		result2 error
+	}
+	ExportStub        func() (apihub.ExportDocument, error)
+	exportMutex       sync.RWMutex
+	exportArgsForCall []struct{}
+	exportReturns     struct {
+		result1 apihub.ExportDocument
+		result2 error
+	}
+	ImportStub        func(apihub.ExportDocument, apihub.ImportOptions) (apihub.ImportReport, error)
+	importMutex       sync.RWMutex
+	importArgsForCall []struct {
+		arg1 apihub.ExportDocument
+		arg2 apihub.ImportOptions
+	}
+	importReturns struct {
+		result1 apihub.ImportReport
+		result2 error
+	}
+	AddTokenStub        func(string, string, []string) (apihub.IssuedToken, error)
+	addTokenMutex       sync.RWMutex
+	addTokenArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 []string
+	}
+	addTokenReturns struct {
+		result1 apihub.IssuedToken
+		result2 error
+	}
+	TokensStub        func() ([]apihub.Token, error)
+	tokensMutex       sync.RWMutex
+	tokensArgsForCall []struct{}
+	tokensReturns     struct {
+		result1 []apihub.Token
+		result2 error
+	}
+	RemoveTokenStub        func(string) error
+	removeTokenMutex       sync.RWMutex
+	removeTokenArgsForCall []struct {
+		arg1 string
+	}
+	removeTokenReturns struct {
+		result1 error
+	}
+	AddNamespaceStub        func(apihub.Namespace) (apihub.Namespace, error)
+	addNamespaceMutex       sync.RWMutex
+	addNamespaceArgsForCall []struct {
+		arg1 apihub.Namespace
+	}
+	addNamespaceReturns struct {
+		result1 apihub.Namespace
+		result2 error
+	}
+	NamespacesStub        func() ([]apihub.Namespace, error)
+	namespacesMutex       sync.RWMutex
+	namespacesArgsForCall []struct{}
+	namespacesReturns     struct {
+		result1 []apihub.Namespace
+		result2 error
+	}
+	FindNamespaceStub        func(string) (apihub.Namespace, error)
+	findNamespaceMutex       sync.RWMutex
+	findNamespaceArgsForCall []struct {
+		arg1 string
+	}
+	findNamespaceReturns struct {
+		result1 apihub.Namespace
+		result2 error
+	}
+	RemoveNamespaceStub        func(string) error
+	removeNamespaceMutex       sync.RWMutex
+	removeNamespaceArgsForCall []struct {
+		arg1 string
+	}
+	removeNamespaceReturns struct {
+		result1 error
+	}
+	AuditEventsStub        func(apihub.AuditQuery) ([]apihub.AuditEvent, error)
+	auditEventsMutex       sync.RWMutex
+	auditEventsArgsForCall []struct {
+		arg1 apihub.AuditQuery
+	}
+	auditEventsReturns struct {
+		result1 []apihub.AuditEvent
+		result2 error
+	}
+	SyncStatusStub        func() (apihub.SyncStatus, error)
+	syncStatusMutex       sync.RWMutex
+	syncStatusArgsForCall []struct{}
+	syncStatusReturns     struct {
+		result1 apihub.SyncStatus
+		result2 error
+	}
+	AddWebhookStub        func(apihub.Webhook) (apihub.Webhook, error)
+	addWebhookMutex       sync.RWMutex
+	addWebhookArgsForCall []struct {
+		arg1 apihub.Webhook
+	}
+	addWebhookReturns struct {
+		result1 apihub.Webhook
+		result2 error
+	}
+	WebhooksStub        func() ([]apihub.Webhook, error)
+	webhooksMutex       sync.RWMutex
+	webhooksArgsForCall []struct{}
+	webhooksReturns     struct {
+		result1 []apihub.Webhook
+		result2 error
+	}
+	FindWebhookStub        func(string) (apihub.Webhook, error)
+	findWebhookMutex       sync.RWMutex
+	findWebhookArgsForCall []struct {
+		arg1 string
+	}
+	findWebhookReturns struct {
+		result1 apihub.Webhook
+		result2 error
+	}
+	RemoveWebhookStub        func(string) error
+	removeWebhookMutex       sync.RWMutex
+	removeWebhookArgsForCall []struct {
+		arg1 string
+	}
+	removeWebhookReturns struct {
+		result1 error
+	}
+	WebhookDeliveriesStub        func(string) ([]apihub.WebhookDelivery, error)
+	webhookDeliveriesMutex       sync.RWMutex
+	webhookDeliveriesArgsForCall []struct {
+		arg1 string
+	}
+	webhookDeliveriesReturns struct {
+		result1 []apihub.WebhookDelivery
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -147,6 +430,76 @@ func (fake *FakeConnection) ServicesReturns(result1 []apihub.ServiceSpec, result
 	}{result1, result2}
 }
 
+func (fake *FakeConnection) ListServices(arg1 apihub.ServiceQuery) (apihub.ServicePage, error) {
+	fake.listServicesMutex.Lock()
+	fake.listServicesArgsForCall = append(fake.listServicesArgsForCall, struct {
+		arg1 apihub.ServiceQuery
+	}{arg1})
+	fake.recordInvocation("ListServices", []interface{}{arg1})
+	fake.listServicesMutex.Unlock()
+	if fake.ListServicesStub != nil {
+		return fake.ListServicesStub(arg1)
+	} else {
+		return fake.listServicesReturns.result1, fake.listServicesReturns.result2
+	}
+}
+
+func (fake *FakeConnection) ListServicesCallCount() int {
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	return len(fake.listServicesArgsForCall)
+}
+
+func (fake *FakeConnection) ListServicesArgsForCall(i int) apihub.ServiceQuery {
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	return fake.listServicesArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) ListServicesReturns(result1 apihub.ServicePage, result2 error) {
+	fake.ListServicesStub = nil
+	fake.listServicesReturns = struct {
+		result1 apihub.ServicePage
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) WatchServices(arg1 apihub.ServiceQuery, arg2 uint64, arg3 <-chan struct{}) (<-chan apihub.WatchEvent, error) {
+	fake.watchServicesMutex.Lock()
+	fake.watchServicesArgsForCall = append(fake.watchServicesArgsForCall, struct {
+		arg1 apihub.ServiceQuery
+		arg2 uint64
+		arg3 <-chan struct{}
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("WatchServices", []interface{}{arg1, arg2, arg3})
+	fake.watchServicesMutex.Unlock()
+	if fake.WatchServicesStub != nil {
+		return fake.WatchServicesStub(arg1, arg2, arg3)
+	} else {
+		return fake.watchServicesReturns.result1, fake.watchServicesReturns.result2
+	}
+}
+
+func (fake *FakeConnection) WatchServicesCallCount() int {
+	fake.watchServicesMutex.RLock()
+	defer fake.watchServicesMutex.RUnlock()
+	return len(fake.watchServicesArgsForCall)
+}
+
+func (fake *FakeConnection) WatchServicesArgsForCall(i int) (apihub.ServiceQuery, uint64, <-chan struct{}) {
+	fake.watchServicesMutex.RLock()
+	defer fake.watchServicesMutex.RUnlock()
+	return fake.watchServicesArgsForCall[i].arg1, fake.watchServicesArgsForCall[i].arg2, fake.watchServicesArgsForCall[i].arg3
+}
+
+func (fake *FakeConnection) WatchServicesReturns(result1 <-chan apihub.WatchEvent, result2 error) {
+	fake.WatchServicesStub = nil
+	fake.watchServicesReturns = struct {
+		result1 <-chan apihub.WatchEvent
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeConnection) RemoveService(arg1 string) error {
 	fake.removeServiceMutex.Lock()
 	fake.removeServiceArgsForCall = append(fake.removeServiceArgsForCall, struct {
@@ -249,6 +602,979 @@ func (fake *FakeConnection) UpdateServiceReturns(result1 apihub.ServiceSpec, res
 	}{result1, result2}
 }
 
+func (fake *FakeConnection) UpdateServiceIfMatch(arg1 string, arg2 uint64, arg3 apihub.ServiceSpec) (apihub.ServiceSpec, error) {
+	fake.updateServiceIfMatchMutex.Lock()
+	fake.updateServiceIfMatchArgsForCall = append(fake.updateServiceIfMatchArgsForCall, struct {
+		arg1 string
+		arg2 uint64
+		arg3 apihub.ServiceSpec
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("UpdateServiceIfMatch", []interface{}{arg1, arg2, arg3})
+	fake.updateServiceIfMatchMutex.Unlock()
+	if fake.UpdateServiceIfMatchStub != nil {
+		return fake.UpdateServiceIfMatchStub(arg1, arg2, arg3)
+	} else {
+		return fake.updateServiceIfMatchReturns.result1, fake.updateServiceIfMatchReturns.result2
+	}
+}
+
+func (fake *FakeConnection) UpdateServiceIfMatchCallCount() int {
+	fake.updateServiceIfMatchMutex.RLock()
+	defer fake.updateServiceIfMatchMutex.RUnlock()
+	return len(fake.updateServiceIfMatchArgsForCall)
+}
+
+func (fake *FakeConnection) UpdateServiceIfMatchArgsForCall(i int) (string, uint64, apihub.ServiceSpec) {
+	fake.updateServiceIfMatchMutex.RLock()
+	defer fake.updateServiceIfMatchMutex.RUnlock()
+	return fake.updateServiceIfMatchArgsForCall[i].arg1, fake.updateServiceIfMatchArgsForCall[i].arg2, fake.updateServiceIfMatchArgsForCall[i].arg3
+}
+
+func (fake *FakeConnection) UpdateServiceIfMatchReturns(result1 apihub.ServiceSpec, result2 error) {
+	fake.UpdateServiceIfMatchStub = nil
+	fake.updateServiceIfMatchReturns = struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) PatchService(arg1 string, arg2 string, arg3 interface{}) (apihub.ServiceSpec, error) {
+	fake.patchServiceMutex.Lock()
+	fake.patchServiceArgsForCall = append(fake.patchServiceArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 interface{}
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("PatchService", []interface{}{arg1, arg2, arg3})
+	fake.patchServiceMutex.Unlock()
+	if fake.PatchServiceStub != nil {
+		return fake.PatchServiceStub(arg1, arg2, arg3)
+	} else {
+		return fake.patchServiceReturns.result1, fake.patchServiceReturns.result2
+	}
+}
+
+func (fake *FakeConnection) PatchServiceCallCount() int {
+	fake.patchServiceMutex.RLock()
+	defer fake.patchServiceMutex.RUnlock()
+	return len(fake.patchServiceArgsForCall)
+}
+
+func (fake *FakeConnection) PatchServiceArgsForCall(i int) (string, string, interface{}) {
+	fake.patchServiceMutex.RLock()
+	defer fake.patchServiceMutex.RUnlock()
+	return fake.patchServiceArgsForCall[i].arg1, fake.patchServiceArgsForCall[i].arg2, fake.patchServiceArgsForCall[i].arg3
+}
+
+func (fake *FakeConnection) PatchServiceReturns(result1 apihub.ServiceSpec, result2 error) {
+	fake.PatchServiceStub = nil
+	fake.patchServiceReturns = struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) ReplaceService(arg1 string, arg2 apihub.ServiceSpec) (apihub.ServiceSpec, error) {
+	fake.replaceServiceMutex.Lock()
+	fake.replaceServiceArgsForCall = append(fake.replaceServiceArgsForCall, struct {
+		arg1 string
+		arg2 apihub.ServiceSpec
+	}{arg1, arg2})
+	fake.recordInvocation("ReplaceService", []interface{}{arg1, arg2})
+	fake.replaceServiceMutex.Unlock()
+	if fake.ReplaceServiceStub != nil {
+		return fake.ReplaceServiceStub(arg1, arg2)
+	} else {
+		return fake.replaceServiceReturns.result1, fake.replaceServiceReturns.result2
+	}
+}
+
+func (fake *FakeConnection) ReplaceServiceCallCount() int {
+	fake.replaceServiceMutex.RLock()
+	defer fake.replaceServiceMutex.RUnlock()
+	return len(fake.replaceServiceArgsForCall)
+}
+
+func (fake *FakeConnection) ReplaceServiceArgsForCall(i int) (string, apihub.ServiceSpec) {
+	fake.replaceServiceMutex.RLock()
+	defer fake.replaceServiceMutex.RUnlock()
+	return fake.replaceServiceArgsForCall[i].arg1, fake.replaceServiceArgsForCall[i].arg2
+}
+
+func (fake *FakeConnection) ReplaceServiceReturns(result1 apihub.ServiceSpec, result2 error) {
+	fake.ReplaceServiceStub = nil
+	fake.replaceServiceReturns = struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) SetServiceFaults(arg1 string, arg2 []apihub.FaultRule) (apihub.ServiceSpec, error) {
+	var arg2Copy []apihub.FaultRule
+	if arg2 != nil {
+		arg2Copy = make([]apihub.FaultRule, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.setServiceFaultsMutex.Lock()
+	fake.setServiceFaultsArgsForCall = append(fake.setServiceFaultsArgsForCall, struct {
+		arg1 string
+		arg2 []apihub.FaultRule
+	}{arg1, arg2Copy})
+	fake.recordInvocation("SetServiceFaults", []interface{}{arg1, arg2Copy})
+	fake.setServiceFaultsMutex.Unlock()
+	if fake.SetServiceFaultsStub != nil {
+		return fake.SetServiceFaultsStub(arg1, arg2)
+	} else {
+		return fake.setServiceFaultsReturns.result1, fake.setServiceFaultsReturns.result2
+	}
+}
+
+func (fake *FakeConnection) SetServiceFaultsCallCount() int {
+	fake.setServiceFaultsMutex.RLock()
+	defer fake.setServiceFaultsMutex.RUnlock()
+	return len(fake.setServiceFaultsArgsForCall)
+}
+
+func (fake *FakeConnection) SetServiceFaultsArgsForCall(i int) (string, []apihub.FaultRule) {
+	fake.setServiceFaultsMutex.RLock()
+	defer fake.setServiceFaultsMutex.RUnlock()
+	return fake.setServiceFaultsArgsForCall[i].arg1, fake.setServiceFaultsArgsForCall[i].arg2
+}
+
+func (fake *FakeConnection) SetServiceFaultsReturns(result1 apihub.ServiceSpec, result2 error) {
+	fake.SetServiceFaultsStub = nil
+	fake.setServiceFaultsReturns = struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) ClearServiceFaults(arg1 string) error {
+	fake.clearServiceFaultsMutex.Lock()
+	fake.clearServiceFaultsArgsForCall = append(fake.clearServiceFaultsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("ClearServiceFaults", []interface{}{arg1})
+	fake.clearServiceFaultsMutex.Unlock()
+	if fake.ClearServiceFaultsStub != nil {
+		return fake.ClearServiceFaultsStub(arg1)
+	} else {
+		return fake.clearServiceFaultsReturns.result1
+	}
+}
+
+func (fake *FakeConnection) ClearServiceFaultsCallCount() int {
+	fake.clearServiceFaultsMutex.RLock()
+	defer fake.clearServiceFaultsMutex.RUnlock()
+	return len(fake.clearServiceFaultsArgsForCall)
+}
+
+func (fake *FakeConnection) ClearServiceFaultsArgsForCall(i int) string {
+	fake.clearServiceFaultsMutex.RLock()
+	defer fake.clearServiceFaultsMutex.RUnlock()
+	return fake.clearServiceFaultsArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) ClearServiceFaultsReturns(result1 error) {
+	fake.ClearServiceFaultsStub = nil
+	fake.clearServiceFaultsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConnection) Backends(arg1 string) ([]apihub.BackendInfo, error) {
+	fake.backendsMutex.Lock()
+	fake.backendsArgsForCall = append(fake.backendsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Backends", []interface{}{arg1})
+	fake.backendsMutex.Unlock()
+	if fake.BackendsStub != nil {
+		return fake.BackendsStub(arg1)
+	} else {
+		return fake.backendsReturns.result1, fake.backendsReturns.result2
+	}
+}
+
+func (fake *FakeConnection) BackendsCallCount() int {
+	fake.backendsMutex.RLock()
+	defer fake.backendsMutex.RUnlock()
+	return len(fake.backendsArgsForCall)
+}
+
+func (fake *FakeConnection) BackendsArgsForCall(i int) string {
+	fake.backendsMutex.RLock()
+	defer fake.backendsMutex.RUnlock()
+	return fake.backendsArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) BackendsReturns(result1 []apihub.BackendInfo, result2 error) {
+	fake.BackendsStub = nil
+	fake.backendsReturns = struct {
+		result1 []apihub.BackendInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) AddBackend(arg1 string, arg2 apihub.BackendInfo) (apihub.BackendInfo, error) {
+	fake.addBackendMutex.Lock()
+	fake.addBackendArgsForCall = append(fake.addBackendArgsForCall, struct {
+		arg1 string
+		arg2 apihub.BackendInfo
+	}{arg1, arg2})
+	fake.recordInvocation("AddBackend", []interface{}{arg1, arg2})
+	fake.addBackendMutex.Unlock()
+	if fake.AddBackendStub != nil {
+		return fake.AddBackendStub(arg1, arg2)
+	} else {
+		return fake.addBackendReturns.result1, fake.addBackendReturns.result2
+	}
+}
+
+func (fake *FakeConnection) AddBackendCallCount() int {
+	fake.addBackendMutex.RLock()
+	defer fake.addBackendMutex.RUnlock()
+	return len(fake.addBackendArgsForCall)
+}
+
+func (fake *FakeConnection) AddBackendArgsForCall(i int) (string, apihub.BackendInfo) {
+	fake.addBackendMutex.RLock()
+	defer fake.addBackendMutex.RUnlock()
+	return fake.addBackendArgsForCall[i].arg1, fake.addBackendArgsForCall[i].arg2
+}
+
+func (fake *FakeConnection) AddBackendReturns(result1 apihub.BackendInfo, result2 error) {
+	fake.AddBackendStub = nil
+	fake.addBackendReturns = struct {
+		result1 apihub.BackendInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) UpdateBackend(arg1 string, arg2 string, arg3 interface{}) (apihub.BackendInfo, error) {
+	fake.updateBackendMutex.Lock()
+	fake.updateBackendArgsForCall = append(fake.updateBackendArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 interface{}
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("UpdateBackend", []interface{}{arg1, arg2, arg3})
+	fake.updateBackendMutex.Unlock()
+	if fake.UpdateBackendStub != nil {
+		return fake.UpdateBackendStub(arg1, arg2, arg3)
+	} else {
+		return fake.updateBackendReturns.result1, fake.updateBackendReturns.result2
+	}
+}
+
+func (fake *FakeConnection) UpdateBackendCallCount() int {
+	fake.updateBackendMutex.RLock()
+	defer fake.updateBackendMutex.RUnlock()
+	return len(fake.updateBackendArgsForCall)
+}
+
+func (fake *FakeConnection) UpdateBackendArgsForCall(i int) (string, string, interface{}) {
+	fake.updateBackendMutex.RLock()
+	defer fake.updateBackendMutex.RUnlock()
+	return fake.updateBackendArgsForCall[i].arg1, fake.updateBackendArgsForCall[i].arg2, fake.updateBackendArgsForCall[i].arg3
+}
+
+func (fake *FakeConnection) UpdateBackendReturns(result1 apihub.BackendInfo, result2 error) {
+	fake.UpdateBackendStub = nil
+	fake.updateBackendReturns = struct {
+		result1 apihub.BackendInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) RemoveBackend(arg1 string, arg2 string) error {
+	fake.removeBackendMutex.Lock()
+	fake.removeBackendArgsForCall = append(fake.removeBackendArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("RemoveBackend", []interface{}{arg1, arg2})
+	fake.removeBackendMutex.Unlock()
+	if fake.RemoveBackendStub != nil {
+		return fake.RemoveBackendStub(arg1, arg2)
+	} else {
+		return fake.removeBackendReturns.result1
+	}
+}
+
+func (fake *FakeConnection) RemoveBackendCallCount() int {
+	fake.removeBackendMutex.RLock()
+	defer fake.removeBackendMutex.RUnlock()
+	return len(fake.removeBackendArgsForCall)
+}
+
+func (fake *FakeConnection) RemoveBackendArgsForCall(i int) (string, string) {
+	fake.removeBackendMutex.RLock()
+	defer fake.removeBackendMutex.RUnlock()
+	return fake.removeBackendArgsForCall[i].arg1, fake.removeBackendArgsForCall[i].arg2
+}
+
+func (fake *FakeConnection) RemoveBackendReturns(result1 error) {
+	fake.RemoveBackendStub = nil
+	fake.removeBackendReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConnection) Revisions(arg1 string) ([]apihub.Revision, error) {
+	fake.revisionsMutex.Lock()
+	fake.revisionsArgsForCall = append(fake.revisionsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Revisions", []interface{}{arg1})
+	fake.revisionsMutex.Unlock()
+	if fake.RevisionsStub != nil {
+		return fake.RevisionsStub(arg1)
+	} else {
+		return fake.revisionsReturns.result1, fake.revisionsReturns.result2
+	}
+}
+
+func (fake *FakeConnection) RevisionsCallCount() int {
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	return len(fake.revisionsArgsForCall)
+}
+
+func (fake *FakeConnection) RevisionsArgsForCall(i int) string {
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	return fake.revisionsArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) RevisionsReturns(result1 []apihub.Revision, result2 error) {
+	fake.RevisionsStub = nil
+	fake.revisionsReturns = struct {
+		result1 []apihub.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) DiffRevisions(arg1 string, arg2 uint64, arg3 uint64) (apihub.RevisionDiff, error) {
+	fake.diffRevisionsMutex.Lock()
+	fake.diffRevisionsArgsForCall = append(fake.diffRevisionsArgsForCall, struct {
+		arg1 string
+		arg2 uint64
+		arg3 uint64
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("DiffRevisions", []interface{}{arg1, arg2, arg3})
+	fake.diffRevisionsMutex.Unlock()
+	if fake.DiffRevisionsStub != nil {
+		return fake.DiffRevisionsStub(arg1, arg2, arg3)
+	} else {
+		return fake.diffRevisionsReturns.result1, fake.diffRevisionsReturns.result2
+	}
+}
+
+func (fake *FakeConnection) DiffRevisionsCallCount() int {
+	fake.diffRevisionsMutex.RLock()
+	defer fake.diffRevisionsMutex.RUnlock()
+	return len(fake.diffRevisionsArgsForCall)
+}
+
+func (fake *FakeConnection) DiffRevisionsArgsForCall(i int) (string, uint64, uint64) {
+	fake.diffRevisionsMutex.RLock()
+	defer fake.diffRevisionsMutex.RUnlock()
+	return fake.diffRevisionsArgsForCall[i].arg1, fake.diffRevisionsArgsForCall[i].arg2, fake.diffRevisionsArgsForCall[i].arg3
+}
+
+func (fake *FakeConnection) DiffRevisionsReturns(result1 apihub.RevisionDiff, result2 error) {
+	fake.DiffRevisionsStub = nil
+	fake.diffRevisionsReturns = struct {
+		result1 apihub.RevisionDiff
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) RollbackService(arg1 string, arg2 uint64) (apihub.ServiceSpec, error) {
+	fake.rollbackServiceMutex.Lock()
+	fake.rollbackServiceArgsForCall = append(fake.rollbackServiceArgsForCall, struct {
+		arg1 string
+		arg2 uint64
+	}{arg1, arg2})
+	fake.recordInvocation("RollbackService", []interface{}{arg1, arg2})
+	fake.rollbackServiceMutex.Unlock()
+	if fake.RollbackServiceStub != nil {
+		return fake.RollbackServiceStub(arg1, arg2)
+	} else {
+		return fake.rollbackServiceReturns.result1, fake.rollbackServiceReturns.result2
+	}
+}
+
+func (fake *FakeConnection) RollbackServiceCallCount() int {
+	fake.rollbackServiceMutex.RLock()
+	defer fake.rollbackServiceMutex.RUnlock()
+	return len(fake.rollbackServiceArgsForCall)
+}
+
+func (fake *FakeConnection) RollbackServiceArgsForCall(i int) (string, uint64) {
+	fake.rollbackServiceMutex.RLock()
+	defer fake.rollbackServiceMutex.RUnlock()
+	return fake.rollbackServiceArgsForCall[i].arg1, fake.rollbackServiceArgsForCall[i].arg2
+}
+
+func (fake *FakeConnection) RollbackServiceReturns(result1 apihub.ServiceSpec, result2 error) {
+	fake.RollbackServiceStub = nil
+	fake.rollbackServiceReturns = struct {
+		result1 apihub.ServiceSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) Apply(arg1 []apihub.ServiceSpec, arg2 apihub.ApplyOptions) (apihub.ApplyPlan, error) {
+	var arg1Copy []apihub.ServiceSpec
+	if arg1 != nil {
+		arg1Copy = make([]apihub.ServiceSpec, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.applyMutex.Lock()
+	fake.applyArgsForCall = append(fake.applyArgsForCall, struct {
+		arg1 []apihub.ServiceSpec
+		arg2 apihub.ApplyOptions
+	}{arg1Copy, arg2})
+	fake.recordInvocation("Apply", []interface{}{arg1Copy, arg2})
+	fake.applyMutex.Unlock()
+	if fake.ApplyStub != nil {
+		return fake.ApplyStub(arg1, arg2)
+	} else {
+		return fake.applyReturns.result1, fake.applyReturns.result2
+	}
+}
+
+func (fake *FakeConnection) ApplyCallCount() int {
+	fake.applyMutex.RLock()
+	defer fake.applyMutex.RUnlock()
+	return len(fake.applyArgsForCall)
+}
+
+func (fake *FakeConnection) ApplyArgsForCall(i int) ([]apihub.ServiceSpec, apihub.ApplyOptions) {
+	fake.applyMutex.RLock()
+	defer fake.applyMutex.RUnlock()
+	return fake.applyArgsForCall[i].arg1, fake.applyArgsForCall[i].arg2
+}
+
+func (fake *FakeConnection) ApplyReturns(result1 apihub.ApplyPlan, result2 error) {
+	fake.ApplyStub = nil
+	fake.applyReturns = struct {
+		result1 apihub.ApplyPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) Export() (apihub.ExportDocument, error) {
+	fake.exportMutex.Lock()
+	fake.exportArgsForCall = append(fake.exportArgsForCall, struct{}{})
+	fake.recordInvocation("Export", []interface{}{})
+	fake.exportMutex.Unlock()
+	if fake.ExportStub != nil {
+		return fake.ExportStub()
+	} else {
+		return fake.exportReturns.result1, fake.exportReturns.result2
+	}
+}
+
+func (fake *FakeConnection) ExportCallCount() int {
+	fake.exportMutex.RLock()
+	defer fake.exportMutex.RUnlock()
+	return len(fake.exportArgsForCall)
+}
+
+func (fake *FakeConnection) ExportReturns(result1 apihub.ExportDocument, result2 error) {
+	fake.ExportStub = nil
+	fake.exportReturns = struct {
+		result1 apihub.ExportDocument
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) Import(arg1 apihub.ExportDocument, arg2 apihub.ImportOptions) (apihub.ImportReport, error) {
+	fake.importMutex.Lock()
+	fake.importArgsForCall = append(fake.importArgsForCall, struct {
+		arg1 apihub.ExportDocument
+		arg2 apihub.ImportOptions
+	}{arg1, arg2})
+	fake.recordInvocation("Import", []interface{}{arg1, arg2})
+	fake.importMutex.Unlock()
+	if fake.ImportStub != nil {
+		return fake.ImportStub(arg1, arg2)
+	} else {
+		return fake.importReturns.result1, fake.importReturns.result2
+	}
+}
+
+func (fake *FakeConnection) ImportCallCount() int {
+	fake.importMutex.RLock()
+	defer fake.importMutex.RUnlock()
+	return len(fake.importArgsForCall)
+}
+
+func (fake *FakeConnection) ImportArgsForCall(i int) (apihub.ExportDocument, apihub.ImportOptions) {
+	fake.importMutex.RLock()
+	defer fake.importMutex.RUnlock()
+	return fake.importArgsForCall[i].arg1, fake.importArgsForCall[i].arg2
+}
+
+func (fake *FakeConnection) ImportReturns(result1 apihub.ImportReport, result2 error) {
+	fake.ImportStub = nil
+	fake.importReturns = struct {
+		result1 apihub.ImportReport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) AddToken(arg1 string, arg2 string, arg3 []string) (apihub.IssuedToken, error) {
+	var arg3Copy []string
+	if arg3 != nil {
+		arg3Copy = make([]string, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.addTokenMutex.Lock()
+	fake.addTokenArgsForCall = append(fake.addTokenArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 []string
+	}{arg1, arg2, arg3Copy})
+	fake.recordInvocation("AddToken", []interface{}{arg1, arg2, arg3Copy})
+	fake.addTokenMutex.Unlock()
+	if fake.AddTokenStub != nil {
+		return fake.AddTokenStub(arg1, arg2, arg3)
+	} else {
+		return fake.addTokenReturns.result1, fake.addTokenReturns.result2
+	}
+}
+
+func (fake *FakeConnection) AddTokenCallCount() int {
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	return len(fake.addTokenArgsForCall)
+}
+
+func (fake *FakeConnection) AddTokenArgsForCall(i int) (string, string, []string) {
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	return fake.addTokenArgsForCall[i].arg1, fake.addTokenArgsForCall[i].arg2, fake.addTokenArgsForCall[i].arg3
+}
+
+func (fake *FakeConnection) AddTokenReturns(result1 apihub.IssuedToken, result2 error) {
+	fake.AddTokenStub = nil
+	fake.addTokenReturns = struct {
+		result1 apihub.IssuedToken
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) Tokens() ([]apihub.Token, error) {
+	fake.tokensMutex.Lock()
+	fake.tokensArgsForCall = append(fake.tokensArgsForCall, struct{}{})
+	fake.recordInvocation("Tokens", []interface{}{})
+	fake.tokensMutex.Unlock()
+	if fake.TokensStub != nil {
+		return fake.TokensStub()
+	} else {
+		return fake.tokensReturns.result1, fake.tokensReturns.result2
+	}
+}
+
+func (fake *FakeConnection) TokensCallCount() int {
+	fake.tokensMutex.RLock()
+	defer fake.tokensMutex.RUnlock()
+	return len(fake.tokensArgsForCall)
+}
+
+func (fake *FakeConnection) TokensReturns(result1 []apihub.Token, result2 error) {
+	fake.TokensStub = nil
+	fake.tokensReturns = struct {
+		result1 []apihub.Token
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) RemoveToken(arg1 string) error {
+	fake.removeTokenMutex.Lock()
+	fake.removeTokenArgsForCall = append(fake.removeTokenArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RemoveToken", []interface{}{arg1})
+	fake.removeTokenMutex.Unlock()
+	if fake.RemoveTokenStub != nil {
+		return fake.RemoveTokenStub(arg1)
+	} else {
+		return fake.removeTokenReturns.result1
+	}
+}
+
+func (fake *FakeConnection) RemoveTokenCallCount() int {
+	fake.removeTokenMutex.RLock()
+	defer fake.removeTokenMutex.RUnlock()
+	return len(fake.removeTokenArgsForCall)
+}
+
+func (fake *FakeConnection) RemoveTokenArgsForCall(i int) string {
+	fake.removeTokenMutex.RLock()
+	defer fake.removeTokenMutex.RUnlock()
+	return fake.removeTokenArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) RemoveTokenReturns(result1 error) {
+	fake.RemoveTokenStub = nil
+	fake.removeTokenReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConnection) AddNamespace(arg1 apihub.Namespace) (apihub.Namespace, error) {
+	fake.addNamespaceMutex.Lock()
+	fake.addNamespaceArgsForCall = append(fake.addNamespaceArgsForCall, struct {
+		arg1 apihub.Namespace
+	}{arg1})
+	fake.recordInvocation("AddNamespace", []interface{}{arg1})
+	fake.addNamespaceMutex.Unlock()
+	if fake.AddNamespaceStub != nil {
+		return fake.AddNamespaceStub(arg1)
+	} else {
+		return fake.addNamespaceReturns.result1, fake.addNamespaceReturns.result2
+	}
+}
+
+func (fake *FakeConnection) AddNamespaceCallCount() int {
+	fake.addNamespaceMutex.RLock()
+	defer fake.addNamespaceMutex.RUnlock()
+	return len(fake.addNamespaceArgsForCall)
+}
+
+func (fake *FakeConnection) AddNamespaceArgsForCall(i int) apihub.Namespace {
+	fake.addNamespaceMutex.RLock()
+	defer fake.addNamespaceMutex.RUnlock()
+	return fake.addNamespaceArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) AddNamespaceReturns(result1 apihub.Namespace, result2 error) {
+	fake.AddNamespaceStub = nil
+	fake.addNamespaceReturns = struct {
+		result1 apihub.Namespace
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) Namespaces() ([]apihub.Namespace, error) {
+	fake.namespacesMutex.Lock()
+	fake.namespacesArgsForCall = append(fake.namespacesArgsForCall, struct{}{})
+	fake.recordInvocation("Namespaces", []interface{}{})
+	fake.namespacesMutex.Unlock()
+	if fake.NamespacesStub != nil {
+		return fake.NamespacesStub()
+	} else {
+		return fake.namespacesReturns.result1, fake.namespacesReturns.result2
+	}
+}
+
+func (fake *FakeConnection) NamespacesCallCount() int {
+	fake.namespacesMutex.RLock()
+	defer fake.namespacesMutex.RUnlock()
+	return len(fake.namespacesArgsForCall)
+}
+
+func (fake *FakeConnection) NamespacesReturns(result1 []apihub.Namespace, result2 error) {
+	fake.NamespacesStub = nil
+	fake.namespacesReturns = struct {
+		result1 []apihub.Namespace
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) FindNamespace(arg1 string) (apihub.Namespace, error) {
+	fake.findNamespaceMutex.Lock()
+	fake.findNamespaceArgsForCall = append(fake.findNamespaceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("FindNamespace", []interface{}{arg1})
+	fake.findNamespaceMutex.Unlock()
+	if fake.FindNamespaceStub != nil {
+		return fake.FindNamespaceStub(arg1)
+	} else {
+		return fake.findNamespaceReturns.result1, fake.findNamespaceReturns.result2
+	}
+}
+
+func (fake *FakeConnection) FindNamespaceCallCount() int {
+	fake.findNamespaceMutex.RLock()
+	defer fake.findNamespaceMutex.RUnlock()
+	return len(fake.findNamespaceArgsForCall)
+}
+
+func (fake *FakeConnection) FindNamespaceArgsForCall(i int) string {
+	fake.findNamespaceMutex.RLock()
+	defer fake.findNamespaceMutex.RUnlock()
+	return fake.findNamespaceArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) FindNamespaceReturns(result1 apihub.Namespace, result2 error) {
+	fake.FindNamespaceStub = nil
+	fake.findNamespaceReturns = struct {
+		result1 apihub.Namespace
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) RemoveNamespace(arg1 string) error {
+	fake.removeNamespaceMutex.Lock()
+	fake.removeNamespaceArgsForCall = append(fake.removeNamespaceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RemoveNamespace", []interface{}{arg1})
+	fake.removeNamespaceMutex.Unlock()
+	if fake.RemoveNamespaceStub != nil {
+		return fake.RemoveNamespaceStub(arg1)
+	} else {
+		return fake.removeNamespaceReturns.result1
+	}
+}
+
+func (fake *FakeConnection) RemoveNamespaceCallCount() int {
+	fake.removeNamespaceMutex.RLock()
+	defer fake.removeNamespaceMutex.RUnlock()
+	return len(fake.removeNamespaceArgsForCall)
+}
+
+func (fake *FakeConnection) RemoveNamespaceArgsForCall(i int) string {
+	fake.removeNamespaceMutex.RLock()
+	defer fake.removeNamespaceMutex.RUnlock()
+	return fake.removeNamespaceArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) RemoveNamespaceReturns(result1 error) {
+	fake.RemoveNamespaceStub = nil
+	fake.removeNamespaceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConnection) AuditEvents(arg1 apihub.AuditQuery) ([]apihub.AuditEvent, error) {
+	fake.auditEventsMutex.Lock()
+	fake.auditEventsArgsForCall = append(fake.auditEventsArgsForCall, struct {
+		arg1 apihub.AuditQuery
+	}{arg1})
+	fake.recordInvocation("AuditEvents", []interface{}{arg1})
+	fake.auditEventsMutex.Unlock()
+	if fake.AuditEventsStub != nil {
+		return fake.AuditEventsStub(arg1)
+	} else {
+		return fake.auditEventsReturns.result1, fake.auditEventsReturns.result2
+	}
+}
+
+func (fake *FakeConnection) AuditEventsCallCount() int {
+	fake.auditEventsMutex.RLock()
+	defer fake.auditEventsMutex.RUnlock()
+	return len(fake.auditEventsArgsForCall)
+}
+
+func (fake *FakeConnection) AuditEventsArgsForCall(i int) apihub.AuditQuery {
+	fake.auditEventsMutex.RLock()
+	defer fake.auditEventsMutex.RUnlock()
+	return fake.auditEventsArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) AuditEventsReturns(result1 []apihub.AuditEvent, result2 error) {
+	fake.AuditEventsStub = nil
+	fake.auditEventsReturns = struct {
+		result1 []apihub.AuditEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) SyncStatus() (apihub.SyncStatus, error) {
+	fake.syncStatusMutex.Lock()
+	fake.syncStatusArgsForCall = append(fake.syncStatusArgsForCall, struct{}{})
+	fake.recordInvocation("SyncStatus", []interface{}{})
+	fake.syncStatusMutex.Unlock()
+	if fake.SyncStatusStub != nil {
+		return fake.SyncStatusStub()
+	} else {
+		return fake.syncStatusReturns.result1, fake.syncStatusReturns.result2
+	}
+}
+
+func (fake *FakeConnection) SyncStatusCallCount() int {
+	fake.syncStatusMutex.RLock()
+	defer fake.syncStatusMutex.RUnlock()
+	return len(fake.syncStatusArgsForCall)
+}
+
+func (fake *FakeConnection) SyncStatusReturns(result1 apihub.SyncStatus, result2 error) {
+	fake.SyncStatusStub = nil
+	fake.syncStatusReturns = struct {
+		result1 apihub.SyncStatus
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) AddWebhook(arg1 apihub.Webhook) (apihub.Webhook, error) {
+	fake.addWebhookMutex.Lock()
+	fake.addWebhookArgsForCall = append(fake.addWebhookArgsForCall, struct {
+		arg1 apihub.Webhook
+	}{arg1})
+	fake.recordInvocation("AddWebhook", []interface{}{arg1})
+	fake.addWebhookMutex.Unlock()
+	if fake.AddWebhookStub != nil {
+		return fake.AddWebhookStub(arg1)
+	} else {
+		return fake.addWebhookReturns.result1, fake.addWebhookReturns.result2
+	}
+}
+
+func (fake *FakeConnection) AddWebhookCallCount() int {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	return len(fake.addWebhookArgsForCall)
+}
+
+func (fake *FakeConnection) AddWebhookArgsForCall(i int) apihub.Webhook {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	return fake.addWebhookArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) AddWebhookReturns(result1 apihub.Webhook, result2 error) {
+	fake.AddWebhookStub = nil
+	fake.addWebhookReturns = struct {
+		result1 apihub.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) Webhooks() ([]apihub.Webhook, error) {
+	fake.webhooksMutex.Lock()
+	fake.webhooksArgsForCall = append(fake.webhooksArgsForCall, struct{}{})
+	fake.recordInvocation("Webhooks", []interface{}{})
+	fake.webhooksMutex.Unlock()
+	if fake.WebhooksStub != nil {
+		return fake.WebhooksStub()
+	} else {
+		return fake.webhooksReturns.result1, fake.webhooksReturns.result2
+	}
+}
+
+func (fake *FakeConnection) WebhooksCallCount() int {
+	fake.webhooksMutex.RLock()
+	defer fake.webhooksMutex.RUnlock()
+	return len(fake.webhooksArgsForCall)
+}
+
+func (fake *FakeConnection) WebhooksReturns(result1 []apihub.Webhook, result2 error) {
+	fake.WebhooksStub = nil
+	fake.webhooksReturns = struct {
+		result1 []apihub.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) FindWebhook(arg1 string) (apihub.Webhook, error) {
+	fake.findWebhookMutex.Lock()
+	fake.findWebhookArgsForCall = append(fake.findWebhookArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("FindWebhook", []interface{}{arg1})
+	fake.findWebhookMutex.Unlock()
+	if fake.FindWebhookStub != nil {
+		return fake.FindWebhookStub(arg1)
+	} else {
+		return fake.findWebhookReturns.result1, fake.findWebhookReturns.result2
+	}
+}
+
+func (fake *FakeConnection) FindWebhookCallCount() int {
+	fake.findWebhookMutex.RLock()
+	defer fake.findWebhookMutex.RUnlock()
+	return len(fake.findWebhookArgsForCall)
+}
+
+func (fake *FakeConnection) FindWebhookArgsForCall(i int) string {
+	fake.findWebhookMutex.RLock()
+	defer fake.findWebhookMutex.RUnlock()
+	return fake.findWebhookArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) FindWebhookReturns(result1 apihub.Webhook, result2 error) {
+	fake.FindWebhookStub = nil
+	fake.findWebhookReturns = struct {
+		result1 apihub.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) RemoveWebhook(arg1 string) error {
+	fake.removeWebhookMutex.Lock()
+	fake.removeWebhookArgsForCall = append(fake.removeWebhookArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RemoveWebhook", []interface{}{arg1})
+	fake.removeWebhookMutex.Unlock()
+	if fake.RemoveWebhookStub != nil {
+		return fake.RemoveWebhookStub(arg1)
+	} else {
+		return fake.removeWebhookReturns.result1
+	}
+}
+
+func (fake *FakeConnection) RemoveWebhookCallCount() int {
+	fake.removeWebhookMutex.RLock()
+	defer fake.removeWebhookMutex.RUnlock()
+	return len(fake.removeWebhookArgsForCall)
+}
+
+func (fake *FakeConnection) RemoveWebhookArgsForCall(i int) string {
+	fake.removeWebhookMutex.RLock()
+	defer fake.removeWebhookMutex.RUnlock()
+	return fake.removeWebhookArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) RemoveWebhookReturns(result1 error) {
+	fake.RemoveWebhookStub = nil
+	fake.removeWebhookReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConnection) WebhookDeliveries(arg1 string) ([]apihub.WebhookDelivery, error) {
+	fake.webhookDeliveriesMutex.Lock()
+	fake.webhookDeliveriesArgsForCall = append(fake.webhookDeliveriesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("WebhookDeliveries", []interface{}{arg1})
+	fake.webhookDeliveriesMutex.Unlock()
+	if fake.WebhookDeliveriesStub != nil {
+		return fake.WebhookDeliveriesStub(arg1)
+	} else {
+		return fake.webhookDeliveriesReturns.result1, fake.webhookDeliveriesReturns.result2
+	}
+}
+
+func (fake *FakeConnection) WebhookDeliveriesCallCount() int {
+	fake.webhookDeliveriesMutex.RLock()
+	defer fake.webhookDeliveriesMutex.RUnlock()
+	return len(fake.webhookDeliveriesArgsForCall)
+}
+
+func (fake *FakeConnection) WebhookDeliveriesArgsForCall(i int) string {
+	fake.webhookDeliveriesMutex.RLock()
+	defer fake.webhookDeliveriesMutex.RUnlock()
+	return fake.webhookDeliveriesArgsForCall[i].arg1
+}
+
+func (fake *FakeConnection) WebhookDeliveriesReturns(result1 []apihub.WebhookDelivery, result2 error) {
+	fake.WebhookDeliveriesStub = nil
+	fake.webhookDeliveriesReturns = struct {
+		result1 []apihub.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeConnection) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -258,12 +1584,74 @@ func (fake *FakeConnection) Invocations() map[string][][]interface{} {
 	defer fake.addServiceMutex.RUnlock()
 	fake.servicesMutex.RLock()
 	defer fake.servicesMutex.RUnlock()
+	fake.listServicesMutex.RLock()
+	defer fake.listServicesMutex.RUnlock()
+	fake.watchServicesMutex.RLock()
+	defer fake.watchServicesMutex.RUnlock()
 	fake.removeServiceMutex.RLock()
 	defer fake.removeServiceMutex.RUnlock()
 	fake.findServiceMutex.RLock()
 	defer fake.findServiceMutex.RUnlock()
 	fake.updateServiceMutex.RLock()
 	defer fake.updateServiceMutex.RUnlock()
+	fake.updateServiceIfMatchMutex.RLock()
+	defer fake.updateServiceIfMatchMutex.RUnlock()
+	fake.patchServiceMutex.RLock()
+	defer fake.patchServiceMutex.RUnlock()
+	fake.replaceServiceMutex.RLock()
+	defer fake.replaceServiceMutex.RUnlock()
+	fake.setServiceFaultsMutex.RLock()
+	defer fake.setServiceFaultsMutex.RUnlock()
+	fake.clearServiceFaultsMutex.RLock()
+	defer fake.clearServiceFaultsMutex.RUnlock()
+	fake.backendsMutex.RLock()
+	defer fake.backendsMutex.RUnlock()
+	fake.addBackendMutex.RLock()
+	defer fake.addBackendMutex.RUnlock()
+	fake.updateBackendMutex.RLock()
+	defer fake.updateBackendMutex.RUnlock()
+	fake.removeBackendMutex.RLock()
+	defer fake.removeBackendMutex.RUnlock()
+	fake.revisionsMutex.RLock()
+	defer fake.revisionsMutex.RUnlock()
+	fake.diffRevisionsMutex.RLock()
+	defer fake.diffRevisionsMutex.RUnlock()
+	fake.rollbackServiceMutex.RLock()
+	defer fake.rollbackServiceMutex.RUnlock()
+	fake.applyMutex.RLock()
+	defer fake.applyMutex.RUnlock()
+	fake.exportMutex.RLock()
+	defer fake.exportMutex.RUnlock()
+	fake.importMutex.RLock()
+	defer fake.importMutex.RUnlock()
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	fake.tokensMutex.RLock()
+	defer fake.tokensMutex.RUnlock()
+	fake.removeTokenMutex.RLock()
+	defer fake.removeTokenMutex.RUnlock()
+	fake.addNamespaceMutex.RLock()
+	defer fake.addNamespaceMutex.RUnlock()
+	fake.namespacesMutex.RLock()
+	defer fake.namespacesMutex.RUnlock()
+	fake.findNamespaceMutex.RLock()
+	defer fake.findNamespaceMutex.RUnlock()
+	fake.removeNamespaceMutex.RLock()
+	defer fake.removeNamespaceMutex.RUnlock()
+	fake.auditEventsMutex.RLock()
+	defer fake.auditEventsMutex.RUnlock()
+	fake.syncStatusMutex.RLock()
+	defer fake.syncStatusMutex.RUnlock()
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	fake.webhooksMutex.RLock()
+	defer fake.webhooksMutex.RUnlock()
+	fake.findWebhookMutex.RLock()
+	defer fake.findWebhookMutex.RUnlock()
+	fake.removeWebhookMutex.RLock()
+	defer fake.removeWebhookMutex.RUnlock()
+	fake.webhookDeliveriesMutex.RLock()
+	defer fake.webhookDeliveriesMutex.RUnlock()
 	return fake.invocations
 }
 
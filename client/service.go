@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
 	"github.com/apihub/apihub/client/connection"
 )
 
@@ -28,26 +29,39 @@ func (s *service) Info() (apihub.ServiceSpec, error) {
 }
 
 func (s *service) Start() error {
-	spec := apihub.ServiceSpec{
-		Disabled: false,
-	}
-	_, err := s.conn.UpdateService(s.Host(), spec)
-	return err
+	return s.patch(map[string]interface{}{"disabled": false})
 }
 
 func (s *service) Stop() error {
-	spec := apihub.ServiceSpec{
-		Disabled: true,
-	}
-	_, err := s.conn.UpdateService(s.Host(), spec)
-	return err
+	return s.patch(map[string]interface{}{"disabled": true})
 }
 
 func (s *service) SetTimeout(duration time.Duration) error {
-	spec := apihub.ServiceSpec{
-		Timeout: duration,
-	}
-	_, err := s.conn.UpdateService(s.Host(), spec)
+	return s.patch(map[string]interface{}{"timeout": duration})
+}
+
+// patch changes only the given members of the service, leaving the others
+// as they are.
+func (s *service) patch(members map[string]interface{}) error {
+	_, err := s.conn.PatchService(s.Host(), api.MERGE_PATCH_CONTENT_TYPE, members)
+	return err
+}
+
+func (s *service) SetFaults(faults []apihub.FaultRule) error {
+	_, err := s.conn.SetServiceFaults(s.Host(), faults)
+	return err
+}
+
+func (s *service) ClearFaults() error {
+	return s.conn.ClearServiceFaults(s.Host())
+}
+
+func (s *service) Revisions() ([]apihub.Revision, error) {
+	return s.conn.Revisions(s.Host())
+}
+
+func (s *service) Rollback(version uint64) error {
+	_, err := s.conn.RollbackService(s.Host(), version)
 	return err
 }
 
@@ -59,3 +73,22 @@ func (s *service) Backends() ([]apihub.BackendInfo, error) {
 
 	return info.Backends, nil
 }
+
+func (s *service) AddBackend(backend apihub.BackendInfo) error {
+	_, err := s.conn.AddBackend(s.Host(), backend)
+	return err
+}
+
+func (s *service) RemoveBackend(address string) error {
+	return s.conn.RemoveBackend(s.Host(), address)
+}
+
+func (s *service) EnableBackend(address string) error {
+	_, err := s.conn.UpdateBackend(s.Host(), address, map[string]interface{}{"disabled": false})
+	return err
+}
+
+func (s *service) DisableBackend(address string) error {
+	_, err := s.conn.UpdateBackend(s.Host(), address, map[string]interface{}{"disabled": true})
+	return err
+}
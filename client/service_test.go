@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/api"
 	"github.com/apihub/apihub/client"
 	"github.com/apihub/apihub/client/connection/connectionfakes"
 
@@ -82,16 +83,16 @@ var _ = Describe("Service", func() {
 	Describe("Start", func() {
 		It("enables the service to start receiving requests", func() {
 			Expect(service.Start()).To(Succeed())
-			Expect(fakeConnection.UpdateServiceCallCount()).To(Equal(1))
-			host, serviceSpec := fakeConnection.UpdateServiceArgsForCall(0)
+			Expect(fakeConnection.PatchServiceCallCount()).To(Equal(1))
+			host, contentType, patch := fakeConnection.PatchServiceArgsForCall(0)
 			Expect(host).To(Equal(spec.Host))
-			Expect(spec.Disabled).To(BeTrue())
-			Expect(serviceSpec.Disabled).To(BeFalse())
+			Expect(contentType).To(Equal(api.MERGE_PATCH_CONTENT_TYPE))
+			Expect(patch).To(Equal(map[string]interface{}{"disabled": false}))
 		})
 
 		Context("when fails to enable", func() {
 			BeforeEach(func() {
-				fakeConnection.UpdateServiceReturns(apihub.ServiceSpec{}, errors.New("failed to update"))
+				fakeConnection.PatchServiceReturns(apihub.ServiceSpec{}, errors.New("failed to update"))
 			})
 
 			It("returns an error", func() {
@@ -101,22 +102,18 @@ var _ = Describe("Service", func() {
 	})
 
 	Describe("Stop", func() {
-		BeforeEach(func() {
-			spec.Disabled = false
-		})
-
 		It("disables the service to stop receiving requests", func() {
 			Expect(service.Stop()).To(Succeed())
-			Expect(fakeConnection.UpdateServiceCallCount()).To(Equal(1))
-			host, serviceSpec := fakeConnection.UpdateServiceArgsForCall(0)
+			Expect(fakeConnection.PatchServiceCallCount()).To(Equal(1))
+			host, contentType, patch := fakeConnection.PatchServiceArgsForCall(0)
 			Expect(host).To(Equal(spec.Host))
-			Expect(spec.Disabled).To(BeFalse())
-			Expect(serviceSpec.Disabled).To(BeTrue())
+			Expect(contentType).To(Equal(api.MERGE_PATCH_CONTENT_TYPE))
+			Expect(patch).To(Equal(map[string]interface{}{"disabled": true}))
 		})
 
 		Context("when fails to disable", func() {
 			BeforeEach(func() {
-				fakeConnection.UpdateServiceReturns(apihub.ServiceSpec{}, errors.New("failed to update"))
+				fakeConnection.PatchServiceReturns(apihub.ServiceSpec{}, errors.New("failed to update"))
 			})
 
 			It("returns an error", func() {
@@ -126,18 +123,19 @@ var _ = Describe("Service", func() {
 	})
 
 	Describe("SetTimeout", func() {
-		It("updates de timeout", func() {
+		It("updates only the timeout", func() {
 			duration := time.Second * 10
 			Expect(service.SetTimeout(duration)).To(Succeed())
-			Expect(fakeConnection.UpdateServiceCallCount()).To(Equal(1))
-			host, serviceSpec := fakeConnection.UpdateServiceArgsForCall(0)
+			Expect(fakeConnection.PatchServiceCallCount()).To(Equal(1))
+			host, contentType, patch := fakeConnection.PatchServiceArgsForCall(0)
 			Expect(host).To(Equal(spec.Host))
-			Expect(serviceSpec.Timeout).To(Equal(duration))
+			Expect(contentType).To(Equal(api.MERGE_PATCH_CONTENT_TYPE))
+			Expect(patch).To(Equal(map[string]interface{}{"timeout": duration}))
 		})
 
-		Context("when fails to disable", func() {
+		Context("when fails to update the timeout", func() {
 			BeforeEach(func() {
-				fakeConnection.UpdateServiceReturns(apihub.ServiceSpec{}, errors.New("failed to update"))
+				fakeConnection.PatchServiceReturns(apihub.ServiceSpec{}, errors.New("failed to update"))
 			})
 
 			It("returns an error", func() {
@@ -146,6 +144,76 @@ var _ = Describe("Service", func() {
 		})
 	})
 
+	Describe("SetFaults", func() {
+		It("replaces the faults of the service", func() {
+			faults := []apihub.FaultRule{
+				apihub.FaultRule{Header: "X-Chaos", Percentage: 50, AbortStatus: 503},
+			}
+			Expect(service.SetFaults(faults)).To(Succeed())
+			Expect(fakeConnection.SetServiceFaultsCallCount()).To(Equal(1))
+			host, serviceFaults := fakeConnection.SetServiceFaultsArgsForCall(0)
+			Expect(host).To(Equal(spec.Host))
+			Expect(serviceFaults).To(Equal(faults))
+		})
+
+		Context("when fails to set the faults", func() {
+			BeforeEach(func() {
+				fakeConnection.SetServiceFaultsReturns(apihub.ServiceSpec{}, errors.New("failed to set faults"))
+			})
+
+			It("returns an error", func() {
+				Expect(service.SetFaults(nil)).To(MatchError(ContainSubstring("failed to set faults")))
+			})
+		})
+	})
+
+	Describe("ClearFaults", func() {
+		It("clears the faults of the service", func() {
+			Expect(service.ClearFaults()).To(Succeed())
+			Expect(fakeConnection.ClearServiceFaultsCallCount()).To(Equal(1))
+			Expect(fakeConnection.ClearServiceFaultsArgsForCall(0)).To(Equal(spec.Host))
+		})
+
+		Context("when fails to clear the faults", func() {
+			BeforeEach(func() {
+				fakeConnection.ClearServiceFaultsReturns(errors.New("failed to clear faults"))
+			})
+
+			It("returns an error", func() {
+				Expect(service.ClearFaults()).To(MatchError(ContainSubstring("failed to clear faults")))
+			})
+		})
+	})
+
+	Describe("Revisions", func() {
+		It("lists the revisions of the service", func() {
+			revisions := []apihub.Revision{apihub.Revision{Version: 1, Spec: spec}}
+			fakeConnection.RevisionsReturns(revisions, nil)
+
+			Expect(service.Revisions()).To(Equal(revisions))
+			Expect(fakeConnection.RevisionsArgsForCall(0)).To(Equal(spec.Host))
+		})
+	})
+
+	Describe("Rollback", func() {
+		It("rolls the service back to the revision", func() {
+			Expect(service.Rollback(3)).To(Succeed())
+			host, version := fakeConnection.RollbackServiceArgsForCall(0)
+			Expect(host).To(Equal(spec.Host))
+			Expect(version).To(BeEquivalentTo(3))
+		})
+
+		Context("when fails to roll back", func() {
+			BeforeEach(func() {
+				fakeConnection.RollbackServiceReturns(apihub.ServiceSpec{}, errors.New("failed to roll back"))
+			})
+
+			It("returns an error", func() {
+				Expect(service.Rollback(3)).To(MatchError(ContainSubstring("failed to roll back")))
+			})
+		})
+	})
+
 	Describe("Backends", func() {
 		BeforeEach(func() {
 			fakeConnection.FindServiceReturns(spec, nil)
@@ -170,24 +238,71 @@ var _ = Describe("Service", func() {
 	})
 
 	Describe("AddBackend", func() {
-		BeforeEach(func() {
-			fakeConnection.FindServiceReturns(spec, nil)
-		})
-
 		It("adds a new backend", func() {
-			backends, err := service.Backends()
-			Expect(err).NotTo(HaveOccurred())
-			Expect(len(backends)).To(Equal(2))
+			backend := apihub.BackendInfo{Address: "http://server-c"}
+			Expect(service.AddBackend(backend)).To(Succeed())
+			Expect(fakeConnection.AddBackendCallCount()).To(Equal(1))
+			host, added := fakeConnection.AddBackendArgsForCall(0)
+			Expect(host).To(Equal(spec.Host))
+			Expect(added).To(Equal(backend))
 		})
 
 		Context("when fails to add a new backend", func() {
 			BeforeEach(func() {
-				fakeConnection.FindServiceReturns(apihub.ServiceSpec{}, errors.New("fail to get the backend list"))
+				fakeConnection.AddBackendReturns(apihub.BackendInfo{}, errors.New("failed to add backend"))
 			})
 
 			It("returns an error", func() {
-				_, err := service.Backends()
-				Expect(err).To(HaveOccurred())
+				Expect(service.AddBackend(apihub.BackendInfo{Address: "http://server-c"})).To(MatchError(ContainSubstring("failed to add backend")))
+			})
+		})
+	})
+
+	Describe("RemoveBackend", func() {
+		It("removes the backend", func() {
+			Expect(service.RemoveBackend("http://server-a")).To(Succeed())
+			host, address := fakeConnection.RemoveBackendArgsForCall(0)
+			Expect(host).To(Equal(spec.Host))
+			Expect(address).To(Equal("http://server-a"))
+		})
+
+		Context("when fails to remove the backend", func() {
+			BeforeEach(func() {
+				fakeConnection.RemoveBackendReturns(errors.New("failed to remove backend"))
+			})
+
+			It("returns an error", func() {
+				Expect(service.RemoveBackend("http://server-a")).To(MatchError(ContainSubstring("failed to remove backend")))
+			})
+		})
+	})
+
+	Describe("EnableBackend", func() {
+		It("updates only the disabled flag of the backend", func() {
+			Expect(service.EnableBackend("http://server-a")).To(Succeed())
+			host, address, patch := fakeConnection.UpdateBackendArgsForCall(0)
+			Expect(host).To(Equal(spec.Host))
+			Expect(address).To(Equal("http://server-a"))
+			Expect(patch).To(Equal(map[string]interface{}{"disabled": false}))
+		})
+	})
+
+	Describe("DisableBackend", func() {
+		It("updates only the disabled flag of the backend", func() {
+			Expect(service.DisableBackend("http://server-a")).To(Succeed())
+			host, address, patch := fakeConnection.UpdateBackendArgsForCall(0)
+			Expect(host).To(Equal(spec.Host))
+			Expect(address).To(Equal("http://server-a"))
+			Expect(patch).To(Equal(map[string]interface{}{"disabled": true}))
+		})
+
+		Context("when fails to update the backend", func() {
+			BeforeEach(func() {
+				fakeConnection.UpdateBackendReturns(apihub.BackendInfo{}, errors.New("failed to update backend"))
+			})
+
+			It("returns an error", func() {
+				Expect(service.DisableBackend("http://server-a")).To(MatchError(ContainSubstring("failed to update backend")))
 			})
 		})
 	})
@@ -1,22 +1,40 @@
 package main
 
 import (
+	"database/sql"
 	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
 
 	"code.cloudfoundry.org/lager"
+	"github.com/apihub/apihub"
 	"github.com/apihub/apihub/api"
 	"github.com/apihub/apihub/api/publisher"
 	"github.com/apihub/apihub/storage"
+	"github.com/apihub/apihub/storage/consul"
 	consulapi "github.com/hashicorp/consul/api"
+	// Registers the pure-Go SQLite driver used by the `sqlite` storage.
+	_ "modernc.org/sqlite"
 )
 
 var (
-	network         = flag.String("network", "unix", "Either `tcp` or `unix`")
-	address         = flag.String("address", "/tmp/apihub.sock", "Port for `tcp` or filepath for `unix`")
-	consulServerURL = flag.String("consul-server", "http://127.0.0.1:8500", "consul server url")
+	network           = flag.String("network", "unix", "Either `tcp` or `unix`")
+	address           = flag.String("address", "/tmp/apihub.sock", "Port for `tcp` or filepath for `unix`")
+	consulServerURL   = flag.String("consul-server", "http://127.0.0.1:8500", "consul server url")
+	storageDriver     = flag.String("storage", "memory", "Either `memory`, `bolt`, `consul` or `sqlite`")
+	storagePath       = flag.String("storage-path", "/tmp/apihub.db", "Database file for the `bolt` and `sqlite` storages")
+	storagePrefix     = flag.String("storage-prefix", consul.DEFAULT_PREFIX, "Key prefix for the `consul` storage")
+	auditRetention    = flag.Duration("audit-retention", consul.DEFAULT_AUDIT_RETENTION, "How long the `consul` storage keeps the audit events, 0 keeps them all")
+	servicesPrefix    = flag.String("services-prefix", apihub.SERVICES_PREFIX, "Key prefix the services are published under in Consul, for example apihub/prod/services/")
+	auth              = flag.Bool("auth", false, "Require a bearer token on every request but pings")
+	adminToken        = flag.String("admin-token", os.Getenv("APIHUB_ADMIN_TOKEN"), "Admin token to create the first tokens with, implies -auth (default $APIHUB_ADMIN_TOKEN)")
+	auditLog          = flag.String("audit-log", "", "File the audit events are appended to, as JSON lines")
+	outboxInterval    = flag.Duration("outbox-interval", api.DEFAULT_OUTBOX_INTERVAL, "How often the services that failed to publish are retried")
+	webhookInterval   = flag.Duration("webhook-interval", api.DEFAULT_WEBHOOK_INTERVAL, "How often the pending webhook payloads are delivered")
+	reconcileInterval = flag.Duration("reconcile-interval", api.DEFAULT_RECONCILE_INTERVAL, "How often the published services are compared with the stored ones")
+	watchInterval     = flag.Duration("watch-interval", api.DEFAULT_WATCH_INTERVAL, "How often the storage is polled for the changes to the watched services")
 )
 
 func main() {
@@ -27,7 +45,6 @@ func main() {
 	logger.RegisterSink(lager.NewWriterSink(os.Stdout, lager.DEBUG))
 
 	// Configure and start server
-	store := storage.New()
 	consulURL, err := url.Parse(*consulServerURL)
 	if err != nil {
 		panic(fmt.Sprintf("Error parsing Consul URL: %s", err))
@@ -39,7 +56,56 @@ func main() {
 	if err != nil {
 		panic(fmt.Sprintf("Error connecting to Consul agent: %s", err))
 	}
+	if err := apihub.CheckServicesPrefix(*servicesPrefix); err != nil {
+		panic(fmt.Sprintf("Error: %s", err))
+	}
+	store, err := newStorage(*storageDriver, consulClient)
+	if err != nil {
+		panic(fmt.Sprintf("Error opening the storage: %s", err))
+	}
 	publisher := publisher.NewPublisher(consulClient)
 	server := api.New(logger, *network, *address, store, publisher)
+	server.SetServicesPrefix(*servicesPrefix)
+	server.SetWatchInterval(*watchInterval)
+	if *auth || *adminToken != "" {
+		server.EnableAuth(*adminToken)
+	}
+	if *auditLog != "" {
+		file, err := os.OpenFile(*auditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			panic(fmt.Sprintf("Error opening the audit log: %s", err))
+		}
+		defer file.Close()
+		server.EnableAuditLog(file)
+	}
+	server.StartSync(*outboxInterval, *reconcileInterval, *webhookInterval)
 	server.Start(true)
 }
+
+func newStorage(driver string, consulClient *consulapi.Client) (apihub.AdminStorage, error) {
+	switch driver {
+	case "memory":
+		return storage.New(), nil
+	case "bolt":
+		return storage.NewBolt(*storagePath)
+	case "consul":
+		// The publisher deletes the keys of the services it unpublishes, and
+		// the gateways read every key under the services prefix.
+		if strings.HasPrefix(*storagePrefix, *servicesPrefix) || strings.HasPrefix(*servicesPrefix, *storagePrefix) {
+			return nil, fmt.Errorf("storage prefix cannot overlap '%s', services are published under it", *servicesPrefix)
+		}
+		store := consul.New(consulClient, *storagePrefix)
+		store.SetAuditRetention(*auditRetention)
+		return store, nil
+	case "sqlite":
+		db, err := sql.Open("sqlite", *storagePath)
+		if err != nil {
+			return nil, err
+		}
+		// SQLite serializes the writes, sharing a connection avoids busy errors.
+		db.SetMaxOpenConns(1)
+		return storage.NewSQL(db)
+	default:
+		return nil, fmt.Errorf("unknown storage: '%s'", driver)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/apihub/apihub"
@@ -15,20 +14,40 @@ import (
 )
 
 var (
-	port            = flag.String("port", ":8080", "Port to be used")
-	consulServerURL = flag.String("consul-server", "http://127.0.0.1:8500", "consul server url")
+	port         = flag.String("port", ":8080", "Port to be used")
+	adminAddress = flag.String("admin-address", "127.0.0.1:8081", "Address of the admin API, kept apart from proxied traffic")
+
+	readTimeout       = flag.Duration("read-timeout", gateway.DEFAULT_READ_TIMEOUT, "Maximum duration for reading a request, including the body")
+	readHeaderTimeout = flag.Duration("read-header-timeout", 0, "Maximum duration for reading the request headers (defaults to read-timeout)")
+	writeTimeout      = flag.Duration("write-timeout", gateway.DEFAULT_WRITE_TIMEOUT, "Maximum duration before timing out writes of the response")
+	idleTimeout       = flag.Duration("idle-timeout", 0, "Maximum duration to wait for the next request on a keep-alive connection (defaults to read-timeout)")
+	maxHeaderBytes    = flag.Int("max-header-bytes", gateway.DEFAULT_MAX_HEADER_BYTES, "Maximum size of the request headers")
+	maxBodyBytes      = flag.Int64("max-body-bytes", 0, "Maximum size of the request body (0 means unlimited)")
+	consulServerURL   = flag.String("consul-server", "http://127.0.0.1:8500", "consul server url")
+	servicesPrefix    = flag.String("services-prefix", apihub.SERVICES_PREFIX, "Key prefix the services are read from in Consul, the one the API publishes them under")
 )
 
 func main() {
 	flag.Parse()
 
+	if err := apihub.CheckServicesPrefix(*servicesPrefix); err != nil {
+		panic(fmt.Sprintf("Error: %s", err))
+	}
+
 	// Configure log
 	logger := lager.NewLogger("apihub-gateway")
 	logger.RegisterSink(lager.NewWriterSink(os.Stdout, lager.DEBUG))
 
 	// Configure and start server
 	reverseProxyCreator := gateway.NewReverseProxyCreator()
-	gw := gateway.New(*port, reverseProxyCreator)
+	gw := gateway.New(*port, reverseProxyCreator, gateway.ServerConfig{
+		ReadTimeout:       *readTimeout,
+		ReadHeaderTimeout: *readHeaderTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+		MaxHeaderBytes:    *maxHeaderBytes,
+		MaxBodyBytes:      *maxBodyBytes,
+	})
 
 	consulURL, err := url.Parse(*consulServerURL)
 	if err != nil {
@@ -46,29 +65,24 @@ func main() {
 	stopCh := make(chan struct{})
 
 	sub := subscriber.NewSubscriber(consulClient)
-	go sub.Subscribe(logger, apihub.SERVICES_PREFIX, servicesCh, stopCh)
+	go sub.Subscribe(logger, *servicesPrefix, servicesCh, stopCh)
+
+	admin := gateway.NewAdmin(*adminAddress, gw, sub)
+	go func() {
+		if err := admin.Start(logger); err != nil {
+			panic(fmt.Errorf("Failed to start Apihub Gateway admin: `%s`.", err))
+		}
+	}()
 
 	go func() {
 		logger.Debug("waiting-for-services")
 		for {
 			select {
 			case spec := <-servicesCh:
-				var backends []string
-				for _, be := range spec.Backends {
-					backends = append(backends, be.Address)
-				}
-
-				proxySpec := gateway.ReverseProxySpec{
-					Host:   spec.Host,
-					Backends: backends,
-					Timeout:  time.Duration(spec.Timeout),
-				}
-				if spec.Disabled {
-					gw.RemoveService(logger, spec.Host)
-					logger.Info("service-removed ", lager.Data{"host": spec.Host})
+				if err := gw.ApplyService(logger, spec); err != nil {
+					logger.Error("failed-to-apply-service", err, lager.Data{"host": spec.Host})
 				} else {
-					gw.AddService(logger, proxySpec)
-					logger.Info("service-added", lager.Data{"spec": proxySpec})
+					logger.Info("service-applied", lager.Data{"host": spec.Host})
 				}
 			}
 		}
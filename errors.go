@@ -1,10 +1,103 @@
 package apihub
 
+import (
+	"errors"
+	"net/http"
+)
+
 const (
-	E_BAD_REQUEST string = "bad_request"
+	E_BAD_REQUEST         string = "bad_request"
+	E_UNAUTHORIZED        string = "unauthorized"
+	E_FORBIDDEN           string = "forbidden"
+	E_NOT_FOUND           string = "not_found"
+	E_CONFLICT            string = "conflict"
+	E_PRECONDITION_FAILED string = "precondition_failed"
+	E_GONE                string = "gone"
+	E_VALIDATION_FAILED   string = "validation_failed"
+	E_INTERNAL_ERROR      string = "internal_error"
+	E_NOT_IMPLEMENTED     string = "not_implemented"
 )
 
+// StatusCodes maps the error codes to the HTTP status of the responses
+// carrying them.
+var StatusCodes = map[string]int{
+	E_BAD_REQUEST:         http.StatusBadRequest,
+	E_UNAUTHORIZED:        http.StatusUnauthorized,
+	E_FORBIDDEN:           http.StatusForbidden,
+	E_NOT_FOUND:           http.StatusNotFound,
+	E_CONFLICT:            http.StatusConflict,
+	E_PRECONDITION_FAILED: http.StatusPreconditionFailed,
+	E_GONE:                http.StatusGone,
+	E_VALIDATION_FAILED:   http.StatusUnprocessableEntity,
+	E_INTERNAL_ERROR:      http.StatusInternalServerError,
+	E_NOT_IMPLEMENTED:     http.StatusNotImplemented,
+}
+
+var (
+	// ErrBadRequest, ErrUnauthorized, ErrForbidden, ErrNotFound, ErrConflict,
+	// ErrPreconditionFailed, ErrGone, ErrValidation, ErrInternal and
+	// ErrNotImplemented are the kinds of errors, to be checked with errors.Is.
+	ErrBadRequest         = &Error{Code: E_BAD_REQUEST}
+	ErrUnauthorized       = &Error{Code: E_UNAUTHORIZED}
+	ErrForbidden          = &Error{Code: E_FORBIDDEN}
+	ErrNotFound           = &Error{Code: E_NOT_FOUND}
+	ErrConflict           = &Error{Code: E_CONFLICT}
+	ErrPreconditionFailed = &Error{Code: E_PRECONDITION_FAILED}
+	ErrGone               = &Error{Code: E_GONE}
+	ErrValidation         = &Error{Code: E_VALIDATION_FAILED}
+	ErrInternal           = &Error{Code: E_INTERNAL_ERROR}
+	ErrNotImplemented     = &Error{Code: E_NOT_IMPLEMENTED}
+)
+
+// Error is an error with a stable code, returned by storages and by the
+// client when the API responds with an error.
+type Error struct {
+	Code        string
+	Description string
+	// Details lists the invalid fields of a request, for validation errors.
+	Details []FieldError
+}
+
+// FieldError describes why a field of a request is invalid. Field is the
+// path of the field in the JSON document, as in "backends[0].address".
+type FieldError struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// NewError returns an error of the given code.
+func NewError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Description
+}
+
+// Is reports whether target is of the same kind, so errors.Is(err,
+// ErrNotFound) holds for every not found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code && (t.Description == "" || t.Description == e.Description)
+}
+
+// ErrorCode returns the code of err, or E_INTERNAL_ERROR when it has none.
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Code != "" {
+		return e.Code
+	}
+	return E_INTERNAL_ERROR
+}
+
 type ErrorResponse struct {
-	Error       string `json:"error,omitempty"`
-	Description string `json:"error_description,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Description string       `json:"error_description,omitempty"`
+	Details     []FieldError `json:"error_details,omitempty"`
 }
@@ -0,0 +1,42 @@
+package apihub
+
+import "time"
+
+// EXPORT_FORMAT_VERSION is the version of the documents written by GET
+// /export. Imports only accept documents of this version.
+const EXPORT_FORMAT_VERSION = 1
+
+const (
+	IMPORT_MERGE         string = "merge"
+	IMPORT_REPLACE       string = "replace"
+	IMPORT_SKIP_EXISTING string = "skip-existing"
+)
+
+// ExportDocument holds the services of an ApiHub, to be imported into
+// another one.
+type ExportDocument struct {
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Services   []ServiceSpec `json:"services"`
+}
+
+// ImportOptions tunes how the services of a document are imported. Mode
+// decides what happens to the services that already exist:
+//
+//	merge          the existing services are replaced by the imported ones
+//	replace        as merge, and the services left out of the document are
+//	               removed
+//	skip-existing  the existing services are kept as they are
+type ImportOptions struct {
+	Mode string
+}
+
+// ImportReport describes the changes made by an import, sorted by host as
+// in ApplyPlan. Skipped lists the existing services kept in the
+// skip-existing mode.
+type ImportReport struct {
+	Mode      string        `json:"mode"`
+	Changes   []ApplyChange `json:"changes"`
+	Skipped   []string      `json:"skipped"`
+	Unchanged []string      `json:"unchanged"`
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/braintree/manners"
+	"github.com/gorilla/mux"
+)
+
+// VersionReporter reports the version of the configuration applied to the
+// gateway.
+type VersionReporter interface {
+	Version() uint64
+}
+
+// Admin serves the routing table loaded in a gateway. It listens on its own
+// address so admin requests never share a listener with proxied traffic.
+type Admin struct {
+	gw      *Gateway
+	version VersionReporter
+	router  *mux.Router
+	server  *manners.GracefulServer
+}
+
+type adminStatus struct {
+	ConfigVersion uint64 `json:"config_version"`
+	ServicesCount int    `json:"services_count"`
+}
+
+type adminService struct {
+	Host string `json:"host"`
+	ReverseProxyStatus
+}
+
+func NewAdmin(addr string, gw *Gateway, version VersionReporter) *Admin {
+	admin := &Admin{
+		gw:      gw,
+		version: version,
+		router:  mux.NewRouter(),
+	}
+
+	admin.router.Methods(http.MethodGet).Path("/status").HandlerFunc(admin.status)
+	admin.router.Methods(http.MethodGet).Path("/services").HandlerFunc(admin.listServices)
+	admin.router.Methods(http.MethodGet).Path("/services/{host}").HandlerFunc(admin.findService)
+	admin.router.NotFoundHandler = http.HandlerFunc(admin.notFound)
+
+	admin.server = manners.NewWithServer(&http.Server{
+		Addr:    addr,
+		Handler: admin,
+	})
+
+	return admin
+}
+
+func (a *Admin) Start(logger lager.Logger) error {
+	log := logger.Session("admin-start")
+	log.Info("starting", lager.Data{"addr": a.server.Addr})
+
+	if err := a.server.ListenAndServe(); err != nil {
+		log.Error("failed-to-start", err)
+		return err
+	}
+	return nil
+}
+
+func (a *Admin) Stop() bool {
+	return a.server.Close()
+}
+
+func (a *Admin) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	a.router.ServeHTTP(rw, req)
+}
+
+func (a *Admin) status(rw http.ResponseWriter, req *http.Request) {
+	status := adminStatus{ServicesCount: len(a.gw.Status())}
+	if a.version != nil {
+		status.ConfigVersion = a.version.Version()
+	}
+
+	writeJSON(rw, response{StatusCode: http.StatusOK, Body: status})
+}
+
+func (a *Admin) listServices(rw http.ResponseWriter, req *http.Request) {
+	services := []adminService{}
+	for host, status := range a.gw.Status() {
+		services = append(services, adminService{Host: host, ReverseProxyStatus: status})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Host < services[j].Host
+	})
+
+	writeJSON(rw, response{
+		StatusCode: http.StatusOK,
+		Body: struct {
+			Items []adminService `json:"items"`
+			Count int            `json:"item_count"`
+		}{services, len(services)},
+	})
+}
+
+func (a *Admin) findService(rw http.ResponseWriter, req *http.Request) {
+	host := mux.Vars(req)["host"]
+	status, ok := a.gw.Status()[host]
+	if !ok {
+		a.notFound(rw, req)
+		return
+	}
+
+	writeJSON(rw, response{
+		StatusCode: http.StatusOK,
+		Body:       adminService{Host: host, ReverseProxyStatus: status},
+	})
+}
+
+func (a *Admin) notFound(rw http.ResponseWriter, req *http.Request) {
+	writeJSON(rw, response{
+		StatusCode: http.StatusNotFound,
+		Body: responseError{
+			ErrType:     "not_found",
+			Description: "The resource does not exist.",
+		},
+	})
+}
+
+func writeJSON(rw http.ResponseWriter, resp response) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(resp.StatusCode)
+	json.NewEncoder(rw).Encode(resp.Body)
+}
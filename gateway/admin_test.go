@@ -0,0 +1,106 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/apihub/apihub/gateway"
+	"github.com/apihub/apihub/gateway/gatewayfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeVersionReporter uint64
+
+func (v fakeVersionReporter) Version() uint64 {
+	return uint64(v)
+}
+
+var _ = Describe("Admin", func() {
+	var (
+		gw                      *gateway.Gateway
+		admin                   *gateway.Admin
+		logger                  *lagertest.TestLogger
+		fakeReverseProxyCreator *gatewayfakes.FakeReverseProxyCreator
+		fakeReverseProxy        *gatewayfakes.FakeReverseProxy
+		spec                    gateway.ReverseProxySpec
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-gateway-admin")
+		fakeReverseProxyCreator = new(gatewayfakes.FakeReverseProxyCreator)
+		fakeReverseProxy = new(gatewayfakes.FakeReverseProxy)
+		fakeReverseProxyCreator.CreateReturns(fakeReverseProxy, nil)
+
+		spec = gateway.ReverseProxySpec{
+			Host:     "my-host.apihub.dev",
+			Backends: []string{"http://server-a"},
+		}
+		fakeReverseProxy.StatusReturns(gateway.ReverseProxyStatus{
+			Spec: spec,
+			Backends: []gateway.BackendStatus{
+				gateway.BackendStatus{Address: "http://server-a", Healthy: true, Circuit: gateway.CircuitClosed},
+			},
+		})
+
+		gw = gateway.New(":0", fakeReverseProxyCreator, gateway.ServerConfig{})
+		Expect(gw.AddService(logger, spec)).To(Succeed())
+		admin = gateway.NewAdmin(":0", gw, fakeVersionReporter(42))
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		rw := httptest.NewRecorder()
+		admin.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describe("GET /status", func() {
+		It("returns the config version applied", func() {
+			rw := serve("/status")
+			Expect(rw.Code).To(Equal(http.StatusOK))
+			Expect(rw.Body.String()).To(MatchJSON(`{"config_version":42,"services_count":1}`))
+		})
+	})
+
+	Describe("GET /services", func() {
+		It("lists the services loaded in the gateway", func() {
+			rw := serve("/services")
+			Expect(rw.Code).To(Equal(http.StatusOK))
+			Expect(rw.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(rw.Body.String()).To(MatchJSON(`{
+				"items": [{
+					"host": "my-host.apihub.dev",
+					"spec": {"host":"my-host.apihub.dev","backends":["http://server-a"],"timeout":0,"limits":{},"transport":{}},
+					"backends": [{"address":"http://server-a","healthy":true,"circuit":"closed","requests":0,"failures":0,"consecutive_failures":0}]
+				}],
+				"item_count": 1
+			}`))
+		})
+	})
+
+	Describe("GET /services/{host}", func() {
+		It("returns the service", func() {
+			rw := serve("/services/my-host.apihub.dev")
+			Expect(rw.Code).To(Equal(http.StatusOK))
+			Expect(rw.Body.String()).To(ContainSubstring(`"host":"my-host.apihub.dev"`))
+		})
+
+		Context("when the service is not loaded", func() {
+			It("returns not found", func() {
+				rw := serve("/services/not-found.apihub.dev")
+				Expect(rw.Code).To(Equal(http.StatusNotFound))
+				Expect(rw.Body.String()).To(MatchJSON(`{"error":"not_found","error_description":"The resource does not exist."}`))
+			})
+		})
+	})
+
+	It("does not proxy requests", func() {
+		rw := serve("/")
+		Expect(rw.Code).To(Equal(http.StatusNotFound))
+		Expect(fakeReverseProxy.ServeHTTPCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_CIRCUIT_COOLDOWN = 10 * time.Second
+)
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+	// CircuitDisabled is the state of the backends of the services without
+	// a circuit breaker, which are always sent requests.
+	CircuitDisabled CircuitState = "disabled"
+)
+
+// BackendStatus holds the health and circuit state of a backend, as observed
+// from the requests proxied to it.
+type BackendStatus struct {
+	Address             string       `json:"address"`
+	Healthy             bool         `json:"healthy"`
+	Circuit             CircuitState `json:"circuit"`
+	Requests            uint64       `json:"requests"`
+	Failures            uint64       `json:"failures"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time   `json:"last_failure_at,omitempty"`
+}
+
+// CircuitSettings configures the circuit breakers of the backends of a
+// service. A zero cooldown falls back to the default.
+type CircuitSettings struct {
+	// Threshold is the number of consecutive failures opening the circuit.
+	Threshold int `json:"threshold,omitempty"`
+	// Cooldown is how long an open circuit waits before letting a trial
+	// request through.
+	Cooldown time.Duration `json:"cooldown,omitempty"`
+}
+
+// circuitBreaker stops sending requests to a backend after a number of
+// consecutive failures. Once the cooldown is over, the circuit is half open:
+// a single trial request is let through, closing the circuit when it
+// succeeds and opening it again when it fails. Without settings, the circuit
+// is disabled: the requests are only counted.
+type circuitBreaker struct {
+	mtx       sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state CircuitState
+	// probing is set while the trial request of a half open circuit is in
+	// flight.
+	probing             bool
+	openedAt            time.Time
+	requests            uint64
+	failures            uint64
+	consecutiveFailures int
+	lastError           string
+	lastFailureAt       time.Time
+}
+
+func newCircuitBreaker(settings *CircuitSettings) *circuitBreaker {
+	if settings == nil {
+		return &circuitBreaker{state: CircuitDisabled}
+	}
+
+	cooldown := DEFAULT_CIRCUIT_COOLDOWN
+	if settings.Cooldown > 0 {
+		cooldown = settings.Cooldown
+	}
+	return &circuitBreaker{
+		threshold: settings.Threshold,
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+	}
+}
+
+// Allow reports whether a request can be sent to the backend. In a half open
+// circuit, it admits the trial request, so the request must then be reported
+// with Success, Failure or Ignore.
+func (cb *circuitBreaker) Allow() bool {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	if !cb.available() {
+		return false
+	}
+	if cb.state == CircuitOpen || cb.state == CircuitHalfOpen {
+		cb.state = CircuitHalfOpen
+		cb.probing = true
+	}
+	return true
+}
+
+// Available reports whether Allow would let a request through, without
+// admitting it.
+func (cb *circuitBreaker) Available() bool {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	return cb.available()
+}
+
+func (cb *circuitBreaker) available() bool {
+	switch cb.state {
+	case CircuitOpen:
+		return time.Since(cb.openedAt) >= cb.cooldown
+	case CircuitHalfOpen:
+		return !cb.probing
+	}
+	return true
+}
+
+func (cb *circuitBreaker) Success() {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	cb.requests++
+	cb.consecutiveFailures = 0
+	cb.probing = false
+	if cb.state != CircuitDisabled {
+		cb.state = CircuitClosed
+	}
+}
+
+func (cb *circuitBreaker) Failure(err error) {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	cb.probing = false
+	cb.requests++
+	cb.failures++
+	cb.consecutiveFailures++
+	cb.lastError = err.Error()
+	cb.lastFailureAt = time.Now()
+
+	if cb.state == CircuitDisabled {
+		return
+	}
+	if cb.state == CircuitHalfOpen || cb.consecutiveFailures >= cb.threshold {
+		cb.state = CircuitOpen
+		cb.openedAt = cb.lastFailureAt
+	}
+}
+
+// Ignore reports an admitted request that tells nothing about the backend,
+// such as one the client gave up on. A half open circuit lets another trial
+// request through.
+func (cb *circuitBreaker) Ignore() {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	cb.probing = false
+}
+
+func (cb *circuitBreaker) Status() BackendStatus {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	// Without a circuit, a backend is healthy until a request to it fails.
+	healthy := cb.state == CircuitClosed
+	if cb.state == CircuitDisabled {
+		healthy = cb.consecutiveFailures == 0
+	}
+	status := BackendStatus{
+		Healthy:             healthy,
+		Circuit:             cb.state,
+		Requests:            cb.requests,
+		Failures:            cb.failures,
+		ConsecutiveFailures: cb.consecutiveFailures,
+		LastError:           cb.lastError,
+	}
+	if !cb.lastFailureAt.IsZero() {
+		lastFailureAt := cb.lastFailureAt
+		status.LastFailureAt = &lastFailureAt
+	}
+	return status
+}
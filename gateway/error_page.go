@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const defaultErrorPageContentType = "text/html; charset=utf-8"
+
+// renderError returns the body and the content type of an error generated by
+// the gateway, preferring the custom page configured for its status code.
+func renderError(pages map[int]ErrorPage, statusCode int, body interface{}) ([]byte, string) {
+	if page, ok := pages[statusCode]; ok {
+		contentType := page.ContentType
+		if contentType == "" {
+			contentType = defaultErrorPageContentType
+		}
+		return []byte(page.Body), contentType
+	}
+
+	data, _ := json.Marshal(body)
+	return data, "application/json"
+}
+
+func writeError(rw http.ResponseWriter, pages map[int]ErrorPage, resp response) {
+	data, contentType := renderError(pages, resp.StatusCode, resp.Body)
+	rw.Header().Set("Content-Type", contentType)
+	rw.WriteHeader(resp.StatusCode)
+	rw.Write(data)
+}
@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+// Fault injects a delay, an error or both in a percentage of the requests
+// carrying a header.
+type Fault struct {
+	Header      string        `json:"header"`
+	HeaderValue string        `json:"header_value,omitempty"`
+	Percentage  float64       `json:"percentage"`
+	Delay       time.Duration `json:"delay,omitempty"`
+	DelayJitter time.Duration `json:"delay_jitter,omitempty"`
+	AbortStatus int           `json:"abort_status,omitempty"`
+}
+
+// Faults returns the faults of the rules of a service. The invalid rules, see
+// apihub.FaultRule.Validate, are logged and left out, so they never keep the
+// service from being routed.
+func Faults(logger lager.Logger, rules []apihub.FaultRule) []Fault {
+	var faults []Fault
+	for _, rule := range rules {
+		if errs := rule.Validate(); len(errs) > 0 {
+			logger.Info("invalid-fault-ignored", lager.Data{"fault": rule, "errors": errs})
+			continue
+		}
+		faults = append(faults, Fault{
+			Header:      rule.Header,
+			HeaderValue: rule.HeaderValue,
+			Percentage:  rule.Percentage,
+			Delay:       time.Duration(rule.Delay) * time.Millisecond,
+			DelayJitter: time.Duration(rule.DelayJitter) * time.Millisecond,
+			AbortStatus: rule.AbortStatus,
+		})
+	}
+	return faults
+}
+
+func (f Fault) matches(req *http.Request) bool {
+	values, ok := req.Header[http.CanonicalHeaderKey(f.Header)]
+	if !ok {
+		return false
+	}
+	if f.HeaderValue != "" && (len(values) == 0 || values[0] != f.HeaderValue) {
+		return false
+	}
+	return rand.Float64()*100 < f.Percentage
+}
+
+func (f Fault) delay() time.Duration {
+	delay := f.Delay
+	if f.DelayJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(f.DelayJitter)))
+	}
+	return delay
+}
+
+// injectFault applies the first fault matching the request. It returns false
+// when the request has been answered and must not be proxied.
+func injectFault(rw http.ResponseWriter, req *http.Request, faults []Fault, pages map[int]ErrorPage) bool {
+	for _, fault := range faults {
+		if !fault.matches(req) {
+			continue
+		}
+
+		if delay := fault.delay(); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-req.Context().Done():
+				return false
+			}
+		}
+
+		if fault.AbortStatus != 0 {
+			writeError(rw, pages, response{
+				StatusCode: fault.AbortStatus,
+				Body: responseError{
+					ErrType:     "fault_injected",
+					Description: "The request was aborted by a fault rule.",
+				},
+			})
+			return false
+		}
+		return true
+	}
+	return true
+}
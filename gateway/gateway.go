@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
-	"time"
 
 	"github.com/braintree/manners"
 
@@ -15,22 +14,26 @@ type Gateway struct {
 	sync.RWMutex
 
 	server    *manners.GracefulServer
+	config    ServerConfig
 	rpCreator ReverseProxyCreator
 	Services  map[string]ReverseProxy
 }
 
-func New(port string, rpCreator ReverseProxyCreator) *Gateway {
+func New(port string, rpCreator ReverseProxyCreator, config ServerConfig) *Gateway {
 	gw := &Gateway{
+		config:    config.withDefaults(),
 		rpCreator: rpCreator,
 		Services:  make(map[string]ReverseProxy, 0),
 	}
 
 	gw.server = manners.NewWithServer(&http.Server{
-		Addr:           port,
-		Handler:        gw,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20, // 1MB
+		Addr:              port,
+		Handler:           gw,
+		ReadTimeout:       gw.config.ReadTimeout,
+		ReadHeaderTimeout: gw.config.ReadHeaderTimeout,
+		WriteTimeout:      gw.config.WriteTimeout,
+		IdleTimeout:       gw.config.IdleTimeout,
+		MaxHeaderBytes:    gw.config.MaxHeaderBytes,
 	})
 
 	return gw
@@ -55,15 +58,22 @@ func (gw *Gateway) AddService(logger lager.Logger, spec ReverseProxySpec) error
 	log.Debug("start", lager.Data{"spec": spec})
 	defer log.Debug("end")
 
+	spec.Limits = gw.config.limits(spec.Limits)
 	reverseProxy, err := gw.rpCreator.Create(log, spec)
 	if err != nil {
 		log.Error("failed-to-create-reverse-proxy", err)
 		return err
 	}
 
+	// The new proxy is created before the old one is closed, so the
+	// connections to the backends they share are kept open.
 	gw.Lock()
+	previous, ok := gw.Services[spec.Host]
 	gw.Services[spec.Host] = reverseProxy
 	gw.Unlock()
+	if ok {
+		previous.Close()
+	}
 
 	log.Info("service-added", lager.Data{"spec": spec})
 	return nil
@@ -74,17 +84,33 @@ func (gw *Gateway) RemoveService(logger lager.Logger, host string) error {
 	log.Debug("start", lager.Data{"host": host})
 	defer log.Debug("end")
 
-	if _, ok := gw.Services[host]; !ok {
+	gw.Lock()
+	reverseProxy, ok := gw.Services[host]
+	if !ok {
+		gw.Unlock()
 		return fmt.Errorf("service not found: '%s'", host)
 	}
-
-	gw.Lock()
 	delete(gw.Services, host)
 	gw.Unlock()
+
+	reverseProxy.Close()
 	log.Info("service-removed")
 	return nil
 }
 
+// Status returns the status of the services loaded in the gateway, keyed by
+// host.
+func (gw *Gateway) Status() map[string]ReverseProxyStatus {
+	gw.RLock()
+	defer gw.RUnlock()
+
+	status := make(map[string]ReverseProxyStatus, len(gw.Services))
+	for host, reverseProxy := range gw.Services {
+		status[host] = reverseProxy.Status()
+	}
+	return status
+}
+
 func (gw *Gateway) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	gw.RLock()
 	if reverseProxy, ok := gw.Services[req.Host]; ok {
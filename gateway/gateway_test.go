@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"code.cloudfoundry.org/lager/lagertest"
 	"github.com/apihub/apihub/gateway"
@@ -28,7 +29,7 @@ var _ = Describe("Gateway", func() {
 		fakeReverseProxyCreator = new(gatewayfakes.FakeReverseProxyCreator)
 		fakeReverseProxy = new(gatewayfakes.FakeReverseProxy)
 
-		gw = gateway.New(port, fakeReverseProxyCreator)
+		gw = gateway.New(port, fakeReverseProxyCreator, gateway.ServerConfig{})
 	})
 
 	var spec gateway.ReverseProxySpec
@@ -54,14 +55,14 @@ var _ = Describe("Gateway", func() {
 
 		It("stops accepting new connections", func() {
 			Eventually(func() error {
-				_, err := http.Get(fmt.Sprintf("http://localhost%s", port))
+				_, err := client.Get(fmt.Sprintf("http://localhost%s", port))
 				return err
 			}).ShouldNot(HaveOccurred())
 
 			Expect(gw.Stop()).To(BeTrue())
 
 			Eventually(func() error {
-				_, err := http.Get(fmt.Sprintf("http://localhost%s", port))
+				_, err := client.Get(fmt.Sprintf("http://localhost%s", port))
 				return err
 			}).Should(HaveOccurred())
 		})
@@ -79,11 +80,34 @@ var _ = Describe("Gateway", func() {
 			Expect(gw.AddService(logger, spec)).To(Succeed())
 			Expect(fakeReverseProxyCreator.CreateCallCount()).To(Equal(1))
 			_, serviceSpec := fakeReverseProxyCreator.CreateArgsForCall(0)
-			Expect(serviceSpec).To(Equal(spec))
+			Expect(serviceSpec.Host).To(Equal(spec.Host))
+			Expect(serviceSpec.Backends).To(Equal(spec.Backends))
 
 			Expect(gw.Services[spec.Host]).NotTo(BeNil())
 		})
 
+		It("applies the limits of the server the service does not override", func() {
+			gw = gateway.New(port, fakeReverseProxyCreator, gateway.ServerConfig{MaxBodyBytes: 1024})
+			spec.Limits = gateway.Limits{WriteTimeout: time.Minute}
+
+			Expect(gw.AddService(logger, spec)).To(Succeed())
+			_, serviceSpec := fakeReverseProxyCreator.CreateArgsForCall(0)
+			Expect(serviceSpec.Limits).To(Equal(gateway.Limits{
+				ReadTimeout:    gateway.DEFAULT_READ_TIMEOUT,
+				WriteTimeout:   time.Minute,
+				MaxHeaderBytes: gateway.DEFAULT_MAX_HEADER_BYTES,
+				MaxBodyBytes:   1024,
+			}))
+		})
+
+		It("closes the reverse proxy it replaces", func() {
+			Expect(gw.AddService(logger, spec)).To(Succeed())
+
+			fakeReverseProxyCreator.CreateReturns(new(gatewayfakes.FakeReverseProxy), nil)
+			Expect(gw.AddService(logger, spec)).To(Succeed())
+			Expect(fakeReverseProxy.CloseCallCount()).To(Equal(1))
+		})
+
 		Context("when fails to create a service hostr", func() {
 			BeforeEach(func() {
 				fakeReverseProxyCreator.CreateReturns(nil, errors.New("failed to create hostr"))
@@ -92,6 +116,16 @@ var _ = Describe("Gateway", func() {
 			It("returns an error", func() {
 				Expect(gw.AddService(logger, spec)).To(MatchError(ContainSubstring("failed to create hostr")))
 			})
+
+			It("keeps the reverse proxy in use", func() {
+				fakeReverseProxyCreator.CreateReturns(fakeReverseProxy, nil)
+				Expect(gw.AddService(logger, spec)).To(Succeed())
+
+				fakeReverseProxyCreator.CreateReturns(nil, errors.New("failed to create hostr"))
+				Expect(gw.AddService(logger, spec)).NotTo(Succeed())
+				Expect(gw.Services[spec.Host]).To(Equal(fakeReverseProxy))
+				Expect(fakeReverseProxy.CloseCallCount()).To(Equal(0))
+			})
 		})
 	})
 
@@ -105,6 +139,7 @@ var _ = Describe("Gateway", func() {
 		It("removes an existing service", func() {
 			Expect(gw.RemoveService(logger, spec.Host)).To(Succeed())
 			Expect(gw.Services[spec.Host]).To(BeNil())
+			Expect(fakeReverseProxy.CloseCallCount()).To(Equal(1))
 		})
 
 		Context("when service is not found", func() {
@@ -15,6 +15,15 @@ type FakeReverseProxy struct {
 		arg1 http.ResponseWriter
 		arg2 *http.Request
 	}
+	StatusStub        func() gateway.ReverseProxyStatus
+	statusMutex       sync.RWMutex
+	statusArgsForCall []struct{}
+	statusReturns     struct {
+		result1 gateway.ReverseProxyStatus
+	}
+	CloseStub        func()
+	closeMutex       sync.RWMutex
+	closeArgsForCall []struct{}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -44,11 +53,56 @@ func (fake *FakeReverseProxy) ServeHTTPArgsForCall(i int) (http.ResponseWriter,
 	return fake.serveHTTPArgsForCall[i].arg1, fake.serveHTTPArgsForCall[i].arg2
 }
 
+func (fake *FakeReverseProxy) Status() gateway.ReverseProxyStatus {
+	fake.statusMutex.Lock()
+	fake.statusArgsForCall = append(fake.statusArgsForCall, struct{}{})
+	fake.recordInvocation("Status", []interface{}{})
+	fake.statusMutex.Unlock()
+	if fake.StatusStub != nil {
+		return fake.StatusStub()
+	} else {
+		return fake.statusReturns.result1
+	}
+}
+
+func (fake *FakeReverseProxy) StatusCallCount() int {
+	fake.statusMutex.RLock()
+	defer fake.statusMutex.RUnlock()
+	return len(fake.statusArgsForCall)
+}
+
+func (fake *FakeReverseProxy) StatusReturns(result1 gateway.ReverseProxyStatus) {
+	fake.StatusStub = nil
+	fake.statusReturns = struct {
+		result1 gateway.ReverseProxyStatus
+	}{result1}
+}
+
+func (fake *FakeReverseProxy) Close() {
+	fake.closeMutex.Lock()
+	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
+	fake.recordInvocation("Close", []interface{}{})
+	fake.closeMutex.Unlock()
+	if fake.CloseStub != nil {
+		fake.CloseStub()
+	}
+}
+
+func (fake *FakeReverseProxy) CloseCallCount() int {
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return len(fake.closeArgsForCall)
+}
+
 func (fake *FakeReverseProxy) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.serveHTTPMutex.RLock()
 	defer fake.serveHTTPMutex.RUnlock()
+	fake.statusMutex.RLock()
+	defer fake.statusMutex.RUnlock()
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	DEFAULT_READ_TIMEOUT     = 10 * time.Second
+	DEFAULT_WRITE_TIMEOUT    = 10 * time.Second
+	DEFAULT_MAX_HEADER_BYTES = 1 << 20 // 1MB
+)
+
+// ServerConfig holds the timeouts and limits of the gateway server. Zero
+// values for the read and write timeouts and for MaxHeaderBytes fall back to
+// the defaults; a zero MaxBodyBytes means the body size is not limited.
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64
+}
+
+// Limits holds the limits applied to the requests of a service. Idle and
+// read header timeouts are not part of it: they are enforced before the
+// gateway knows which service a connection is for.
+type Limits struct {
+	ReadTimeout  time.Duration `json:"read_timeout,omitempty"`
+	WriteTimeout time.Duration `json:"write_timeout,omitempty"`
+	// MaxHeaderBytes cannot go beyond the limit of the gateway server.
+	MaxHeaderBytes int   `json:"max_header_bytes,omitempty"`
+	MaxBodyBytes   int64 `json:"max_body_bytes,omitempty"`
+}
+
+func (c ServerConfig) withDefaults() ServerConfig {
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = DEFAULT_READ_TIMEOUT
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = DEFAULT_WRITE_TIMEOUT
+	}
+	if c.MaxHeaderBytes == 0 {
+		c.MaxHeaderBytes = DEFAULT_MAX_HEADER_BYTES
+	}
+	return c
+}
+
+// limits returns the service limits, completed with the ones of the server
+// for those the service does not override.
+func (c ServerConfig) limits(l Limits) Limits {
+	if l.ReadTimeout == 0 {
+		l.ReadTimeout = c.ReadTimeout
+	}
+	if l.WriteTimeout == 0 {
+		l.WriteTimeout = c.WriteTimeout
+	}
+	if l.MaxHeaderBytes == 0 {
+		l.MaxHeaderBytes = c.MaxHeaderBytes
+	}
+	if l.MaxBodyBytes == 0 {
+		l.MaxBodyBytes = c.MaxBodyBytes
+	}
+	return l
+}
+
+// applyLimits enforces the limits on the request, returning false when the
+// request was rejected.
+func applyLimits(rw http.ResponseWriter, req *http.Request, limits Limits, pages map[int]ErrorPage) bool {
+	if limits.MaxHeaderBytes > 0 && headerSize(req) > limits.MaxHeaderBytes {
+		writeError(rw, pages, response{
+			StatusCode: http.StatusRequestHeaderFieldsTooLarge,
+			Body: responseError{
+				ErrType:     "request_header_fields_too_large",
+				Description: "The request headers are larger than the service accepts.",
+			},
+		})
+		return false
+	}
+
+	if limits.MaxBodyBytes > 0 {
+		if req.ContentLength > limits.MaxBodyBytes {
+			writeError(rw, pages, entityTooLarge())
+			return false
+		}
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(rw, req.Body, limits.MaxBodyBytes)
+		}
+	}
+
+	// Deadlines are set on the connection, so they are not supported by every
+	// ResponseWriter (e.g. when the gateway is not the one serving it).
+	rc := http.NewResponseController(rw)
+	if limits.ReadTimeout > 0 {
+		rc.SetReadDeadline(time.Now().Add(limits.ReadTimeout))
+	}
+	if limits.WriteTimeout > 0 {
+		rc.SetWriteDeadline(time.Now().Add(limits.WriteTimeout))
+	}
+
+	return true
+}
+
+func entityTooLarge() response {
+	return response{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Body: responseError{
+			ErrType:     "request_entity_too_large",
+			Description: "The request body is larger than the service accepts.",
+		},
+	}
+}
+
+// headerSize approximates the size of the request line and headers the same
+// way the http server does when enforcing MaxHeaderBytes.
+func headerSize(req *http.Request) int {
+	size := len(req.Method) + len(req.RequestURI) + len(req.Proto) + 4
+	for name, values := range req.Header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4
+		}
+	}
+	return size
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -26,19 +29,68 @@ type ReverseProxyCreator interface {
 
 type ReverseProxy interface {
 	ServeHTTP(http.ResponseWriter, *http.Request)
+	// Status returns the spec in use and the state of each backend.
+	Status() ReverseProxyStatus
+	// Close releases the connections to the backends. It is called once the
+	// proxy is no longer routable.
+	Close()
 }
 
 type ReverseProxySpec struct {
-	Host     string
-	Backends []string
+	Host     string   `json:"host"`
+	Backends []string `json:"backends"`
 	// Timeout in Milliseconds
-	Timeout time.Duration
+	Timeout time.Duration `json:"timeout"`
+	// Maintenance, when set, answers every request with 503 instead of
+	// proxying it.
+	Maintenance *Maintenance `json:"maintenance,omitempty"`
+	// ErrorPages overrides the body of the errors generated by the gateway,
+	// keyed by status code.
+	ErrorPages map[int]ErrorPage `json:"error_pages,omitempty"`
+	// Limits overrides the limits of the gateway server for the service.
+	Limits Limits `json:"limits"`
+	// Transport holds the settings of the connections to the backends.
+	Transport TransportSettings `json:"transport"`
+	// Faults injects delays and errors in the requests to the service.
+	Faults []Fault `json:"faults,omitempty"`
+	// Circuit, when set, stops sending requests to the backends failing
+	// consecutively.
+	Circuit *CircuitSettings `json:"circuit,omitempty"`
 }
 
-type reverseProxyCreator struct{}
+// Maintenance holds the response sent while a service is under maintenance.
+type Maintenance struct {
+	Body        string        `json:"body,omitempty"`
+	ContentType string        `json:"content_type,omitempty"`
+	RetryAfter  time.Duration `json:"retry_after,omitempty"`
+}
+
+// ErrorPage holds a custom body for an error generated by the gateway.
+type ErrorPage struct {
+	Body        string `json:"body"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// ReverseProxyStatus holds the effective spec of a service loaded in the
+// gateway and the state of its backends.
+type ReverseProxyStatus struct {
+	Spec     ReverseProxySpec `json:"spec"`
+	Backends []BackendStatus  `json:"backends"`
+}
+
+type reverseProxyCreator struct {
+	pool *TransportPool
+}
 
 func NewReverseProxyCreator() *reverseProxyCreator {
-	return &reverseProxyCreator{}
+	return &reverseProxyCreator{
+		pool: NewTransportPool(),
+	}
+}
+
+// Pool returns the transports shared by the reverse proxies.
+func (rpc *reverseProxyCreator) Pool() *TransportPool {
+	return rpc.pool
 }
 
 func (rpc *reverseProxyCreator) Create(logger lager.Logger, spec ReverseProxySpec) (ReverseProxy, error) {
@@ -46,8 +98,12 @@ func (rpc *reverseProxyCreator) Create(logger lager.Logger, spec ReverseProxySpe
 	log.Info("start", lager.Data{"spec": spec})
 	defer log.Info("end")
 
-	if len(spec.Backends) == 0 {
-		return nil, emptyBackendList
+	// A service without backends is only routable when it has something to
+	// answer with: the maintenance response or its own not found page.
+	if len(spec.Backends) == 0 && spec.Maintenance == nil {
+		if _, ok := spec.ErrorPages[http.StatusNotFound]; !ok {
+			return nil, emptyBackendList
+		}
 	}
 
 	timeout := DEFAULT_TIMEOUT
@@ -55,20 +111,128 @@ func (rpc *reverseProxyCreator) Create(logger lager.Logger, spec ReverseProxySpe
 		timeout = spec.Timeout
 	}
 
+	targets := []*url.URL{}
+	for _, address := range spec.Backends {
+		target, err := url.Parse(address)
+		if err != nil {
+			log.Error("failed-to-parse-backend", err, lager.Data{"backend": address})
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	// Backends sharing a host share their circuit and their transport.
+	backends := []*backend{}
+	hosts := map[string]*backend{}
+	for i, target := range targets {
+		be := &backend{address: spec.Backends[i], target: target}
+		if shared, ok := hosts[target.Host]; ok {
+			be.breaker = shared.breaker
+			be.transport = shared.transport
+		} else {
+			be.breaker = newCircuitBreaker(spec.Circuit)
+			be.transport = rpc.pool.Acquire(target, spec.Transport)
+			hosts[target.Host] = be
+		}
+		backends = append(backends, be)
+	}
+
 	return &reverseProxy{
-		spec: spec,
+		spec:     spec,
+		pool:     rpc.pool,
+		backends: backends,
+		hosts:    hosts,
 		rp: &httputil.ReverseProxy{
-			Director:  director(logger, spec),
-			Transport: roundTripper(logger, timeout),
+			Director:  director(logger, backends),
+			Transport: roundTripper(logger, timeout, spec.ErrorPages, hosts),
 		},
 	}, nil
 }
 
+type backend struct {
+	address   string
+	target    *url.URL
+	breaker   *circuitBreaker
+	transport *http.Transport
+}
+
 type reverseProxy struct {
-	spec ReverseProxySpec
-	rp   *httputil.ReverseProxy
+	spec      ReverseProxySpec
+	pool      *TransportPool
+	backends  []*backend
+	hosts     map[string]*backend
+	closeOnce sync.Once
+	rp        *httputil.ReverseProxy
+}
+
+func (n *reverseProxy) Close() {
+	n.closeOnce.Do(func() {
+		for _, be := range n.hosts {
+			n.pool.Release(be.target, n.spec.Transport)
+		}
+	})
+}
+
+func (n *reverseProxy) Status() ReverseProxyStatus {
+	status := ReverseProxyStatus{
+		Spec:     n.spec,
+		Backends: []BackendStatus{},
+	}
+	for _, be := range n.backends {
+		backendStatus := be.breaker.Status()
+		backendStatus.Address = be.address
+		status.Backends = append(status.Backends, backendStatus)
+	}
+	return status
 }
 
 func (n *reverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if n.spec.Maintenance != nil {
+		n.serveMaintenance(rw)
+		return
+	}
+
+	if !injectFault(rw, req, n.spec.Faults, n.spec.ErrorPages) {
+		return
+	}
+
+	if !applyLimits(rw, req, n.spec.Limits, n.spec.ErrorPages) {
+		return
+	}
+
+	if len(n.spec.Backends) == 0 {
+		writeError(rw, n.spec.ErrorPages, response{
+			StatusCode: http.StatusNotFound,
+			Body: responseError{
+				ErrType:     "not_found",
+				Description: "The requested resource could not be found but may be available again in the future.",
+			},
+		})
+		return
+	}
+
 	n.rp.ServeHTTP(rw, req)
 }
+
+func (n *reverseProxy) serveMaintenance(rw http.ResponseWriter) {
+	maintenance := n.spec.Maintenance
+	if maintenance.RetryAfter > 0 {
+		seconds := (maintenance.RetryAfter + time.Second - 1) / time.Second
+		rw.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+
+	var pages map[int]ErrorPage
+	if maintenance.Body != "" {
+		pages = map[int]ErrorPage{
+			http.StatusServiceUnavailable: ErrorPage{Body: maintenance.Body, ContentType: maintenance.ContentType},
+		}
+	}
+
+	writeError(rw, pages, response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body: responseError{
+			ErrType:     "service_unavailable",
+			Description: "The service is under maintenance.",
+		},
+	})
+}
@@ -1,12 +1,18 @@
 package gateway_test
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"code.cloudfoundry.org/lager/lagertest"
 
+	"github.com/apihub/apihub"
 	"github.com/apihub/apihub/gateway"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -70,6 +76,478 @@ var _ = Describe("ReverseProxyCreator", func() {
 				_, err := creator.Create(logger, badSpec)
 				Expect(err).To(MatchError(ContainSubstring("Backends cannot be empty")))
 			})
+
+			Context("and the service has a custom not found page", func() {
+				BeforeEach(func() {
+					badSpec.ErrorPages = map[int]gateway.ErrorPage{
+						http.StatusNotFound: gateway.ErrorPage{Body: "<h1>Gone fishing</h1>"},
+					}
+				})
+
+				It("answers with the custom page", func() {
+					reverseProxy, err := creator.Create(logger, badSpec)
+					Expect(err).NotTo(HaveOccurred())
+
+					req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+					Expect(err).NotTo(HaveOccurred())
+
+					rw := httptest.NewRecorder()
+					reverseProxy.ServeHTTP(rw, req)
+
+					Expect(rw.Code).To(Equal(http.StatusNotFound))
+					Expect(rw.Body.String()).To(Equal("<h1>Gone fishing</h1>"))
+					Expect(rw.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+				})
+			})
+		})
+
+		Context("when the service has limits", func() {
+			JustBeforeEach(func() {
+				spec.Limits = gateway.Limits{MaxHeaderBytes: 256, MaxBodyBytes: 8}
+			})
+
+			It("rejects bodies larger than the limit", func() {
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				req, err := http.NewRequest(http.MethodPost, "http://my-host.apihub.dev?foo=bar&bar=foo", strings.NewReader("a body too large"))
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(rw.Body.String()).To(ContainSubstring(`"error":"request_entity_too_large"`))
+			})
+
+			It("rejects streamed bodies larger than the limit", func() {
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				req, err := http.NewRequest(http.MethodPost, "http://my-host.apihub.dev?foo=bar&bar=foo", ioutil.NopCloser(strings.NewReader("a body too large")))
+				Expect(err).NotTo(HaveOccurred())
+				req.ContentLength = -1
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			})
+
+			It("keeps the circuit closed when the bodies are larger than the limit", func() {
+				spec.Circuit = &gateway.CircuitSettings{Threshold: 2}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				for i := 0; i < 5; i++ {
+					req, err := http.NewRequest(http.MethodPost, "http://my-host.apihub.dev?foo=bar&bar=foo", ioutil.NopCloser(strings.NewReader("a body too large")))
+					Expect(err).NotTo(HaveOccurred())
+					req.ContentLength = -1
+
+					rw := httptest.NewRecorder()
+					reverseProxy.ServeHTTP(rw, req)
+					Expect(rw.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				}
+
+				status := reverseProxy.Status()
+				Expect(status.Backends[0].Circuit).To(Equal(gateway.CircuitClosed))
+				Expect(status.Backends[0].Failures).To(BeZero())
+
+				req, err := http.NewRequest(http.MethodPost, "http://my-host.apihub.dev?foo=bar&bar=foo", strings.NewReader("small"))
+				Expect(err).NotTo(HaveOccurred())
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+
+			It("rejects headers larger than the limit", func() {
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev?foo=bar&bar=foo", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("X-Large", strings.Repeat("a", 512))
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Code).To(Equal(http.StatusRequestHeaderFieldsTooLarge))
+			})
+
+			It("proxies requests within the limits", func() {
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				req, err := http.NewRequest(http.MethodPost, "http://my-host.apihub.dev?foo=bar&bar=foo", strings.NewReader("small"))
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+		})
+
+		Context("when the service is under maintenance", func() {
+			var req *http.Request
+
+			BeforeEach(func() {
+				var err error
+				req, err = http.NewRequest(http.MethodGet, "http://my-host.apihub.dev?foo=bar&bar=foo", nil)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("answers with service unavailable", func() {
+				spec.Maintenance = &gateway.Maintenance{}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+
+				Expect(rw.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(rw.Body.String()).To(Equal(`{"error":"service_unavailable","error_description":"The service is under maintenance."}`))
+				Expect(rw.Header().Get("Content-Type")).To(Equal("application/json"))
+				Expect(rw.Header().Get("Retry-After")).To(BeEmpty())
+			})
+
+			It("answers with the configured body and retry after", func() {
+				spec.Maintenance = &gateway.Maintenance{
+					Body:        "Back soon.",
+					ContentType: "text/plain",
+					RetryAfter:  90 * time.Second,
+				}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+
+				Expect(rw.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(rw.Body.String()).To(Equal("Back soon."))
+				Expect(rw.Header().Get("Content-Type")).To(Equal("text/plain"))
+				Expect(rw.Header().Get("Retry-After")).To(Equal("90"))
+			})
+		})
+
+		Context("when the service has faults", func() {
+			var req *http.Request
+
+			BeforeEach(func() {
+				var err error
+				req, err = http.NewRequest(http.MethodGet, "http://my-host.apihub.dev?foo=bar&bar=foo", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("X-Chaos", "on")
+			})
+
+			It("aborts the matching requests with the status code", func() {
+				spec.Faults = []gateway.Fault{
+					gateway.Fault{Header: "X-Chaos", Percentage: 100, AbortStatus: http.StatusServiceUnavailable},
+				}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(rw.Body.String()).To(Equal(`{"error":"fault_injected","error_description":"The request was aborted by a fault rule."}`))
+			})
+
+			It("delays the matching requests", func() {
+				spec.Faults = []gateway.Fault{
+					gateway.Fault{Header: "X-Chaos", HeaderValue: "on", Percentage: 100, Delay: 30 * time.Millisecond},
+				}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				started := time.Now()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(time.Since(started)).To(BeNumerically(">=", 30*time.Millisecond))
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+
+			It("does not affect requests without the header", func() {
+				spec.Faults = []gateway.Fault{
+					gateway.Fault{Header: "X-Chaos", HeaderValue: "off", Percentage: 100, AbortStatus: http.StatusInternalServerError},
+				}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+
+			It("does not affect requests out of the percentage", func() {
+				spec.Faults = []gateway.Fault{
+					gateway.Fault{Header: "X-Chaos", Percentage: 0, AbortStatus: http.StatusInternalServerError},
+				}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+
+			It("ignores the invalid fault rules", func() {
+				spec.Faults = gateway.Faults(logger, []apihub.FaultRule{
+					apihub.FaultRule{Header: "X-Chaos", Percentage: 150, AbortStatus: http.StatusServiceUnavailable},
+					apihub.FaultRule{Header: "X-Chaos", Percentage: 100, Delay: -10},
+				})
+				Expect(spec.Faults).To(BeEmpty())
+
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+
+			It("converts the valid fault rules", func() {
+				faults := gateway.Faults(logger, []apihub.FaultRule{
+					apihub.FaultRule{Header: "X-Chaos", Percentage: 150},
+					apihub.FaultRule{Header: "X-Chaos", HeaderValue: "on", Percentage: 50, Delay: 30, DelayJitter: 10, AbortStatus: 503},
+				})
+				Expect(faults).To(Equal([]gateway.Fault{
+					gateway.Fault{Header: "X-Chaos", HeaderValue: "on", Percentage: 50, Delay: 30 * time.Millisecond, DelayJitter: 10 * time.Millisecond, AbortStatus: 503},
+				}))
+			})
+		})
+
+		Context("when the backend is not reachable", func() {
+			It("answers with the custom bad gateway page", func() {
+				spec.Backends = []string{"http://127.0.0.1:1"}
+				spec.ErrorPages = map[int]gateway.ErrorPage{
+					http.StatusBadGateway: gateway.ErrorPage{Body: `{"message":"try again"}`, ContentType: "application/json"},
+				}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+
+				Expect(rw.Code).To(Equal(http.StatusBadGateway))
+				Expect(rw.Body.String()).To(Equal(`{"message":"try again"}`))
+			})
+
+			It("keeps sending requests to the backend when the service has no circuit breaker", func() {
+				spec.Backends = []string{"http://127.0.0.1:1"}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				for i := 0; i < 10; i++ {
+					req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+					Expect(err).NotTo(HaveOccurred())
+					rw := httptest.NewRecorder()
+					reverseProxy.ServeHTTP(rw, req)
+					Expect(rw.Code).To(Equal(http.StatusBadGateway))
+				}
+
+				status := reverseProxy.Status()
+				Expect(status.Backends[0].Healthy).To(BeFalse())
+				Expect(status.Backends[0].Circuit).To(Equal(gateway.CircuitDisabled))
+				Expect(status.Backends[0].Failures).To(BeEquivalentTo(10))
+			})
+
+			It("opens the circuit after consecutive failures", func() {
+				spec.Backends = []string{"http://127.0.0.1:1"}
+				spec.Circuit = &gateway.CircuitSettings{Threshold: 3}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				for i := 0; i < 3; i++ {
+					req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+					Expect(err).NotTo(HaveOccurred())
+					rw := httptest.NewRecorder()
+					reverseProxy.ServeHTTP(rw, req)
+					Expect(rw.Code).To(Equal(http.StatusBadGateway))
+				}
+
+				req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+				Expect(err).NotTo(HaveOccurred())
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Code).To(Equal(http.StatusServiceUnavailable))
+
+				status := reverseProxy.Status()
+				Expect(status.Spec).To(Equal(spec))
+				Expect(status.Backends).To(HaveLen(1))
+				Expect(status.Backends[0].Address).To(Equal("http://127.0.0.1:1"))
+				Expect(status.Backends[0].Healthy).To(BeFalse())
+				Expect(status.Backends[0].Circuit).To(Equal(gateway.CircuitOpen))
+				Expect(status.Backends[0].Failures).To(BeEquivalentTo(3))
+			})
+
+			It("lets a single trial request through once the cooldown is over", func() {
+				var failing int32 = 1
+				release := make(chan struct{})
+				received := make(chan struct{}, 10)
+				flaky := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					if atomic.LoadInt32(&failing) == 1 {
+						conn, _, _ := rw.(http.Hijacker).Hijack()
+						conn.Close()
+						return
+					}
+					received <- struct{}{}
+					<-release
+				}))
+				defer flaky.Close()
+
+				spec.Backends = []string{flaky.URL}
+				spec.Circuit = &gateway.CircuitSettings{Threshold: 1, Cooldown: 10 * time.Millisecond}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				serve := func() int {
+					req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+					Expect(err).NotTo(HaveOccurred())
+					rw := httptest.NewRecorder()
+					reverseProxy.ServeHTTP(rw, req)
+					return rw.Code
+				}
+
+				Expect(serve()).To(Equal(http.StatusBadGateway))
+				Expect(serve()).To(Equal(http.StatusServiceUnavailable))
+
+				time.Sleep(20 * time.Millisecond)
+				atomic.StoreInt32(&failing, 0)
+				trial := make(chan int)
+				go func() {
+					defer GinkgoRecover()
+					trial <- serve()
+				}()
+				Eventually(received).Should(Receive())
+
+				Expect(serve()).To(Equal(http.StatusServiceUnavailable))
+				Expect(reverseProxy.Status().Backends[0].Circuit).To(Equal(gateway.CircuitHalfOpen))
+
+				close(release)
+				Eventually(trial).Should(Receive(Equal(http.StatusOK)))
+				Expect(reverseProxy.Status().Backends[0].Circuit).To(Equal(gateway.CircuitClosed))
+				Expect(serve()).To(Equal(http.StatusOK))
+			})
+
+			It("leaves the requests canceled by the client out of the circuit", func() {
+				spec.Backends = []string{"http://127.0.0.1:1"}
+				spec.Circuit = &gateway.CircuitSettings{Threshold: 1}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://my-host.apihub.dev", nil)
+				Expect(err).NotTo(HaveOccurred())
+				reverseProxy.ServeHTTP(httptest.NewRecorder(), req)
+
+				status := reverseProxy.Status()
+				Expect(status.Backends[0].Circuit).To(Equal(gateway.CircuitClosed))
+				Expect(status.Backends[0].Failures).To(BeZero())
+			})
+
+			It("fails over to the next backend once the circuit is open", func() {
+				spec.Backends = []string{"http://127.0.0.1:1", spec.Backends[0]}
+				spec.Circuit = &gateway.CircuitSettings{Threshold: 3}
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				for i := 0; i < 3; i++ {
+					req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+					Expect(err).NotTo(HaveOccurred())
+					reverseProxy.ServeHTTP(httptest.NewRecorder(), req)
+				}
+
+				req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev?foo=bar&bar=foo", nil)
+				Expect(err).NotTo(HaveOccurred())
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+		})
+
+		Context("when the backend does not answer in time", func() {
+			var slowServer *httptest.Server
+
+			BeforeEach(func() {
+				slowServer = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					time.Sleep(50 * time.Millisecond)
+				}))
+			})
+
+			AfterEach(func() {
+				slowServer.Close()
+			})
+
+			It("answers with gateway timeout", func() {
+				spec.Backends = []string{slowServer.URL}
+				spec.Timeout = 10 * time.Millisecond
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+				Expect(err).NotTo(HaveOccurred())
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Code).To(Equal(http.StatusGatewayTimeout))
+				Expect(rw.Body.String()).To(ContainSubstring("gateway_timeout"))
+			})
+		})
+
+		Context("when the backend sends the body slowly", func() {
+			var streamingServer *httptest.Server
+
+			BeforeEach(func() {
+				streamingServer = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					rw.Write([]byte("Hello"))
+					rw.(http.Flusher).Flush()
+					time.Sleep(50 * time.Millisecond)
+					rw.Write([]byte(" world."))
+				}))
+			})
+
+			AfterEach(func() {
+				streamingServer.Close()
+			})
+
+			It("only bounds the wait for the response headers by the timeout", func() {
+				spec.Backends = []string{streamingServer.URL}
+				spec.Timeout = 20 * time.Millisecond
+				reverseProxy, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+
+				req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev", nil)
+				Expect(err).NotTo(HaveOccurred())
+				rw := httptest.NewRecorder()
+				reverseProxy.ServeHTTP(rw, req)
+				Expect(rw.Code).To(Equal(http.StatusOK))
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+			})
+		})
+
+		Context("when the backend is used by other proxies", func() {
+			It("shares the transport until the last proxy is closed", func() {
+				creator := gateway.NewReverseProxyCreator()
+				pool := creator.Pool()
+
+				first, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+				second, err := creator.Create(logger, spec)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pool.Len()).To(Equal(1))
+
+				first.Close()
+				Expect(pool.Len()).To(Equal(1))
+
+				req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev?foo=bar&bar=foo", nil)
+				Expect(err).NotTo(HaveOccurred())
+				rw := httptest.NewRecorder()
+				second.ServeHTTP(rw, req)
+				Expect(rw.Body.String()).To(Equal("Hello world."))
+
+				second.Close()
+				Expect(pool.Len()).To(Equal(0))
+			})
 		})
 	})
 
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/apihub/apihub"
+)
+
+// ApplyService routes the service as its spec says, removing it from the
+// gateway when it is disabled. A disabled service under maintenance keeps
+// its route, so its callers get the maintenance response instead of a not
+// found.
+func (gw *Gateway) ApplyService(logger lager.Logger, spec apihub.ServiceSpec) error {
+	log := logger.Session("apply-service")
+	log.Debug("start", lager.Data{"host": spec.Host})
+	defer log.Debug("end")
+
+	underMaintenance := spec.Maintenance != nil && spec.Maintenance.Enabled
+	if spec.Disabled && !underMaintenance {
+		gw.RemoveService(log, spec.Host)
+		return nil
+	}
+
+	if err := gw.AddService(log, NewReverseProxySpec(log, spec)); err != nil {
+		// Stop routing to backends that are no longer valid.
+		gw.RemoveService(log, spec.Host)
+		return err
+	}
+	return nil
+}
+
+// NewReverseProxySpec returns the spec of the reverse proxy routing the
+// service.
+func NewReverseProxySpec(logger lager.Logger, spec apihub.ServiceSpec) ReverseProxySpec {
+	var backends []string
+	for _, be := range spec.Backends {
+		// Disabled backends, see the backend endpoints of the API, take no
+		// traffic.
+		if be.Disabled {
+			continue
+		}
+		backends = append(backends, be.Address)
+	}
+
+	proxySpec := ReverseProxySpec{
+		Host:     spec.Host,
+		Backends: backends,
+		Timeout:  time.Duration(spec.Timeout),
+	}
+
+	if spec.Maintenance != nil && spec.Maintenance.Enabled {
+		proxySpec.Maintenance = &Maintenance{
+			Body:        spec.Maintenance.Body,
+			ContentType: spec.Maintenance.ContentType,
+			RetryAfter:  time.Duration(spec.Maintenance.RetryAfter) * time.Second,
+		}
+	}
+
+	if len(spec.ErrorPages) > 0 {
+		proxySpec.ErrorPages = make(map[int]ErrorPage, len(spec.ErrorPages))
+		for status, page := range spec.ErrorPages {
+			proxySpec.ErrorPages[status] = ErrorPage{
+				Body:        page.Body,
+				ContentType: page.ContentType,
+			}
+		}
+	}
+
+	if spec.Limits != nil {
+		proxySpec.Limits = Limits{
+			ReadTimeout:    time.Duration(spec.Limits.ReadTimeout) * time.Millisecond,
+			WriteTimeout:   time.Duration(spec.Limits.WriteTimeout) * time.Millisecond,
+			MaxHeaderBytes: spec.Limits.MaxHeaderBytes,
+			MaxBodyBytes:   spec.Limits.MaxBodyBytes,
+		}
+	}
+
+	if spec.Transport != nil {
+		proxySpec.Transport = TransportSettings{
+			MaxIdleConns:          spec.Transport.MaxIdleConns,
+			MaxConnsPerHost:       spec.Transport.MaxConnsPerHost,
+			KeepAlive:             time.Duration(spec.Transport.KeepAlive) * time.Millisecond,
+			TLSServerName:         spec.Transport.TLSServerName,
+			TLSInsecureSkipVerify: spec.Transport.TLSInsecureSkipVerify,
+		}
+	}
+
+	if spec.CircuitBreaker != nil {
+		proxySpec.Circuit = &CircuitSettings{
+			Threshold: spec.CircuitBreaker.Threshold,
+			Cooldown:  time.Duration(spec.CircuitBreaker.Cooldown) * time.Millisecond,
+		}
+	}
+
+	proxySpec.Faults = Faults(logger, spec.Faults)
+
+	return proxySpec
+}
@@ -0,0 +1,104 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/gateway"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		gw     *gateway.Gateway
+		logger *lagertest.TestLogger
+		spec   apihub.ServiceSpec
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-service")
+		gw = gateway.New(":0", gateway.NewReverseProxyCreator(), gateway.ServerConfig{})
+		spec = apihub.ServiceSpec{
+			Host:     "my-host.apihub.dev",
+			Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://127.0.0.1:1"}},
+		}
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "http://my-host.apihub.dev/ping", nil)
+		Expect(err).NotTo(HaveOccurred())
+		rw := httptest.NewRecorder()
+		gw.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describe("ApplyService", func() {
+		It("routes the service", func() {
+			Expect(gw.ApplyService(logger, spec)).To(Succeed())
+			Expect(gw.Status()).To(HaveKey(spec.Host))
+		})
+
+		It("removes the disabled services", func() {
+			Expect(gw.ApplyService(logger, spec)).To(Succeed())
+
+			spec.Disabled = true
+			Expect(gw.ApplyService(logger, spec)).To(Succeed())
+			Expect(gw.Status()).NotTo(HaveKey(spec.Host))
+			Expect(serve().Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("keeps routing the disabled services under maintenance", func() {
+			spec.Disabled = true
+			spec.Maintenance = &apihub.MaintenanceInfo{Enabled: true, Body: "Back soon.", ContentType: "text/plain", RetryAfter: 60}
+			Expect(gw.ApplyService(logger, spec)).To(Succeed())
+
+			rw := serve()
+			Expect(rw.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(rw.Body.String()).To(Equal("Back soon."))
+			Expect(rw.Header().Get("Retry-After")).To(Equal("60"))
+		})
+
+		It("removes the services that can no longer be routed", func() {
+			Expect(gw.ApplyService(logger, spec)).To(Succeed())
+
+			spec.Backends = nil
+			Expect(gw.ApplyService(logger, spec)).NotTo(Succeed())
+			Expect(gw.Status()).NotTo(HaveKey(spec.Host))
+		})
+	})
+
+	Describe("NewReverseProxySpec", func() {
+		It("converts the spec of the service", func() {
+			spec.Timeout = 500
+			spec.Backends = append(spec.Backends, apihub.BackendInfo{Address: "http://127.0.0.1:2", Disabled: true})
+			spec.Maintenance = &apihub.MaintenanceInfo{Enabled: true, RetryAfter: 30}
+			spec.ErrorPages = map[int]apihub.ErrorPage{
+				http.StatusBadGateway: apihub.ErrorPage{Body: "Oops.", ContentType: "text/plain"},
+			}
+			spec.Limits = &apihub.ServiceLimits{WriteTimeout: 2000, MaxBodyBytes: 1024}
+			spec.CircuitBreaker = &apihub.CircuitBreakerInfo{Threshold: 3, Cooldown: 5000}
+
+			Expect(gateway.NewReverseProxySpec(logger, spec)).To(Equal(gateway.ReverseProxySpec{
+				Host:        "my-host.apihub.dev",
+				Backends:    []string{"http://127.0.0.1:1"},
+				Timeout:     500,
+				Maintenance: &gateway.Maintenance{RetryAfter: 30 * time.Second},
+				ErrorPages: map[int]gateway.ErrorPage{
+					http.StatusBadGateway: gateway.ErrorPage{Body: "Oops.", ContentType: "text/plain"},
+				},
+				Limits:  gateway.Limits{WriteTimeout: 2 * time.Second, MaxBodyBytes: 1024},
+				Circuit: &gateway.CircuitSettings{Threshold: 3, Cooldown: 5 * time.Second},
+			}))
+		})
+
+		It("leaves out the maintenance that is not enabled", func() {
+			spec.Maintenance = &apihub.MaintenanceInfo{Body: "Back soon."}
+			Expect(gateway.NewReverseProxySpec(logger, spec).Maintenance).To(BeNil())
+		})
+	})
+})
@@ -2,6 +2,7 @@ package subscriber
 
 import (
 	"encoding/json"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -10,7 +11,8 @@ import (
 )
 
 type Subscriber struct {
-	client *api.Client
+	client  *api.Client
+	version uint64
 }
 
 func NewSubscriber(client *api.Client) *Subscriber {
@@ -19,11 +21,19 @@ func NewSubscriber(client *api.Client) *Subscriber {
 	}
 }
 
+// Subscribe sends the services published under the prefix to servicesCh
+// until stop is closed. The prefix must end with a separator, see
+// apihub.CheckServicesPrefix.
 func (s *Subscriber) Subscribe(logger lager.Logger, prefix string, servicesCh chan apihub.ServiceSpec, stop <-chan struct{}) error {
 	log := logger.Session("subscriber")
 	log.Debug("start")
 	defer log.Debug("end")
 
+	if err := apihub.CheckServicesPrefix(prefix); err != nil {
+		log.Error("invalid-prefix", err)
+		return err
+	}
+
 	go func() {
 		defer logger.Info("done")
 
@@ -57,6 +67,7 @@ func (s *Subscriber) Subscribe(logger lager.Logger, prefix string, servicesCh ch
 
 				keys = newKeys
 			}
+			atomic.StoreUint64(&s.version, queryMeta.LastIndex)
 		}
 	}()
 
@@ -68,6 +79,12 @@ func (s *Subscriber) Subscribe(logger lager.Logger, prefix string, servicesCh ch
 	return nil
 }
 
+// Version returns the Consul index of the last configuration delivered to the
+// services channel.
+func (s *Subscriber) Version() uint64 {
+	return atomic.LoadUint64(&s.version)
+}
+
 type keySet map[string]*api.KVPair
 
 func newKeySet(pairs api.KVPairs) keySet {
@@ -1,6 +1,8 @@
 package subscriber_test
 
 import (
+	"errors"
+
 	"code.cloudfoundry.org/lager/lagertest"
 	"github.com/apihub/apihub"
 	"github.com/apihub/apihub/api/publisher"
@@ -91,6 +93,58 @@ var _ = Describe("Subscriber", func() {
 			Eventually(stop).Should(BeClosed())
 		})
 
+		It("reports the version of the configuration delivered", func() {
+			Expect(sub.Version()).To(BeZero())
+
+			go func() {
+				err := sub.Subscribe(logger, apihub.SERVICES_PREFIX, servicesCh, stop)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+			Expect(pub.Publish(logger, apihub.SERVICES_PREFIX, spec)).To(Succeed())
+			Eventually(servicesCh).Should(Receive(Equal(spec)))
+			Eventually(sub.Version).ShouldNot(BeZero())
+
+			close(stop)
+			Eventually(stop).Should(BeClosed())
+		})
+
+		It("only receives the services published under its prefix", func() {
+			go func() {
+				err := sub.Subscribe(logger, "apihub/prod/services/", servicesCh, stop)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			stagingSpec := apihub.ServiceSpec{Host: "staging-host"}
+			Expect(pub.Publish(logger, "apihub/staging/services/", stagingSpec)).To(Succeed())
+			Expect(pub.Publish(logger, "apihub/prod/services/", spec)).To(Succeed())
+			Eventually(servicesCh).Should(Receive(Equal(spec)))
+			Consistently(servicesCh).ShouldNot(Receive())
+
+			close(stop)
+			Eventually(stop).Should(BeClosed())
+		})
+
+		It("does not receive the services of a prefix starting with its own", func() {
+			go func() {
+				err := sub.Subscribe(logger, "apihub/prod/", servicesCh, stop)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			stagingSpec := apihub.ServiceSpec{Host: "staging-host"}
+			Expect(pub.Publish(logger, "apihub/prod-staging/", stagingSpec)).To(Succeed())
+			Expect(pub.Publish(logger, "apihub/prod/", spec)).To(Succeed())
+			Eventually(servicesCh).Should(Receive(Equal(spec)))
+			Consistently(servicesCh).ShouldNot(Receive())
+
+			close(stop)
+			Eventually(stop).Should(BeClosed())
+		})
+
+		It("rejects a prefix without a trailing separator", func() {
+			err := sub.Subscribe(logger, "apihub/prod", servicesCh, stop)
+			Expect(errors.Is(err, apihub.ErrValidation)).To(BeTrue())
+		})
+
 		Context("when the subscription is stopped", func() {
 			It("closes services channel", func() {
 				close(stop)
@@ -3,7 +3,7 @@ package gateway
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -18,9 +18,15 @@ import (
 	"code.cloudfoundry.org/lager"
 )
 
+// errResponseTimeout is returned when the backend does not send the response
+// headers within the timeout of the service.
+var errResponseTimeout = errors.New("timeout awaiting response headers")
+
 type transport struct {
-	*http.Transport
-	logger lager.Logger
+	logger     lager.Logger
+	timeout    time.Duration
+	errorPages map[int]ErrorPage
+	backends   map[string]*backend
 }
 
 func (r *transport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -35,11 +41,43 @@ func (r *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		req.Header.Set("Via", via)
 	}
 
-	resp, err := r.Transport.RoundTrip(req)
+	be, ok := r.backends[req.URL.Host]
+	if !ok {
+		err := fmt.Errorf("backend not found: '%s'", req.URL.Host)
+		log.Error("failed-to-find-backend", err)
+		return nil, err
+	}
+
+	if !be.breaker.Allow() {
+		log.Info("circuit-open", lager.Data{"backend": req.URL.Host})
+		return r.Response(req, response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body: responseError{
+				ErrType:     "service_unavailable",
+				Description: "The backend is temporarily unavailable.",
+			},
+		}), nil
+	}
+
+	// The transport is shared with other services, so the timeout of the
+	// service is enforced on the request. It only bounds the wait for the
+	// response headers: the body is bounded by the write timeout, see
+	// Limits.
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(r.timeout, func() { cancel(errResponseTimeout) })
+	resp, err := be.transport.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() && err == nil {
+		resp.Body.Close()
+		err = errResponseTimeout
+	}
 	if err == nil {
+		be.breaker.Success()
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
 		via, err = headerVia(resp.Header.Get("Via"), req.ProtoMajor, req.ProtoMinor)
 		if err != nil {
 			log.Error("failed-read-response-via-hader", err)
+			resp.Body.Close()
 			return nil, err
 		}
 		if via != "" {
@@ -47,8 +85,29 @@ func (r *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 		return resp, nil
 	}
+	if context.Cause(ctx) == errResponseTimeout {
+		err = errResponseTimeout
+	}
+	cancel(nil)
+
+	// Neither a body larger than the service accepts nor a client going
+	// away tell anything about the backend, so they are left out of its
+	// circuit.
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		be.breaker.Ignore()
+		log.Info("request-body-too-large", lager.Data{"backend": req.URL.Host})
+		return r.Response(req, entityTooLarge()), nil
+	}
+	if req.Context().Err() != nil {
+		be.breaker.Ignore()
+		log.Info("request-canceled", lager.Data{"backend": req.URL.Host})
+		return nil, err
+	}
 
+	be.breaker.Failure(err)
 	log.Error("failed-round-trip-request", err)
+
 	respErr := response{
 		StatusCode: http.StatusBadGateway,
 		Body: responseError{
@@ -56,15 +115,17 @@ func (r *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 			Description: err.Error(),
 		},
 	}
-	if e, ok := err.(*net.OpError); ok {
-		if e.Timeout() {
-			respErr = response{
-				StatusCode: http.StatusGatewayTimeout,
-				Body: responseError{
-					ErrType:     "gateway_timeout",
-					Description: err.Error(),
-				},
-			}
+	timedOut := err == errResponseTimeout
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		timedOut = true
+	}
+	if timedOut {
+		respErr = response{
+			StatusCode: http.StatusGatewayTimeout,
+			Body: responseError{
+				ErrType:     "gateway_timeout",
+				Description: err.Error(),
+			},
 		}
 	}
 
@@ -82,7 +143,7 @@ type responseError struct {
 }
 
 func (r *transport) Response(req *http.Request, resp response) *http.Response {
-	data, _ := json.Marshal(resp.Body)
+	data, contentType := renderError(r.errorPages, resp.StatusCode, resp.Body)
 	var closerBuffer io.ReadCloser = ioutil.NopCloser(bytes.NewBuffer(data))
 	response := &http.Response{
 		Request:       req,
@@ -94,32 +155,39 @@ func (r *transport) Response(req *http.Request, resp response) *http.Response {
 	}
 
 	response.Header = make(map[string][]string)
-	response.Header.Add("Content-Type", "application/json")
+	response.Header.Add("Content-Type", contentType)
 	return response
 }
 
-func roundTripper(logger lager.Logger, timeout time.Duration) *transport {
+func roundTripper(logger lager.Logger, timeout time.Duration, errorPages map[int]ErrorPage, backends map[string]*backend) *transport {
 	return &transport{
-		logger: logger,
-		Transport: &http.Transport{
-			DialContext:         timeoutDialer(timeout, timeout),
-			Proxy:               http.ProxyFromEnvironment,
-			TLSHandshakeTimeout: timeout * time.Second,
-		},
+		logger:     logger,
+		timeout:    timeout,
+		errorPages: errorPages,
+		backends:   backends,
 	}
 }
 
-func director(logger lager.Logger, spec ReverseProxySpec) func(req *http.Request) {
+// cancelBody releases the context of a request once its response body is
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelCauseFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
+
+func director(logger lager.Logger, backends []*backend) func(req *http.Request) {
 	log := logger.Session("create-director")
 	log.Debug("start")
 	defer log.Debug("end")
 
 	return func(req *http.Request) {
-		backend, err := url.Parse(spec.Backends[0])
-		if err != nil {
-			log.Error("failed-to-parse-backend", err)
-			return
-		}
+		backend := pickBackend(backends)
 
 		targetQuery := backend.RawQuery
 		req.URL.Scheme = backend.Scheme
@@ -137,6 +205,18 @@ func director(logger lager.Logger, spec ReverseProxySpec) func(req *http.Request
 	}
 }
 
+// pickBackend returns the first backend whose circuit lets requests through,
+// falling back to the first one so the transport can report the open circuit.
+// The request is only admitted by the transport.
+func pickBackend(backends []*backend) *url.URL {
+	for _, be := range backends {
+		if be.breaker.Available() {
+			return be.target
+		}
+	}
+	return backends[0].target
+}
+
 func headerVia(original string, protoMajor int, protoMinor int) (string, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -146,15 +226,3 @@ func headerVia(original string, protoMajor int, protoMinor int) (string, error)
 	via := strings.Join([]string{original, fmt.Sprintf("%d.%d %s", protoMajor, protoMinor, hostname)}, ", ")
 	return strings.Trim(via, ", "), nil
 }
-
-func timeoutDialer(timeout time.Duration, rwTimeout time.Duration) func(ctx context.Context, network string, addr string) (net.Conn, error) {
-	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
-		conn, err := net.DialTimeout(network, addr, timeout)
-		if err != nil {
-			return nil, err
-		}
-
-		conn.SetDeadline(time.Now().Add(rwTimeout))
-		return conn, nil
-	}
-}
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_MAX_IDLE_CONNS        = 100
+	DEFAULT_KEEP_ALIVE            = 30 * time.Second
+	DEFAULT_IDLE_CONN_TIMEOUT     = 90 * time.Second
+	DEFAULT_TLS_HANDSHAKE_TIMEOUT = 10 * time.Second
+)
+
+// TransportSettings holds the settings of the connections to a backend.
+type TransportSettings struct {
+	MaxIdleConns    int           `json:"max_idle_conns,omitempty"`
+	MaxConnsPerHost int           `json:"max_conns_per_host,omitempty"`
+	KeepAlive       time.Duration `json:"keep_alive,omitempty"`
+
+	TLSServerName         string `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify,omitempty"`
+}
+
+type transportKey struct {
+	scheme   string
+	host     string
+	settings TransportSettings
+}
+
+type pooledTransport struct {
+	transport *http.Transport
+	refs      int
+}
+
+// TransportPool shares the transports, and therefore the keep-alive
+// connections, of the backends across the versions of the services using
+// them.
+type TransportPool struct {
+	mtx        sync.Mutex
+	transports map[transportKey]*pooledTransport
+}
+
+func NewTransportPool() *TransportPool {
+	return &TransportPool{
+		transports: make(map[transportKey]*pooledTransport),
+	}
+}
+
+// Acquire returns the transport for the backend, creating it when no other
+// service uses it yet. Every call must be paired with a call to Release.
+func (p *TransportPool) Acquire(target *url.URL, settings TransportSettings) *http.Transport {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	key := transportKey{scheme: target.Scheme, host: target.Host, settings: settings}
+	pooled, ok := p.transports[key]
+	if !ok {
+		pooled = &pooledTransport{transport: newTransport(settings)}
+		p.transports[key] = pooled
+	}
+	pooled.refs++
+	return pooled.transport
+}
+
+// Release gives the transport back to the pool, closing its idle connections
+// once no service uses the backend anymore.
+func (p *TransportPool) Release(target *url.URL, settings TransportSettings) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	key := transportKey{scheme: target.Scheme, host: target.Host, settings: settings}
+	pooled, ok := p.transports[key]
+	if !ok {
+		return
+	}
+
+	pooled.refs--
+	if pooled.refs <= 0 {
+		pooled.transport.CloseIdleConnections()
+		delete(p.transports, key)
+	}
+}
+
+// Len returns the number of transports in use.
+func (p *TransportPool) Len() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return len(p.transports)
+}
+
+func newTransport(settings TransportSettings) *http.Transport {
+	maxIdleConns := DEFAULT_MAX_IDLE_CONNS
+	if settings.MaxIdleConns > 0 {
+		maxIdleConns = settings.MaxIdleConns
+	}
+
+	keepAlive := DEFAULT_KEEP_ALIVE
+	if settings.KeepAlive > 0 {
+		keepAlive = settings.KeepAlive
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   DEFAULT_TIMEOUT,
+		KeepAlive: keepAlive,
+	}
+
+	// Each transport talks to a single backend, so the idle connections per
+	// host are the idle connections of the transport.
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConns,
+		MaxConnsPerHost:     settings.MaxConnsPerHost,
+		IdleConnTimeout:     DEFAULT_IDLE_CONN_TIMEOUT,
+		TLSHandshakeTimeout: DEFAULT_TLS_HANDSHAKE_TIMEOUT,
+		TLSClientConfig: &tls.Config{
+			ServerName:         settings.TLSServerName,
+			InsecureSkipVerify: settings.TLSInsecureSkipVerify,
+		},
+	}
+}
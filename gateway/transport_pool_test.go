@@ -0,0 +1,80 @@
+package gateway_test
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/apihub/apihub/gateway"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransportPool", func() {
+	var (
+		pool   *gateway.TransportPool
+		target *url.URL
+	)
+
+	BeforeEach(func() {
+		var err error
+		pool = gateway.NewTransportPool()
+		target, err = url.Parse("http://server-a:8080/path")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Acquire", func() {
+		It("shares the transport of a backend", func() {
+			transport := pool.Acquire(target, gateway.TransportSettings{})
+			Expect(pool.Acquire(target, gateway.TransportSettings{})).To(BeIdenticalTo(transport))
+			Expect(pool.Len()).To(Equal(1))
+		})
+
+		It("does not share transports across backends", func() {
+			another, err := url.Parse("https://server-a:8080")
+			Expect(err).NotTo(HaveOccurred())
+
+			transport := pool.Acquire(target, gateway.TransportSettings{})
+			Expect(pool.Acquire(another, gateway.TransportSettings{})).NotTo(BeIdenticalTo(transport))
+			Expect(pool.Len()).To(Equal(2))
+		})
+
+		It("does not share transports across settings", func() {
+			transport := pool.Acquire(target, gateway.TransportSettings{})
+			Expect(pool.Acquire(target, gateway.TransportSettings{MaxIdleConns: 10})).NotTo(BeIdenticalTo(transport))
+			Expect(pool.Len()).To(Equal(2))
+		})
+
+		It("applies the settings", func() {
+			transport := pool.Acquire(target, gateway.TransportSettings{
+				MaxIdleConns:          10,
+				MaxConnsPerHost:       20,
+				KeepAlive:             time.Minute,
+				TLSServerName:         "server-a.apihub.dev",
+				TLSInsecureSkipVerify: true,
+			})
+			Expect(transport.MaxIdleConnsPerHost).To(Equal(10))
+			Expect(transport.MaxConnsPerHost).To(Equal(20))
+			Expect(transport.TLSClientConfig.ServerName).To(Equal("server-a.apihub.dev"))
+			Expect(transport.TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+		})
+	})
+
+	Describe("Release", func() {
+		It("keeps the transport while it is in use", func() {
+			transport := pool.Acquire(target, gateway.TransportSettings{})
+			pool.Acquire(target, gateway.TransportSettings{})
+
+			pool.Release(target, gateway.TransportSettings{})
+			Expect(pool.Len()).To(Equal(1))
+			Expect(pool.Acquire(target, gateway.TransportSettings{})).To(BeIdenticalTo(transport))
+		})
+
+		It("drops the transport once it is no longer used", func() {
+			transport := pool.Acquire(target, gateway.TransportSettings{})
+
+			pool.Release(target, gateway.TransportSettings{})
+			Expect(pool.Len()).To(Equal(0))
+			Expect(pool.Acquire(target, gateway.TransportSettings{})).NotTo(BeIdenticalTo(transport))
+		})
+	})
+})
@@ -26,7 +26,7 @@ var _ = Describe("Gateway", func() {
 		portGateway = 9000 + GinkgoParallelNode()
 		reverseProxyCreator = gateway.NewReverseProxyCreator()
 
-		gw = gateway.New(fmt.Sprintf(":%d", portGateway), reverseProxyCreator)
+		gw = gateway.New(fmt.Sprintf(":%d", portGateway), reverseProxyCreator, gateway.ServerConfig{})
 
 		spec = gateway.ReverseProxySpec{
 			Host:     "my-host.apihub.dev",
@@ -103,7 +103,7 @@ var _ = Describe("Gateway", func() {
 				gw.ServeHTTP(rw, req)
 
 				Expect(rw.Code).To(Equal(http.StatusGatewayTimeout))
-				Expect(rw.Body.String()).To(ContainSubstring("i/o timeout"))
+				Expect(rw.Body.String()).To(ContainSubstring("gateway_timeout"))
 			})
 		})
 	})
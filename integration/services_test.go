@@ -1,11 +1,13 @@
 package integration_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 
+	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 
 	"github.com/apihub/apihub"
@@ -60,7 +62,10 @@ var _ = Describe("Service", func() {
 
 		c := &http.Client{}
 		// It is ok not to check the error because it may eventually be up n' running
-		resp, _ := c.Do(req)
+		resp, err := c.Do(req)
+		if err != nil {
+			logger.Info("gateway-not-ready", lager.Data{"error": err.Error()})
+		}
 
 		return resp
 	}
@@ -93,9 +98,10 @@ var _ = Describe("Service", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 
-			It("returns an error message with bad request", func() {
+			It("returns a conflict error", func() {
 				_, err := client.AddService(spec)
 				Expect(err).To(MatchError(ContainSubstring("host already in use")))
+				Expect(errors.Is(err, apihub.ErrConflict)).To(BeTrue())
 			})
 		})
 	})
@@ -175,6 +181,7 @@ var _ = Describe("Service", func() {
 			It("returns an error", func() {
 				_, err := client.FindService("invalid-host")
 				Expect(err).To(MatchError(ContainSubstring("Failed to find service.")))
+				Expect(errors.Is(err, apihub.ErrNotFound)).To(BeTrue())
 			})
 		})
 	})
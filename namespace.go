@@ -0,0 +1,31 @@
+package apihub
+
+import "strings"
+
+// Namespace groups the services of a team. Tokens restricted to namespaces
+// only see and change the services in them, see Token.Namespaces.
+type Namespace struct {
+	Name string `json:"name"`
+	// DomainSuffixes restricts the hosts of the services in the namespace to
+	// these domains and their subdomains, when not empty.
+	DomainSuffixes []string `json:"domain_suffixes,omitempty"`
+}
+
+// AllowsHost reports whether a service of the namespace may use the host.
+func (n Namespace) AllowsHost(host string) bool {
+	if len(n.DomainSuffixes) == 0 {
+		return true
+	}
+
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, suffix := range n.DomainSuffixes {
+		suffix = strings.ToLower(strings.Trim(suffix, "."))
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
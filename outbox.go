@@ -0,0 +1,54 @@
+package apihub
+
+import "time"
+
+// OutboxEntry records that the service with the host has to be published,
+// or unpublished when it is disabled or removed. Entries are keyed by host:
+// the state published is always the stored one, so queuing a host again only
+// replaces its entry. ID tells the entries of the same host apart, so one is
+// not removed after a newer change queued it again.
+type OutboxEntry struct {
+	ID        string    `json:"id"`
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"created_at"`
+	// Attempts counts the failed attempts to publish the service, retried
+	// from NextAttempt on.
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// ReconcileReport describes a comparison of the stored services with the
+// published ones. Missing services are stored but not published, stale ones
+// are published with another spec, and orphaned ones are published but
+// removed or disabled in the storage.
+type ReconcileReport struct {
+	StartedAt time.Time `json:"started_at"`
+	Missing   []string  `json:"missing"`
+	Stale     []string  `json:"stale"`
+	Orphaned  []string  `json:"orphaned"`
+	Repaired  int       `json:"repaired"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// DriftMetrics counts the drift found by the reconciler, and the outcome of
+// the publishing, since the server started.
+type DriftMetrics struct {
+	Reconciles        uint64 `json:"reconciles"`
+	Missing           uint64 `json:"missing"`
+	Stale             uint64 `json:"stale"`
+	Orphaned          uint64 `json:"orphaned"`
+	Repaired          uint64 `json:"repaired"`
+	RepairFailures    uint64 `json:"repair_failures"`
+	PublishRetries    uint64 `json:"publish_retries"`
+	PublishFailures   uint64 `json:"publish_failures"`
+	ReconcileFailures uint64 `json:"reconcile_failures"`
+}
+
+// SyncStatus tells how far the published services are from the stored ones:
+// the services waiting to be published, and what the reconciler found.
+type SyncStatus struct {
+	Pending       []OutboxEntry    `json:"pending"`
+	LastReconcile *ReconcileReport `json:"last_reconcile,omitempty"`
+	Metrics       DriftMetrics     `json:"metrics"`
+}
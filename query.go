@@ -0,0 +1,181 @@
+package apihub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+const (
+	SORT_BY_HOST    string = "host"
+	SORT_BY_VERSION string = "version"
+)
+
+// ServiceQuery selects a page of services. The zero value selects every
+// service, sorted by host.
+type ServiceQuery struct {
+	// HostPrefix keeps the services whose host starts with it.
+	HostPrefix string
+	// Disabled keeps the services in the given state, when set.
+	Disabled *bool
+	// Backend keeps the services with a backend at the address.
+	Backend string
+	// Labels keeps the services having every one of the labels.
+	Labels map[string]string
+	// Namespaces keeps the services in one of the namespaces, when not
+	// empty. The empty name selects the services of no namespace.
+	Namespaces []string
+	// Sort is the field the services are sorted by, "host" or "version",
+	// prefixed with "-" for the descending order. Services with the same
+	// version are sorted by host.
+	Sort string
+	// Limit is the maximum number of services in the page, 0 for no limit.
+	Limit int
+	// Cursor continues the listing after the page it was returned with.
+	Cursor string
+}
+
+// ServicePage holds the services selected by a ServiceQuery. NextCursor is
+// empty on the last page.
+type ServicePage struct {
+	Services   []ServiceSpec
+	NextCursor string
+}
+
+// ServiceCursor is the position of a page, decoded from ServiceQuery.Cursor:
+// the last service of the previous page.
+type ServiceCursor struct {
+	Sort    string `json:"s"`
+	Host    string `json:"h"`
+	Version uint64 `json:"v,omitempty"`
+}
+
+// SortBy returns the field the services are sorted by, and whether the order
+// is descending.
+func (q ServiceQuery) SortBy() (string, bool) {
+	field := strings.TrimPrefix(q.Sort, "-")
+	if field == "" {
+		field = SORT_BY_HOST
+	}
+	return field, strings.HasPrefix(q.Sort, "-")
+}
+
+// Validate checks the sort, limit and cursor of the query.
+func (q ServiceQuery) Validate() error {
+	if field, _ := q.SortBy(); field != SORT_BY_HOST && field != SORT_BY_VERSION {
+		return NewError(E_BAD_REQUEST, "Services can only be sorted by host or version.")
+	}
+	if q.Limit < 0 {
+		return NewError(E_BAD_REQUEST, "The limit cannot be negative.")
+	}
+	_, err := q.ParseCursor()
+	return err
+}
+
+// ParseCursor decodes the cursor of the query. It returns a zero cursor when
+// the query starts from the first page.
+func (q ServiceQuery) ParseCursor() (ServiceCursor, error) {
+	var cursor ServiceCursor
+	if q.Cursor == "" {
+		return cursor, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.Host == "" {
+		return ServiceCursor{}, NewError(E_BAD_REQUEST, "Invalid cursor.")
+	}
+	if cursor.Sort != q.Sort {
+		return ServiceCursor{}, NewError(E_BAD_REQUEST, "The cursor belongs to another sort order.")
+	}
+	return cursor, nil
+}
+
+// NextCursor returns the cursor of the page following the given service.
+func (q ServiceQuery) NextCursor(last ServiceSpec) string {
+	data, _ := json.Marshal(ServiceCursor{Sort: q.Sort, Host: last.Host, Version: last.Version})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Matches reports whether the service passes the filters of the query.
+func (q ServiceQuery) Matches(spec ServiceSpec) bool {
+	if !strings.HasPrefix(spec.Host, q.HostPrefix) {
+		return false
+	}
+	if q.Disabled != nil && spec.Disabled != *q.Disabled {
+		return false
+	}
+	if q.Backend != "" {
+		found := false
+		for _, be := range spec.Backends {
+			if be.Address == q.Backend {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for name, value := range q.Labels {
+		if v, ok := spec.Labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	if len(q.Namespaces) > 0 {
+		found := false
+		for _, ns := range q.Namespaces {
+			if ns == spec.Namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Less reports whether a comes before b in the order of the query.
+func (q ServiceQuery) Less(a, b ServiceSpec) bool {
+	field, desc := q.SortBy()
+	if desc {
+		a, b = b, a
+	}
+	if field == SORT_BY_VERSION && a.Version != b.Version {
+		return a.Version < b.Version
+	}
+	return a.Host < b.Host
+}
+
+// SelectServices applies the query to services, for the storages that cannot
+// filter and sort them on their own.
+func SelectServices(services []ServiceSpec, q ServiceQuery) (ServicePage, error) {
+	cursor, err := q.ParseCursor()
+	if err != nil {
+		return ServicePage{}, err
+	}
+	after := ServiceSpec{Host: cursor.Host, Version: cursor.Version}
+
+	selected := []ServiceSpec{}
+	for _, spec := range services {
+		if !q.Matches(spec) || (cursor.Host != "" && !q.Less(after, spec)) {
+			continue
+		}
+		selected = append(selected, spec)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return q.Less(selected[i], selected[j])
+	})
+
+	page := ServicePage{Services: selected}
+	if q.Limit > 0 && len(selected) > q.Limit {
+		page.Services = selected[:q.Limit]
+		page.NextCursor = q.NextCursor(selected[q.Limit-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,27 @@
+package apihub
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision is a version of a service as it was accepted. The storage keeps a
+// revision on every change, so the service can be rolled back to it.
+type Revision struct {
+	Version   uint64      `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Spec      ServiceSpec `json:"spec"`
+}
+
+// RevisionDiff holds the JSON Merge Patch, see RFC 7396, turning the revision
+// From of a service into the revision To.
+type RevisionDiff struct {
+	From uint64          `json:"from"`
+	To   uint64          `json:"to"`
+	Diff json.RawMessage `json:"diff"`
+}
+
+// NewRevision returns the revision of the spec, created now.
+func NewRevision(spec ServiceSpec) Revision {
+	return Revision{Version: spec.Version, CreatedAt: time.Now().UTC(), Spec: spec}
+}
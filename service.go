@@ -1,13 +1,28 @@
 package apihub
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager"
 )
 
+// SERVICES_PREFIX is the key prefix the services are published under by
+// default.
 const SERVICES_PREFIX string = "services_"
 
+// CheckServicesPrefix fails unless the prefix ends with a slash or an
+// underscore, which hosts never contain. Consul lists the keys by prefix, so
+// the services published under "apihub/prod-staging/" would also be read
+// under "apihub/prod".
+func CheckServicesPrefix(prefix string) error {
+	if !strings.HasSuffix(prefix, "/") && !strings.HasSuffix(prefix, "_") {
+		return NewError(E_VALIDATION_FAILED, fmt.Sprintf("services prefix '%s' must end with '/' or '_'", prefix))
+	}
+	return nil
+}
+
 //go:generate counterfeiter . Service
 //go:generate counterfeiter . ServicePublisher
 //go:generate counterfeiter . ServiceSubscriber
@@ -28,13 +43,43 @@ type Service interface {
 	// Backends returns all backends in the service.
 	Backends() ([]BackendInfo, error)
 
+	// AddBackend adds a backend to the service, leaving the others as they
+	// are.
+	AddBackend(BackendInfo) error
+
+	// RemoveBackend removes the backend with the given address.
+	RemoveBackend(string) error
+
+	// EnableBackend and DisableBackend start and stop proxying the requests
+	// to the backend with the given address.
+	EnableBackend(string) error
+	DisableBackend(string) error
+
 	// Timeout waits for the duration before returning an error to the client.
 	SetTimeout(time.Duration) error
+
+	// SetFaults replaces the faults injected by the gateway in the requests
+	// to the service.
+	SetFaults([]FaultRule) error
+
+	// ClearFaults stops injecting faults in the requests to the service.
+	ClearFaults() error
+
+	// Revisions returns the revisions of the service, oldest first.
+	Revisions() ([]Revision, error)
+
+	// Rollback makes the revision with the given version the current spec of
+	// the service, as a new version.
+	Rollback(uint64) error
 }
 
+// ServicePublisher makes the services known to the gateways. Published
+// returns the specs of the services published under the prefix, to compare
+// them with the stored ones.
 type ServicePublisher interface {
 	Publish(logger lager.Logger, prefix string, spec ServiceSpec) error
 	Unpublish(logger lager.Logger, prefix string, host string) error
+	Published(logger lager.Logger, prefix string) ([]ServiceSpec, error)
 }
 
 type ServiceSubscriber interface {
@@ -44,10 +89,119 @@ type ServiceSubscriber interface {
 // ServiceInfo holds information about a service.
 type ServiceSpec struct {
 	// Host specifies the subdomain/host used to access the service.
-	Host   string        `json:"host"`
+	Host string `json:"host"`
+	// Version is increased by the storage on every update. It is sent as
+	// the ETag of the service, see the If-Match header.
+	Version  uint64        `json:"version,omitempty"`
 	Disabled bool          `json:"disabled"`
 	Timeout  time.Duration `json:"timeout"` // in milliseconds
 	Backends []BackendInfo `json:"backends,omitempty"`
+	// Maintenance keeps the service routed while answering every request
+	// with 503 Service Unavailable.
+	Maintenance *MaintenanceInfo `json:"maintenance,omitempty"`
+	// ErrorPages overrides the body of the errors generated by the gateway on
+	// behalf of the service, keyed by status code (404, 502 and 504).
+	ErrorPages map[int]ErrorPage `json:"error_pages,omitempty"`
+	// Limits overrides the request limits of the gateway for the service.
+	Limits *ServiceLimits `json:"limits,omitempty"`
+	// Transport tunes the connections from the gateway to the backends.
+	Transport *TransportInfo `json:"transport,omitempty"`
+	// CircuitBreaker, when set, makes the gateway stop sending requests to
+	// the backends failing consecutively.
+	CircuitBreaker *CircuitBreakerInfo `json:"circuit_breaker,omitempty"`
+	// Faults injects delays and errors in the requests to the service, for
+	// testing the resilience of its clients.
+	Faults []FaultRule `json:"faults,omitempty"`
+	// Labels are free-form names and values to organize the services, see
+	// ServiceQuery.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Namespace is the name of the namespace owning the service, empty for
+	// the services of no team.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// MaintenanceInfo holds the response sent while a service is under maintenance.
+type MaintenanceInfo struct {
+	Enabled     bool   `json:"enabled"`
+	Body        string `json:"body,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	RetryAfter  int    `json:"retry_after,omitempty"` // in seconds
+}
+
+// ServiceLimits holds the request limits applied to a service. Zero values
+// keep the limits configured in the gateway.
+type ServiceLimits struct {
+	ReadTimeout    int   `json:"read_timeout,omitempty"`  // in milliseconds
+	WriteTimeout   int   `json:"write_timeout,omitempty"` // in milliseconds
+	MaxHeaderBytes int   `json:"max_header_bytes,omitempty"`
+	MaxBodyBytes   int64 `json:"max_body_bytes,omitempty"`
+}
+
+// TransportInfo holds the settings of the connections to the backends of a
+// service. Zero values keep the defaults of the gateway.
+type TransportInfo struct {
+	MaxIdleConns          int    `json:"max_idle_conns,omitempty"`
+	MaxConnsPerHost       int    `json:"max_conns_per_host,omitempty"`
+	KeepAlive             int    `json:"keep_alive,omitempty"` // in milliseconds
+	TLSServerName         string `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify,omitempty"`
+}
+
+// FaultRule injects a delay, an error or both in a percentage of the requests
+// carrying a header. The first matching rule applies.
+type FaultRule struct {
+	// Header restricts the rule to the requests carrying it, so only test
+	// traffic is affected. HeaderValue, when set, must match its value too.
+	Header      string `json:"header"`
+	HeaderValue string `json:"header_value,omitempty"`
+	// Percentage of the matching requests affected, from 0 to 100.
+	Percentage float64 `json:"percentage"`
+	// Delay holds the pause before the request is handled, increased by a
+	// random amount up to DelayJitter.
+	Delay       int `json:"delay,omitempty"`        // in milliseconds
+	DelayJitter int `json:"delay_jitter,omitempty"` // in milliseconds
+	// AbortStatus, when set, answers the request with this status code
+	// instead of proxying it.
+	AbortStatus int `json:"abort_status,omitempty"`
+}
+
+// Validate checks the rule, returning its invalid fields along with the
+// reason. It is shared by the API, rejecting the invalid rules, and the
+// gateway, ignoring them.
+func (r FaultRule) Validate() []FieldError {
+	var errs []FieldError
+	if r.Header == "" {
+		errs = append(errs, FieldError{Field: "header", Description: "Faults must be restricted to a header."})
+	}
+	if r.Percentage < 0 || r.Percentage > 100 {
+		errs = append(errs, FieldError{Field: "percentage", Description: "Fault percentage must be between 0 and 100."})
+	}
+	if r.AbortStatus != 0 && (r.AbortStatus < 400 || r.AbortStatus > 599) {
+		errs = append(errs, FieldError{Field: "abort_status", Description: "Fault abort status must be between 400 and 599."})
+	}
+	if r.Delay < 0 {
+		errs = append(errs, FieldError{Field: "delay", Description: "Fault delays cannot be negative."})
+	}
+	if r.DelayJitter < 0 {
+		errs = append(errs, FieldError{Field: "delay_jitter", Description: "Fault delays cannot be negative."})
+	}
+	return errs
+}
+
+// ErrorPage holds a custom body for an error generated by the gateway.
+type ErrorPage struct {
+	Body        string `json:"body"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// CircuitBreakerInfo holds when the gateway stops sending requests to a
+// backend, and for how long.
+type CircuitBreakerInfo struct {
+	// Threshold is the number of consecutive failures opening the circuit.
+	Threshold int `json:"threshold"`
+	// Cooldown is how long an open circuit waits before letting a trial
+	// request through, zero for the default of the gateway.
+	Cooldown int `json:"cooldown,omitempty"` // in milliseconds
 }
 
 // Backend holds information about a backend.
@@ -1,8 +1,68 @@
 package apihub
 
-//go:generate counterfeiter . Storage
+import "time"
+
+var (
+	// ErrHostInUse is returned when adding a service whose host is taken.
+	ErrHostInUse = NewError(E_CONFLICT, "host already in use")
+	// ErrServiceNotFound is returned when the host has no service.
+	ErrServiceNotFound = NewError(E_NOT_FOUND, "service not found")
+	// ErrVersionConflict is returned when updating a service from a version
+	// that is no longer the stored one.
+	ErrVersionConflict = NewError(E_CONFLICT, "service version conflict")
+	// ErrTokenInUse is returned when adding a token whose ID or hash is taken.
+	ErrTokenInUse = NewError(E_CONFLICT, "token already in use")
+	// ErrTokenNotFound is returned when no token has the ID or hash.
+	ErrTokenNotFound = NewError(E_NOT_FOUND, "token not found")
+	// ErrNamespaceInUse is returned when adding a namespace whose name is
+	// taken.
+	ErrNamespaceInUse = NewError(E_CONFLICT, "namespace already in use")
+	// ErrNamespaceNotFound is returned when no namespace has the name.
+	ErrNamespaceNotFound = NewError(E_NOT_FOUND, "namespace not found")
+	// ErrRevisionNotFound is returned when the service has no revision with
+	// the version.
+	ErrRevisionNotFound = NewError(E_NOT_FOUND, "revision not found")
+	// ErrWebhookInUse is returned when adding a webhook whose ID is taken.
+	ErrWebhookInUse = NewError(E_CONFLICT, "webhook already in use")
+	// ErrWebhookNotFound is returned when no webhook has the ID.
+	ErrWebhookNotFound = NewError(E_NOT_FOUND, "webhook not found")
+	// ErrUnknownChange is returned when a change to the services has none of
+	// the APPLY_* actions.
+	ErrUnknownChange = NewError(E_VALIDATION_FAILED, "unknown change action")
+	// ErrServiceEventsPruned is returned when reading the service events after
+	// a version the storage no longer keeps the events of.
+	ErrServiceEventsPruned = NewError(E_GONE, "service events pruned")
+)
+
+// BackfillVersion returns the spec with version 1 when it has none: the
+// first version of a service, and the one of the specs stored before services
+// were versioned.
+func BackfillVersion(spec ServiceSpec) ServiceSpec {
+	if spec.Version == 0 {
+		spec.Version = 1
+	}
+	return spec
+}
+
+//go:generate counterfeiter -o apihubfakes/fake_storage.go --fake-name FakeStorage . AdminStorage
 
 // Storage is an interface for "storage".
+//
+// AddService stores the spec with the version it holds, or version 1 when it
+// has none. UpdateService stores the spec with the next version, and fails
+// with ErrVersionConflict when the spec has a version other than the stored
+// one, version 0 included. Specs stored before services were versioned are
+// read as version 1, see BackfillVersion.
+//
+// Implementations must be safe for concurrent use and return ErrHostInUse and
+// ErrServiceNotFound as described by the storagetest conformance suite. Specs
+// the storage cannot hold are rejected with an error matching ErrValidation;
+// any other error is reported by the API as an internal error.
+//
+// Storage is all the API server needs to manage the services. The other
+// features of the API keep their state in the storages implementing their
+// interface, such as TokenStorage, and answer with ErrNotImplemented
+// otherwise.
 type Storage interface {
 	AddService(ServiceSpec) error
 	UpdateService(ServiceSpec) error
@@ -10,3 +70,116 @@ type Storage interface {
 	Services() ([]ServiceSpec, error)
 	RemoveService(string) error
 }
+
+// ServiceLister is implemented by the storages selecting the services of a
+// query themselves. ListServices returns them in the order of the query,
+// pushing the filters down to the backing store where it can. The API falls
+// back to SelectServices on the other storages.
+type ServiceLister interface {
+	ListServices(ServiceQuery) (ServicePage, error)
+}
+
+// ServiceChanger is implemented by the storages changing several services in
+// a single transaction. ChangeServices makes the changes in order: each one
+// behaves as AddService, UpdateService or RemoveService would, and when one
+// fails none of them is stored and its error is returned.
+type ServiceChanger interface {
+	ChangeServices([]ServiceChange) error
+}
+
+// RevisionStorage is implemented by the storages keeping the revisions of the
+// services. AddService and UpdateService also keep the spec they store as a
+// revision, with the same version. Revisions are never changed, and are only
+// removed along with their service. Revisions returns them oldest first, and
+// fails with ErrServiceNotFound when the host has no service.
+type RevisionStorage interface {
+	Revisions(string) ([]Revision, error)
+	FindRevision(string, uint64) (Revision, error)
+}
+
+// TokenStorage keeps the tokens apart from the services, with their hash and
+// never their secret. Tokens returns them sorted by ID.
+type TokenStorage interface {
+	AddToken(Token) error
+	FindTokenByHash(string) (Token, error)
+	Tokens() ([]Token, error)
+	RemoveToken(string) error
+}
+
+// NamespaceStorage keeps the namespaces. Namespaces returns them sorted by
+// name.
+type NamespaceStorage interface {
+	AddNamespace(Namespace) error
+	FindNamespace(string) (Namespace, error)
+	Namespaces() ([]Namespace, error)
+	RemoveNamespace(string) error
+}
+
+// AuditStorage keeps the audit events. They are append-only: once added they
+// are never changed, and only removed by storages keeping the newest ones.
+// AuditEvents returns them newest first, see SelectAuditEvents.
+type AuditStorage interface {
+	AddAuditEvent(AuditEvent) error
+	AuditEvents(AuditQuery) ([]AuditEvent, error)
+}
+
+// OutboxStorage keeps the outbox, which holds an entry per host, see
+// OutboxEntry. PutOutboxEntry adds or replaces the entry of the host, and
+// OutboxEntries sorts them by host. RemoveOutboxEntry only removes the entry
+// of the host when it still has the ID; removing an entry that is gone or was
+// replaced is not an error.
+type OutboxStorage interface {
+	PutOutboxEntry(OutboxEntry) error
+	OutboxEntries() ([]OutboxEntry, error)
+	RemoveOutboxEntry(host, id string) error
+}
+
+// WebhookStorage keeps the webhooks and their deliveries. Webhooks returns the
+// webhooks sorted by ID. PutWebhookDelivery adds or replaces the delivery with
+// the ID, and WebhookDeliveries returns the ones of a webhook newest first,
+// see SortWebhookDeliveries. RemoveWebhook also removes the deliveries of the
+// webhook. DueWebhookDeliveries returns the pending deliveries of every
+// webhook due at the time, see SelectDueWebhookDeliveries, and
+// PruneWebhookDeliveries removes the delivered and failed ones created before
+// the time.
+type WebhookStorage interface {
+	AddWebhook(Webhook) error
+	FindWebhook(string) (Webhook, error)
+	Webhooks() ([]Webhook, error)
+	RemoveWebhook(string) error
+	PutWebhookDelivery(WebhookDelivery) error
+	WebhookDeliveries(string) ([]WebhookDelivery, error)
+	DueWebhookDeliveries(time.Time) ([]WebhookDelivery, error)
+	PruneWebhookDeliveries(time.Time) error
+}
+
+// ServiceEventStorage is implemented by the storages logging the changes to
+// the services, for the watches of the services. AddService, UpdateService,
+// RemoveService and ChangeServices log an event per change to a service, in
+// the same transaction, with a version greater than the ones of the events
+// logged before it, see NewServiceEvent. The storages only keep the newest
+// events.
+//
+// ServiceEvents returns the events logged after the version, oldest first,
+// and fails with ErrServiceEventsPruned when the storage no longer keeps some
+// of them or the version was never logged. ServiceEventsVersion returns the
+// version of the last event logged, 0 before the first one.
+type ServiceEventStorage interface {
+	ServiceEvents(after uint64) ([]ServiceEvent, error)
+	ServiceEventsVersion() (uint64, error)
+}
+
+// AdminStorage is implemented by the storages keeping the state of every
+// feature of the API, as the storages of this repository do.
+type AdminStorage interface {
+	Storage
+	ServiceLister
+	ServiceChanger
+	RevisionStorage
+	TokenStorage
+	NamespaceStorage
+	AuditStorage
+	OutboxStorage
+	WebhookStorage
+	ServiceEventStorage
+}
@@ -0,0 +1,805 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/apihub/apihub"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	servicesBucket   = []byte("services")
+	tokensBucket     = []byte("tokens")
+	namespacesBucket = []byte("namespaces")
+	auditBucket      = []byte("audit")
+	revisionsBucket  = []byte("revisions")
+	outboxBucket     = []byte("outbox")
+	webhooksBucket   = []byte("webhooks")
+	deliveriesBucket = []byte("webhook_deliveries")
+	eventsBucket     = []byte("service_events")
+
+	// errFound stops iterating over a bucket once the value is found.
+	errFound = errors.New("found")
+)
+
+// Bolt stores the services in an embedded BoltDB file, so they survive
+// restarts of the API server. Every write runs in its own transaction. The
+// revisions of each service are kept in a bucket of its own, keyed by
+// version. The tokens and the namespaces are kept in buckets of their own,
+// keyed by ID and name, the audit events in order of recording and the
+// outbox by host. The webhooks are keyed by ID, and their deliveries kept in
+// a bucket per webhook. The service events are keyed by version, the sequence
+// of their bucket.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// NewBolt opens, creating when needed, the database at path. It fails when
+// another process holds the file for more than a second.
+func NewBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{servicesBucket, revisionsBucket, tokensBucket, namespacesBucket, auditBucket, outboxBucket, webhooksBucket, deliveriesBucket, eventsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// Close releases the database file.
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+func (b *Bolt) AddService(s apihub.ServiceSpec) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return addService(tx, s)
+	})
+}
+
+func (b *Bolt) UpdateService(s apihub.ServiceSpec) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return updateService(tx, s)
+	})
+}
+
+func (b *Bolt) FindServiceByHost(host string) (apihub.ServiceSpec, error) {
+	var service apihub.ServiceSpec
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(servicesBucket).Get([]byte(host))
+		if data == nil {
+			return apihub.ErrServiceNotFound
+		}
+
+		var err error
+		service, err = decodeService(data)
+		return err
+	})
+	if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	return service, nil
+}
+
+func (b *Bolt) Services() ([]apihub.ServiceSpec, error) {
+	services := []apihub.ServiceSpec{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(servicesBucket).ForEach(func(_, data []byte) error {
+			service, err := decodeService(data)
+			if err != nil {
+				return err
+			}
+
+			services = append(services, service)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
+// ListServices seeks the keys by the host prefix. When sorting by host, the
+// keys are already in order, so the listing starts after the cursor and stops
+// once the page is full.
+func (b *Bolt) ListServices(q apihub.ServiceQuery) (apihub.ServicePage, error) {
+	if err := q.Validate(); err != nil {
+		return apihub.ServicePage{}, err
+	}
+	cursor, err := q.ParseCursor()
+	if err != nil {
+		return apihub.ServicePage{}, err
+	}
+	field, desc := q.SortBy()
+	byHost := field == apihub.SORT_BY_HOST && !desc
+
+	services := []apihub.ServiceSpec{}
+	err = b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(servicesBucket).Cursor()
+		prefix := []byte(q.HostPrefix)
+		start := prefix
+		if byHost && cursor.Host > q.HostPrefix {
+			start = []byte(cursor.Host)
+		}
+
+		for k, data := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, data = c.Next() {
+			if byHost && q.Limit > 0 && len(services) > q.Limit {
+				break
+			}
+			if byHost && string(k) == cursor.Host {
+				continue
+			}
+
+			service, err := decodeService(data)
+			if err != nil {
+				return err
+			}
+			if q.Matches(service) {
+				services = append(services, service)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return apihub.ServicePage{}, err
+	}
+
+	return apihub.SelectServices(services, q)
+}
+
+func (b *Bolt) RemoveService(host string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return removeService(tx, host)
+	})
+}
+
+// ChangeServices makes all the changes in one transaction, which is rolled
+// back by the first one failing.
+func (b *Bolt) ChangeServices(changes []apihub.ServiceChange) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, change := range changes {
+			var err error
+			switch change.Action {
+			case apihub.APPLY_CREATE:
+				err = addService(tx, change.Spec)
+			case apihub.APPLY_UPDATE:
+				err = updateService(tx, change.Spec)
+			case apihub.APPLY_DELETE:
+				err = removeService(tx, change.Spec.Host)
+			default:
+				err = apihub.ErrUnknownChange
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func addService(tx *bolt.Tx, s apihub.ServiceSpec) error {
+	bucket := tx.Bucket(servicesBucket)
+	if bucket.Get([]byte(s.Host)) != nil {
+		return apihub.ErrHostInUse
+	}
+
+	s = apihub.BackfillVersion(s)
+	if err := putService(bucket, s); err != nil {
+		return err
+	}
+	if err := putRevision(tx, s); err != nil {
+		return err
+	}
+	return putServiceEvent(tx, nil, &s)
+}
+
+func updateService(tx *bolt.Tx, s apihub.ServiceSpec) error {
+	bucket := tx.Bucket(servicesBucket)
+	data := bucket.Get([]byte(s.Host))
+	if data == nil {
+		return apihub.ErrServiceNotFound
+	}
+
+	stored, err := decodeService(data)
+	if err != nil {
+		return err
+	}
+	if s.Version != stored.Version {
+		return apihub.ErrVersionConflict
+	}
+
+	s.Version = stored.Version + 1
+	if err := putService(bucket, s); err != nil {
+		return err
+	}
+	if err := putRevision(tx, s); err != nil {
+		return err
+	}
+	return putServiceEvent(tx, &stored, &s)
+}
+
+func removeService(tx *bolt.Tx, host string) error {
+	bucket := tx.Bucket(servicesBucket)
+	data := bucket.Get([]byte(host))
+	if data == nil {
+		return apihub.ErrServiceNotFound
+	}
+
+	stored, err := decodeService(data)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Delete([]byte(host)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(revisionsBucket).DeleteBucket([]byte(host)); err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+	return putServiceEvent(tx, &stored, nil)
+}
+
+// putServiceEvent logs a change to a service with the next version of the
+// events, and removes the event SERVICE_EVENT_LIMIT versions older. The
+// versions have no gaps, as the sequence is only kept along with the event.
+func putServiceEvent(tx *bolt.Tx, before, after *apihub.ServiceSpec) error {
+	bucket := tx.Bucket(eventsBucket)
+	version, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	event := apihub.NewServiceEvent(before, after)
+	event.Version = version
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Put(versionKey(version), data); err != nil {
+		return err
+	}
+	if version > SERVICE_EVENT_LIMIT {
+		return bucket.Delete(versionKey(version - SERVICE_EVENT_LIMIT))
+	}
+	return nil
+}
+
+func (b *Bolt) ServiceEvents(after uint64) ([]apihub.ServiceEvent, error) {
+	events := []apihub.ServiceEvent{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(eventsBucket)
+		last := bucket.Sequence()
+		c := bucket.Cursor()
+		if first, _ := c.First(); after > last || (after < last && (first == nil || binary.BigEndian.Uint64(first) > after+1)) {
+			return apihub.ErrServiceEventsPruned
+		}
+
+		for k, data := c.Seek(versionKey(after + 1)); k != nil; k, data = c.Next() {
+			var event apihub.ServiceEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (b *Bolt) ServiceEventsVersion() (uint64, error) {
+	var version uint64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		version = tx.Bucket(eventsBucket).Sequence()
+		return nil
+	})
+	return version, err
+}
+
+func (b *Bolt) Revisions(host string) ([]apihub.Revision, error) {
+	revisions := []apihub.Revision{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(servicesBucket).Get([]byte(host)) == nil {
+			return apihub.ErrServiceNotFound
+		}
+
+		bucket := tx.Bucket(revisionsBucket).Bucket([]byte(host))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var r apihub.Revision
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+
+			revisions = append(revisions, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (b *Bolt) FindRevision(host string, version uint64) (apihub.Revision, error) {
+	var revision apihub.Revision
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(revisionsBucket).Bucket([]byte(host))
+		if bucket == nil {
+			return apihub.ErrRevisionNotFound
+		}
+
+		data := bucket.Get(versionKey(version))
+		if data == nil {
+			return apihub.ErrRevisionNotFound
+		}
+		return json.Unmarshal(data, &revision)
+	})
+	if err != nil {
+		return apihub.Revision{}, err
+	}
+
+	return revision, nil
+}
+
+func (b *Bolt) AddToken(t apihub.Token) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tokensBucket)
+		if bucket.Get([]byte(t.ID)) != nil {
+			return apihub.ErrTokenInUse
+		}
+		if _, err := findToken(bucket, t.Hash); err == nil {
+			return apihub.ErrTokenInUse
+		} else if err != apihub.ErrTokenNotFound {
+			return err
+		}
+
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(t.ID), data)
+	})
+}
+
+// FindTokenByHash scans the tokens, which are expected to be few.
+func (b *Bolt) FindTokenByHash(hash string) (apihub.Token, error) {
+	var token apihub.Token
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		token, err = findToken(tx.Bucket(tokensBucket), hash)
+		return err
+	})
+	if err != nil {
+		return apihub.Token{}, err
+	}
+
+	return token, nil
+}
+
+func (b *Bolt) Tokens() ([]apihub.Token, error) {
+	tokens := []apihub.Token{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokensBucket).ForEach(func(_, data []byte) error {
+			var token apihub.Token
+			if err := json.Unmarshal(data, &token); err != nil {
+				return err
+			}
+
+			tokens = append(tokens, token)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (b *Bolt) RemoveToken(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tokensBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return apihub.ErrTokenNotFound
+		}
+
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (b *Bolt) AddNamespace(ns apihub.Namespace) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespacesBucket)
+		if bucket.Get([]byte(ns.Name)) != nil {
+			return apihub.ErrNamespaceInUse
+		}
+
+		data, err := json.Marshal(ns)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(ns.Name), data)
+	})
+}
+
+func (b *Bolt) FindNamespace(name string) (apihub.Namespace, error) {
+	var ns apihub.Namespace
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(namespacesBucket).Get([]byte(name))
+		if data == nil {
+			return apihub.ErrNamespaceNotFound
+		}
+
+		return json.Unmarshal(data, &ns)
+	})
+	if err != nil {
+		return apihub.Namespace{}, err
+	}
+
+	return ns, nil
+}
+
+func (b *Bolt) Namespaces() ([]apihub.Namespace, error) {
+	namespaces := []apihub.Namespace{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(namespacesBucket).ForEach(func(_, data []byte) error {
+			var ns apihub.Namespace
+			if err := json.Unmarshal(data, &ns); err != nil {
+				return err
+			}
+
+			namespaces = append(namespaces, ns)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaces, nil
+}
+
+func (b *Bolt) RemoveNamespace(name string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespacesBucket)
+		if bucket.Get([]byte(name)) == nil {
+			return apihub.ErrNamespaceNotFound
+		}
+
+		return bucket.Delete([]byte(name))
+	})
+}
+
+func (b *Bolt) AddAuditEvent(e apihub.AuditEvent) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(auditBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(versionKey(seq), data)
+	})
+}
+
+func (b *Bolt) AuditEvents(q apihub.AuditQuery) ([]apihub.AuditEvent, error) {
+	events := []apihub.AuditEvent{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(auditBucket).ForEach(func(_, data []byte) error {
+			var e apihub.AuditEvent
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			if q.Matches(e) {
+				events = append(events, e)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apihub.SelectAuditEvents(events, q), nil
+}
+
+func (b *Bolt) PutOutboxEntry(e apihub.OutboxEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Put([]byte(e.Host), data)
+	})
+}
+
+func (b *Bolt) OutboxEntries() ([]apihub.OutboxEntry, error) {
+	entries := []apihub.OutboxEntry{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).ForEach(func(_, data []byte) error {
+			var e apihub.OutboxEntry
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (b *Bolt) RemoveOutboxEntry(host, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		data := bucket.Get([]byte(host))
+		if data == nil {
+			return nil
+		}
+
+		var e apihub.OutboxEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if e.ID != id {
+			return nil
+		}
+		return bucket.Delete([]byte(host))
+	})
+}
+
+func (b *Bolt) AddWebhook(w apihub.Webhook) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhooksBucket)
+		if bucket.Get([]byte(w.ID)) != nil {
+			return apihub.ErrWebhookInUse
+		}
+
+		data, err := json.Marshal(w)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(w.ID), data)
+	})
+}
+
+func (b *Bolt) FindWebhook(id string) (apihub.Webhook, error) {
+	var w apihub.Webhook
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(webhooksBucket).Get([]byte(id))
+		if data == nil {
+			return apihub.ErrWebhookNotFound
+		}
+
+		return json.Unmarshal(data, &w)
+	})
+	if err != nil {
+		return apihub.Webhook{}, err
+	}
+
+	return w, nil
+}
+
+func (b *Bolt) Webhooks() ([]apihub.Webhook, error) {
+	webhooks := []apihub.Webhook{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(webhooksBucket).ForEach(func(_, data []byte) error {
+			var w apihub.Webhook
+			if err := json.Unmarshal(data, &w); err != nil {
+				return err
+			}
+
+			webhooks = append(webhooks, w)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (b *Bolt) RemoveWebhook(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhooksBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return apihub.ErrWebhookNotFound
+		}
+
+		if err := bucket.Delete([]byte(id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(deliveriesBucket).DeleteBucket([]byte(id)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	})
+}
+
+func (b *Bolt) PutWebhookDelivery(d apihub.WebhookDelivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(deliveriesBucket).CreateBucketIfNotExists([]byte(d.WebhookID))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(d.ID), data)
+	})
+}
+
+func (b *Bolt) WebhookDeliveries(webhookID string) ([]apihub.WebhookDelivery, error) {
+	deliveries := []apihub.WebhookDelivery{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(deliveriesBucket).Bucket([]byte(webhookID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var d apihub.WebhookDelivery
+			if err := json.Unmarshal(data, &d); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, d)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	apihub.SortWebhookDeliveries(deliveries)
+	return deliveries, nil
+}
+
+// DueWebhookDeliveries reads the deliveries of every webhook, as they are
+// kept in a bucket per webhook.
+func (b *Bolt) DueWebhookDeliveries(now time.Time) ([]apihub.WebhookDelivery, error) {
+	deliveries := []apihub.WebhookDelivery{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).ForEach(func(webhookID, _ []byte) error {
+			return tx.Bucket(deliveriesBucket).Bucket(webhookID).ForEach(func(_, data []byte) error {
+				var d apihub.WebhookDelivery
+				if err := json.Unmarshal(data, &d); err != nil {
+					return err
+				}
+				deliveries = append(deliveries, d)
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apihub.SelectDueWebhookDeliveries(deliveries, now), nil
+}
+
+func (b *Bolt) PruneWebhookDeliveries(before time.Time) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).ForEach(func(webhookID, _ []byte) error {
+			bucket := tx.Bucket(deliveriesBucket).Bucket(webhookID)
+			pruned := [][]byte{}
+			err := bucket.ForEach(func(id, data []byte) error {
+				var d apihub.WebhookDelivery
+				if err := json.Unmarshal(data, &d); err != nil {
+					return err
+				}
+				if d.Status != apihub.DELIVERY_PENDING && d.CreatedAt.Before(before) {
+					pruned = append(pruned, id)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// Keys cannot be deleted while iterating over the bucket.
+			for _, id := range pruned {
+				if err := bucket.Delete(id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func findToken(bucket *bolt.Bucket, hash string) (apihub.Token, error) {
+	var token apihub.Token
+	err := bucket.ForEach(func(_, data []byte) error {
+		var t apihub.Token
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		if t.Hash == hash {
+			token = t
+			return errFound
+		}
+		return nil
+	})
+	switch err {
+	case errFound:
+		return token, nil
+	case nil:
+		return apihub.Token{}, apihub.ErrTokenNotFound
+	}
+	return apihub.Token{}, err
+}
+
+// decodeService reads a stored spec, with version 1 when it was stored
+// before services were versioned.
+func decodeService(data []byte) (apihub.ServiceSpec, error) {
+	var s apihub.ServiceSpec
+	if err := json.Unmarshal(data, &s); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	return apihub.BackfillVersion(s), nil
+}
+
+func putService(bucket *bolt.Bucket, s apihub.ServiceSpec) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(s.Host), data)
+}
+
+// putRevision keeps the spec as a revision of its service.
+func putRevision(tx *bolt.Tx, s apihub.ServiceSpec) error {
+	bucket, err := tx.Bucket(revisionsBucket).CreateBucketIfNotExists([]byte(s.Host))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(apihub.NewRevision(s))
+	if err != nil {
+		return err
+	}
+	return bucket.Put(versionKey(s.Version), data)
+}
+
+// versionKey encodes a version or sequence as a key sorting in numeric order.
+func versionKey(version uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, version)
+	return key
+}
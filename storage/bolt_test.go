@@ -0,0 +1,167 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/storage"
+	"github.com/apihub/apihub/storage/storagetest"
+	bolt "go.etcd.io/bbolt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bolt", func() {
+	var (
+		tmpDir string
+		dbPath string
+		store  *storage.Bolt
+		spec   apihub.ServiceSpec
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir(os.TempDir(), "apihub-storage-bolt-test")
+		Expect(err).NotTo(HaveOccurred())
+		dbPath = filepath.Join(tmpDir, "apihub.db")
+
+		store, err = storage.NewBolt(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		spec = apihub.ServiceSpec{
+			Host:    "my-host",
+			Version: 1,
+			Backends: []apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-a"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		store.Close()
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("AddService", func() {
+		It("adds a service", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		Context("when the service already exists for given host", func() {
+			BeforeEach(func() {
+				Expect(store.AddService(spec)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				Expect(store.AddService(spec)).To(MatchError("host already in use"))
+			})
+		})
+	})
+
+	Describe("UpdateService", func() {
+		It("updates a service", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+			spec.Disabled = true
+			Expect(store.UpdateService(spec)).To(Succeed())
+
+			found, err := store.FindServiceByHost(spec.Host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Disabled).To(BeTrue())
+		})
+
+		Context("when service does not exits", func() {
+			It("returns an error", func() {
+				Expect(store.UpdateService(spec)).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("FindServiceByHost", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		It("finds a service", func() {
+			found, err := store.FindServiceByHost("my-host")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(Equal(spec))
+		})
+
+		Context("when service is not found", func() {
+			It("returns an error", func() {
+				_, err := store.FindServiceByHost("invalid-host")
+				Expect(err).To(MatchError(ContainSubstring("service not found")))
+			})
+		})
+	})
+
+	Describe("Services", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		It("lists all services", func() {
+			services, err := store.Services()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services).To(ConsistOf(spec))
+		})
+	})
+
+	Describe("RemoveService", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		It("removes service by host", func() {
+			Expect(store.RemoveService(spec.Host)).NotTo(HaveOccurred())
+
+			_, err := store.FindServiceByHost(spec.Host)
+			Expect(err).To(MatchError("service not found"))
+		})
+
+		Context("when service is not found", func() {
+			It("returns an error", func() {
+				Expect(store.RemoveService("invalid-host")).To(MatchError(ContainSubstring("service not found")))
+			})
+		})
+	})
+
+	Context("when the database is reopened", func() {
+		It("keeps the services", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+			Expect(store.Close()).To(Succeed())
+
+			var err error
+			store, err = storage.NewBolt(dbPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			services, err := store.Services()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services).To(ConsistOf(spec))
+		})
+
+		It("reads the services stored without a version as version 1", func() {
+			Expect(store.Close()).To(Succeed())
+
+			db, err := bolt.Open(dbPath, 0600, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(db.Update(func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte("services")).Put([]byte("my-host"), []byte(`{"host":"my-host"}`))
+			})).To(Succeed())
+			Expect(db.Close()).To(Succeed())
+
+			store, err = storage.NewBolt(dbPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			found, err := store.FindServiceByHost("my-host")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Version).To(BeEquivalentTo(1))
+			Expect(store.UpdateService(found)).To(Succeed())
+		})
+	})
+	storagetest.Conformance(func() apihub.Storage {
+		return store
+	})
+})
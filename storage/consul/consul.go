@@ -0,0 +1,1195 @@
+// package consul provides an apihub.Storage implementation on Consul KV, so
+// that several API servers can share the same services.
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/apihub/apihub"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	DEFAULT_PREFIX    = "apihub/storage/"
+	SERVICES_SUFFIX   = "services/"
+	TOKENS_SUFFIX     = "_tokens/"
+	TOKEN_IDS_SUFFIX  = "_token_ids/"
+	NAMESPACES_SUFFIX = "_namespaces/"
+	AUDIT_SUFFIX      = "_audit/"
+	REVISIONS_SUFFIX  = "_revisions/"
+	OUTBOX_SUFFIX     = "_outbox/"
+	WEBHOOKS_SUFFIX   = "_webhooks/"
+	DELIVERIES_SUFFIX = "_webhook_deliveries/"
+	PENDING_SUFFIX    = "_webhook_pending/"
+	EVENTS_SUFFIX     = "_events/"
+	// EVENTS_VERSION_SUFFIX names the key holding the version of the last
+	// service event.
+	EVENTS_VERSION_SUFFIX = "_events_version"
+
+	// DEFAULT_AUDIT_RETENTION is how long the audit events are kept by
+	// default.
+	DEFAULT_AUDIT_RETENTION = 90 * 24 * time.Hour
+	// The audit events are kept in a bucket per day, and the deliveries of
+	// the webhooks in a bucket per hour, named after these layouts.
+	AUDIT_BUCKET_LAYOUT    = "2006-01-02"
+	DELIVERY_BUCKET_LAYOUT = "2006-01-02T15"
+	// MAX_TXN_OPS is the number of operations a Consul transaction holds.
+	MAX_TXN_OPS = 64
+	// EVENTS_BUCKET_SIZE is the number of service events kept under a key
+	// prefix, which is removed as a whole once two newer ones are in use.
+	EVENTS_BUCKET_SIZE = 1000
+)
+
+var (
+	errTokenConflict   = apihub.NewError(apihub.E_CONFLICT, "token modified concurrently")
+	errAuditEventInUse = apihub.NewError(apihub.E_CONFLICT, "audit event already recorded")
+	errWebhookConflict = apihub.NewError(apihub.E_CONFLICT, "webhook modified concurrently")
+	errPartialChange   = apihub.NewError(apihub.E_CONFLICT, "services modified concurrently, some of the changes could not be rolled back")
+)
+
+// Storage keeps each service spec in a key made of the prefix,
+// SERVICES_SUFFIX and its host, so listing the services only reads their
+// keys. Writes use check-and-set on the ModifyIndex of the key, so a write
+// based on a stale read fails instead of overwriting a concurrent one.
+//
+// The revisions of the services are kept under the prefix followed by
+// REVISIONS_SUFFIX and the host, written in the same transaction as the
+// service. The tokens, the namespaces, the outbox and the webhooks are kept
+// under the prefix followed by TOKENS_SUFFIX, NAMESPACES_SUFFIX,
+// OUTBOX_SUFFIX and WEBHOOKS_SUFFIX, keyed by hash, name, host and ID, and
+// the hashes of the tokens under TOKEN_IDS_SUFFIX and their ID. The audit
+// events are kept under AUDIT_SUFFIX, keyed by the day and the time they were
+// recorded at, and the deliveries of the webhooks under DELIVERIES_SUFFIX,
+// keyed by the webhook ID and the hour they were created at, with a copy of
+// the pending ones under PENDING_SUFFIX. Reading a token, the recent audit
+// events or the deliveries to make so only lists a few keys.
+//
+// The service events are kept under the prefix followed by EVENTS_SUFFIX, in
+// buckets of EVENTS_BUCKET_SIZE versions, and the version of the last one
+// under EVENTS_VERSION_SUFFIX. Each change to the services checks-and-sets
+// that version in the same transaction as its events, so the versions are
+// never reused.
+//
+// Consul is not meant to hold an ever growing log, so the audit events are
+// only kept for a while, see SetAuditRetention, and only the last two
+// buckets of service events.
+//
+// The prefix must not be the one the services are published under, as the
+// publisher deletes the keys of the services it unpublishes. Publishing
+// writes a second copy of each service under that prefix, on purpose: the
+// storage holds every service with its version and revisions, and is only
+// read by the API servers, while the published copy is what the gateways
+// read, and only holds the services published. The outbox retries the
+// publishing until both copies agree.
+type Storage struct {
+	kv             *consulapi.KV
+	prefix         string
+	auditRetention time.Duration
+}
+
+func New(client *consulapi.Client, prefix string) *Storage {
+	if prefix == "" {
+		prefix = DEFAULT_PREFIX
+	}
+
+	return &Storage{
+		kv:             client.KV(),
+		prefix:         prefix,
+		auditRetention: DEFAULT_AUDIT_RETENTION,
+	}
+}
+
+// SetAuditRetention sets how long the audit events are kept: adding an event
+// removes the days of events older than it. A retention of 0 keeps every
+// event.
+func (s *Storage) SetAuditRetention(retention time.Duration) {
+	s.auditRetention = retention
+}
+
+func (s *Storage) AddService(spec apihub.ServiceSpec) error {
+	spec = apihub.BackfillVersion(spec)
+	ops, err := s.createOps(spec)
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := s.txn(ops, []apihub.ServiceEvent{apihub.NewServiceEvent(nil, &spec)})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apihub.ErrHostInUse
+	}
+	return nil
+}
+
+func (s *Storage) UpdateService(spec apihub.ServiceSpec) error {
+	kvp, _, err := s.kv.Get(s.key(spec.Host), nil)
+	if err != nil {
+		return err
+	}
+	if kvp == nil {
+		return apihub.ErrServiceNotFound
+	}
+
+	stored, err := decodeService(kvp.Value)
+	if err != nil {
+		return err
+	}
+	if spec.Version != stored.Version {
+		return apihub.ErrVersionConflict
+	}
+
+	// The key changing since it was read means another update won.
+	spec.Version = stored.Version + 1
+	ops, err := s.serviceOps(spec, kvp.ModifyIndex)
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := s.txn(ops, []apihub.ServiceEvent{apihub.NewServiceEvent(&stored, &spec)})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apihub.ErrVersionConflict
+	}
+	return nil
+}
+
+func (s *Storage) FindServiceByHost(host string) (apihub.ServiceSpec, error) {
+	kvp, _, err := s.kv.Get(s.key(host), nil)
+	if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	if kvp == nil {
+		return apihub.ServiceSpec{}, apihub.ErrServiceNotFound
+	}
+
+	return decodeService(kvp.Value)
+}
+
+func (s *Storage) Services() ([]apihub.ServiceSpec, error) {
+	pairs, _, err := s.kv.List(s.key(""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	services := []apihub.ServiceSpec{}
+	for _, kvp := range pairs {
+		spec, err := decodeService(kvp.Value)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, spec)
+	}
+	return services, nil
+}
+
+// ListServices lists the keys under the host prefix, then filters and sorts
+// the services in memory.
+func (s *Storage) ListServices(q apihub.ServiceQuery) (apihub.ServicePage, error) {
+	if err := q.Validate(); err != nil {
+		return apihub.ServicePage{}, err
+	}
+
+	pairs, _, err := s.kv.List(s.key(q.HostPrefix), nil)
+	if err != nil {
+		return apihub.ServicePage{}, err
+	}
+
+	services := []apihub.ServiceSpec{}
+	for _, kvp := range pairs {
+		spec, err := decodeService(kvp.Value)
+		if err != nil {
+			return apihub.ServicePage{}, err
+		}
+		services = append(services, spec)
+	}
+	return apihub.SelectServices(services, q)
+}
+
+func (s *Storage) RemoveService(host string) error {
+	kvp, _, err := s.kv.Get(s.key(host), nil)
+	if err != nil {
+		return err
+	}
+	if kvp == nil {
+		return apihub.ErrServiceNotFound
+	}
+
+	stored, err := decodeService(kvp.Value)
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := s.txn(s.removeOps(host, kvp.ModifyIndex), []apihub.ServiceEvent{apihub.NewServiceEvent(&stored, nil)})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.removalConflict(kvp.Key, apihub.ErrServiceNotFound, apihub.ErrVersionConflict)
+	}
+	return nil
+}
+
+// removalConflict returns the error of a removal whose check-and-set failed:
+// notFound when the key was removed concurrently, conflict when it was
+// changed.
+func (s *Storage) removalConflict(key string, notFound, conflict error) error {
+	kvp, _, err := s.kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if kvp == nil {
+		return notFound
+	}
+	return conflict
+}
+
+// ChangeServices reads the services to change, then makes the changes in
+// transactions of at most MAX_TXN_OPS operations, which fail with
+// ErrVersionConflict when any of their services changed since it was read.
+// When a transaction fails, the ones before it are rolled back, restoring the
+// services as they were read; the watchers then see the changes rolled back
+// as changes of their own. The revisions of the removed services are only
+// removed once every change is made, so they are still there to roll back to.
+func (s *Storage) ChangeServices(changes []apihub.ServiceChange) error {
+	planned := []plannedChange{}
+	for _, change := range changes {
+		spec := change.Spec
+		kvp, _, err := s.kv.Get(s.key(spec.Host), nil)
+		if err != nil {
+			return err
+		}
+
+		p := plannedChange{host: spec.Host, previous: kvp}
+		switch change.Action {
+		case apihub.APPLY_CREATE:
+			if kvp != nil {
+				return apihub.ErrHostInUse
+			}
+			spec = apihub.BackfillVersion(spec)
+			p.after = &spec
+			p.ops, err = s.createOps(spec)
+
+		case apihub.APPLY_UPDATE:
+			if kvp == nil {
+				return apihub.ErrServiceNotFound
+			}
+			var stored apihub.ServiceSpec
+			if stored, err = decodeService(kvp.Value); err != nil {
+				return err
+			}
+			if spec.Version != stored.Version {
+				return apihub.ErrVersionConflict
+			}
+			spec.Version = stored.Version + 1
+			p.before, p.after = &stored, &spec
+			p.ops, err = s.serviceOps(spec, kvp.ModifyIndex)
+
+		case apihub.APPLY_DELETE:
+			if kvp == nil {
+				return apihub.ErrServiceNotFound
+			}
+			var stored apihub.ServiceSpec
+			if stored, err = decodeService(kvp.Value); err != nil {
+				return err
+			}
+			p.before = &stored
+			p.ops = consulapi.KVTxnOps{
+				&consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: kvp.Key, Index: kvp.ModifyIndex},
+				&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: kvp.Key},
+			}
+
+		default:
+			return apihub.ErrUnknownChange
+		}
+		if err != nil {
+			return err
+		}
+		planned = append(planned, p)
+	}
+
+	batches := batchChanges(planned)
+	for i, batch := range batches {
+		ok, err := s.applyBatch(batch)
+		if err == nil && !ok {
+			err = apihub.ErrVersionConflict
+		}
+		if err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if rollbackErr := s.rollbackBatch(batches[j]); rollbackErr != nil {
+					return errPartialChange
+				}
+			}
+			return err
+		}
+	}
+
+	// A failure here only leaves revisions behind, which are removed when
+	// their host is added again.
+	cleanup := consulapi.KVTxnOps{}
+	for _, p := range planned {
+		if p.after == nil {
+			cleanup = append(cleanup, &consulapi.KVTxnOp{Verb: consulapi.KVDeleteTree, Key: s.revisionKey(p.host, "")})
+		}
+		if len(cleanup) == MAX_TXN_OPS {
+			s.kv.Txn(cleanup, nil)
+			cleanup = consulapi.KVTxnOps{}
+		}
+	}
+	if len(cleanup) > 0 {
+		s.kv.Txn(cleanup, nil)
+	}
+	return nil
+}
+
+// plannedChange holds the operations of a change to the service of the host,
+// and the key of the service as it was read, nil when it is created. before
+// and after are the spec the change replaces and the one it stores, nil when
+// the service is created and removed.
+type plannedChange struct {
+	host          string
+	previous      *consulapi.KVPair
+	before, after *apihub.ServiceSpec
+	ops           consulapi.KVTxnOps
+	// index is the one the change left the key of the service at.
+	index uint64
+}
+
+// batchChanges groups the changes, in order, in batches fitting in a
+// transaction along with their events, see eventOps.
+func batchChanges(changes []plannedChange) [][]*plannedChange {
+	batches := [][]*plannedChange{}
+	batch, ops := []*plannedChange{}, 0
+	for i := range changes {
+		// Each change logs an event, and the events of a transaction take
+		// up to two operations more.
+		if ops+len(changes[i].ops)+1 > MAX_TXN_OPS-2 {
+			batches = append(batches, batch)
+			batch, ops = []*plannedChange{}, 0
+		}
+		batch = append(batch, &changes[i])
+		ops += len(changes[i].ops) + 1
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// applyBatch makes the changes of the batch in a transaction, keeping the
+// index each one left the key of its service at.
+func (s *Storage) applyBatch(batch []*plannedChange) (bool, error) {
+	txn, events := consulapi.KVTxnOps{}, []apihub.ServiceEvent{}
+	for _, p := range batch {
+		txn = append(txn, p.ops...)
+		events = append(events, apihub.NewServiceEvent(p.before, p.after))
+	}
+
+	ok, resp, err := s.txn(txn, events)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	indexes := map[string]uint64{}
+	for _, kvp := range resp.Results {
+		indexes[kvp.Key] = kvp.ModifyIndex
+	}
+	for _, p := range batch {
+		p.index = indexes[s.key(p.host)]
+	}
+	return true, nil
+}
+
+// rollbackBatch restores the services changed by the batch as they were
+// read, unless they changed again since.
+func (s *Storage) rollbackBatch(batch []*plannedChange) error {
+	txn, events := consulapi.KVTxnOps{}, []apihub.ServiceEvent{}
+	for _, p := range batch {
+		switch {
+		case p.previous == nil:
+			txn = append(txn,
+				&consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: s.key(p.host), Index: p.index},
+				&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: s.key(p.host)},
+				&consulapi.KVTxnOp{Verb: consulapi.KVDeleteTree, Key: s.revisionKey(p.host, "")},
+			)
+		case p.after != nil:
+			txn = append(txn,
+				&consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: s.key(p.host), Value: p.previous.Value, Index: p.index},
+				&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: s.revisionKey(p.host, versionKey(p.after.Version))},
+			)
+		default:
+			txn = append(txn, &consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: s.key(p.host), Value: p.previous.Value, Index: 0})
+		}
+		events = append(events, apihub.NewServiceEvent(p.after, p.before))
+	}
+
+	ok, _, err := s.txn(txn, events)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errPartialChange
+	}
+	return nil
+}
+
+// txn runs the operations changing the services in a transaction logging
+// their events. It is retried while only the logging fails, when another
+// change logged its events first.
+func (s *Storage) txn(ops consulapi.KVTxnOps, events []apihub.ServiceEvent) (bool, *consulapi.KVTxnResponse, error) {
+	for {
+		eventOps, err := s.eventOps(events)
+		if err != nil {
+			return false, nil, err
+		}
+
+		ok, resp, _, err := s.kv.Txn(append(ops[:len(ops):len(ops)], eventOps...), nil)
+		if err != nil || ok {
+			return ok, resp, err
+		}
+		for _, txnErr := range resp.Errors {
+			if txnErr.OpIndex < len(ops) {
+				return false, resp, nil
+			}
+		}
+	}
+}
+
+// eventOps log the events after the version of the last one, which they
+// check-and-set, removing the oldest bucket of events once they start a new
+// one.
+func (s *Storage) eventOps(events []apihub.ServiceEvent) (consulapi.KVTxnOps, error) {
+	kvp, _, err := s.kv.Get(s.prefix+EVENTS_VERSION_SUFFIX, nil)
+	if err != nil {
+		return nil, err
+	}
+	var last, index uint64
+	if kvp != nil {
+		if last, err = strconv.ParseUint(string(kvp.Value), 10, 64); err != nil {
+			return nil, err
+		}
+		index = kvp.ModifyIndex
+	}
+
+	version := last + uint64(len(events))
+	ops := consulapi.KVTxnOps{
+		&consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: s.prefix + EVENTS_VERSION_SUFFIX, Value: []byte(strconv.FormatUint(version, 10)), Index: index},
+	}
+	for i, event := range events {
+		event.Version = last + uint64(i) + 1
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, &consulapi.KVTxnOp{Verb: string(consulapi.KVSet), Key: s.eventKey(event.Version), Value: data})
+	}
+
+	bucket := version / EVENTS_BUCKET_SIZE
+	if bucket > last/EVENTS_BUCKET_SIZE && bucket >= 2 {
+		ops = append(ops, &consulapi.KVTxnOp{Verb: consulapi.KVDeleteTree, Key: s.eventsBucketKey(bucket - 2)})
+	}
+	return ops, nil
+}
+
+// ServiceEvents lists the buckets holding the events after the version. The
+// events of the last two buckets are kept, so the first one kept is the first
+// of the bucket before the one of the last event.
+func (s *Storage) ServiceEvents(after uint64) ([]apihub.ServiceEvent, error) {
+	last, err := s.ServiceEventsVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	first := uint64(1)
+	if bucket := last / EVENTS_BUCKET_SIZE; bucket >= 2 {
+		first = (bucket - 1) * EVENTS_BUCKET_SIZE
+	}
+	if after > last || (after < last && after+1 < first) {
+		return nil, apihub.ErrServiceEventsPruned
+	}
+
+	events := []apihub.ServiceEvent{}
+	if after == last {
+		return events, nil
+	}
+	for bucket := (after + 1) / EVENTS_BUCKET_SIZE; bucket <= last/EVENTS_BUCKET_SIZE; bucket++ {
+		pairs, _, err := s.kv.List(s.eventsBucketKey(bucket), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, kvp := range pairs {
+			var event apihub.ServiceEvent
+			if err := json.Unmarshal(kvp.Value, &event); err != nil {
+				return nil, err
+			}
+			if event.Version > after && event.Version <= last {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
+func (s *Storage) ServiceEventsVersion() (uint64, error) {
+	kvp, _, err := s.kv.Get(s.prefix+EVENTS_VERSION_SUFFIX, nil)
+	if err != nil || kvp == nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(kvp.Value), 10, 64)
+}
+
+func (s *Storage) Revisions(host string) ([]apihub.Revision, error) {
+	if _, err := s.FindServiceByHost(host); err != nil {
+		return nil, err
+	}
+
+	pairs, _, err := s.kv.List(s.revisionKey(host, ""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []apihub.Revision{}
+	for _, kvp := range pairs {
+		var r apihub.Revision
+		if err := json.Unmarshal(kvp.Value, &r); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+func (s *Storage) FindRevision(host string, version uint64) (apihub.Revision, error) {
+	kvp, _, err := s.kv.Get(s.revisionKey(host, versionKey(version)), nil)
+	if err != nil {
+		return apihub.Revision{}, err
+	}
+	if kvp == nil {
+		return apihub.Revision{}, apihub.ErrRevisionNotFound
+	}
+
+	var r apihub.Revision
+	if err := json.Unmarshal(kvp.Value, &r); err != nil {
+		return apihub.Revision{}, err
+	}
+	return r, nil
+}
+
+func (s *Storage) AddToken(t apihub.Token) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	// Index 0 only writes the keys of the hash and the ID when they are not
+	// in use.
+	ok, _, _, err := s.kv.Txn(consulapi.KVTxnOps{
+		&consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: s.tokenKey(t.Hash), Value: data, Index: 0},
+		&consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: s.tokenIDKey(t.ID), Value: []byte(t.Hash), Index: 0},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apihub.ErrTokenInUse
+	}
+	return nil
+}
+
+func (s *Storage) FindTokenByHash(hash string) (apihub.Token, error) {
+	kvp, _, err := s.kv.Get(s.tokenKey(hash), nil)
+	if err != nil {
+		return apihub.Token{}, err
+	}
+	if kvp == nil {
+		return apihub.Token{}, apihub.ErrTokenNotFound
+	}
+
+	var token apihub.Token
+	if err := json.Unmarshal(kvp.Value, &token); err != nil {
+		return apihub.Token{}, err
+	}
+	return token, nil
+}
+
+func (s *Storage) Tokens() ([]apihub.Token, error) {
+	pairs, _, err := s.kv.List(s.tokenKey(""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []apihub.Token{}
+	for _, kvp := range pairs {
+		var token apihub.Token
+		if err := json.Unmarshal(kvp.Value, &token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+// RemoveToken reads the hash of the token from its ID, then deletes both keys
+// in a transaction.
+func (s *Storage) RemoveToken(id string) error {
+	kvp, _, err := s.kv.Get(s.tokenIDKey(id), nil)
+	if err != nil {
+		return err
+	}
+	if kvp == nil {
+		return apihub.ErrTokenNotFound
+	}
+
+	ok, _, _, err := s.kv.Txn(consulapi.KVTxnOps{
+		&consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: kvp.Key, Index: kvp.ModifyIndex},
+		&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: kvp.Key},
+		&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: s.tokenKey(string(kvp.Value))},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.removalConflict(kvp.Key, apihub.ErrTokenNotFound, errTokenConflict)
+	}
+	return nil
+}
+
+func (s *Storage) AddNamespace(ns apihub.Namespace) error {
+	data, err := json.Marshal(ns)
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := s.kv.CAS(&consulapi.KVPair{Key: s.namespaceKey(ns.Name), Value: data}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apihub.ErrNamespaceInUse
+	}
+	return nil
+}
+
+func (s *Storage) FindNamespace(name string) (apihub.Namespace, error) {
+	kvp, _, err := s.kv.Get(s.namespaceKey(name), nil)
+	if err != nil {
+		return apihub.Namespace{}, err
+	}
+	if kvp == nil {
+		return apihub.Namespace{}, apihub.ErrNamespaceNotFound
+	}
+
+	var ns apihub.Namespace
+	if err := json.Unmarshal(kvp.Value, &ns); err != nil {
+		return apihub.Namespace{}, err
+	}
+	return ns, nil
+}
+
+func (s *Storage) Namespaces() ([]apihub.Namespace, error) {
+	pairs, _, err := s.kv.List(s.namespaceKey(""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := []apihub.Namespace{}
+	for _, kvp := range pairs {
+		var ns apihub.Namespace
+		if err := json.Unmarshal(kvp.Value, &ns); err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
+
+func (s *Storage) RemoveNamespace(name string) error {
+	kvp, _, err := s.kv.Get(s.namespaceKey(name), nil)
+	if err != nil {
+		return err
+	}
+	if kvp == nil {
+		return apihub.ErrNamespaceNotFound
+	}
+
+	_, err = s.kv.Delete(kvp.Key, nil)
+	return err
+}
+
+// AddAuditEvent never overwrites an event. It first removes the days of
+// events past the retention.
+func (s *Storage) AddAuditEvent(e apihub.AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := s.pruneAuditEvents(time.Now()); err != nil {
+		return err
+	}
+
+	ok, _, err := s.kv.CAS(&consulapi.KVPair{Key: s.auditKey(e), Value: data}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errAuditEventInUse
+	}
+	return nil
+}
+
+// AuditEvents lists the days of events, newest first, only reading the ones
+// between the times of the query, until the limit of the query is reached.
+func (s *Storage) AuditEvents(q apihub.AuditQuery) ([]apihub.AuditEvent, error) {
+	days, _, err := s.kv.Keys(s.prefix+AUDIT_SUFFIX, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	events := []apihub.AuditEvent{}
+	for _, day := range days {
+		if !q.Until.IsZero() && day > s.auditDayKey(q.Until) {
+			continue
+		}
+		if !q.Since.IsZero() && day < s.auditDayKey(q.Since) {
+			break
+		}
+
+		pairs, _, err := s.kv.List(day, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, kvp := range pairs {
+			var e apihub.AuditEvent
+			if err := json.Unmarshal(kvp.Value, &e); err != nil {
+				return nil, err
+			}
+			if q.Matches(e) {
+				events = append(events, e)
+			}
+		}
+		if q.Limit > 0 && len(events) >= q.Limit {
+			break
+		}
+	}
+	return apihub.SelectAuditEvents(events, q), nil
+}
+
+// pruneAuditEvents removes the days of events before the one the retention
+// starts on, so the events are kept up to a day longer than the retention.
+// Only the keys of the days are listed.
+func (s *Storage) pruneAuditEvents(now time.Time) error {
+	if s.auditRetention <= 0 {
+		return nil
+	}
+
+	days, _, err := s.kv.Keys(s.prefix+AUDIT_SUFFIX, "/", nil)
+	if err != nil {
+		return err
+	}
+
+	first := s.auditDayKey(now.Add(-s.auditRetention))
+	for _, day := range days {
+		if day < first {
+			if _, err := s.kv.DeleteTree(day, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Storage) key(host string) string {
+	return s.prefix + SERVICES_SUFFIX + host
+}
+
+// revisionKey returns the key of a revision of the service. The versions are
+// zero padded, so the keys of a service list in order.
+func (s *Storage) revisionKey(host, version string) string {
+	return s.prefix + REVISIONS_SUFFIX + host + "/" + version
+}
+
+func (s *Storage) PutOutboxEntry(e apihub.OutboxEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.kv.Put(&consulapi.KVPair{Key: s.outboxKey(e.Host), Value: data}, nil)
+	return err
+}
+
+func (s *Storage) OutboxEntries() ([]apihub.OutboxEntry, error) {
+	pairs, _, err := s.kv.List(s.outboxKey(""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []apihub.OutboxEntry{}
+	for _, kvp := range pairs {
+		var e apihub.OutboxEntry
+		if err := json.Unmarshal(kvp.Value, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// RemoveOutboxEntry deletes the entry with check-and-set, so an entry
+// replaced after it was read is kept.
+func (s *Storage) RemoveOutboxEntry(host, id string) error {
+	kvp, _, err := s.kv.Get(s.outboxKey(host), nil)
+	if err != nil {
+		return err
+	}
+	if kvp == nil {
+		return nil
+	}
+
+	var e apihub.OutboxEntry
+	if err := json.Unmarshal(kvp.Value, &e); err != nil {
+		return err
+	}
+	if e.ID != id {
+		return nil
+	}
+
+	_, _, err = s.kv.DeleteCAS(kvp, nil)
+	return err
+}
+
+func (s *Storage) AddWebhook(w apihub.Webhook) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := s.kv.CAS(&consulapi.KVPair{Key: s.webhookKey(w.ID), Value: data}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apihub.ErrWebhookInUse
+	}
+	return nil
+}
+
+func (s *Storage) FindWebhook(id string) (apihub.Webhook, error) {
+	kvp, _, err := s.kv.Get(s.webhookKey(id), nil)
+	if err != nil {
+		return apihub.Webhook{}, err
+	}
+	if kvp == nil {
+		return apihub.Webhook{}, apihub.ErrWebhookNotFound
+	}
+
+	var w apihub.Webhook
+	if err := json.Unmarshal(kvp.Value, &w); err != nil {
+		return apihub.Webhook{}, err
+	}
+	return w, nil
+}
+
+func (s *Storage) Webhooks() ([]apihub.Webhook, error) {
+	pairs, _, err := s.kv.List(s.webhookKey(""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := []apihub.Webhook{}
+	for _, kvp := range pairs {
+		var w apihub.Webhook
+		if err := json.Unmarshal(kvp.Value, &w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// RemoveWebhook deletes the webhook and its deliveries in a transaction.
+func (s *Storage) RemoveWebhook(id string) error {
+	kvp, _, err := s.kv.Get(s.webhookKey(id), nil)
+	if err != nil {
+		return err
+	}
+	if kvp == nil {
+		return apihub.ErrWebhookNotFound
+	}
+
+	ok, _, _, err := s.kv.Txn(consulapi.KVTxnOps{
+		&consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: kvp.Key, Index: kvp.ModifyIndex},
+		&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: kvp.Key},
+		&consulapi.KVTxnOp{Verb: consulapi.KVDeleteTree, Key: s.webhookDeliveriesKey(id)},
+		&consulapi.KVTxnOp{Verb: consulapi.KVDeleteTree, Key: s.pendingKey(id, "")},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.removalConflict(kvp.Key, apihub.ErrWebhookNotFound, errWebhookConflict)
+	}
+	return nil
+}
+
+// PutWebhookDelivery writes the delivery, and the copy of the pending ones,
+// in a transaction. The time a delivery was created at is part of its key, so
+// it must not change.
+func (s *Storage) PutWebhookDelivery(d apihub.WebhookDelivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	pending := &consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: s.pendingKey(d.WebhookID, d.ID)}
+	if d.Status == apihub.DELIVERY_PENDING {
+		pending = &consulapi.KVTxnOp{Verb: string(consulapi.KVSet), Key: s.pendingKey(d.WebhookID, d.ID), Value: data}
+	}
+
+	ok, _, _, err := s.kv.Txn(consulapi.KVTxnOps{
+		&consulapi.KVTxnOp{Verb: string(consulapi.KVSet), Key: s.deliveryKey(d), Value: data},
+		pending,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errWebhookConflict
+	}
+	return nil
+}
+
+func (s *Storage) WebhookDeliveries(webhookID string) ([]apihub.WebhookDelivery, error) {
+	pairs, _, err := s.kv.List(s.webhookDeliveriesKey(webhookID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := decodeDeliveries(pairs)
+	if err != nil {
+		return nil, err
+	}
+	apihub.SortWebhookDeliveries(deliveries)
+	return deliveries, nil
+}
+
+// DueWebhookDeliveries only lists the copies of the pending deliveries.
+func (s *Storage) DueWebhookDeliveries(now time.Time) ([]apihub.WebhookDelivery, error) {
+	pairs, _, err := s.kv.List(s.prefix+PENDING_SUFFIX, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := decodeDeliveries(pairs)
+	if err != nil {
+		return nil, err
+	}
+	return apihub.SelectDueWebhookDeliveries(deliveries, now), nil
+}
+
+// PruneWebhookDeliveries lists the hours of deliveries of each webhook. The
+// ones before the hour of the time are removed as a whole, unless they hold
+// a pending delivery, and only the deliveries of the others are listed.
+func (s *Storage) PruneWebhookDeliveries(before time.Time) error {
+	pairs, _, err := s.kv.List(s.prefix+PENDING_SUFFIX, nil)
+	if err != nil {
+		return err
+	}
+	pending, err := decodeDeliveries(pairs)
+	if err != nil {
+		return err
+	}
+	keep := map[string]bool{}
+	for _, d := range pending {
+		keep[s.deliveryHourKey(d.WebhookID, d.CreatedAt)] = true
+	}
+
+	webhooks, _, err := s.kv.Keys(s.prefix+DELIVERIES_SUFFIX, "/", nil)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range webhooks {
+		hours, _, err := s.kv.Keys(webhook, "/", nil)
+		if err != nil {
+			return err
+		}
+
+		last := webhook + before.UTC().Format(DELIVERY_BUCKET_LAYOUT) + "/"
+		for _, hour := range hours {
+			if hour > last {
+				continue
+			}
+			if hour < last && !keep[hour] {
+				if _, err := s.kv.DeleteTree(hour, nil); err != nil {
+					return err
+				}
+				continue
+			}
+
+			pairs, _, err := s.kv.List(hour, nil)
+			if err != nil {
+				return err
+			}
+			deliveries, err := decodeDeliveries(pairs)
+			if err != nil {
+				return err
+			}
+			for _, d := range deliveries {
+				if d.Status != apihub.DELIVERY_PENDING && d.CreatedAt.Before(before) {
+					if _, err := s.kv.Delete(s.deliveryKey(d), nil); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func decodeDeliveries(pairs consulapi.KVPairs) ([]apihub.WebhookDelivery, error) {
+	deliveries := []apihub.WebhookDelivery{}
+	for _, kvp := range pairs {
+		var d apihub.WebhookDelivery
+		if err := json.Unmarshal(kvp.Value, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// serviceOps write the spec when its key is still at the index, along with
+// its revision.
+func (s *Storage) serviceOps(spec apihub.ServiceSpec, index uint64) (consulapi.KVTxnOps, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := s.revisionOp(spec)
+	if err != nil {
+		return nil, err
+	}
+	return consulapi.KVTxnOps{
+		&consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: s.key(spec.Host), Value: data, Index: index},
+		revision,
+	}, nil
+}
+
+// createOps write the spec of a new service, only when its key does not exist
+// yet. The revisions left behind by ChangeServices are removed first.
+func (s *Storage) createOps(spec apihub.ServiceSpec) (consulapi.KVTxnOps, error) {
+	ops, err := s.serviceOps(spec, 0)
+	if err != nil {
+		return nil, err
+	}
+	return consulapi.KVTxnOps{
+		ops[0],
+		&consulapi.KVTxnOp{Verb: consulapi.KVDeleteTree, Key: s.revisionKey(spec.Host, "")},
+		ops[1],
+	}, nil
+}
+
+// removeOps delete the service when its key is still at the index, along
+// with its revisions. The index is checked on its own, as a check-and-set
+// delete of a missing key succeeds.
+func (s *Storage) removeOps(host string, index uint64) consulapi.KVTxnOps {
+	return consulapi.KVTxnOps{
+		&consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: s.key(host), Index: index},
+		&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: s.key(host)},
+		&consulapi.KVTxnOp{Verb: consulapi.KVDeleteTree, Key: s.revisionKey(host, "")},
+	}
+}
+
+// revisionOp writes the spec as a revision of its service.
+func (s *Storage) revisionOp(spec apihub.ServiceSpec) (*consulapi.KVTxnOp, error) {
+	data, err := json.Marshal(apihub.NewRevision(spec))
+	if err != nil {
+		return nil, err
+	}
+	return &consulapi.KVTxnOp{Verb: string(consulapi.KVSet), Key: s.revisionKey(spec.Host, versionKey(spec.Version)), Value: data}, nil
+}
+
+func (s *Storage) tokenKey(hash string) string {
+	return s.prefix + TOKENS_SUFFIX + hash
+}
+
+func (s *Storage) tokenIDKey(id string) string {
+	return s.prefix + TOKEN_IDS_SUFFIX + id
+}
+
+func (s *Storage) namespaceKey(name string) string {
+	return s.prefix + NAMESPACES_SUFFIX + name
+}
+
+// auditKey returns the key of an audit event, named after its time and ID so
+// the keys list oldest first.
+func (s *Storage) auditKey(e apihub.AuditEvent) string {
+	return s.auditDayKey(e.Time) + fmt.Sprintf("%020d-%s", e.Time.UnixNano(), e.ID)
+}
+
+// auditDayKey returns the prefix of the keys of the audit events recorded on
+// the day of the time.
+func (s *Storage) auditDayKey(t time.Time) string {
+	return s.prefix + AUDIT_SUFFIX + t.UTC().Format(AUDIT_BUCKET_LAYOUT) + "/"
+}
+
+func (s *Storage) outboxKey(host string) string {
+	return s.prefix + OUTBOX_SUFFIX + host
+}
+
+func (s *Storage) webhookKey(id string) string {
+	return s.prefix + WEBHOOKS_SUFFIX + id
+}
+
+func (s *Storage) webhookDeliveriesKey(webhookID string) string {
+	return s.prefix + DELIVERIES_SUFFIX + webhookID + "/"
+}
+
+// deliveryHourKey returns the prefix of the keys of the deliveries of the
+// webhook created in the hour of the time.
+func (s *Storage) deliveryHourKey(webhookID string, t time.Time) string {
+	return s.webhookDeliveriesKey(webhookID) + t.UTC().Format(DELIVERY_BUCKET_LAYOUT) + "/"
+}
+
+func (s *Storage) deliveryKey(d apihub.WebhookDelivery) string {
+	return s.deliveryHourKey(d.WebhookID, d.CreatedAt) + d.ID
+}
+
+func (s *Storage) pendingKey(webhookID, id string) string {
+	return s.prefix + PENDING_SUFFIX + webhookID + "/" + id
+}
+
+// eventsBucketKey returns the prefix of the keys of the service events of a
+// bucket, see EVENTS_BUCKET_SIZE.
+func (s *Storage) eventsBucketKey(bucket uint64) string {
+	return s.prefix + EVENTS_SUFFIX + versionKey(bucket) + "/"
+}
+
+func (s *Storage) eventKey(version uint64) string {
+	return s.eventsBucketKey(version/EVENTS_BUCKET_SIZE) + versionKey(version)
+}
+
+func versionKey(version uint64) string {
+	return fmt.Sprintf("%020d", version)
+}
+
+// decodeService reads a stored spec, with version 1 when it was stored
+// before services were versioned.
+func decodeService(data []byte) (apihub.ServiceSpec, error) {
+	var spec apihub.ServiceSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	return apihub.BackfillVersion(spec), nil
+}
@@ -0,0 +1,327 @@
+package consul_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/storage/consul"
+	"github.com/apihub/apihub/storage/storagetest"
+	consulapi "github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Consul", func() {
+	var (
+		store *consul.Storage
+		spec  apihub.ServiceSpec
+	)
+
+	BeforeEach(func() {
+		store = consul.New(consulClient, "apihub-test/")
+		spec = apihub.ServiceSpec{
+			Host:    "my-host",
+			Version: 1,
+			Backends: []apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-a"},
+			},
+		}
+	})
+
+	Describe("AddService", func() {
+		It("stores the service under the services of the prefix", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+
+			kvp, _, err := consulClient.KV().Get("apihub-test/services/my-host", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp).NotTo(BeNil())
+
+			data, err := json.Marshal(spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp.Value).To(Equal(data))
+		})
+
+		Context("when the service already exists for given host", func() {
+			BeforeEach(func() {
+				Expect(store.AddService(spec)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				Expect(store.AddService(spec)).To(MatchError("host already in use"))
+			})
+		})
+	})
+
+	Describe("UpdateService", func() {
+		It("updates a service", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+			spec.Disabled = true
+			Expect(store.UpdateService(spec)).To(Succeed())
+
+			found, err := store.FindServiceByHost(spec.Host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Disabled).To(BeTrue())
+		})
+
+		Context("when service does not exits", func() {
+			It("returns an error", func() {
+				Expect(store.UpdateService(spec)).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("FindServiceByHost", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		It("finds a service", func() {
+			found, err := store.FindServiceByHost("my-host")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(Equal(spec))
+		})
+
+		Context("when service is not found", func() {
+			It("returns an error", func() {
+				_, err := store.FindServiceByHost("invalid-host")
+				Expect(err).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("Services", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+			_, err := consulClient.KV().Put(&consulapi.KVPair{Key: "another-prefix/another-host", Value: []byte("{}")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("lists the services under the prefix", func() {
+			services, err := store.Services()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services).To(ConsistOf(spec))
+		})
+
+		It("does not read the keys kept next to the services", func() {
+			Expect(store.AddToken(apihub.Token{ID: "deploys", Hash: "hash"})).To(Succeed())
+			Expect(store.AddAuditEvent(apihub.AuditEvent{ID: "e1", Time: time.Now(), Host: spec.Host})).To(Succeed())
+
+			pairs, _, err := consulClient.KV().List("apihub-test/services/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs).To(HaveLen(1))
+		})
+	})
+
+	Describe("AddAuditEvent", func() {
+		It("removes the days of events past the retention", func() {
+			store.SetAuditRetention(24 * time.Hour)
+			now := time.Now()
+			for i, id := range []string{"e1", "e2", "e3"} {
+				Expect(store.AddAuditEvent(apihub.AuditEvent{ID: id, Time: now.Add(time.Duration(i-2) * 24 * time.Hour)})).To(Succeed())
+			}
+			Expect(store.AddAuditEvent(apihub.AuditEvent{ID: "e4", Time: now})).To(Succeed())
+
+			events, err := store.AuditEvents(apihub.AuditQuery{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(3))
+			Expect(events[0].ID).To(Equal("e4"))
+			Expect(events[2].ID).To(Equal("e2"))
+		})
+	})
+
+	Describe("AuditEvents", func() {
+		It("only reads the days of events up to the limit", func() {
+			day := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+			store.SetAuditRetention(0)
+			for i, id := range []string{"e1", "e2", "e3"} {
+				Expect(store.AddAuditEvent(apihub.AuditEvent{ID: id, Time: day.Add(time.Duration(i) * 24 * time.Hour)})).To(Succeed())
+			}
+			_, err := consulClient.KV().Put(&consulapi.KVPair{Key: "apihub-test/_audit/2020-05-01/unreadable", Value: []byte("{")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			events, err := store.AuditEvents(apihub.AuditQuery{Limit: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].ID).To(Equal("e3"))
+			Expect(events[1].ID).To(Equal("e2"))
+
+			events, err = store.AuditEvents(apihub.AuditQuery{Since: day.Add(24 * time.Hour), Until: day.Add(24 * time.Hour)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].ID).To(Equal("e2"))
+		})
+	})
+
+	Describe("ChangeServices", func() {
+		var specs []apihub.ServiceSpec
+
+		BeforeEach(func() {
+			specs = []apihub.ServiceSpec{}
+			changes := []apihub.ServiceChange{}
+			for i := 0; i < 40; i++ {
+				spec.Host = fmt.Sprintf("host-%d", i)
+				specs = append(specs, spec)
+				changes = append(changes, apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: spec})
+			}
+			Expect(store.ChangeServices(changes)).To(Succeed())
+		})
+
+		It("makes more changes than a transaction holds", func() {
+			services, err := store.Services()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services).To(ConsistOf(specs))
+
+			revisions, err := store.Revisions("host-39")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(revisions).To(HaveLen(1))
+		})
+
+		Context("when a later transaction fails", func() {
+			It("rolls back the ones before it", func() {
+				changes := []apihub.ServiceChange{
+					{Action: apihub.APPLY_DELETE, Spec: apihub.ServiceSpec{Host: "host-0"}},
+				}
+				for _, s := range specs[1:30] {
+					s.Disabled = true
+					changes = append(changes, apihub.ServiceChange{Action: apihub.APPLY_UPDATE, Spec: s})
+				}
+				spec.Host = "new-host"
+				changes = append(changes,
+					apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: spec},
+					apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: spec},
+				)
+
+				Expect(store.ChangeServices(changes)).To(MatchError(apihub.ErrVersionConflict))
+
+				services, err := store.Services()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(services).To(ConsistOf(specs))
+
+				revisions, err := store.Revisions("host-1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(revisions).To(HaveLen(1))
+				revisions, err = store.Revisions("host-0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(revisions).To(HaveLen(1))
+				_, err = store.FindServiceByHost("new-host")
+				Expect(err).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("ServiceEvents", func() {
+		It("only keeps the events of the last two buckets", func() {
+			version := 2*consul.EVENTS_BUCKET_SIZE + 500
+			_, err := consulClient.KV().Put(&consulapi.KVPair{Key: "apihub-test/_events_version", Value: []byte(fmt.Sprint(version))}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.AddService(spec)).To(Succeed())
+
+			_, err = store.ServiceEvents(consul.EVENTS_BUCKET_SIZE - 2)
+			Expect(err).To(MatchError(apihub.ErrServiceEventsPruned))
+
+			events, err := store.ServiceEvents(consul.EVENTS_BUCKET_SIZE - 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Version).To(BeEquivalentTo(version + 1))
+		})
+
+		It("removes the oldest bucket once it starts a new one", func() {
+			_, err := consulClient.KV().Put(&consulapi.KVPair{Key: "apihub-test/_events_version", Value: []byte(fmt.Sprint(2*consul.EVENTS_BUCKET_SIZE - 1))}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = consulClient.KV().Put(&consulapi.KVPair{Key: "apihub-test/_events/00000000000000000000/00000000000000000001", Value: []byte("{}")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(store.AddService(spec)).To(Succeed())
+
+			pairs, _, err := consulClient.KV().List("apihub-test/_events/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs).To(HaveLen(1))
+			Expect(pairs[0].Key).To(Equal(fmt.Sprintf("apihub-test/_events/%020d/%020d", 2, 2*consul.EVENTS_BUCKET_SIZE)))
+		})
+	})
+
+	Describe("RemoveService", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		It("removes service by host", func() {
+			Expect(store.RemoveService(spec.Host)).To(Succeed())
+
+			_, err := store.FindServiceByHost(spec.Host)
+			Expect(err).To(MatchError("service not found"))
+		})
+
+		Context("when service is not found", func() {
+			It("returns an error", func() {
+				Expect(store.RemoveService("invalid-host")).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("tokens", func() {
+		It("keeps the tokens under their hash", func() {
+			Expect(store.AddToken(apihub.Token{ID: "deploys", Hash: "hash"})).To(Succeed())
+
+			kvp, _, err := consulClient.KV().Get("apihub-test/_tokens/hash", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp).NotTo(BeNil())
+
+			Expect(store.RemoveToken("deploys")).To(Succeed())
+			pairs, _, err := consulClient.KV().List("apihub-test/_token", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs).To(BeEmpty())
+		})
+	})
+
+	Describe("webhook deliveries", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+			for i, status := range []string{apihub.DELIVERY_DELIVERED, apihub.DELIVERY_PENDING, apihub.DELIVERY_FAILED} {
+				Expect(store.PutWebhookDelivery(apihub.WebhookDelivery{
+					ID:          fmt.Sprintf("d%d", i),
+					WebhookID:   "w1",
+					Status:      status,
+					CreatedAt:   now.Add(-time.Duration(3-i) * time.Hour),
+					NextAttempt: now,
+				})).To(Succeed())
+			}
+		})
+
+		It("keeps a copy of the pending deliveries only", func() {
+			pairs, _, err := consulClient.KV().List("apihub-test/_webhook_pending/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs).To(HaveLen(1))
+
+			Expect(store.PutWebhookDelivery(apihub.WebhookDelivery{ID: "d1", WebhookID: "w1", Status: apihub.DELIVERY_DELIVERED, CreatedAt: now.Add(-2 * time.Hour)})).To(Succeed())
+			due, err := store.DueWebhookDeliveries(now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(due).To(BeEmpty())
+		})
+
+		It("removes the hours of deliveries before the time, but the pending ones", func() {
+			Expect(store.PruneWebhookDeliveries(now)).To(Succeed())
+
+			deliveries, err := store.WebhookDeliveries("w1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].ID).To(Equal("d1"))
+
+			hours, _, err := consulClient.KV().Keys("apihub-test/_webhook_deliveries/w1/", "/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hours).To(ConsistOf("apihub-test/_webhook_deliveries/w1/2020-05-01T10/"))
+		})
+	})
+
+	storagetest.Conformance(func() apihub.Storage {
+		// The conformance specs record audit events at fixed dates, long
+		// past the default retention.
+		store.SetAuditRetention(0)
+		return store
+	})
+})
@@ -1,21 +1,44 @@
-// package storage provides in memory storage implementation, for test purposes.
+// package storage provides the implementations of apihub.Storage: in memory,
+// for test purposes, and persistent ones for production.
 package storage
 
 import (
-	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/apihub/apihub"
 )
 
+// SERVICE_EVENT_LIMIT is the number of service events the storages of this
+// package keep, see apihub.ServiceEventStorage.
+const SERVICE_EVENT_LIMIT = 1000
+
 type Memory struct {
-	mtx      sync.RWMutex
-	services map[string]apihub.ServiceSpec
+	mtx        sync.RWMutex
+	services   map[string]apihub.ServiceSpec
+	revisions  map[string][]apihub.Revision
+	tokens     map[string]apihub.Token
+	namespaces map[string]apihub.Namespace
+	audit      []apihub.AuditEvent
+	outbox     map[string]apihub.OutboxEntry
+	webhooks   map[string]apihub.Webhook
+	deliveries map[string]map[string]apihub.WebhookDelivery
+	// events holds the newest service events, and eventsVersion the version
+	// of the last one.
+	events        []apihub.ServiceEvent
+	eventsVersion uint64
 }
 
 func New() *Memory {
 	return &Memory{
-		services: make(map[string]apihub.ServiceSpec),
+		services:   make(map[string]apihub.ServiceSpec),
+		revisions:  make(map[string][]apihub.Revision),
+		tokens:     make(map[string]apihub.Token),
+		namespaces: make(map[string]apihub.Namespace),
+		outbox:     make(map[string]apihub.OutboxEntry),
+		webhooks:   make(map[string]apihub.Webhook),
+		deliveries: make(map[string]map[string]apihub.WebhookDelivery),
 	}
 }
 
@@ -23,11 +46,18 @@ func (m *Memory) AddService(s apihub.ServiceSpec) error {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
+	return m.addService(s)
+}
+
+func (m *Memory) addService(s apihub.ServiceSpec) error {
 	if _, ok := m.services[s.Host]; ok {
-		return errors.New("host already in use")
+		return apihub.ErrHostInUse
 	}
 
+	s = apihub.BackfillVersion(s)
 	m.services[s.Host] = s
+	m.revisions[s.Host] = append(m.revisions[s.Host], apihub.NewRevision(s))
+	m.logEvent(nil, &s)
 	return nil
 }
 
@@ -35,11 +65,22 @@ func (m *Memory) UpdateService(s apihub.ServiceSpec) error {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
-	if _, ok := m.services[s.Host]; !ok {
-		return errors.New("service not found")
+	return m.updateService(s)
+}
+
+func (m *Memory) updateService(s apihub.ServiceSpec) error {
+	stored, ok := m.services[s.Host]
+	if !ok {
+		return apihub.ErrServiceNotFound
+	}
+	if s.Version != stored.Version {
+		return apihub.ErrVersionConflict
 	}
 
+	s.Version = stored.Version + 1
 	m.services[s.Host] = s
+	m.revisions[s.Host] = append(m.revisions[s.Host], apihub.NewRevision(s))
+	m.logEvent(&stored, &s)
 	return nil
 }
 
@@ -48,7 +89,7 @@ func (m *Memory) FindServiceByHost(host string) (apihub.ServiceSpec, error) {
 	defer m.mtx.RUnlock()
 
 	if service, ok := m.services[host]; !ok {
-		return apihub.ServiceSpec{}, errors.New("service not found")
+		return apihub.ServiceSpec{}, apihub.ErrServiceNotFound
 	} else {
 		return service, nil
 	}
@@ -62,18 +103,397 @@ func (m *Memory) Services() ([]apihub.ServiceSpec, error) {
 	for _, service := range m.services {
 		services = append(services, service)
 	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Host < services[j].Host
+	})
 
 	return services, nil
 }
 
+func (m *Memory) ListServices(q apihub.ServiceQuery) (apihub.ServicePage, error) {
+	if err := q.Validate(); err != nil {
+		return apihub.ServicePage{}, err
+	}
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	services := make([]apihub.ServiceSpec, 0, len(m.services))
+	for _, service := range m.services {
+		services = append(services, service)
+	}
+	return apihub.SelectServices(services, q)
+}
+
 func (m *Memory) RemoveService(host string) error {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
-	if _, ok := m.services[host]; !ok {
-		return errors.New("service not found")
+	return m.removeService(host)
+}
+
+func (m *Memory) removeService(host string) error {
+	stored, ok := m.services[host]
+	if !ok {
+		return apihub.ErrServiceNotFound
 	}
 
 	delete(m.services, host)
+	delete(m.revisions, host)
+	m.logEvent(&stored, nil)
+	return nil
+}
+
+// logEvent logs a change to a service, keeping the newest
+// SERVICE_EVENT_LIMIT events.
+func (m *Memory) logEvent(before, after *apihub.ServiceSpec) {
+	event := apihub.NewServiceEvent(before, after)
+	m.eventsVersion++
+	event.Version = m.eventsVersion
+	m.events = append(m.events, event)
+	if len(m.events) > SERVICE_EVENT_LIMIT {
+		m.events = append([]apihub.ServiceEvent{}, m.events[len(m.events)-SERVICE_EVENT_LIMIT:]...)
+	}
+}
+
+// ChangeServices makes the changes on copies of the services and their
+// revisions, which only replace the stored ones once all the changes are made.
+// The events logged by the changes are dropped when one fails.
+func (m *Memory) ChangeServices(changes []apihub.ServiceChange) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	services, revisions := m.services, m.revisions
+	events, eventsVersion := m.events, m.eventsVersion
+	m.services = make(map[string]apihub.ServiceSpec, len(services))
+	for host, service := range services {
+		m.services[host] = service
+	}
+	m.revisions = make(map[string][]apihub.Revision, len(revisions))
+	for host, revs := range revisions {
+		m.revisions[host] = append([]apihub.Revision{}, revs...)
+	}
+
+	for _, change := range changes {
+		var err error
+		switch change.Action {
+		case apihub.APPLY_CREATE:
+			err = m.addService(change.Spec)
+		case apihub.APPLY_UPDATE:
+			err = m.updateService(change.Spec)
+		case apihub.APPLY_DELETE:
+			err = m.removeService(change.Spec.Host)
+		default:
+			err = apihub.ErrUnknownChange
+		}
+		if err != nil {
+			m.services, m.revisions = services, revisions
+			m.events, m.eventsVersion = events, eventsVersion
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ServiceEvents(after uint64) ([]apihub.ServiceEvent, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return selectServiceEvents(m.events, m.eventsVersion, after)
+}
+
+func (m *Memory) ServiceEventsVersion() (uint64, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.eventsVersion, nil
+}
+
+// selectServiceEvents returns the events logged after the version, given the
+// events kept oldest first and the version of the last one logged.
+func selectServiceEvents(events []apihub.ServiceEvent, last, after uint64) ([]apihub.ServiceEvent, error) {
+	if after > last || (len(events) > 0 && after+1 < events[0].Version) || (len(events) == 0 && after < last) {
+		return nil, apihub.ErrServiceEventsPruned
+	}
+
+	selected := []apihub.ServiceEvent{}
+	for _, event := range events {
+		if event.Version > after {
+			selected = append(selected, event)
+		}
+	}
+	return selected, nil
+}
+
+func (m *Memory) Revisions(host string) ([]apihub.Revision, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if _, ok := m.services[host]; !ok {
+		return nil, apihub.ErrServiceNotFound
+	}
+
+	return append([]apihub.Revision{}, m.revisions[host]...), nil
+}
+
+func (m *Memory) FindRevision(host string, version uint64) (apihub.Revision, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for _, r := range m.revisions[host] {
+		if r.Version == version {
+			return r, nil
+		}
+	}
+	return apihub.Revision{}, apihub.ErrRevisionNotFound
+}
+
+func (m *Memory) AddToken(t apihub.Token) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for _, token := range m.tokens {
+		if token.ID == t.ID || token.Hash == t.Hash {
+			return apihub.ErrTokenInUse
+		}
+	}
+
+	m.tokens[t.ID] = t
+	return nil
+}
+
+func (m *Memory) FindTokenByHash(hash string) (apihub.Token, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for _, token := range m.tokens {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+	return apihub.Token{}, apihub.ErrTokenNotFound
+}
+
+func (m *Memory) Tokens() ([]apihub.Token, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	tokens := []apihub.Token{}
+	for _, token := range m.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID < tokens[j].ID
+	})
+
+	return tokens, nil
+}
+
+func (m *Memory) RemoveToken(id string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.tokens[id]; !ok {
+		return apihub.ErrTokenNotFound
+	}
+
+	delete(m.tokens, id)
+	return nil
+}
+
+func (m *Memory) AddNamespace(ns apihub.Namespace) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.namespaces[ns.Name]; ok {
+		return apihub.ErrNamespaceInUse
+	}
+
+	m.namespaces[ns.Name] = ns
+	return nil
+}
+
+func (m *Memory) FindNamespace(name string) (apihub.Namespace, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if ns, ok := m.namespaces[name]; !ok {
+		return apihub.Namespace{}, apihub.ErrNamespaceNotFound
+	} else {
+		return ns, nil
+	}
+}
+
+func (m *Memory) Namespaces() ([]apihub.Namespace, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	namespaces := []apihub.Namespace{}
+	for _, ns := range m.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+
+	return namespaces, nil
+}
+
+func (m *Memory) RemoveNamespace(name string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.namespaces[name]; !ok {
+		return apihub.ErrNamespaceNotFound
+	}
+
+	delete(m.namespaces, name)
+	return nil
+}
+
+func (m *Memory) AddAuditEvent(e apihub.AuditEvent) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.audit = append(m.audit, e)
+	return nil
+}
+
+func (m *Memory) AuditEvents(q apihub.AuditQuery) ([]apihub.AuditEvent, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return apihub.SelectAuditEvents(m.audit, q), nil
+}
+
+func (m *Memory) PutOutboxEntry(e apihub.OutboxEntry) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.outbox[e.Host] = e
+	return nil
+}
+
+func (m *Memory) OutboxEntries() ([]apihub.OutboxEntry, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	entries := []apihub.OutboxEntry{}
+	for _, e := range m.outbox {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Host < entries[j].Host
+	})
+	return entries, nil
+}
+
+func (m *Memory) RemoveOutboxEntry(host, id string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if e, ok := m.outbox[host]; ok && e.ID == id {
+		delete(m.outbox, host)
+	}
+	return nil
+}
+
+func (m *Memory) AddWebhook(w apihub.Webhook) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.webhooks[w.ID]; ok {
+		return apihub.ErrWebhookInUse
+	}
+
+	m.webhooks[w.ID] = w
+	return nil
+}
+
+func (m *Memory) FindWebhook(id string) (apihub.Webhook, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	w, ok := m.webhooks[id]
+	if !ok {
+		return apihub.Webhook{}, apihub.ErrWebhookNotFound
+	}
+	return w, nil
+}
+
+func (m *Memory) Webhooks() ([]apihub.Webhook, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	webhooks := []apihub.Webhook{}
+	for _, w := range m.webhooks {
+		webhooks = append(webhooks, w)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+func (m *Memory) RemoveWebhook(id string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.webhooks[id]; !ok {
+		return apihub.ErrWebhookNotFound
+	}
+
+	delete(m.webhooks, id)
+	delete(m.deliveries, id)
+	return nil
+}
+
+func (m *Memory) PutWebhookDelivery(d apihub.WebhookDelivery) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.deliveries[d.WebhookID] == nil {
+		m.deliveries[d.WebhookID] = make(map[string]apihub.WebhookDelivery)
+	}
+	m.deliveries[d.WebhookID][d.ID] = d
+	return nil
+}
+
+func (m *Memory) WebhookDeliveries(webhookID string) ([]apihub.WebhookDelivery, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	deliveries := []apihub.WebhookDelivery{}
+	for _, d := range m.deliveries[webhookID] {
+		deliveries = append(deliveries, d)
+	}
+	apihub.SortWebhookDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (m *Memory) DueWebhookDeliveries(now time.Time) ([]apihub.WebhookDelivery, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	deliveries := []apihub.WebhookDelivery{}
+	for _, webhookDeliveries := range m.deliveries {
+		for _, d := range webhookDeliveries {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return apihub.SelectDueWebhookDeliveries(deliveries, now), nil
+}
+
+func (m *Memory) PruneWebhookDeliveries(before time.Time) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for _, webhookDeliveries := range m.deliveries {
+		for id, d := range webhookDeliveries {
+			if d.Status != apihub.DELIVERY_PENDING && d.CreatedAt.Before(before) {
+				delete(webhookDeliveries, id)
+			}
+		}
+	}
 	return nil
 }
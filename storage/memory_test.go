@@ -3,6 +3,7 @@ package storage_test
 import (
 	"github.com/apihub/apihub"
 	"github.com/apihub/apihub/storage"
+	"github.com/apihub/apihub/storage/storagetest"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,7 +17,7 @@ var _ = Describe("Memory", func() {
 
 	BeforeEach(func() {
 		store = storage.New()
-		spec = apihub.ServiceSpec{Host: "my-host"}
+		spec = apihub.ServiceSpec{Host: "my-host", Version: 1}
 	})
 
 	Describe("AddService", func() {
@@ -94,4 +95,33 @@ var _ = Describe("Memory", func() {
 			})
 		})
 	})
+	Describe("ServiceEvents", func() {
+		It("only keeps the newest events", func() {
+			events := store.(apihub.ServiceEventStorage)
+			Expect(store.AddService(spec)).To(Succeed())
+			for i := 0; i < storage.SERVICE_EVENT_LIMIT; i++ {
+				spec.Version = uint64(i + 1)
+				Expect(store.UpdateService(spec)).To(Succeed())
+			}
+
+			_, err := events.ServiceEvents(0)
+			Expect(err).To(MatchError(apihub.ErrServiceEventsPruned))
+
+			kept, err := events.ServiceEvents(1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kept).To(HaveLen(storage.SERVICE_EVENT_LIMIT))
+			Expect(kept[0].Version).To(BeEquivalentTo(2))
+		})
+	})
+	storagetest.Conformance(func() apihub.Storage {
+		return store
+	})
+
+	Context("when only the services are kept", func() {
+		// The struct hides the methods beyond apihub.Storage, so only its
+		// specs run.
+		storagetest.Conformance(func() apihub.Storage {
+			return struct{ apihub.Storage }{store}
+		})
+	})
 })
@@ -0,0 +1,1137 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/apihub/apihub"
+)
+
+// migrations holds the schema of the SQL storage, in order. A migration is
+// never changed once released: changes to the schema go in a new one.
+var migrations = [][]string{
+	{
+		`CREATE TABLE services (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			host VARCHAR(255) NOT NULL,
+			disabled BOOLEAN NOT NULL DEFAULT 0,
+			timeout INTEGER NOT NULL DEFAULT 0,
+			spec TEXT NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX services_host ON services (host)`,
+		`CREATE TABLE backends (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			service_id INTEGER NOT NULL REFERENCES services (id),
+			position INTEGER NOT NULL,
+			address TEXT NOT NULL,
+			disabled BOOLEAN NOT NULL DEFAULT 0,
+			heart_beat_address TEXT NOT NULL DEFAULT '',
+			heart_beat_timeout INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX backends_service_id ON backends (service_id, position)`,
+	},
+	{
+		`CREATE TABLE service_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			host VARCHAR(255) NOT NULL,
+			action VARCHAR(16) NOT NULL,
+			spec TEXT NOT NULL,
+			changed_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX service_history_host ON service_history (host, id)`,
+	},
+	{
+		`ALTER TABLE services ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	},
+	{
+		`CREATE TABLE service_labels (
+			service_id INTEGER NOT NULL REFERENCES services (id),
+			name VARCHAR(255) NOT NULL,
+			value TEXT NOT NULL
+		)`,
+		`CREATE INDEX service_labels_name ON service_labels (name, value)`,
+		`CREATE INDEX backends_address ON backends (address)`,
+	},
+	{
+		`CREATE TABLE tokens (
+			id VARCHAR(64) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			role VARCHAR(16) NOT NULL,
+			hash CHAR(64) NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX tokens_hash ON tokens (hash)`,
+	},
+	{
+		`ALTER TABLE services ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT ''`,
+		`CREATE INDEX services_namespace ON services (namespace)`,
+		`ALTER TABLE tokens ADD COLUMN namespaces TEXT NOT NULL DEFAULT ''`,
+		`CREATE TABLE namespaces (
+			name VARCHAR(255) PRIMARY KEY,
+			domain_suffixes TEXT NOT NULL DEFAULT ''
+		)`,
+	},
+	{
+		`CREATE TABLE audit_events (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			id VARCHAR(64) NOT NULL,
+			recorded_at INTEGER NOT NULL,
+			actor VARCHAR(255) NOT NULL,
+			action VARCHAR(32) NOT NULL,
+			host VARCHAR(255) NOT NULL,
+			event TEXT NOT NULL
+		)`,
+		`CREATE INDEX audit_events_recorded_at ON audit_events (recorded_at, id)`,
+		`CREATE INDEX audit_events_host ON audit_events (host, recorded_at)`,
+	},
+	{
+		`CREATE TABLE service_revisions (
+			host VARCHAR(255) NOT NULL,
+			version INTEGER NOT NULL,
+			spec TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (host, version)
+		)`,
+	},
+	{
+		// The history is read from the revisions, which hold the same specs.
+		`DROP TABLE service_history`,
+	},
+	{
+		`CREATE TABLE outbox (
+			host VARCHAR(255) PRIMARY KEY,
+			id VARCHAR(64) NOT NULL,
+			entry TEXT NOT NULL
+		)`,
+	},
+	{
+		`CREATE TABLE service_events (
+			version INTEGER PRIMARY KEY AUTOINCREMENT,
+			event TEXT NOT NULL
+		)`,
+	},
+	{
+		`CREATE TABLE webhooks (
+			id VARCHAR(64) PRIMARY KEY,
+			webhook TEXT NOT NULL
+		)`,
+		`CREATE TABLE webhook_deliveries (
+			id VARCHAR(64) PRIMARY KEY,
+			webhook_id VARCHAR(64) NOT NULL,
+			created_at INTEGER NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			next_attempt INTEGER NOT NULL,
+			delivery TEXT NOT NULL
+		)`,
+		`CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at)`,
+		`CREATE INDEX webhook_deliveries_status ON webhook_deliveries (status, next_attempt)`,
+	},
+}
+
+// SQL stores the services in a SQL database, with the backends as rows of
+// their own. The labels are also copied to rows, so services can be queried
+// by them. The spec stored by every change is also kept in the
+// service_revisions table, which History reads. The tokens, the namespaces,
+// the audit events, the outbox, the service events and the webhooks and their
+// deliveries are kept in tables of their own; the time of the audit events
+// and of the deliveries is stored in nanoseconds, so they keep their order.
+//
+// It is written against SQLite, but only relies on database/sql: the driver
+// is registered by the binary, see cmd/api.
+type SQL struct {
+	db *sql.DB
+}
+
+// HistoryEntry holds a change made to a service.
+type HistoryEntry struct {
+	Action    string             `json:"action"`
+	Spec      apihub.ServiceSpec `json:"spec"`
+	ChangedAt time.Time          `json:"changed_at"`
+}
+
+// NewSQL applies the pending migrations to the database.
+func NewSQL(db *sql.DB) (*SQL, error) {
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	return &SQL{db: db}, nil
+}
+
+// SchemaVersion returns the number of migrations applied to the database.
+func (s *SQL) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// History returns the changes made to the service with the given host, oldest
+// first: the first revision it was added with, then the ones it was updated
+// to. The revisions are removed along with the service.
+func (s *SQL) History(host string) ([]HistoryEntry, error) {
+	revisions, err := s.revisions(`WHERE host = ?`, host)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []HistoryEntry{}
+	for i, r := range revisions {
+		action := "update"
+		if i == 0 {
+			action = "add"
+		}
+		history = append(history, HistoryEntry{Action: action, Spec: r.Spec, ChangedAt: r.CreatedAt})
+	}
+	return history, nil
+}
+
+func (s *SQL) AddService(spec apihub.ServiceSpec) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return insertService(tx, spec)
+	})
+}
+
+func (s *SQL) UpdateService(spec apihub.ServiceSpec) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return replaceService(tx, spec)
+	})
+}
+
+func (s *SQL) FindServiceByHost(host string) (apihub.ServiceSpec, error) {
+	return findService(s.db, host)
+}
+
+func (s *SQL) Services() ([]apihub.ServiceSpec, error) {
+	page, err := s.ListServices(apihub.ServiceQuery{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Services, nil
+}
+
+// ListServices runs the whole query in the database, reading one more row
+// than the limit to know whether there is a next page.
+func (s *SQL) ListServices(q apihub.ServiceQuery) (apihub.ServicePage, error) {
+	if err := q.Validate(); err != nil {
+		return apihub.ServicePage{}, err
+	}
+	cursor, err := q.ParseCursor()
+	if err != nil {
+		return apihub.ServicePage{}, err
+	}
+
+	where := []string{"1 = 1"}
+	args := []interface{}{}
+	if q.HostPrefix != "" {
+		// A range on the host, unlike a function of it, uses its index.
+		where = append(where, `host >= ?`)
+		args = append(args, q.HostPrefix)
+		if end := prefixEnd(q.HostPrefix); end != "" {
+			where = append(where, `host < ?`)
+			args = append(args, end)
+		}
+	}
+	if q.Disabled != nil {
+		where = append(where, `disabled = ?`)
+		args = append(args, *q.Disabled)
+	}
+	if q.Backend != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM backends b WHERE b.service_id = services.id AND b.address = ?)`)
+		args = append(args, q.Backend)
+	}
+	for name, value := range q.Labels {
+		where = append(where, `EXISTS (SELECT 1 FROM service_labels l WHERE l.service_id = services.id AND l.name = ? AND l.value = ?)`)
+		args = append(args, name, value)
+	}
+	if len(q.Namespaces) > 0 {
+		where = append(where, `namespace IN (`+placeholders(len(q.Namespaces))+`)`)
+		for _, ns := range q.Namespaces {
+			args = append(args, ns)
+		}
+	}
+
+	field, desc := q.SortBy()
+	op, order := ">", "ASC"
+	if desc {
+		op, order = "<", "DESC"
+	}
+	orderBy := "host " + order
+	if field == apihub.SORT_BY_VERSION {
+		orderBy = "version " + order + ", " + orderBy
+		if cursor.Host != "" {
+			where = append(where, `(version `+op+` ? OR (version = ? AND host `+op+` ?))`)
+			args = append(args, cursor.Version, cursor.Version, cursor.Host)
+		}
+	} else if cursor.Host != "" {
+		where = append(where, `host `+op+` ?`)
+		args = append(args, cursor.Host)
+	}
+
+	query := `SELECT id, version, spec FROM services WHERE ` + strings.Join(where, " AND ") + ` ORDER BY ` + orderBy
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit+1)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return apihub.ServicePage{}, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	services := []apihub.ServiceSpec{}
+	for rows.Next() {
+		var (
+			id      int64
+			version uint64
+			data    string
+			spec    apihub.ServiceSpec
+		)
+		if err := rows.Scan(&id, &version, &data); err != nil {
+			return apihub.ServicePage{}, err
+		}
+		if err := json.Unmarshal([]byte(data), &spec); err != nil {
+			return apihub.ServicePage{}, err
+		}
+		spec.Version = version
+		ids = append(ids, id)
+		services = append(services, spec)
+	}
+	if err := rows.Err(); err != nil {
+		return apihub.ServicePage{}, err
+	}
+
+	page := apihub.ServicePage{Services: services}
+	if q.Limit > 0 && len(services) > q.Limit {
+		ids = ids[:q.Limit]
+		page.Services = services[:q.Limit]
+		page.NextCursor = q.NextCursor(page.Services[q.Limit-1])
+	}
+
+	var backends map[int64][]apihub.BackendInfo
+	if q.Limit > 0 {
+		backends, err = s.backends(`WHERE service_id IN (`+placeholders(len(ids))+`)`, int64Args(ids)...)
+	} else {
+		backends, err = s.backends("")
+	}
+	if err != nil {
+		return apihub.ServicePage{}, err
+	}
+	for i, id := range ids {
+		page.Services[i].Backends = backends[id]
+	}
+	return page, nil
+}
+
+func (s *SQL) RemoveService(host string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return deleteService(tx, host)
+	})
+}
+
+// ChangeServices makes all the changes in one transaction, which is rolled
+// back by the first one failing.
+func (s *SQL) ChangeServices(changes []apihub.ServiceChange) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		for _, change := range changes {
+			var err error
+			switch change.Action {
+			case apihub.APPLY_CREATE:
+				err = insertService(tx, change.Spec)
+			case apihub.APPLY_UPDATE:
+				err = replaceService(tx, change.Spec)
+			case apihub.APPLY_DELETE:
+				err = deleteService(tx, change.Spec.Host)
+			default:
+				err = apihub.ErrUnknownChange
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQL) ServiceEvents(after uint64) ([]apihub.ServiceEvent, error) {
+	var first, last uint64
+	if err := s.db.QueryRow(`SELECT COALESCE(MIN(version), 0), COALESCE(MAX(version), 0) FROM service_events`).Scan(&first, &last); err != nil {
+		return nil, err
+	}
+	if after > last || (after < last && first > after+1) {
+		return nil, apihub.ErrServiceEventsPruned
+	}
+
+	rows, err := s.db.Query(`SELECT version, event FROM service_events WHERE version > ? ORDER BY version`, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []apihub.ServiceEvent{}
+	for rows.Next() {
+		var (
+			version uint64
+			data    string
+			event   apihub.ServiceEvent
+		)
+		if err := rows.Scan(&version, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, err
+		}
+		event.Version = version
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQL) ServiceEventsVersion() (uint64, error) {
+	var version uint64
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM service_events`).Scan(&version)
+	return version, err
+}
+
+func (s *SQL) Revisions(host string) ([]apihub.Revision, error) {
+	var found int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM services WHERE host = ?`, host).Scan(&found); err != nil {
+		return nil, err
+	}
+	if found == 0 {
+		return nil, apihub.ErrServiceNotFound
+	}
+
+	return s.revisions(`WHERE host = ?`, host)
+}
+
+func (s *SQL) FindRevision(host string, version uint64) (apihub.Revision, error) {
+	revisions, err := s.revisions(`WHERE host = ? AND version = ?`, host, version)
+	if err != nil {
+		return apihub.Revision{}, err
+	}
+	if len(revisions) == 0 {
+		return apihub.Revision{}, apihub.ErrRevisionNotFound
+	}
+	return revisions[0], nil
+}
+
+func (s *SQL) revisions(where string, args ...interface{}) ([]apihub.Revision, error) {
+	rows, err := s.db.Query(`SELECT version, spec, created_at FROM service_revisions `+where+` ORDER BY version`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []apihub.Revision{}
+	for rows.Next() {
+		var (
+			r    apihub.Revision
+			data string
+		)
+		if err := rows.Scan(&r.Version, &data, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &r.Spec); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = r.CreatedAt.UTC()
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQL) AddToken(t apihub.Token) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		var found int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM tokens WHERE id = ? OR hash = ?`, t.ID, t.Hash).Scan(&found)
+		if err != nil {
+			return err
+		}
+		if found > 0 {
+			return apihub.ErrTokenInUse
+		}
+
+		_, err = tx.Exec(`INSERT INTO tokens (id, name, role, namespaces, hash, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			t.ID, t.Name, t.Role, strings.Join(t.Namespaces, ","), t.Hash, t.CreatedAt.UTC())
+		return err
+	})
+}
+
+func (s *SQL) FindTokenByHash(hash string) (apihub.Token, error) {
+	tokens, err := s.tokens(`WHERE hash = ?`, hash)
+	if err != nil {
+		return apihub.Token{}, err
+	}
+	if len(tokens) == 0 {
+		return apihub.Token{}, apihub.ErrTokenNotFound
+	}
+	return tokens[0], nil
+}
+
+func (s *SQL) Tokens() ([]apihub.Token, error) {
+	return s.tokens(``)
+}
+
+func (s *SQL) RemoveToken(id string) error {
+	result, err := s.db.Exec(`DELETE FROM tokens WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return err
+	} else if removed == 0 {
+		return apihub.ErrTokenNotFound
+	}
+	return nil
+}
+
+func (s *SQL) tokens(where string, args ...interface{}) ([]apihub.Token, error) {
+	rows, err := s.db.Query(`SELECT id, name, role, namespaces, hash, created_at FROM tokens `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []apihub.Token{}
+	for rows.Next() {
+		var (
+			t          apihub.Token
+			namespaces string
+		)
+		if err := rows.Scan(&t.ID, &t.Name, &t.Role, &namespaces, &t.Hash, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		t.Namespaces = splitList(namespaces)
+		t.CreatedAt = t.CreatedAt.UTC()
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *SQL) AddNamespace(ns apihub.Namespace) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		var found int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM namespaces WHERE name = ?`, ns.Name).Scan(&found); err != nil {
+			return err
+		}
+		if found > 0 {
+			return apihub.ErrNamespaceInUse
+		}
+
+		_, err := tx.Exec(`INSERT INTO namespaces (name, domain_suffixes) VALUES (?, ?)`, ns.Name, strings.Join(ns.DomainSuffixes, ","))
+		return err
+	})
+}
+
+func (s *SQL) FindNamespace(name string) (apihub.Namespace, error) {
+	namespaces, err := s.namespaces(`WHERE name = ?`, name)
+	if err != nil {
+		return apihub.Namespace{}, err
+	}
+	if len(namespaces) == 0 {
+		return apihub.Namespace{}, apihub.ErrNamespaceNotFound
+	}
+	return namespaces[0], nil
+}
+
+func (s *SQL) Namespaces() ([]apihub.Namespace, error) {
+	return s.namespaces(``)
+}
+
+func (s *SQL) RemoveNamespace(name string) error {
+	result, err := s.db.Exec(`DELETE FROM namespaces WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return err
+	} else if removed == 0 {
+		return apihub.ErrNamespaceNotFound
+	}
+	return nil
+}
+
+func (s *SQL) namespaces(where string, args ...interface{}) ([]apihub.Namespace, error) {
+	rows, err := s.db.Query(`SELECT name, domain_suffixes FROM namespaces `+where+` ORDER BY name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	namespaces := []apihub.Namespace{}
+	for rows.Next() {
+		var (
+			ns       apihub.Namespace
+			suffixes string
+		)
+		if err := rows.Scan(&ns.Name, &suffixes); err != nil {
+			return nil, err
+		}
+		ns.DomainSuffixes = splitList(suffixes)
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, rows.Err()
+}
+
+func (s *SQL) AddAuditEvent(e apihub.AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO audit_events (id, recorded_at, actor, action, host, event) VALUES (?, ?, ?, ?, ?, ?)`,
+		e.ID, e.Time.UnixNano(), e.Actor, e.Action, e.Host, string(data))
+	return err
+}
+
+func (s *SQL) AuditEvents(q apihub.AuditQuery) ([]apihub.AuditEvent, error) {
+	where := []string{"1 = 1"}
+	args := []interface{}{}
+	for _, filter := range [][2]string{{"host", q.Host}, {"actor", q.Actor}, {"action", q.Action}} {
+		if filter[1] != "" {
+			where = append(where, filter[0]+" = ?")
+			args = append(args, filter[1])
+		}
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "recorded_at >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "recorded_at <= ?")
+		args = append(args, q.Until.UnixNano())
+	}
+
+	query := `SELECT event FROM audit_events WHERE ` + strings.Join(where, " AND ") + ` ORDER BY recorded_at DESC, id DESC`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []apihub.AuditEvent{}
+	for rows.Next() {
+		var (
+			e    apihub.AuditEvent
+			data string
+		)
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQL) PutOutboxEntry(e apihub.OutboxEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM outbox WHERE host = ?`, e.Host); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO outbox (host, id, entry) VALUES (?, ?, ?)`, e.Host, e.ID, string(data))
+		return err
+	})
+}
+
+func (s *SQL) OutboxEntries() ([]apihub.OutboxEntry, error) {
+	rows, err := s.db.Query(`SELECT entry FROM outbox ORDER BY host`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []apihub.OutboxEntry{}
+	for rows.Next() {
+		var (
+			e    apihub.OutboxEntry
+			data string
+		)
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQL) RemoveOutboxEntry(host, id string) error {
+	_, err := s.db.Exec(`DELETE FROM outbox WHERE host = ? AND id = ?`, host, id)
+	return err
+}
+
+func (s *SQL) AddWebhook(w apihub.Webhook) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+
+	return s.transaction(func(tx *sql.Tx) error {
+		var found int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE id = ?`, w.ID).Scan(&found); err != nil {
+			return err
+		}
+		if found > 0 {
+			return apihub.ErrWebhookInUse
+		}
+
+		_, err := tx.Exec(`INSERT INTO webhooks (id, webhook) VALUES (?, ?)`, w.ID, string(data))
+		return err
+	})
+}
+
+func (s *SQL) FindWebhook(id string) (apihub.Webhook, error) {
+	webhooks, err := s.webhooks(`WHERE id = ?`, id)
+	if err != nil {
+		return apihub.Webhook{}, err
+	}
+	if len(webhooks) == 0 {
+		return apihub.Webhook{}, apihub.ErrWebhookNotFound
+	}
+	return webhooks[0], nil
+}
+
+func (s *SQL) Webhooks() ([]apihub.Webhook, error) {
+	return s.webhooks(``)
+}
+
+func (s *SQL) RemoveWebhook(id string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if removed, err := result.RowsAffected(); err != nil {
+			return err
+		} else if removed == 0 {
+			return apihub.ErrWebhookNotFound
+		}
+
+		_, err = tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id)
+		return err
+	})
+}
+
+func (s *SQL) webhooks(where string, args ...interface{}) ([]apihub.Webhook, error) {
+	rows, err := s.db.Query(`SELECT webhook FROM webhooks `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []apihub.Webhook{}
+	for rows.Next() {
+		var (
+			w    apihub.Webhook
+			data string
+		)
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *SQL) PutWebhookDelivery(d apihub.WebhookDelivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	return s.transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE id = ?`, d.ID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO webhook_deliveries (id, webhook_id, created_at, status, next_attempt, delivery) VALUES (?, ?, ?, ?, ?, ?)`,
+			d.ID, d.WebhookID, d.CreatedAt.UnixNano(), d.Status, d.NextAttempt.UnixNano(), string(data))
+		return err
+	})
+}
+
+func (s *SQL) WebhookDeliveries(webhookID string) ([]apihub.WebhookDelivery, error) {
+	return s.deliveries(`WHERE webhook_id = ? ORDER BY created_at DESC, id DESC`, webhookID)
+}
+
+func (s *SQL) DueWebhookDeliveries(now time.Time) ([]apihub.WebhookDelivery, error) {
+	return s.deliveries(`WHERE status = ? AND next_attempt <= ? ORDER BY created_at, id`, apihub.DELIVERY_PENDING, now.UnixNano())
+}
+
+func (s *SQL) PruneWebhookDeliveries(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM webhook_deliveries WHERE status <> ? AND created_at < ?`, apihub.DELIVERY_PENDING, before.UnixNano())
+	return err
+}
+
+func (s *SQL) deliveries(where string, args ...interface{}) ([]apihub.WebhookDelivery, error) {
+	rows, err := s.db.Query(`SELECT delivery FROM webhook_deliveries `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []apihub.WebhookDelivery{}
+	for rows.Next() {
+		var (
+			d    apihub.WebhookDelivery
+			data string
+		)
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *SQL) transaction(fn func(*sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// backends returns the backends matching the condition, keyed by the id of
+// their service.
+func (s *SQL) backends(where string, args ...interface{}) (map[int64][]apihub.BackendInfo, error) {
+	return selectBackends(s.db, where, args...)
+}
+
+func selectBackends(q queryer, where string, args ...interface{}) (map[int64][]apihub.BackendInfo, error) {
+	rows, err := q.Query(`SELECT service_id, address, disabled, heart_beat_address, heart_beat_timeout
+		FROM backends `+where+` ORDER BY service_id, position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backends := map[int64][]apihub.BackendInfo{}
+	for rows.Next() {
+		var (
+			id int64
+			be apihub.BackendInfo
+		)
+		if err := rows.Scan(&id, &be.Address, &be.Disabled, &be.HeartBeatAddress, &be.HeartBeatTimeout); err != nil {
+			return nil, err
+		}
+		backends[id] = append(backends[id], be)
+	}
+	return backends, rows.Err()
+}
+
+func insertService(tx *sql.Tx, spec apihub.ServiceSpec) error {
+	spec = apihub.BackfillVersion(spec)
+	if _, err := findServiceID(tx, spec.Host); err == nil {
+		return apihub.ErrHostInUse
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	data, err := encodeSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`INSERT INTO services (host, version, disabled, timeout, namespace, spec) VALUES (?, ?, ?, ?, ?, ?)`,
+		spec.Host, spec.Version, spec.Disabled, int64(spec.Timeout), spec.Namespace, data)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := insertBackends(tx, id, spec.Backends); err != nil {
+		return err
+	}
+	if err := insertLabels(tx, id, spec.Labels); err != nil {
+		return err
+	}
+	if err := recordRevision(tx, spec); err != nil {
+		return err
+	}
+	return recordEvent(tx, nil, &spec)
+}
+
+func replaceService(tx *sql.Tx, spec apihub.ServiceSpec) error {
+	stored, err := findService(tx, spec.Host)
+	if err != nil {
+		return err
+	}
+	if spec.Version != stored.Version {
+		return apihub.ErrVersionConflict
+	}
+	id, err := findServiceID(tx, spec.Host)
+	if err != nil {
+		return err
+	}
+	version := stored.Version
+
+	data, err := encodeSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	// The version is checked again on write, in case another connection
+	// updated the service since it was read.
+	result, err := tx.Exec(`UPDATE services SET version = ?, disabled = ?, timeout = ?, namespace = ?, spec = ? WHERE id = ? AND version = ?`,
+		version+1, spec.Disabled, int64(spec.Timeout), spec.Namespace, data, id, version)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		return apihub.ErrVersionConflict
+	}
+	spec.Version = version + 1
+	if _, err := tx.Exec(`DELETE FROM backends WHERE service_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM service_labels WHERE service_id = ?`, id); err != nil {
+		return err
+	}
+
+	if err := insertBackends(tx, id, spec.Backends); err != nil {
+		return err
+	}
+	if err := insertLabels(tx, id, spec.Labels); err != nil {
+		return err
+	}
+	if err := recordRevision(tx, spec); err != nil {
+		return err
+	}
+	return recordEvent(tx, &stored, &spec)
+}
+
+func deleteService(tx *sql.Tx, host string) error {
+	stored, err := findService(tx, host)
+	if err != nil {
+		return err
+	}
+	id, err := findServiceID(tx, host)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM backends WHERE service_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM service_labels WHERE service_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM services WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM service_revisions WHERE host = ?`, host); err != nil {
+		return err
+	}
+	return recordEvent(tx, &stored, nil)
+}
+
+// queryer runs queries in the database, or in a transaction.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func findService(q queryer, host string) (apihub.ServiceSpec, error) {
+	var (
+		id      int64
+		version uint64
+		data    string
+	)
+	err := q.QueryRow(`SELECT id, version, spec FROM services WHERE host = ?`, host).Scan(&id, &version, &data)
+	if err == sql.ErrNoRows {
+		return apihub.ServiceSpec{}, apihub.ErrServiceNotFound
+	} else if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+
+	var spec apihub.ServiceSpec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	spec.Version = version
+
+	backends, err := selectBackends(q, `WHERE service_id = ?`, id)
+	if err != nil {
+		return apihub.ServiceSpec{}, err
+	}
+	spec.Backends = backends[id]
+	return spec, nil
+}
+
+func findServiceID(tx *sql.Tx, host string) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM services WHERE host = ?`, host).Scan(&id)
+	return id, err
+}
+
+func insertBackends(tx *sql.Tx, serviceID int64, backends []apihub.BackendInfo) error {
+	for i, be := range backends {
+		if _, err := tx.Exec(`INSERT INTO backends (service_id, position, address, disabled, heart_beat_address, heart_beat_timeout)
+			VALUES (?, ?, ?, ?, ?, ?)`, serviceID, i, be.Address, be.Disabled, be.HeartBeatAddress, be.HeartBeatTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertLabels(tx *sql.Tx, serviceID int64, labels map[string]string) error {
+	for name, value := range labels {
+		if _, err := tx.Exec(`INSERT INTO service_labels (service_id, name, value) VALUES (?, ?, ?)`, serviceID, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitList reads a comma separated column, the names it holds cannot have
+// commas.
+func splitList(column string) []string {
+	if column == "" {
+		return nil
+	}
+	return strings.Split(column, ",")
+}
+
+// prefixEnd returns the smallest string after every string starting with the
+// prefix, or "" when there is none.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
+
+// placeholders returns n comma separated bind parameters.
+func placeholders(n int) string {
+	if n == 0 {
+		return "NULL"
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func int64Args(values []int64) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+// recordEvent logs a change to a service, with the next id of the
+// service_events table as its version, and removes the event
+// SERVICE_EVENT_LIMIT versions older. The ids have no gaps, as the sequence
+// of the table is rolled back along with the transaction.
+func recordEvent(tx *sql.Tx, before, after *apihub.ServiceSpec) error {
+	data, err := json.Marshal(apihub.NewServiceEvent(before, after))
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`INSERT INTO service_events (event) VALUES (?)`, string(data))
+	if err != nil {
+		return err
+	}
+	version, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM service_events WHERE version = ?`, version-SERVICE_EVENT_LIMIT)
+	return err
+}
+
+// recordRevision keeps the whole spec, with its version and backends, as a
+// revision.
+func recordRevision(tx *sql.Tx, spec apihub.ServiceSpec) error {
+	r := apihub.NewRevision(spec)
+	data, err := json.Marshal(r.Spec)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO service_revisions (host, version, spec, created_at) VALUES (?, ?, ?, ?)`,
+		spec.Host, r.Version, string(data), r.CreatedAt)
+	return err
+}
+
+// encodeSpec returns the spec without its version and backends, which are
+// stored as columns and rows.
+func encodeSpec(spec apihub.ServiceSpec) (string, error) {
+	spec.Version = 0
+	spec.Backends = nil
+	data, err := json.Marshal(spec)
+	return string(data), err
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, statement := range migrations[i] {
+			if _, err := tx.Exec(statement); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
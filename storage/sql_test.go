@@ -0,0 +1,220 @@
+package storage_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apihub/apihub"
+	"github.com/apihub/apihub/storage"
+	"github.com/apihub/apihub/storage/storagetest"
+	_ "modernc.org/sqlite"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SQL", func() {
+	var (
+		tmpDir string
+		db     *sql.DB
+		store  *storage.SQL
+		spec   apihub.ServiceSpec
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir(os.TempDir(), "apihub-storage-sql-test")
+		Expect(err).NotTo(HaveOccurred())
+
+		db, err = sql.Open("sqlite", filepath.Join(tmpDir, "apihub.db"))
+		Expect(err).NotTo(HaveOccurred())
+		db.SetMaxOpenConns(1)
+
+		store, err = storage.NewSQL(db)
+		Expect(err).NotTo(HaveOccurred())
+		spec = apihub.ServiceSpec{
+			Host:    "my-host",
+			Version: 1,
+			Timeout: 100,
+			Backends: []apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-a"},
+				apihub.BackendInfo{Address: "http://server-b", Disabled: true},
+			},
+			Maintenance: &apihub.MaintenanceInfo{Enabled: true},
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("NewSQL", func() {
+		It("applies the migrations once", func() {
+			version, err := store.SchemaVersion()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(12))
+
+			store, err = storage.NewSQL(db)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.SchemaVersion()).To(Equal(version))
+		})
+	})
+
+	Describe("AddService", func() {
+		It("stores the backends as rows", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+
+			var count int
+			Expect(db.QueryRow(`SELECT COUNT(*) FROM backends`).Scan(&count)).To(Succeed())
+			Expect(count).To(Equal(2))
+		})
+
+		It("stores the labels as rows", func() {
+			spec.Labels = map[string]string{"team": "payments", "tier": "gold"}
+			Expect(store.AddService(spec)).To(Succeed())
+
+			var count int
+			Expect(db.QueryRow(`SELECT COUNT(*) FROM service_labels`).Scan(&count)).To(Succeed())
+			Expect(count).To(Equal(2))
+		})
+
+		Context("when the service already exists for given host", func() {
+			BeforeEach(func() {
+				Expect(store.AddService(spec)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				Expect(store.AddService(spec)).To(MatchError("host already in use"))
+			})
+		})
+	})
+
+	Describe("UpdateService", func() {
+		It("replaces the backends", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+			spec.Backends = []apihub.BackendInfo{
+				apihub.BackendInfo{Address: "http://server-c"},
+			}
+			Expect(store.UpdateService(spec)).To(Succeed())
+
+			found, err := store.FindServiceByHost(spec.Host)
+			Expect(err).NotTo(HaveOccurred())
+			spec.Version = 2
+			Expect(found).To(Equal(spec))
+		})
+
+		Context("when service does not exits", func() {
+			It("returns an error", func() {
+				Expect(store.UpdateService(spec)).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("FindServiceByHost", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		It("finds a service", func() {
+			found, err := store.FindServiceByHost("my-host")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(Equal(spec))
+		})
+
+		Context("when service is not found", func() {
+			It("returns an error", func() {
+				_, err := store.FindServiceByHost("invalid-host")
+				Expect(err).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("Services", func() {
+		It("lists all services with their backends", func() {
+			another := apihub.ServiceSpec{
+				Host:    "another-host",
+				Version: 1,
+				Backends: []apihub.BackendInfo{
+					apihub.BackendInfo{Address: "http://server-d"},
+				},
+			}
+			Expect(store.AddService(spec)).To(Succeed())
+			Expect(store.AddService(another)).To(Succeed())
+
+			services, err := store.Services()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services).To(ConsistOf(spec, another))
+		})
+	})
+
+	Describe("RemoveService", func() {
+		BeforeEach(func() {
+			Expect(store.AddService(spec)).To(Succeed())
+		})
+
+		It("removes the service and its backends", func() {
+			Expect(store.RemoveService(spec.Host)).To(Succeed())
+
+			var count int
+			Expect(db.QueryRow(`SELECT COUNT(*) FROM backends`).Scan(&count)).To(Succeed())
+			Expect(count).To(Equal(0))
+		})
+
+		Context("when service is not found", func() {
+			It("returns an error", func() {
+				Expect(store.RemoveService("invalid-host")).To(MatchError("service not found"))
+			})
+		})
+	})
+
+	Describe("ListServices", func() {
+		It("selects the hosts by prefix with a range of hosts", func() {
+			for _, host := range []string{"my-host", "my-host-2", "my-hosu", "my-hos", "other"} {
+				spec.Host = host
+				Expect(store.AddService(spec)).To(Succeed())
+			}
+
+			page, err := store.ListServices(apihub.ServiceQuery{HostPrefix: "my-host"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(page.Services).To(HaveLen(2))
+			Expect(page.Services[0].Host).To(Equal("my-host"))
+			Expect(page.Services[1].Host).To(Equal("my-host-2"))
+		})
+	})
+
+	Describe("History", func() {
+		It("records the changes of the service", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+			spec.Disabled = true
+			Expect(store.UpdateService(spec)).To(Succeed())
+
+			history, err := store.History(spec.Host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(HaveLen(2))
+			Expect(history[0].Action).To(Equal("add"))
+			Expect(history[0].Spec.Version).To(BeEquivalentTo(1))
+			Expect(history[1].Action).To(Equal("update"))
+			Expect(history[1].Spec.Disabled).To(BeTrue())
+			Expect(history[1].Spec.Version).To(BeEquivalentTo(2))
+		})
+
+		It("forgets the changes of a removed service", func() {
+			Expect(store.AddService(spec)).To(Succeed())
+			Expect(store.RemoveService(spec.Host)).To(Succeed())
+
+			Expect(store.History(spec.Host)).To(BeEmpty())
+		})
+
+		It("drops the table the changes were recorded in", func() {
+			var count int
+			Expect(db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'service_history'`).Scan(&count)).To(Succeed())
+			Expect(count).To(BeZero())
+		})
+	})
+	storagetest.Conformance(func() apihub.Storage {
+		return store
+	})
+})
@@ -0,0 +1,1311 @@
+// package storagetest provides the conformance suite of apihub.Storage and of
+// the interfaces storages implement besides it, such as apihub.ServiceLister.
+// The specs of the interfaces a storage does not implement are skipped.
+//
+// Run it from the Ginkgo suite of an implementation:
+//
+//	var _ = Describe("MyStorage", func() {
+//		storagetest.Conformance(func() apihub.Storage {
+//			return mystorage.New()
+//		})
+//	})
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apihub/apihub"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// LargeCollectionSize holds the number of services stored by the large
+// collection specs. Slow implementations may lower it.
+var LargeCollectionSize = 1000
+
+// Concurrency holds the number of goroutines used by the concurrency specs.
+var Concurrency = 20
+
+// Conformance describes the behavior every apihub.Storage must have.
+// newStorage is called before each spec and must return an empty storage.
+func Conformance(newStorage func() apihub.Storage) {
+	Describe("apihub.Storage conformance", func() {
+		var (
+			store apihub.Storage
+			spec  apihub.ServiceSpec
+
+			// The interfaces the storage implements besides apihub.Storage,
+			// or nil. Their specs are skipped for the storages without them.
+			lister           apihub.ServiceLister
+			changer          apihub.ServiceChanger
+			revisionStorage  apihub.RevisionStorage
+			tokenStorage     apihub.TokenStorage
+			namespaceStorage apihub.NamespaceStorage
+			auditStorage     apihub.AuditStorage
+			outboxStorage    apihub.OutboxStorage
+			webhookStorage   apihub.WebhookStorage
+			eventStorage     apihub.ServiceEventStorage
+		)
+
+		BeforeEach(func() {
+			store = newStorage()
+			lister, _ = store.(apihub.ServiceLister)
+			changer, _ = store.(apihub.ServiceChanger)
+			revisionStorage, _ = store.(apihub.RevisionStorage)
+			tokenStorage, _ = store.(apihub.TokenStorage)
+			namespaceStorage, _ = store.(apihub.NamespaceStorage)
+			auditStorage, _ = store.(apihub.AuditStorage)
+			outboxStorage, _ = store.(apihub.OutboxStorage)
+			webhookStorage, _ = store.(apihub.WebhookStorage)
+			eventStorage, _ = store.(apihub.ServiceEventStorage)
+			spec = apihub.ServiceSpec{
+				Host:    "my-host.apihub.dev",
+				Version: 1,
+				Timeout: 100,
+				Backends: []apihub.BackendInfo{
+					apihub.BackendInfo{Address: "http://server-a", HeartBeatAddress: "http://server-a/ping", HeartBeatTimeout: 10},
+					apihub.BackendInfo{Address: "http://server-b", Disabled: true},
+				},
+				Maintenance: &apihub.MaintenanceInfo{Enabled: true, Body: "Back soon.", RetryAfter: 60},
+				ErrorPages: map[int]apihub.ErrorPage{
+					404: apihub.ErrorPage{Body: "Not here.", ContentType: "text/plain"},
+				},
+				Faults: []apihub.FaultRule{
+					apihub.FaultRule{Header: "X-Chaos", Percentage: 50, AbortStatus: 503},
+				},
+			}
+		})
+
+		Describe("AddService", func() {
+			It("keeps every field of the spec", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(Equal(spec))
+			})
+
+			It("stores a spec without a version as version 1", func() {
+				spec.Version = 0
+				Expect(store.AddService(spec)).To(Succeed())
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Version).To(BeEquivalentTo(1))
+			})
+
+			It("rejects a host already in use", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				spec.Disabled = true
+				Expect(store.AddService(spec)).To(MatchError(apihub.ErrHostInUse))
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Disabled).To(BeFalse())
+			})
+
+			It("accepts a host that has been removed", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(store.RemoveService(spec.Host)).To(Succeed())
+				Expect(store.AddService(spec)).To(Succeed())
+			})
+		})
+
+		Describe("UpdateService", func() {
+			It("increases the version of the spec", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(store.UpdateService(spec)).To(Succeed())
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Version).To(BeEquivalentTo(2))
+
+				Expect(store.UpdateService(found)).To(Succeed())
+				found, err = store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Version).To(BeEquivalentTo(3))
+			})
+
+			It("returns ErrVersionConflict for a stale version", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(store.UpdateService(spec)).To(Succeed())
+
+				spec.Disabled = true
+				Expect(store.UpdateService(spec)).To(MatchError(apihub.ErrVersionConflict))
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Disabled).To(BeFalse())
+				Expect(found.Version).To(BeEquivalentTo(2))
+			})
+
+			It("returns ErrVersionConflict for a spec without a version", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				spec.Version = 0
+				spec.Disabled = true
+				Expect(store.UpdateService(spec)).To(MatchError(apihub.ErrVersionConflict))
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Disabled).To(BeFalse())
+				Expect(found.Version).To(BeEquivalentTo(1))
+			})
+
+			It("replaces the spec", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				updated := apihub.ServiceSpec{
+					Host:     spec.Host,
+					Version:  1,
+					Disabled: true,
+					Backends: []apihub.BackendInfo{
+						apihub.BackendInfo{Address: "http://server-c"},
+					},
+				}
+				Expect(store.UpdateService(updated)).To(Succeed())
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				updated.Version = found.Version
+				Expect(found).To(Equal(updated))
+			})
+
+			It("returns ErrServiceNotFound for an unknown host", func() {
+				Expect(store.UpdateService(spec)).To(MatchError(apihub.ErrServiceNotFound))
+
+				_, err := store.FindServiceByHost(spec.Host)
+				Expect(err).To(MatchError(apihub.ErrServiceNotFound))
+			})
+		})
+
+		Describe("FindServiceByHost", func() {
+			It("returns ErrServiceNotFound for an unknown host", func() {
+				_, err := store.FindServiceByHost("not-found.apihub.dev")
+				Expect(err).To(MatchError(apihub.ErrServiceNotFound))
+			})
+
+			It("does not share the returned spec with the storage", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				found.Host = "changed.apihub.dev"
+				found.Timeout = 0
+
+				found, err = store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(Equal(spec))
+			})
+		})
+
+		Describe("Services", func() {
+			It("returns an empty list when there are no services", func() {
+				services, err := store.Services()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(services).NotTo(BeNil())
+				Expect(services).To(BeEmpty())
+			})
+
+			It("lists every service", func() {
+				another := apihub.ServiceSpec{Host: "another-host.apihub.dev", Version: 1}
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(store.AddService(another)).To(Succeed())
+
+				services, err := store.Services()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(services).To(ConsistOf(spec, another))
+			})
+
+			Context("when there are many services", func() {
+				It("lists all of them", func() {
+					hosts := []string{}
+					for i := 0; i < LargeCollectionSize; i++ {
+						host := fmt.Sprintf("host-%d.apihub.dev", i)
+						hosts = append(hosts, host)
+						Expect(store.AddService(apihub.ServiceSpec{Host: host})).To(Succeed())
+					}
+
+					services, err := store.Services()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(services).To(HaveLen(LargeCollectionSize))
+
+					found := []string{}
+					for _, service := range services {
+						found = append(found, service.Host)
+					}
+					Expect(found).To(ConsistOf(hosts))
+				})
+			})
+		})
+
+		Describe("ListServices", func() {
+			BeforeEach(func() {
+				skipUnless(lister != nil, "apihub.ServiceLister")
+			})
+
+			var disabled bool
+
+			hosts := func(page apihub.ServicePage) []string {
+				found := []string{}
+				for _, service := range page.Services {
+					found = append(found, service.Host)
+				}
+				return found
+			}
+
+			// walk lists the services one page at a time, following the cursors.
+			walk := func(query apihub.ServiceQuery) []string {
+				query.Limit = 1
+				found := []string{}
+				for pages := 0; pages <= 10; pages++ {
+					page, err := lister.ListServices(query)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(len(page.Services)).To(BeNumerically("<=", 1))
+					found = append(found, hosts(page)...)
+					if page.NextCursor == "" {
+						break
+					}
+					query.Cursor = page.NextCursor
+				}
+				return found
+			}
+
+			BeforeEach(func() {
+				disabled = true
+				specs := []apihub.ServiceSpec{
+					apihub.ServiceSpec{
+						Host:      "b.apihub.dev",
+						Version:   2,
+						Labels:    map[string]string{"team": "payments", "tier": "gold"},
+						Backends:  []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}},
+						Namespace: "payments",
+					},
+					apihub.ServiceSpec{
+						Host:      "a.apihub.dev",
+						Version:   3,
+						Disabled:  true,
+						Labels:    map[string]string{"team": "payments"},
+						Backends:  []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-b"}},
+						Namespace: "payments",
+					},
+					apihub.ServiceSpec{
+						Host:      "c.apihub.dev",
+						Version:   2,
+						Labels:    map[string]string{"team": "search"},
+						Backends:  []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-a"}, apihub.BackendInfo{Address: "http://server-c"}},
+						Namespace: "search",
+					},
+					apihub.ServiceSpec{
+						Host:    "ab.apihub.io",
+						Version: 1,
+					},
+				}
+				for _, spec := range specs {
+					Expect(store.AddService(spec)).To(Succeed())
+				}
+			})
+
+			It("lists every service sorted by host", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev", "ab.apihub.io", "b.apihub.dev", "c.apihub.dev"}))
+				Expect(page.NextCursor).To(BeEmpty())
+			})
+
+			It("keeps every field of the specs", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{HostPrefix: "c."})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(page.Services).To(HaveLen(1))
+				Expect(page.Services[0].Labels).To(Equal(map[string]string{"team": "search"}))
+				Expect(page.Services[0].Backends).To(HaveLen(2))
+			})
+
+			It("filters by host prefix", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{HostPrefix: "a"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev", "ab.apihub.io"}))
+			})
+
+			It("filters by disabled state", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{Disabled: &disabled})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev"}))
+
+				disabled = false
+				page, err = lister.ListServices(apihub.ServiceQuery{Disabled: &disabled})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"ab.apihub.io", "b.apihub.dev", "c.apihub.dev"}))
+			})
+
+			It("filters by backend address", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{Backend: "http://server-a"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"b.apihub.dev", "c.apihub.dev"}))
+			})
+
+			It("filters by every label", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{Labels: map[string]string{"team": "payments"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev", "b.apihub.dev"}))
+
+				page, err = lister.ListServices(apihub.ServiceQuery{Labels: map[string]string{"team": "payments", "tier": "gold"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"b.apihub.dev"}))
+			})
+
+			It("filters by namespace", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{Namespaces: []string{"payments"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev", "b.apihub.dev"}))
+
+				page, err = lister.ListServices(apihub.ServiceQuery{Namespaces: []string{"search", ""}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"ab.apihub.io", "c.apihub.dev"}))
+			})
+
+			It("filters by the namespace the service was moved to", func() {
+				moved, err := store.FindServiceByHost("c.apihub.dev")
+				Expect(err).NotTo(HaveOccurred())
+				moved.Namespace = "payments"
+				Expect(store.UpdateService(moved)).To(Succeed())
+
+				page, err := lister.ListServices(apihub.ServiceQuery{Namespaces: []string{"payments"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev", "b.apihub.dev", "c.apihub.dev"}))
+			})
+
+			It("sorts by version, then host", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{Sort: "version"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"ab.apihub.io", "b.apihub.dev", "c.apihub.dev", "a.apihub.dev"}))
+
+				page, err = lister.ListServices(apihub.ServiceQuery{Sort: "-version"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev", "c.apihub.dev", "b.apihub.dev", "ab.apihub.io"}))
+			})
+
+			It("walks through the pages with the cursor", func() {
+				Expect(walk(apihub.ServiceQuery{})).To(Equal([]string{"a.apihub.dev", "ab.apihub.io", "b.apihub.dev", "c.apihub.dev"}))
+				Expect(walk(apihub.ServiceQuery{Sort: "-host"})).To(Equal([]string{"c.apihub.dev", "b.apihub.dev", "ab.apihub.io", "a.apihub.dev"}))
+				Expect(walk(apihub.ServiceQuery{Sort: "version"})).To(Equal([]string{"ab.apihub.io", "b.apihub.dev", "c.apihub.dev", "a.apihub.dev"}))
+				Expect(walk(apihub.ServiceQuery{Sort: "-version"})).To(Equal([]string{"a.apihub.dev", "c.apihub.dev", "b.apihub.dev", "ab.apihub.io"}))
+				Expect(walk(apihub.ServiceQuery{HostPrefix: "a"})).To(Equal([]string{"a.apihub.dev", "ab.apihub.io"}))
+			})
+
+			It("does not skip services added after the cursor was returned", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{Limit: 2})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"a.apihub.dev", "ab.apihub.io"}))
+
+				Expect(store.AddService(apihub.ServiceSpec{Host: "aa.apihub.dev"})).To(Succeed())
+				Expect(store.AddService(apihub.ServiceSpec{Host: "bb.apihub.dev"})).To(Succeed())
+
+				page, err = lister.ListServices(apihub.ServiceQuery{Limit: 2, Cursor: page.NextCursor})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hosts(page)).To(Equal([]string{"b.apihub.dev", "bb.apihub.dev"}))
+			})
+
+			It("rejects an invalid cursor", func() {
+				_, err := lister.ListServices(apihub.ServiceQuery{Cursor: "not-a-cursor"})
+				Expect(errors.Is(err, apihub.ErrBadRequest)).To(BeTrue())
+			})
+
+			It("rejects a cursor of another sort order", func() {
+				page, err := lister.ListServices(apihub.ServiceQuery{Limit: 1})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = lister.ListServices(apihub.ServiceQuery{Sort: "version", Cursor: page.NextCursor})
+				Expect(errors.Is(err, apihub.ErrBadRequest)).To(BeTrue())
+			})
+
+			It("rejects an unknown sort field", func() {
+				_, err := lister.ListServices(apihub.ServiceQuery{Sort: "timeout"})
+				Expect(errors.Is(err, apihub.ErrBadRequest)).To(BeTrue())
+			})
+		})
+
+		Describe("RemoveService", func() {
+			It("removes the service", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(store.RemoveService(spec.Host)).To(Succeed())
+
+				_, err := store.FindServiceByHost(spec.Host)
+				Expect(err).To(MatchError(apihub.ErrServiceNotFound))
+				Expect(store.Services()).To(BeEmpty())
+			})
+
+			It("returns ErrServiceNotFound for an unknown host", func() {
+				Expect(store.RemoveService("not-found.apihub.dev")).To(MatchError(apihub.ErrServiceNotFound))
+			})
+
+			It("returns ErrServiceNotFound when removing twice", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(store.RemoveService(spec.Host)).To(Succeed())
+				Expect(store.RemoveService(spec.Host)).To(MatchError(apihub.ErrServiceNotFound))
+			})
+		})
+
+		Describe("ChangeServices", func() {
+			BeforeEach(func() {
+				skipUnless(changer != nil, "apihub.ServiceChanger")
+			})
+
+			var other, removed apihub.ServiceSpec
+
+			BeforeEach(func() {
+				other = apihub.ServiceSpec{Host: "other.apihub.dev", Version: 1, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-c"}}}
+				removed = apihub.ServiceSpec{Host: "removed.apihub.dev", Version: 1, Backends: []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-d"}}}
+				Expect(store.AddService(other)).To(Succeed())
+				Expect(store.AddService(removed)).To(Succeed())
+			})
+
+			It("makes every change", func() {
+				updated := other
+				updated.Disabled = true
+				Expect(changer.ChangeServices([]apihub.ServiceChange{
+					apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: spec},
+					apihub.ServiceChange{Action: apihub.APPLY_UPDATE, Spec: updated},
+					apihub.ServiceChange{Action: apihub.APPLY_DELETE, Spec: apihub.ServiceSpec{Host: removed.Host}},
+				})).To(Succeed())
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(Equal(spec))
+
+				found, err = store.FindServiceByHost(other.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Disabled).To(BeTrue())
+				Expect(found.Version).To(BeEquivalentTo(2))
+
+				if revisionStorage != nil {
+					revisions, err := revisionStorage.Revisions(other.Host)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(revisions).To(HaveLen(2))
+				}
+
+				_, err = store.FindServiceByHost(removed.Host)
+				Expect(err).To(MatchError(apihub.ErrServiceNotFound))
+			})
+
+			It("makes none of the changes when one fails", func() {
+				updated := other
+				updated.Version = 2
+				updated.Disabled = true
+				Expect(changer.ChangeServices([]apihub.ServiceChange{
+					apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: spec},
+					apihub.ServiceChange{Action: apihub.APPLY_DELETE, Spec: apihub.ServiceSpec{Host: removed.Host}},
+					apihub.ServiceChange{Action: apihub.APPLY_UPDATE, Spec: updated},
+				})).To(MatchError(apihub.ErrVersionConflict))
+
+				_, err := store.FindServiceByHost(spec.Host)
+				Expect(err).To(MatchError(apihub.ErrServiceNotFound))
+
+				found, err := store.FindServiceByHost(other.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(Equal(other))
+
+				found, err = store.FindServiceByHost(removed.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(Equal(removed))
+
+				if revisionStorage != nil {
+					revisions, err := revisionStorage.Revisions(removed.Host)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(revisions).To(HaveLen(1))
+				}
+			})
+
+			It("returns the errors of the single changes", func() {
+				Expect(changer.ChangeServices([]apihub.ServiceChange{
+					apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: other},
+				})).To(MatchError(apihub.ErrHostInUse))
+				Expect(changer.ChangeServices([]apihub.ServiceChange{
+					apihub.ServiceChange{Action: apihub.APPLY_DELETE, Spec: spec},
+				})).To(MatchError(apihub.ErrServiceNotFound))
+			})
+
+			It("rejects unknown actions", func() {
+				err := changer.ChangeServices([]apihub.ServiceChange{
+					apihub.ServiceChange{Action: "rename", Spec: other},
+				})
+				Expect(errors.Is(err, apihub.ErrValidation)).To(BeTrue())
+			})
+		})
+
+		Describe("revisions", func() {
+			BeforeEach(func() {
+				skipUnless(revisionStorage != nil, "apihub.RevisionStorage")
+			})
+
+			BeforeEach(func() {
+				Expect(store.AddService(spec)).To(Succeed())
+			})
+
+			It("keeps a revision of every change", func() {
+				updated := spec
+				updated.Backends = []apihub.BackendInfo{apihub.BackendInfo{Address: "http://server-c"}}
+				Expect(store.UpdateService(updated)).To(Succeed())
+				updated.Version = 2
+				updated.Disabled = true
+				Expect(store.UpdateService(updated)).To(Succeed())
+
+				revisions, err := revisionStorage.Revisions(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(revisions).To(HaveLen(3))
+				for i, r := range revisions {
+					Expect(r.Version).To(BeEquivalentTo(i + 1))
+					Expect(r.Spec.Version).To(Equal(r.Version))
+					Expect(r.CreatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+				}
+				Expect(revisions[0].Spec).To(Equal(spec))
+				Expect(revisions[1].Spec.Backends).To(Equal(updated.Backends))
+				Expect(revisions[2].Spec.Disabled).To(BeTrue())
+			})
+
+			It("does not keep rejected changes", func() {
+				stale := spec
+				stale.Version = 5
+				Expect(store.UpdateService(stale)).To(MatchError(apihub.ErrVersionConflict))
+
+				Expect(revisionStorage.Revisions(spec.Host)).To(HaveLen(1))
+			})
+
+			It("finds a revision by its version", func() {
+				updated := spec
+				updated.Timeout = 200
+				Expect(store.UpdateService(updated)).To(Succeed())
+
+				r, err := revisionStorage.FindRevision(spec.Host, 1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(r.Spec).To(Equal(spec))
+
+				r, err = revisionStorage.FindRevision(spec.Host, 2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(r.Spec.Timeout).To(BeEquivalentTo(200))
+
+				_, err = revisionStorage.FindRevision(spec.Host, 3)
+				Expect(err).To(MatchError(apihub.ErrRevisionNotFound))
+				_, err = revisionStorage.FindRevision("not-found.apihub.dev", 1)
+				Expect(err).To(MatchError(apihub.ErrRevisionNotFound))
+			})
+
+			It("returns ErrServiceNotFound for an unknown host", func() {
+				_, err := revisionStorage.Revisions("not-found.apihub.dev")
+				Expect(err).To(MatchError(apihub.ErrServiceNotFound))
+			})
+
+			It("removes the revisions along with the service", func() {
+				Expect(store.RemoveService(spec.Host)).To(Succeed())
+				_, err := revisionStorage.FindRevision(spec.Host, 1)
+				Expect(err).To(MatchError(apihub.ErrRevisionNotFound))
+
+				spec.Timeout = 300
+				Expect(store.AddService(spec)).To(Succeed())
+				revisions, err := revisionStorage.Revisions(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(revisions).To(HaveLen(1))
+				Expect(revisions[0].Spec.Timeout).To(BeEquivalentTo(300))
+			})
+
+			It("keeps the revisions apart from the services", func() {
+				Expect(store.Services()).To(HaveLen(1))
+				if lister != nil {
+					page, err := lister.ListServices(apihub.ServiceQuery{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(page.Services).To(HaveLen(1))
+				}
+			})
+		})
+
+		Describe("tokens", func() {
+			BeforeEach(func() {
+				skipUnless(tokenStorage != nil, "apihub.TokenStorage")
+			})
+
+			var token apihub.Token
+
+			BeforeEach(func() {
+				token = apihub.Token{
+					ID:         "b-token",
+					Name:       "deploys",
+					Role:       apihub.ROLE_EDITOR,
+					Namespaces: []string{"payments", "search"},
+					Hash:       apihub.HashToken("secret"),
+					CreatedAt:  time.Date(2017, 3, 1, 10, 30, 0, 0, time.UTC),
+				}
+			})
+
+			It("finds a token by its hash", func() {
+				Expect(tokenStorage.AddToken(token)).To(Succeed())
+
+				found, err := tokenStorage.FindTokenByHash(apihub.HashToken("secret"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.ID).To(Equal(token.ID))
+				Expect(found.Name).To(Equal(token.Name))
+				Expect(found.Role).To(Equal(token.Role))
+				Expect(found.Namespaces).To(Equal(token.Namespaces))
+				Expect(found.Hash).To(Equal(token.Hash))
+				Expect(found.CreatedAt).To(BeTemporally("==", token.CreatedAt))
+			})
+
+			It("returns ErrTokenNotFound for an unknown hash", func() {
+				_, err := tokenStorage.FindTokenByHash(apihub.HashToken("another-secret"))
+				Expect(err).To(MatchError(apihub.ErrTokenNotFound))
+			})
+
+			It("rejects an ID or a hash already in use", func() {
+				Expect(tokenStorage.AddToken(token)).To(Succeed())
+
+				sameID := token
+				sameID.Hash = apihub.HashToken("another-secret")
+				Expect(tokenStorage.AddToken(sameID)).To(MatchError(apihub.ErrTokenInUse))
+
+				sameHash := token
+				sameHash.ID = "c-token"
+				Expect(tokenStorage.AddToken(sameHash)).To(MatchError(apihub.ErrTokenInUse))
+			})
+
+			It("lists the tokens sorted by ID", func() {
+				Expect(tokenStorage.Tokens()).To(BeEmpty())
+
+				Expect(tokenStorage.AddToken(token)).To(Succeed())
+				another := token
+				another.ID = "a-token"
+				another.Hash = apihub.HashToken("another-secret")
+				Expect(tokenStorage.AddToken(another)).To(Succeed())
+
+				tokens, err := tokenStorage.Tokens()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tokens).To(HaveLen(2))
+				Expect(tokens[0].ID).To(Equal("a-token"))
+				Expect(tokens[1].ID).To(Equal("b-token"))
+			})
+
+			It("keeps the tokens apart from the services", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(tokenStorage.AddToken(token)).To(Succeed())
+
+				Expect(store.Services()).To(HaveLen(1))
+				if lister != nil {
+					page, err := lister.ListServices(apihub.ServiceQuery{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(page.Services).To(HaveLen(1))
+				}
+			})
+
+			It("removes a token", func() {
+				Expect(tokenStorage.AddToken(token)).To(Succeed())
+				Expect(tokenStorage.RemoveToken(token.ID)).To(Succeed())
+
+				_, err := tokenStorage.FindTokenByHash(token.Hash)
+				Expect(err).To(MatchError(apihub.ErrTokenNotFound))
+				Expect(tokenStorage.RemoveToken(token.ID)).To(MatchError(apihub.ErrTokenNotFound))
+			})
+		})
+
+		Describe("namespaces", func() {
+			BeforeEach(func() {
+				skipUnless(namespaceStorage != nil, "apihub.NamespaceStorage")
+			})
+
+			var ns apihub.Namespace
+
+			BeforeEach(func() {
+				ns = apihub.Namespace{Name: "payments", DomainSuffixes: []string{"payments.apihub.dev", "pay.apihub.io"}}
+			})
+
+			It("finds a namespace by its name", func() {
+				Expect(namespaceStorage.AddNamespace(ns)).To(Succeed())
+
+				found, err := namespaceStorage.FindNamespace("payments")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(Equal(ns))
+			})
+
+			It("returns ErrNamespaceNotFound for an unknown name", func() {
+				_, err := namespaceStorage.FindNamespace("search")
+				Expect(err).To(MatchError(apihub.ErrNamespaceNotFound))
+			})
+
+			It("rejects a name already in use", func() {
+				Expect(namespaceStorage.AddNamespace(ns)).To(Succeed())
+				Expect(namespaceStorage.AddNamespace(apihub.Namespace{Name: "payments"})).To(MatchError(apihub.ErrNamespaceInUse))
+			})
+
+			It("lists the namespaces sorted by name", func() {
+				Expect(namespaceStorage.Namespaces()).To(BeEmpty())
+
+				Expect(namespaceStorage.AddNamespace(apihub.Namespace{Name: "search"})).To(Succeed())
+				Expect(namespaceStorage.AddNamespace(ns)).To(Succeed())
+				Expect(namespaceStorage.Namespaces()).To(Equal([]apihub.Namespace{ns, apihub.Namespace{Name: "search"}}))
+			})
+
+			It("keeps the namespaces apart from the services", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				Expect(namespaceStorage.AddNamespace(ns)).To(Succeed())
+
+				Expect(store.Services()).To(HaveLen(1))
+				if lister != nil {
+					page, err := lister.ListServices(apihub.ServiceQuery{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(page.Services).To(HaveLen(1))
+				}
+			})
+
+			It("removes a namespace", func() {
+				Expect(namespaceStorage.AddNamespace(ns)).To(Succeed())
+				Expect(namespaceStorage.RemoveNamespace("payments")).To(Succeed())
+
+				_, err := namespaceStorage.FindNamespace("payments")
+				Expect(err).To(MatchError(apihub.ErrNamespaceNotFound))
+				Expect(namespaceStorage.RemoveNamespace("payments")).To(MatchError(apihub.ErrNamespaceNotFound))
+			})
+		})
+
+		Describe("audit events", func() {
+			BeforeEach(func() {
+				skipUnless(auditStorage != nil, "apihub.AuditStorage")
+			})
+
+			var events []apihub.AuditEvent
+
+			BeforeEach(func() {
+				start := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+				before := apihub.ServiceSpec{Host: "my-host", Version: 1}
+				after := apihub.ServiceSpec{Host: "my-host", Version: 2, Disabled: true}
+				events = []apihub.AuditEvent{
+					apihub.AuditEvent{ID: "e1", Time: start, Actor: "deploys", Action: apihub.AUDIT_ADD_SERVICE, Host: "my-host", RequestID: "r1", After: &before},
+					apihub.AuditEvent{ID: "e2", Time: start.Add(time.Minute), Actor: "oncall", Action: apihub.AUDIT_UPDATE_SERVICE, Host: "my-host", RequestID: "r2", Before: &before, After: &after, Diff: []byte(`{"disabled":true}`)},
+					apihub.AuditEvent{ID: "e3", Time: start.Add(2 * time.Minute), Actor: "deploys", Action: apihub.AUDIT_ADD_SERVICE, Host: "other-host", RequestID: "r3", After: &apihub.ServiceSpec{Host: "other-host", Version: 1}},
+					apihub.AuditEvent{ID: "e4", Time: start.Add(3*time.Minute + time.Nanosecond), Actor: "oncall", Action: apihub.AUDIT_REMOVE_SERVICE, Host: "my-host", RequestID: "r4", Before: &after},
+				}
+				for _, e := range events {
+					Expect(auditStorage.AddAuditEvent(e)).To(Succeed())
+				}
+			})
+
+			ids := func(events []apihub.AuditEvent, err error) []string {
+				Expect(err).NotTo(HaveOccurred())
+				ids := []string{}
+				for _, e := range events {
+					ids = append(ids, e.ID)
+				}
+				return ids
+			}
+
+			It("lists the events newest first", func() {
+				found, err := auditStorage.AuditEvents(apihub.AuditQuery{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(HaveLen(4))
+				Expect(found[0].Time.Equal(events[3].Time)).To(BeTrue())
+				Expect(found[2].ID).To(Equal("e2"))
+				Expect(found[2].Before).To(Equal(events[1].Before))
+				Expect(found[2].After).To(Equal(events[1].After))
+				Expect(string(found[2].Diff)).To(Equal(`{"disabled":true}`))
+				Expect(ids(found, err)).To(Equal([]string{"e4", "e3", "e2", "e1"}))
+			})
+
+			It("filters the events", func() {
+				Expect(ids(auditStorage.AuditEvents(apihub.AuditQuery{Host: "my-host"}))).To(Equal([]string{"e4", "e2", "e1"}))
+				Expect(ids(auditStorage.AuditEvents(apihub.AuditQuery{Actor: "deploys"}))).To(Equal([]string{"e3", "e1"}))
+				Expect(ids(auditStorage.AuditEvents(apihub.AuditQuery{Host: "my-host", Action: apihub.AUDIT_ADD_SERVICE}))).To(Equal([]string{"e1"}))
+				Expect(ids(auditStorage.AuditEvents(apihub.AuditQuery{Since: events[1].Time, Until: events[2].Time}))).To(Equal([]string{"e3", "e2"}))
+				Expect(ids(auditStorage.AuditEvents(apihub.AuditQuery{Host: "unknown-host"}))).To(BeEmpty())
+			})
+
+			It("limits the number of events", func() {
+				Expect(ids(auditStorage.AuditEvents(apihub.AuditQuery{Limit: 2}))).To(Equal([]string{"e4", "e3"}))
+				Expect(ids(auditStorage.AuditEvents(apihub.AuditQuery{Actor: "oncall", Limit: 1}))).To(Equal([]string{"e4"}))
+			})
+
+			It("keeps the events apart from the services", func() {
+				Expect(store.Services()).To(BeEmpty())
+				page, err := lister.ListServices(apihub.ServiceQuery{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(page.Services).To(BeEmpty())
+			})
+		})
+
+		Describe("outbox", func() {
+			BeforeEach(func() {
+				skipUnless(outboxStorage != nil, "apihub.OutboxStorage")
+			})
+
+			var entry apihub.OutboxEntry
+
+			BeforeEach(func() {
+				entry = apihub.OutboxEntry{
+					ID:        "e1",
+					Host:      "my-host",
+					CreatedAt: time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+				}
+				Expect(outboxStorage.PutOutboxEntry(entry)).To(Succeed())
+				Expect(outboxStorage.PutOutboxEntry(apihub.OutboxEntry{ID: "e2", Host: "another-host"})).To(Succeed())
+			})
+
+			It("lists the entries sorted by host", func() {
+				entries, err := outboxStorage.OutboxEntries()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Host).To(Equal("another-host"))
+				Expect(entries[1].ID).To(Equal("e1"))
+				Expect(entries[1].CreatedAt.Equal(entry.CreatedAt)).To(BeTrue())
+			})
+
+			It("replaces the entry of the host", func() {
+				entry.ID = "e3"
+				entry.Attempts = 2
+				entry.LastError = "connection refused"
+				Expect(outboxStorage.PutOutboxEntry(entry)).To(Succeed())
+
+				entries, err := outboxStorage.OutboxEntries()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[1].ID).To(Equal("e3"))
+				Expect(entries[1].Attempts).To(Equal(2))
+				Expect(entries[1].LastError).To(Equal("connection refused"))
+			})
+
+			It("removes the entry only when it has the ID", func() {
+				Expect(outboxStorage.RemoveOutboxEntry("my-host", "e3")).To(Succeed())
+				Expect(outboxStorage.OutboxEntries()).To(HaveLen(2))
+
+				Expect(outboxStorage.RemoveOutboxEntry("my-host", "e1")).To(Succeed())
+				entries, err := outboxStorage.OutboxEntries()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Host).To(Equal("another-host"))
+
+				Expect(outboxStorage.RemoveOutboxEntry("my-host", "e1")).To(Succeed())
+			})
+
+			It("keeps the entries apart from the services", func() {
+				Expect(store.Services()).To(BeEmpty())
+			})
+		})
+
+		Describe("webhooks", func() {
+			BeforeEach(func() {
+				skipUnless(webhookStorage != nil, "apihub.WebhookStorage")
+			})
+
+			var webhook apihub.Webhook
+
+			BeforeEach(func() {
+				webhook = apihub.Webhook{
+					ID:        "b-webhook",
+					URL:       "https://deploy-bot.example.com/hooks",
+					Events:    []string{apihub.WEBHOOK_SERVICE_CREATED, apihub.WEBHOOK_SERVICE_DELETED},
+					Secret:    "secret",
+					CreatedAt: time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+				}
+				Expect(webhookStorage.AddWebhook(webhook)).To(Succeed())
+			})
+
+			It("finds a webhook by its ID", func() {
+				found, err := webhookStorage.FindWebhook("b-webhook")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.URL).To(Equal(webhook.URL))
+				Expect(found.Events).To(Equal(webhook.Events))
+				Expect(found.Secret).To(Equal(webhook.Secret))
+				Expect(found.CreatedAt).To(BeTemporally("==", webhook.CreatedAt))
+
+				_, err = webhookStorage.FindWebhook("unknown")
+				Expect(err).To(MatchError(apihub.ErrWebhookNotFound))
+			})
+
+			It("rejects an ID already in use", func() {
+				Expect(webhookStorage.AddWebhook(webhook)).To(MatchError(apihub.ErrWebhookInUse))
+			})
+
+			It("lists the webhooks sorted by ID", func() {
+				Expect(webhookStorage.AddWebhook(apihub.Webhook{ID: "a-webhook", URL: "https://chat.example.com"})).To(Succeed())
+
+				webhooks, err := webhookStorage.Webhooks()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(webhooks).To(HaveLen(2))
+				Expect(webhooks[0].ID).To(Equal("a-webhook"))
+				Expect(webhooks[1].ID).To(Equal("b-webhook"))
+			})
+
+			It("lists the deliveries of a webhook newest first", func() {
+				first := apihub.WebhookDelivery{
+					ID:        "d1",
+					WebhookID: "b-webhook",
+					Event:     apihub.WEBHOOK_SERVICE_CREATED,
+					Host:      "my-host",
+					Status:    apihub.DELIVERY_PENDING,
+					Payload:   []byte(`{"event":"service.created"}`),
+					CreatedAt: time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+				}
+				second := first
+				second.ID = "d2"
+				second.CreatedAt = first.CreatedAt.Add(time.Second)
+				Expect(webhookStorage.PutWebhookDelivery(first)).To(Succeed())
+				Expect(webhookStorage.PutWebhookDelivery(second)).To(Succeed())
+				Expect(webhookStorage.PutWebhookDelivery(apihub.WebhookDelivery{ID: "d3", WebhookID: "another-webhook"})).To(Succeed())
+
+				first.Status = apihub.DELIVERY_DELIVERED
+				first.Attempts = 1
+				first.ResponseStatus = 200
+				Expect(webhookStorage.PutWebhookDelivery(first)).To(Succeed())
+
+				deliveries, err := webhookStorage.WebhookDeliveries("b-webhook")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deliveries).To(HaveLen(2))
+				Expect(deliveries[0].ID).To(Equal("d2"))
+				Expect(deliveries[1].ID).To(Equal("d1"))
+				Expect(deliveries[1].Status).To(Equal(apihub.DELIVERY_DELIVERED))
+				Expect(deliveries[1].ResponseStatus).To(Equal(200))
+				Expect(string(deliveries[1].Payload)).To(MatchJSON(`{"event":"service.created"}`))
+			})
+
+			Context("with deliveries of several webhooks", func() {
+				var now time.Time
+
+				BeforeEach(func() {
+					now = time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+					deliveries := []apihub.WebhookDelivery{
+						apihub.WebhookDelivery{ID: "due-b", WebhookID: "b-webhook", Status: apihub.DELIVERY_PENDING, CreatedAt: now.Add(-time.Hour), NextAttempt: now},
+						apihub.WebhookDelivery{ID: "due-a", WebhookID: "a-webhook", Status: apihub.DELIVERY_PENDING, CreatedAt: now.Add(-2 * time.Hour), NextAttempt: now.Add(-time.Minute)},
+						apihub.WebhookDelivery{ID: "later", WebhookID: "b-webhook", Status: apihub.DELIVERY_PENDING, CreatedAt: now.Add(-3 * time.Hour), NextAttempt: now.Add(time.Minute)},
+						apihub.WebhookDelivery{ID: "delivered", WebhookID: "b-webhook", Status: apihub.DELIVERY_DELIVERED, CreatedAt: now.Add(-3 * time.Hour), NextAttempt: now.Add(-time.Hour)},
+						apihub.WebhookDelivery{ID: "failed", WebhookID: "a-webhook", Status: apihub.DELIVERY_FAILED, CreatedAt: now.Add(-time.Minute), NextAttempt: now.Add(-time.Hour)},
+					}
+					for _, d := range deliveries {
+						Expect(webhookStorage.PutWebhookDelivery(d)).To(Succeed())
+					}
+				})
+
+				It("lists the pending deliveries due at a time, oldest first", func() {
+					due, err := webhookStorage.DueWebhookDeliveries(now)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(due).To(HaveLen(2))
+					Expect(due[0].ID).To(Equal("due-a"))
+					Expect(due[1].ID).To(Equal("due-b"))
+				})
+
+				It("prunes the delivered and failed deliveries created before a time", func() {
+					Expect(webhookStorage.PruneWebhookDeliveries(now.Add(-time.Hour))).To(Succeed())
+
+					deliveries, err := webhookStorage.WebhookDeliveries("b-webhook")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(deliveries).To(HaveLen(2))
+					Expect(deliveries[0].ID).To(Equal("due-b"))
+					Expect(deliveries[1].ID).To(Equal("later"))
+
+					deliveries, err = webhookStorage.WebhookDeliveries("a-webhook")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(deliveries).To(HaveLen(2))
+				})
+			})
+
+			It("removes a webhook along with its deliveries", func() {
+				Expect(webhookStorage.PutWebhookDelivery(apihub.WebhookDelivery{ID: "d1", WebhookID: "b-webhook"})).To(Succeed())
+
+				Expect(webhookStorage.RemoveWebhook("b-webhook")).To(Succeed())
+				Expect(webhookStorage.Webhooks()).To(BeEmpty())
+				Expect(webhookStorage.WebhookDeliveries("b-webhook")).To(BeEmpty())
+
+				Expect(webhookStorage.RemoveWebhook("b-webhook")).To(MatchError(apihub.ErrWebhookNotFound))
+			})
+
+			It("keeps the webhooks apart from the services", func() {
+				Expect(webhookStorage.PutWebhookDelivery(apihub.WebhookDelivery{ID: "d1", WebhookID: "b-webhook"})).To(Succeed())
+				Expect(store.Services()).To(BeEmpty())
+			})
+		})
+
+		Describe("service events", func() {
+			BeforeEach(func() {
+				skipUnless(eventStorage != nil, "apihub.ServiceEventStorage")
+			})
+
+			It("starts at version 0 without events", func() {
+				version, err := eventStorage.ServiceEventsVersion()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(BeZero())
+
+				events, err := eventStorage.ServiceEvents(0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(BeEmpty())
+			})
+
+			It("logs an event per change to a service, oldest first", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				updated := spec
+				updated.Disabled = true
+				Expect(store.UpdateService(updated)).To(Succeed())
+				Expect(store.RemoveService(spec.Host)).To(Succeed())
+
+				version, err := eventStorage.ServiceEventsVersion()
+				Expect(err).NotTo(HaveOccurred())
+
+				events, err := eventStorage.ServiceEvents(0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(HaveLen(3))
+				Expect(events[0].Type).To(Equal(apihub.WATCH_ADDED))
+				Expect(events[0].Before).To(BeNil())
+				Expect(*events[0].After).To(Equal(spec))
+				Expect(events[1].Type).To(Equal(apihub.WATCH_UPDATED))
+				Expect(*events[1].Before).To(Equal(spec))
+				Expect(events[1].After.Disabled).To(BeTrue())
+				Expect(events[1].After.Version).To(BeEquivalentTo(2))
+				Expect(events[2].Type).To(Equal(apihub.WATCH_REMOVED))
+				Expect(events[2].Before.Version).To(BeEquivalentTo(2))
+				Expect(events[2].After).To(BeNil())
+				Expect(events[0].Version).To(BeNumerically("<", events[1].Version))
+				Expect(events[1].Version).To(BeNumerically("<", events[2].Version))
+				Expect(events[2].Version).To(Equal(version))
+
+				events, err = eventStorage.ServiceEvents(version - 1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].Type).To(Equal(apihub.WATCH_REMOVED))
+			})
+
+			It("does not log the changes that fail", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				version, err := eventStorage.ServiceEventsVersion()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(store.AddService(spec)).To(MatchError(apihub.ErrHostInUse))
+				Expect(store.UpdateService(apihub.ServiceSpec{Host: spec.Host, Version: 2})).To(MatchError(apihub.ErrVersionConflict))
+				Expect(store.RemoveService("missing.apihub.dev")).To(MatchError(apihub.ErrServiceNotFound))
+
+				events, err := eventStorage.ServiceEvents(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(BeEmpty())
+			})
+
+			It("logs the changes made at once, and none when one fails", func() {
+				if changer == nil {
+					Skip("the storage does not implement apihub.ServiceChanger")
+				}
+				other := apihub.ServiceSpec{Host: "other.apihub.dev", Version: 1}
+				Expect(changer.ChangeServices([]apihub.ServiceChange{
+					apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: spec},
+					apihub.ServiceChange{Action: apihub.APPLY_CREATE, Spec: other},
+				})).To(Succeed())
+
+				version, err := eventStorage.ServiceEventsVersion()
+				Expect(err).NotTo(HaveOccurred())
+				events, err := eventStorage.ServiceEvents(0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(HaveLen(2))
+
+				Expect(changer.ChangeServices([]apihub.ServiceChange{
+					apihub.ServiceChange{Action: apihub.APPLY_DELETE, Spec: spec},
+					apihub.ServiceChange{Action: apihub.APPLY_UPDATE, Spec: apihub.ServiceSpec{Host: other.Host, Version: 2}},
+				})).To(MatchError(apihub.ErrVersionConflict))
+
+				events, err = eventStorage.ServiceEvents(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(BeEmpty())
+			})
+
+			It("fails for the versions it never logged", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+				version, err := eventStorage.ServiceEventsVersion()
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = eventStorage.ServiceEvents(version + 1)
+				Expect(err).To(MatchError(apihub.ErrServiceEventsPruned))
+			})
+		})
+
+		Describe("concurrency", func() {
+			It("lets a single concurrent add of the same host succeed", func() {
+				var (
+					wg        sync.WaitGroup
+					succeeded int32
+				)
+				for i := 0; i < Concurrency; i++ {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						err := store.AddService(spec)
+						if err == nil {
+							atomic.AddInt32(&succeeded, 1)
+						} else {
+							Expect(err).To(MatchError(apihub.ErrHostInUse))
+						}
+					}()
+				}
+				wg.Wait()
+
+				Expect(succeeded).To(BeEquivalentTo(1))
+			})
+
+			It("keeps the services added concurrently", func() {
+				var wg sync.WaitGroup
+				for i := 0; i < Concurrency; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						Expect(store.AddService(apihub.ServiceSpec{Host: fmt.Sprintf("host-%d.apihub.dev", i)})).To(Succeed())
+					}(i)
+				}
+				wg.Wait()
+
+				Expect(store.Services()).To(HaveLen(Concurrency))
+			})
+
+			It("logs the services added concurrently with distinct versions", func() {
+				if eventStorage == nil {
+					Skip("the storage does not implement apihub.ServiceEventStorage")
+				}
+
+				var wg sync.WaitGroup
+				for i := 0; i < Concurrency; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						Expect(store.AddService(apihub.ServiceSpec{Host: fmt.Sprintf("host-%d.apihub.dev", i)})).To(Succeed())
+					}(i)
+				}
+				wg.Wait()
+
+				events, err := eventStorage.ServiceEvents(0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(HaveLen(Concurrency))
+				for i := 1; i < len(events); i++ {
+					Expect(events[i].Version).To(BeNumerically(">", events[i-1].Version))
+				}
+			})
+
+			It("keeps one of the specs written by concurrent updates", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				var wg sync.WaitGroup
+				for i := 0; i < Concurrency; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						// The updates of the same version are rejected but
+						// one, and never corrupt the spec.
+						found, err := store.FindServiceByHost(spec.Host)
+						Expect(err).NotTo(HaveOccurred())
+						store.UpdateService(apihub.ServiceSpec{Host: spec.Host, Version: found.Version, Timeout: time.Duration(100 + i)})
+						_, err = store.FindServiceByHost(spec.Host)
+						Expect(err).NotTo(HaveOccurred())
+					}(i)
+				}
+				wg.Wait()
+
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Backends).To(BeEmpty())
+				Expect(found.Timeout).To(BeNumerically(">=", 100))
+				Expect(found.Timeout).To(BeNumerically("<", 100+Concurrency))
+			})
+
+			It("lets a single concurrent update of the same version succeed", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				var (
+					wg        sync.WaitGroup
+					succeeded int32
+				)
+				for i := 0; i < Concurrency; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						err := store.UpdateService(apihub.ServiceSpec{Host: spec.Host, Version: 1, Timeout: time.Duration(100 + i)})
+						if err == nil {
+							atomic.AddInt32(&succeeded, 1)
+						} else {
+							Expect(err).To(MatchError(apihub.ErrVersionConflict))
+						}
+					}(i)
+				}
+				wg.Wait()
+
+				Expect(succeeded).To(BeEquivalentTo(1))
+				found, err := store.FindServiceByHost(spec.Host)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Version).To(BeEquivalentTo(2))
+			})
+
+			It("removes a service once when removed concurrently", func() {
+				Expect(store.AddService(spec)).To(Succeed())
+
+				var (
+					wg        sync.WaitGroup
+					succeeded int32
+				)
+				for i := 0; i < Concurrency; i++ {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						err := store.RemoveService(spec.Host)
+						if err == nil {
+							atomic.AddInt32(&succeeded, 1)
+						} else {
+							Expect(err).To(MatchError(apihub.ErrServiceNotFound))
+						}
+					}()
+				}
+				wg.Wait()
+
+				Expect(succeeded).To(BeEquivalentTo(1))
+				Expect(store.Services()).To(BeEmpty())
+			})
+
+			It("removes a webhook once when removed concurrently", func() {
+				skipUnless(webhookStorage != nil, "apihub.WebhookStorage")
+				Expect(webhookStorage.AddWebhook(apihub.Webhook{ID: "my-webhook", URL: "http://hooks.apihub.dev"})).To(Succeed())
+
+				var (
+					wg        sync.WaitGroup
+					succeeded int32
+				)
+				for i := 0; i < Concurrency; i++ {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						err := webhookStorage.RemoveWebhook("my-webhook")
+						if err == nil {
+							atomic.AddInt32(&succeeded, 1)
+						} else {
+							Expect(err).To(MatchError(apihub.ErrWebhookNotFound))
+						}
+					}()
+				}
+				wg.Wait()
+
+				Expect(succeeded).To(BeEquivalentTo(1))
+				Expect(webhookStorage.Webhooks()).To(BeEmpty())
+			})
+		})
+	})
+}
+
+// skipUnless skips the spec when the storage does not implement the
+// interface it describes.
+func skipUnless(implemented bool, iface string) {
+	if !implemented {
+		Skip("the storage does not implement " + iface)
+	}
+}